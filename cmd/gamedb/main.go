@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/pkg/logger"
+)
+
+// gamedb nes20dbのXMLをcartridgeのゲームデータベース(gamedb.tsv)に変換する
+// usage: go run ./cmd/gamedb nes20db.xml > internal/domain/cartridge/gamedb.tsv
+// doc: https://forums.nesdev.org/viewtopic.php?t=19940
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: gamedb <nes20db.xml>")
+		os.Exit(2)
+	}
+	if err := convert(os.Args[1], os.Stdout); err != nil {
+		logger.Logger.Error("gamedb: failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+type nes20db struct {
+	Games []game `xml:"game"`
+}
+
+// game nes20dbの1件、ゲーム名はコメントに書かれている
+type game struct {
+	Comment  string  `xml:",comment"`
+	ROM      hash    `xml:"rom"`
+	PRGRAM   size    `xml:"prgram"`
+	PRGNVRAM size    `xml:"prgnvram"`
+	CHRRAM   size    `xml:"chrram"`
+	CHRNVRAM size    `xml:"chrnvram"`
+	MiscROM  *size   `xml:"miscrom"`
+	PCB      pcb     `xml:"pcb"`
+	Console  console `xml:"console"`
+}
+
+type hash struct {
+	CRC32 string `xml:"crc32,attr"`
+	SHA1  string `xml:"sha1,attr"`
+}
+
+type size struct {
+	Size int `xml:"size,attr"`
+}
+
+type pcb struct {
+	Mapper    int    `xml:"mapper,attr"`
+	Submapper int    `xml:"submapper,attr"`
+	Mirroring string `xml:"mirroring,attr"`
+	Battery   int    `xml:"battery,attr"`
+}
+
+type console struct {
+	Region int `xml:"region,attr"`
+}
+
+func convert(path string, out io.Writer) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed read xml. path: %s, err: %w", path, err)
+	}
+	var db nes20db
+	if err := xml.Unmarshal(data, &db); err != nil {
+		return fmt.Errorf("failed parse xml. err: %w", err)
+	}
+	if len(db.Games) == 0 {
+		return errors.New("no game is found")
+	}
+
+	var lines []string
+	for _, g := range db.Games {
+		// cartridgeはPRG-ROMとCHR-ROMだけのハッシュで引くので、miscellaneous ROMを含むハッシュは使えない
+		if g.MiscROM != nil || g.ROM.CRC32 == "" || g.ROM.SHA1 == "" {
+			continue
+		}
+		lines = append(lines, strings.Join([]string{
+			strings.ToUpper(g.ROM.CRC32),
+			strings.ToUpper(g.ROM.SHA1),
+			fmt.Sprint(g.PCB.Mapper),
+			fmt.Sprint(g.PCB.Submapper),
+			mirroring(g.PCB.Mirroring),
+			fmt.Sprint(g.PCB.Battery),
+			fmt.Sprint(g.PRGRAM.Size),
+			fmt.Sprint(g.PRGNVRAM.Size),
+			fmt.Sprint(g.CHRRAM.Size),
+			fmt.Sprint(g.CHRNVRAM.Size),
+			fmt.Sprint(g.Console.Region),
+			name(g.Comment),
+		}, "\t"))
+	}
+	sort.Strings(lines)
+
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, "# ゲームデータベース、parseGameDBを参照")
+	fmt.Fprintln(w, "# crc32\tsha1\tmapper\tsubmapper\tmirroring\tbattery\tprgram\tprgnvram\tchrram\tchrnvram\ttiming\tname")
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	return w.Flush()
+}
+
+// mirroring H, V, 4以外はmapperが切り替える(M)
+func mirroring(value string) string {
+	switch value {
+	case "H", "V", "4":
+		return value
+	}
+	return "M"
+}
+
+// name コメントのパスからゲーム名を取り出す 例: "\nGames\Super Mario Bros. (World).nes\n"
+func name(comment string) string {
+	comment = strings.TrimSpace(comment)
+	if i := strings.LastIndexAny(comment, `\/`); i >= 0 {
+		comment = comment[i+1:]
+	}
+	comment = strings.TrimSuffix(comment, ".nes")
+	return strings.Join(strings.Fields(comment), " ")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestConvert$ github.com/sunjin110/nes_emu/cmd/gamedb
+func TestConvert(t *testing.T) {
+	Convey("TestConvert", t, func() {
+		// PRG-ROM 16KB, CHR-ROM 8KB
+		rom := make([]byte, 0x4000+0x2000)
+		for i := range rom {
+			rom[i] = byte(i * 7)
+		}
+		crc := fmt.Sprintf("%08x", crc32.ChecksumIEEE(rom))
+		sum := fmt.Sprintf("%x", sha1.Sum(rom))
+
+		xml := `<?xml version="1.0" encoding="UTF-8"?>
+<nes20db>
+	<game>
+		<!-- Games\Licensed\Test Game (Europe).nes -->
+		<rom size="24576" crc32="` + crc + `" sha1="` + sum + `"/>
+		<prgram size="8192"/>
+		<prgnvram size="8192"/>
+		<pcb mapper="1" submapper="5" mirroring="H" battery="1"/>
+		<console type="0" region="1"/>
+	</game>
+	<game>
+		<!-- Games\Licensed\Alpha.nes -->
+		<rom size="24576" crc32="0a0b0c0d" sha1="0102030405060708090a0b0c0d0e0f1011121314"/>
+		<chrram size="8192"/>
+		<pcb mapper="4" submapper="0" mirroring="4" battery="0"/>
+		<console type="0" region="0"/>
+	</game>
+	<game>
+		<!-- Games\Licensed\Mapper Mirroring.nes -->
+		<rom size="24576" crc32="ffffffff" sha1="ffffffffffffffffffffffffffffffffffffffff"/>
+		<pcb mapper="7" submapper="0" mirroring="1" battery="0"/>
+		<console type="0" region="3"/>
+	</game>
+	<game>
+		<!-- Games\Unlicensed\With Misc ROM.nes -->
+		<rom size="25600" crc32="12345678" sha1="1234567812345678123456781234567812345678"/>
+		<miscrom size="1024" number="1"/>
+		<pcb mapper="0" submapper="0" mirroring="V" battery="0"/>
+		<console type="0" region="0"/>
+	</game>
+</nes20db>
+`
+		path := filepath.Join(t.TempDir(), "nes20db.xml")
+		So(os.WriteFile(path, []byte(xml), 0o644), ShouldBeNil)
+
+		var out bytes.Buffer
+		So(convert(path, &out), ShouldBeNil)
+
+		Convey("miscellaneous ROMのあるゲームを除いて、ハッシュの順に並べる", func() {
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			So(lines[0], ShouldStartWith, "#")
+			So(lines[1], ShouldStartWith, "#")
+			So(lines[2:], ShouldHaveLength, 3)
+			So(lines[2], ShouldEqual, "0A0B0C0D\t0102030405060708090A0B0C0D0E0F1011121314\t4\t0\t4\t0\t0\t0\t8192\t0\t0\tAlpha")
+			So(lines[len(lines)-1], ShouldEqual, "FFFFFFFF\tFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF\t7\t0\tM\t0\t0\t0\t0\t0\t3\tMapper Mirroring")
+			So(out.String(), ShouldNotContainSubstring, "With Misc ROM")
+		})
+
+		Convey("書き出したTSVをcartridgeのゲームデータベースとして読み込める", func() {
+			So(cartridge.SetGameDB(out.Bytes()), ShouldBeNil)
+
+			// ヘッダーはmapper 0, 垂直ミラーリング, バッテリーなし
+			header := []byte{'N', 'E', 'S', 0x1A, 1, 1, 0x01, 0x00, 0, 0, 0, 0, 0, 0, 0, 0}
+			cart, err := cartridge.NewCartridge(append(header, rom...))
+			So(err, ShouldBeNil)
+			So(cart.Game, ShouldNotBeNil)
+			So(cart.Game.Name, ShouldEqual, "Test Game (Europe)")
+			So(cart.Board(), ShouldResemble, cartridge.Board{
+				MapperNo:     1,
+				Submapper:    5,
+				Mirroring:    ppu.MirroringHorizontal,
+				Battery:      true,
+				PRGRAMSize:   0x2000,
+				PRGNVRAMSize: 0x2000,
+				Timing:       cartridge.TimingPAL,
+			})
+		})
+
+		Convey("ゲームがないXMLはエラー", func() {
+			empty := filepath.Join(t.TempDir(), "empty.xml")
+			So(os.WriteFile(empty, []byte("<nes20db></nes20db>"), 0o644), ShouldBeNil)
+			So(convert(empty, &bytes.Buffer{}), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/fds"
+	"github.com/sunjin110/nes_emu/internal/domain/patch"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/file"
+)
+
+// loadFDS ディスクのイメージとBIOS(disksys.rom)からディスクシステムを作る
+// .savファイル(IPS)があれば、元のイメージに当ててから読み込む
+func loadFDS(image []byte, biosPath, savePath string) (*fds.FDS, *diskSave, error) {
+	if biosPath == "" {
+		return nil, nil, fmt.Errorf("FDS requires BIOS. e.g. --fds-bios disksys.rom")
+	}
+	bios, err := os.ReadFile(biosPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed read FDS BIOS. path: %s, err: %w", biosPath, err)
+	}
+
+	diff, err := file.LoadSaveFile(savePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	current := image
+	if diff != nil {
+		if current, err = patch.ApplyIPS(image, diff); err != nil {
+			return nil, nil, fmt.Errorf("failed apply save file. path: %s, err: %w", savePath, err)
+		}
+	}
+
+	disk, err := fds.ParseDisk(current)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed parse disk. err: %w", err)
+	}
+	device, err := fds.New(bios, disk)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed new FDS. err: %w", err)
+	}
+	return device, &diskSave{path: savePath, device: device, original: image, saved: current}, nil
+}
+
+// diskSave ディスクへの書き込みを、元のイメージとの差分(IPS)として.savファイルに保存する
+type diskSave struct {
+	path     string
+	device   *fds.FDS
+	original []byte
+	// saved 最後に保存した時のイメージ、変わっていない場合は書き込まない
+	saved []byte
+}
+
+func (s *diskSave) flush() error {
+	if !s.device.Modified() {
+		return nil
+	}
+	image := s.device.Image()
+	if bytes.Equal(image, s.saved) {
+		return nil
+	}
+	diff, err := patch.CreateIPS(s.original, image)
+	if err != nil {
+		return fmt.Errorf("failed create disk diff. err: %w", err)
+	}
+	if err := file.WriteSaveFile(s.path, diff); err != nil {
+		return err
+	}
+	s.saved = image
+	return nil
+}
+
+// diskSchedule フレームごとのディスクの入れ替え、値は面の番号(ejectの場合は-1)
+// 例: --disk 600:eject,700:1B
+type diskSchedule map[int]int
+
+const diskEject = -1
+
+func parseDiskSchedule(value string) (diskSchedule, error) {
+	schedule := diskSchedule{}
+	for _, item := range splitList(value) {
+		frameText, sideText, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid --disk. expected <frame>:<side>. value: %s", item)
+		}
+		frame, err := strconv.Atoi(frameText)
+		if err != nil || frame < 0 {
+			return nil, fmt.Errorf("invalid --disk. frame must be a non-negative number. value: %s", item)
+		}
+		side, err := parseDiskSide(sideText)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --disk. err: %w", err)
+		}
+		schedule[frame] = side
+	}
+	return schedule, nil
+}
+
+// parseDiskSide eject、または1A, 1B, 2A...(ディスクの番号と面)
+func parseDiskSide(value string) (int, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "EJECT" {
+		return diskEject, nil
+	}
+	if len(value) < 2 {
+		return 0, fmt.Errorf("side must be eject or <disk><A|B>. value: %s", value)
+	}
+	disk, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || disk < 1 {
+		return 0, fmt.Errorf("side must be eject or <disk><A|B>. value: %s", value)
+	}
+	switch value[len(value)-1] {
+	case 'A':
+		return (disk - 1) * 2, nil
+	case 'B':
+		return (disk-1)*2 + 1, nil
+	}
+	return 0, fmt.Errorf("side must be eject or <disk><A|B>. value: %s", value)
+}
+
+// diskSideName parseDiskSideの逆、0 -> 1A
+func diskSideName(side int) string {
+	return fmt.Sprintf("%d%c", side/2+1, 'A'+side%2)
+}
+
+// apply frameの開始時にディスクを入れ替える
+func (s diskSchedule) apply(frame int, device *fds.FDS) error {
+	side, ok := s[frame]
+	if !ok {
+		return nil
+	}
+	if side == diskEject {
+		device.Eject()
+		return nil
+	}
+	return device.Insert(side)
+}
+
+// validate 存在しない面を指定していないか
+func (s diskSchedule) validate(sideCount int) error {
+	for frame, side := range s {
+		if side >= sideCount {
+			return fmt.Errorf("invalid --disk. disk has %d sides. frame: %d, side: %s", sideCount, frame, diskSideName(side))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/file"
+)
+
+// parseInterspersed flagと位置引数が混在していても読み込めるようにする
+// 例: nes nsf render song.nsf --track 2
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// channelFlags チャンネルごとのミュート・ソロ・音量のflag
+// 例: --mute noise,dmc --solo pulse1 --volume triangle=0.5,vrc6-saw=2
+type channelFlags struct {
+	mute   *string
+	solo   *string
+	volume *string
+}
+
+func addChannelFlags(fs *flag.FlagSet) *channelFlags {
+	return &channelFlags{
+		mute:   fs.String("mute", "", "ミュートするチャンネル(カンマ区切り 例: noise,dmc)"),
+		solo:   fs.String("solo", "", "ソロにするチャンネル(カンマ区切り 例: pulse1,vrc6-saw)"),
+		volume: fs.String("volume", "", "チャンネルごとの音量の倍率(カンマ区切り 例: triangle=0.5,dmc=2)"),
+	}
+}
+
+// controls flagからapu.ChannelControlsを作る
+func (f *channelFlags) controls() (apu.ChannelControls, error) {
+	controls := apu.NewChannelControls()
+
+	for _, name := range splitList(*f.mute) {
+		channel, err := apu.ParseChannel(name)
+		if err != nil {
+			return controls, fmt.Errorf("invalid --mute. err: %w", err)
+		}
+		if err := controls.SetMute(channel, true); err != nil {
+			return controls, fmt.Errorf("invalid --mute. err: %w", err)
+		}
+	}
+	for _, name := range splitList(*f.solo) {
+		channel, err := apu.ParseChannel(name)
+		if err != nil {
+			return controls, fmt.Errorf("invalid --solo. err: %w", err)
+		}
+		if err := controls.SetSolo(channel, true); err != nil {
+			return controls, fmt.Errorf("invalid --solo. err: %w", err)
+		}
+	}
+	for _, item := range splitList(*f.volume) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return controls, fmt.Errorf("invalid --volume. expected <channel>=<volume>. value: %s", item)
+		}
+		channel, err := apu.ParseChannel(name)
+		if err != nil {
+			return controls, fmt.Errorf("invalid --volume. err: %w", err)
+		}
+		volume, err := strconv.ParseFloat(value, 32)
+		if err != nil || volume < 0 {
+			return controls, fmt.Errorf("invalid --volume. volume must be a non-negative number. value: %s", item)
+		}
+		if err := controls.SetVolume(channel, float32(volume)); err != nil {
+			return controls, fmt.Errorf("invalid --volume. err: %w", err)
+		}
+	}
+	return controls, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// inputFlags コントローラーポートと拡張端子に接続するデバイスのflag
+// 例: --port2 zapper --expansion keyboard
+type inputFlags struct {
+	port1        *string
+	port2        *string
+	expansion    *string
+	inputConfig  *string
+	zapperScript *string
+}
+
+func addInputFlags(fs *flag.FlagSet) *inputFlags {
+	usage := "(none, pad, zapper, fourscore, hori, vaus, vaus-famicom, powerpad-a, powerpad-b, keyboard, snes-mouse)、省略時は--input-configかROMのヘッダーの設定"
+	return &inputFlags{
+		port1:        fs.String("port1", "", "ポート1に接続するデバイス"+usage),
+		port2:        fs.String("port2", "", "ポート2に接続するデバイス"+usage),
+		expansion:    fs.String("expansion", "", "拡張端子に接続するデバイス"+usage),
+		inputConfig:  fs.String("input-config", "", "ROMごとの入力デバイスの設定ファイルのパス(各行: <port1|port2|expansion> = <device>)、省略時はROMと同じ場所の.input"),
+		zapperScript: fs.String("zapper-script", "", "Zapperの操作を書いたファイルのパス(各行: <frame> <x> <y> [trigger] or <frame> off [trigger])"),
+	}
+}
+
+// config ROMのヘッダーの設定(base)を、設定ファイル、flagで指定されたデバイスの順に上書きする
+func (f *inputFlags) config(base controller.InputConfig, romPath string) (controller.InputConfig, error) {
+	path := *f.inputConfig
+	if path == "" {
+		path = file.InputConfigPath(romPath)
+	}
+	data, err := file.LoadInputConfigFile(path)
+	if err != nil {
+		return base, err
+	}
+	if data == nil && *f.inputConfig != "" {
+		return base, fmt.Errorf("input config is not found. path: %s", path)
+	}
+	if data != nil {
+		if base, err = controller.ParseInputConfig(bytes.NewReader(data), base); err != nil {
+			return base, fmt.Errorf("failed parse input config. path: %s, err: %w", path, err)
+		}
+	}
+
+	for _, item := range []struct {
+		name   string
+		value  string
+		target *controller.DeviceType
+	}{
+		{name: "port1", value: *f.port1, target: &base.Port1},
+		{name: "port2", value: *f.port2, target: &base.Port2},
+		{name: "expansion", value: *f.expansion, target: &base.Expansion},
+	} {
+		if item.value == "" {
+			continue
+		}
+		deviceType, err := controller.ParseDeviceType(item.value)
+		if err != nil {
+			return base, fmt.Errorf("invalid --%s. err: %w", item.name, err)
+		}
+		*item.target = deviceType
+	}
+	return base, nil
+}
+
+// loadZapperScript --zapper-scriptのスクリプトと、それを反映する接続済みのZapper
+// 指定されていない場合はnil
+func (f *inputFlags) loadZapperScript(c *controller.Controller) (*controller.ZapperScript, *controller.Zapper, error) {
+	if *f.zapperScript == "" {
+		return nil, nil, nil
+	}
+
+	var zapper *controller.Zapper
+	for _, device := range []controller.InputDevice{c.Device(controller.Port2), c.Device(controller.Port1), c.Expansion()} {
+		if z, ok := device.(*controller.Zapper); ok {
+			zapper = z
+			break
+		}
+	}
+	if zapper == nil {
+		return nil, nil, errors.New("--zapper-script requires a zapper. e.g. --port2 zapper")
+	}
+
+	scriptFile, err := os.Open(*f.zapperScript)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed open zapper script. err: %w", err)
+	}
+	defer scriptFile.Close()
+	script, err := controller.ParseZapperScript(scriptFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed parse zapper script. err: %w", err)
+	}
+	return script, zapper, nil
+}
+
+// gameDBUsage --gamedbのflagの説明
+const gameDBUsage = "ゲームデータベース(go run ./cmd/gamedbで作ったTSV)のパス、省略時は同梱のgamedb.tsv"
+
+// loadGameDB --gamedbのTSVを同梱のゲームデータベースの代わりに使う、空の場合は何もしない
+func loadGameDB(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed read game database. path: %s, err: %w", path, err)
+	}
+	if err := cartridge.SetGameDB(data); err != nil {
+		return fmt.Errorf("failed load game database. path: %s, err: %w", path, err)
+	}
+	return nil
+}
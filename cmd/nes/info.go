@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+)
+
+// runInfo nes info <rom>
+// ヘッダーの値と、ゲームデータベースで上書きした値を並べて表示する
+func runInfo(args []string) error {
+	fs := flag.NewFlagSet("nes info", flag.ContinueOnError)
+	patchFlag := fs.String("patch", "", patchUsage)
+	gameDBPath := fs.String("gamedb", "", gameDBUsage)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nes info <rom>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("rom path is required")
+	}
+	if err := loadGameDB(*gameDBPath); err != nil {
+		return err
+	}
+
+	data, format, patches, err := loadROM(fs.Arg(0), *patchFlag)
+	if err != nil {
+		return err
+	}
+	if !format.IsCartridge() {
+		return fmt.Errorf("not a cartridge. format: %s", format)
+	}
+	cart, err := cartridge.NewCartridge(data)
+	if err != nil {
+		return fmt.Errorf("failed new cartridge. err: %w", err)
+	}
+	return printInfo(os.Stdout, cart, patches)
+}
+
+func printInfo(out io.Writer, cart *cartridge.Cartridge, patches []string) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Format:\t%s\n", cart.Format)
+	for _, path := range patches {
+		fmt.Fprintf(w, "Patch:\t%s\n", path)
+	}
+	if cart.BoardName != "" {
+		fmt.Fprintf(w, "Board:\t%s\n", cart.BoardName)
+	}
+	fmt.Fprintf(w, "CRC32:\t%08X\n", cart.CRC32)
+	fmt.Fprintf(w, "SHA-1:\t%X\n", cart.SHA1)
+	fmt.Fprintf(w, "PRG-ROM:\t%d KB\n", len(cart.PRG)/1024)
+	fmt.Fprintf(w, "CHR-ROM:\t%d KB\n", len(cart.CHR)/1024)
+	fmt.Fprintf(w, "Trainer:\t%t\n", len(cart.Trainer) > 0)
+	if cart.DirtyHeader {
+		fmt.Fprintf(w, "DirtyHeader:\tbyte 7-15 are ignored\n")
+	}
+	if cart.Game == nil {
+		fmt.Fprintf(w, "Game:\tnot found in database\n")
+	} else {
+		fmt.Fprintf(w, "Game:\t%s\n", cart.Game.Name)
+	}
+	fmt.Fprintln(w)
+
+	// 差分がある行に*を付ける
+	detected := cart.Board()
+	header := cart.Header
+	fmt.Fprintf(w, "\theader\tdetected\t\n")
+	for _, row := range []struct {
+		name             string
+		header, detected any
+	}{
+		{name: "Mapper", header: mapperName(header.MapperNo), detected: mapperName(detected.MapperNo)},
+		{name: "Submapper", header: header.Submapper, detected: detected.Submapper},
+		{name: "Mirroring", header: header.Mirroring, detected: detected.Mirroring},
+		{name: "Battery", header: header.Battery, detected: detected.Battery},
+		{name: "PRG-RAM", header: header.PRGRAMSize, detected: detected.PRGRAMSize},
+		{name: "PRG-NVRAM", header: header.PRGNVRAMSize, detected: detected.PRGNVRAMSize},
+		{name: "CHR-RAM", header: header.CHRRAMSize, detected: detected.CHRRAMSize},
+		{name: "CHR-NVRAM", header: header.CHRNVRAMSize, detected: detected.CHRNVRAMSize},
+		{name: "Timing", header: header.Timing, detected: detected.Timing},
+	} {
+		mark := ""
+		if fmt.Sprint(row.header) != fmt.Sprint(row.detected) {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%s:\t%v\t%v\t%s\n", row.name, row.header, row.detected, mark)
+	}
+	return w.Flush()
+}
+
+// mapperName 例: 4 (MMC3)
+func mapperName(mapperNo int) string {
+	if name := mapper.Name(mapperNo); name != "" {
+		return fmt.Sprintf("%d (%s)", mapperNo, name)
+	}
+	return fmt.Sprint(mapperNo)
+}
@@ -1,9 +1,30 @@
 package main
 
 import (
-	"fmt"
+	"os"
+
+	"github.com/sunjin110/nes_emu/pkg/logger"
 )
 
 func main() {
-	fmt.Println("hello world nes")
+	if err := dispatch(os.Args[1:]); err != nil {
+		logger.Logger.Error("nes: failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func dispatch(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "nsf":
+			return runNSF(args[1:])
+		case "info":
+			return runInfo(args[1:])
+		case "patch":
+			return runPatch(args[1:])
+		case "sram":
+			return runSRAM(args[1:])
+		}
+	}
+	return run(args)
 }
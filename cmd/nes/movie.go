@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/md5"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/console"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+	"github.com/sunjin110/nes_emu/internal/domain/input"
+	"github.com/sunjin110/nes_emu/internal/domain/movie"
+	"github.com/sunjin110/nes_emu/pkg/logger"
+)
+
+// loadMovie 拡張子が.bk2の場合はBK2、それ以外はFM2として読み込む
+// ROMのチェックサムが記録されていて一致しない場合は警告を出す
+func loadMovie(path string, checksum [md5.Size]byte) (*movie.Movie, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed read movie. path: %s, err: %w", path, err)
+	}
+
+	var m *movie.Movie
+	if strings.EqualFold(filepath.Ext(path), ".bk2") {
+		m, err = movie.ParseBK2(data)
+	} else {
+		m, err = movie.ParseFM2(strings.NewReader(string(data)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed parse movie. path: %s, err: %w", path, err)
+	}
+
+	if m.ROMChecksum != ([16]byte{}) && m.ROMChecksum != checksum {
+		logger.Logger.Warn("movie rom checksum does not match", "movie", path, "romFilename", m.ROMFilename)
+	}
+	return m, nil
+}
+
+func saveMovie(path string, m *movie.Movie) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed create movie. path: %s, err: %w", path, err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed close movie. path: %s, err: %w", path, closeErr)
+		}
+	}()
+	return m.WriteFM2(f)
+}
+
+// runMovieCommand フレームの開始時のリセット・電源の操作を行う
+func runMovieCommand(nes *console.Console, command movie.Command) error {
+	if command&movie.CommandPowerCycle != 0 {
+		if err := nes.PowerCycle(); err != nil {
+			return fmt.Errorf("failed power cycle. err: %w", err)
+		}
+	}
+	if command&movie.CommandSoftReset != 0 {
+		if err := nes.Reset(); err != nil {
+			return fmt.Errorf("failed reset. err: %w", err)
+		}
+	}
+	return nil
+}
+
+// macroName --macroで読み込んだマクロの名前
+const macroName = "macro"
+
+// loadMacroLayer マクロを読み込んで1Pで再生を始めたLayerを返す
+func loadMacroLayer(path string) (*input.Layer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed open macro. path: %s, err: %w", path, err)
+	}
+	defer f.Close()
+	macro, err := input.ParseMacro(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed parse macro. path: %s, err: %w", path, err)
+	}
+
+	layer := input.NewLayer()
+	layer.BindMacro(macroName, macro)
+	if err := layer.PlayMacro(controller.Player1, macroName); err != nil {
+		return nil, err
+	}
+	return layer, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/nsf"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/audio"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/file"
+	"github.com/sunjin110/nes_emu/pkg/logger"
+)
+
+// defaultNSFSeconds NSFeで曲の長さが指定されていない場合の再生時間
+const defaultNSFSeconds = 150
+
+// runNSF nes nsf play|render <file> [options]
+func runNSF(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: nes nsf play|render <file> [options]")
+	}
+	command := args[0]
+
+	fs := flag.NewFlagSet("nes nsf "+command, flag.ContinueOnError)
+	track := fs.Int("track", 0, "再生する曲番号(1始まり)、0の場合はNSFの開始曲")
+	seconds := fs.Float64("seconds", 0, "再生する秒数、0の場合はNSFeの曲の長さか150秒")
+	rate := fs.Int("rate", audio.DefaultSampleRate, "WAVのサンプリングレート(Hz)")
+	out := fs.String("out", "", "render: 書き出すWAVファイルのパス、省略時は<file>.wav")
+	split := fs.Bool("wav-split", false, "render: チャンネルごとのWAVファイルも書き出す")
+	channels := addChannelFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nes nsf play|render <file> [options]")
+		fmt.Fprintln(fs.Output(), "  play:   WAVを標準出力に書き出す (例: nes nsf play song.nsf | aplay)")
+		fmt.Fprintln(fs.Output(), "  render: WAVファイルに書き出す")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return errors.New("nsf file path is required")
+	}
+	path := positional[0]
+
+	controls, err := channels.controls()
+	if err != nil {
+		return err
+	}
+
+	data, _, err := file.LoadNESFile(path)
+	if err != nil {
+		return fmt.Errorf("failed load nsf. err: %w", err)
+	}
+	n, err := nsf.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed parse nsf. err: %w", err)
+	}
+
+	if *track == 0 {
+		*track = n.StartingSong
+	}
+	if *seconds == 0 {
+		*seconds = nsfTrackSeconds(n, *track)
+	}
+
+	var recorder *audio.AudioRecorder
+	switch command {
+	case "play":
+		recorder, err = audio.NewStreamRecorder(os.Stdout, *rate)
+	case "render":
+		if *out == "" {
+			*out = strings.TrimSuffix(path, filepath.Ext(path)) + ".wav"
+		}
+		recorder, err = audio.NewAudioRecorder(*out, audio.RecorderOption{
+			SampleRate:    *rate,
+			SplitChannels: *split,
+		})
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown nsf command: %s", command)
+	}
+	if err != nil {
+		return fmt.Errorf("failed new audio recorder. err: %w", err)
+	}
+
+	logger.Logger.Info("nsf", "title", n.Title, "artist", n.Artist, "track", *track, "songs", n.SongCount, "seconds", *seconds)
+	return errors.Join(playNSF(n, *track, *seconds, recorder, controls), recorder.Close())
+}
+
+// playNSF 曲を指定した秒数だけ再生してsinkに書き出す
+func playNSF(n *nsf.NSF, track int, seconds float64, sink apu.SampleSink, controls apu.ChannelControls) error {
+	player := nsf.NewPlayer(n)
+	player.SetSampleSink(sink)
+	player.SetChannelControls(controls)
+	if err := player.Init(track); err != nil {
+		return fmt.Errorf("failed init nsf. track: %d, err: %w", track, err)
+	}
+	if err := player.RunSeconds(seconds); err != nil {
+		return fmt.Errorf("failed play nsf. track: %d, err: %w", track, err)
+	}
+	return nil
+}
+
+func nsfTrackSeconds(n *nsf.NSF, track int) float64 {
+	ms := n.TrackTime(track)
+	if ms < 0 {
+		return defaultNSFSeconds
+	}
+	if fade := n.TrackFade(track); fade > 0 {
+		ms += fade
+	}
+	return float64(ms) / 1000
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/patch"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/file"
+	"github.com/sunjin110/nes_emu/pkg/logger"
+)
+
+// patchUsage --patchのflagの説明
+const patchUsage = "ROMに当てるパッチ(.ips, .ups, .bps)のパス(カンマ区切りで順に当てる)、省略時はROMと同じ場所の同じ名前のパッチ"
+
+// loadROM ROMを読み込んで、--patchのパッチか、なければROMと同じ場所にあるパッチを当てる
+// 当てたパッチのパスも返す
+func loadROM(romPath, patchFlag string) ([]byte, file.Format, []string, error) {
+	patches := splitList(patchFlag)
+	if len(patches) == 0 {
+		path, err := file.FindPatch(romPath)
+		if err != nil {
+			return nil, file.FormatUnknown, nil, err
+		}
+		if path != "" {
+			patches = []string{path}
+		}
+	}
+	data, format, err := file.LoadPatchedNESFile(romPath, patches)
+	if err != nil {
+		return nil, file.FormatUnknown, nil, fmt.Errorf("failed load rom. err: %w", err)
+	}
+	return data, format, patches, nil
+}
+
+// runPatch nes patch create <original> <modified> [--out <patch>]
+func runPatch(args []string) error {
+	if len(args) == 0 || args[0] != "create" {
+		return errors.New("usage: nes patch create <original> <modified> [--out <patch>]")
+	}
+
+	fs := flag.NewFlagSet("nes patch create", flag.ContinueOnError)
+	out := fs.String("out", "", "書き出すBPSファイルのパス、省略時は<original>.bps")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nes patch create <original> <modified> [--out <patch>]")
+		fmt.Fprintln(fs.Output(), "  originalをmodifiedにするBPSのパッチを書き出す")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		fs.Usage()
+		return errors.New("original and modified rom paths are required")
+	}
+	originalPath, modifiedPath := positional[0], positional[1]
+	// originalと同じ名前にしておくと、originalを読み込んだ時にパッチが自動で当たる
+	if *out == "" {
+		*out = strings.TrimSuffix(originalPath, filepath.Ext(originalPath)) + ".bps"
+	}
+
+	original, err := os.ReadFile(originalPath)
+	if err != nil {
+		return fmt.Errorf("failed read original. path: %s, err: %w", originalPath, err)
+	}
+	modified, err := os.ReadFile(modifiedPath)
+	if err != nil {
+		return fmt.Errorf("failed read modified. path: %s, err: %w", modifiedPath, err)
+	}
+	data, err := patch.CreateBPS(original, modified)
+	if err != nil {
+		return fmt.Errorf("failed create patch. err: %w", err)
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		return fmt.Errorf("failed write patch. path: %s, err: %w", *out, err)
+	}
+	logger.Logger.Info("patch", "out", *out, "size", len(data))
+	return nil
+}
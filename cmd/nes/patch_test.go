@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/file"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestRunPatch$ github.com/sunjin110/nes_emu/cmd/nes
+func TestRunPatch(t *testing.T) {
+	Convey("TestRunPatch", t, func() {
+		original, err := os.ReadFile("../../static/roms/hello.nes")
+		So(err, ShouldBeNil)
+		modified := append([]byte(nil), original...)
+		modified[16] ^= 0xFF
+		modified[len(modified)-1] ^= 0xFF
+
+		dir := t.TempDir()
+		originalPath := filepath.Join(dir, "game.nes")
+		modifiedPath := filepath.Join(dir, "hack.nes")
+		So(os.WriteFile(originalPath, original, 0o644), ShouldBeNil)
+		So(os.WriteFile(modifiedPath, modified, 0o644), ShouldBeNil)
+
+		Convey("--outを省略した場合はoriginalと同じ名前の.bpsに書き出し、originalを読み込むとパッチが当たる", func() {
+			So(runPatch([]string{"create", originalPath, modifiedPath}), ShouldBeNil)
+			_, err := os.Stat(filepath.Join(dir, "game.bps"))
+			So(err, ShouldBeNil)
+
+			data, format, patches, err := loadROM(originalPath, "")
+			So(err, ShouldBeNil)
+			So(format, ShouldEqual, file.FormatINES)
+			So(patches, ShouldResemble, []string{filepath.Join(dir, "game.bps")})
+			So(bytes.Equal(data, modified), ShouldBeTrue)
+			_, err = cartridge.NewCartridge(data)
+			So(err, ShouldBeNil)
+
+			// modifiedにはパッチを当てない
+			data, format, patches, err = loadROM(modifiedPath, "")
+			So(err, ShouldBeNil)
+			So(format, ShouldEqual, file.FormatINES)
+			So(patches, ShouldBeEmpty)
+			So(bytes.Equal(data, modified), ShouldBeTrue)
+			_, err = cartridge.NewCartridge(data)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("--outを指定した場合は--patchで当てられる", func() {
+			out := filepath.Join(dir, "out.bps")
+			So(runPatch([]string{"create", originalPath, modifiedPath, "--out", out}), ShouldBeNil)
+
+			data, _, patches, err := loadROM(originalPath, out)
+			So(err, ShouldBeNil)
+			So(patches, ShouldResemble, []string{out})
+			So(bytes.Equal(data, modified), ShouldBeTrue)
+		})
+	})
+}
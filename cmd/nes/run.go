@@ -0,0 +1,280 @@
+package main
+
+import (
+	"crypto/md5"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/domain/console"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+	"github.com/sunjin110/nes_emu/internal/domain/fds"
+	"github.com/sunjin110/nes_emu/internal/domain/input"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/movie"
+	"github.com/sunjin110/nes_emu/internal/domain/nsf"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/audio"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/file"
+	"github.com/sunjin110/nes_emu/pkg/logger"
+)
+
+// run nes [options] <rom>
+// NSF/NSFeの場合は開始曲を--framesの長さだけ再生する
+func run(args []string) (err error) {
+	fs := flag.NewFlagSet("nes", flag.ContinueOnError)
+	wavPath := fs.String("wav", "", "APUの出力を書き出すWAVファイルのパス")
+	wavRate := fs.Int("wav-rate", audio.DefaultSampleRate, "WAVのサンプリングレート(Hz)")
+	wavSplit := fs.Bool("wav-split", false, "チャンネルごとのWAVファイルも書き出す")
+	frames := fs.Int("frames", 60*60, "実行するフレーム数")
+	moviePlay := fs.String("movie-play", "", "再生するムービー(.fm2, .bk2)のパス")
+	movieRecord := fs.String("movie-record", "", "入力を記録するムービー(.fm2)のパス")
+	macroPath := fs.String("macro", "", "最初のフレームから1Pで再生するマクロのパス(各行: <フレーム数> <ボタン>)")
+	savePath := fs.String("save", "", "バッテリーバックアップされたRAMの.savファイルのパス、省略時はROMと同じ場所")
+	saveInterval := fs.Int("save-interval", 60*10, ".savファイルに保存する間隔(フレーム数)、0の場合は終了時のみ")
+	fdsBIOS := fs.String("fds-bios", "", "ディスクシステムのBIOS(disksys.rom)のパス")
+	diskFlag := fs.String("disk", "", "ディスクを入れ替えるフレームと面(カンマ区切り 例: 600:eject,700:1B)")
+	patchFlag := fs.String("patch", "", patchUsage)
+	gameDBPath := fs.String("gamedb", "", gameDBUsage)
+	channels := addChannelFlags(fs)
+	inputs := addInputFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: nes [options] <rom>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("rom path is required")
+	}
+
+	controls, err := channels.controls()
+	if err != nil {
+		return err
+	}
+	if err := loadGameDB(*gameDBPath); err != nil {
+		return err
+	}
+
+	data, format, patches, err := loadROM(fs.Arg(0), *patchFlag)
+	if err != nil {
+		return err
+	}
+	if len(patches) > 0 {
+		logger.Logger.Info("patched", "patches", patches)
+	}
+	if format == file.FormatNSF {
+		return runNSFFile(data, *frames, *wavPath, *wavRate, *wavSplit, controls)
+	}
+
+	if *savePath == "" {
+		*savePath = file.SaveFilePath(fs.Arg(0))
+	}
+	m, err := newMachine(data, format, *savePath, *fdsBIOS)
+	if err != nil {
+		return err
+	}
+	if m.save != nil {
+		defer func() {
+			if saveErr := m.save.flush(); saveErr != nil {
+				err = errors.Join(err, saveErr)
+			}
+		}()
+	}
+	schedule, err := parseDiskSchedule(*diskFlag)
+	if err != nil {
+		return err
+	}
+	if len(schedule) > 0 {
+		if m.disk == nil {
+			return errors.New("--disk requires an FDS disk image")
+		}
+		if err := schedule.validate(m.disk.SideCount()); err != nil {
+			return err
+		}
+	}
+	nes := m.nes
+	nes.APU().SetChannelControls(controls)
+
+	inputConfig, err := inputs.config(m.input, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var playback *movie.Movie
+	if *moviePlay != "" {
+		if playback, err = loadMovie(*moviePlay, m.checksum); err != nil {
+			return err
+		}
+		if playback.FourScore {
+			inputConfig.Port1 = controller.DeviceFourScore
+			inputConfig.Port2 = controller.DeviceFourScore
+		}
+	}
+	var layer *input.Layer
+	if *macroPath != "" {
+		if playback != nil {
+			return errors.New("--macro and --movie-play cannot be used together")
+		}
+		if layer, err = loadMacroLayer(*macroPath); err != nil {
+			return err
+		}
+	}
+	var recording *movie.Movie
+	if *movieRecord != "" {
+		fourScore := inputConfig.Port1 == controller.DeviceFourScore
+		recording = movie.NewMovie(filepath.Base(fs.Arg(0)), m.checksum, fourScore)
+		defer func() {
+			if saveErr := saveMovie(*movieRecord, recording); saveErr != nil {
+				err = errors.Join(err, saveErr)
+			}
+		}()
+	}
+	if err := nes.Controller().ApplyInputConfig(inputConfig); err != nil {
+		return fmt.Errorf("failed connect input devices. err: %w", err)
+	}
+	zapperScript, zapper, err := inputs.loadZapperScript(nes.Controller())
+	if err != nil {
+		return err
+	}
+
+	if *wavPath != "" {
+		recorder, recorderErr := audio.NewAudioRecorder(*wavPath, audio.RecorderOption{
+			SampleRate:    *wavRate,
+			ClockRate:     m.clockRate,
+			SplitChannels: *wavSplit,
+		})
+		if recorderErr != nil {
+			return fmt.Errorf("failed new audio recorder. err: %w", recorderErr)
+		}
+		defer func() {
+			if closeErr := recorder.Close(); closeErr != nil {
+				err = errors.Join(err, closeErr)
+			}
+		}()
+		nes.APU().SetSampleSink(recorder)
+	}
+
+	for i := 0; i < *frames; i++ {
+		var command movie.Command
+		if playback != nil {
+			command, _ = playback.Apply(i, nes.Controller())
+		}
+		if layer != nil {
+			layer.Apply(nes.Controller(), nes.Controller().Players())
+		}
+		if err := runMovieCommand(nes, command); err != nil {
+			return fmt.Errorf("failed run movie command. frame: %d, err: %w", i, err)
+		}
+		if recording != nil {
+			recording.Record(nes.Controller(), command)
+		}
+		if zapperScript != nil {
+			zapperScript.Apply(i, zapper)
+		}
+		if m.disk != nil {
+			if err := schedule.apply(i, m.disk); err != nil {
+				return fmt.Errorf("failed change disk. frame: %d, err: %w", i, err)
+			}
+		}
+		if err := nes.RunFrame(); err != nil {
+			return fmt.Errorf("failed run frame. frame: %d, err: %w", i, err)
+		}
+		if m.save != nil && *saveInterval > 0 && (i+1)%*saveInterval == 0 {
+			if err := m.save.flush(); err != nil {
+				return fmt.Errorf("failed save. frame: %d, err: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// saver .savファイルに保存するもの
+type saver interface {
+	flush() error
+}
+
+// machine ROMを接続したconsole
+type machine struct {
+	nes *console.Console
+	// checksum ムービーに記録するROMのチェックサム
+	checksum [md5.Size]byte
+	// save 保存するものがない場合はnil
+	save saver
+	// disk ディスクシステムの場合のみ
+	disk *fds.FDS
+	// input ROMのヘッダーで指定された入力デバイス
+	input controller.InputConfig
+	// clockRate WAVに書き出す時のCPUのクロック周波数(Hz)、ヘッダーかゲームDBのタイミングで決まる
+	clockRate int
+}
+
+// newMachine ROMの形式に合わせてカートリッジかディスクシステムをconsoleに接続する
+func newMachine(data []byte, format file.Format, savePath, biosPath string) (*machine, error) {
+	m := &machine{input: controller.DefaultInputConfig(), clockRate: apu.CPUClockNTSC}
+	var slot mapper.Mapper
+	if format == file.FormatFDS {
+		device, save, err := loadFDS(data, biosPath, savePath)
+		if err != nil {
+			return nil, err
+		}
+		slot = device
+		m.checksum = movie.ROMChecksum(data, nil)
+		m.save = save
+		m.disk = device
+	} else {
+		cart, err := cartridge.NewCartridge(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed new cartridge. err: %w", err)
+		}
+		save, err := openBatterySave(savePath, cart)
+		if err != nil {
+			return nil, err
+		}
+		if save != nil {
+			m.save = save
+		}
+		slot = cart.Mapper
+		m.checksum = movie.ROMChecksum(cart.PRG, cart.CHR)
+		m.input = controller.InputConfigFromExpansionDevice(byte(cart.DefaultExpansionDevice))
+		m.clockRate = cart.Timing.CPUClock()
+	}
+
+	nes, err := console.NewConsoleWithMapper(slot)
+	if err != nil {
+		return nil, fmt.Errorf("failed new console. err: %w", err)
+	}
+	m.nes = nes
+	return m, nil
+}
+
+func runNSFFile(data []byte, frames int, wavPath string, wavRate int, wavSplit bool, controls apu.ChannelControls) (err error) {
+	n, err := nsf.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed parse nsf. err: %w", err)
+	}
+
+	var sink apu.SampleSink
+	if wavPath != "" {
+		recorder, recorderErr := audio.NewAudioRecorder(wavPath, audio.RecorderOption{
+			SampleRate:    wavRate,
+			SplitChannels: wavSplit,
+		})
+		if recorderErr != nil {
+			return fmt.Errorf("failed new audio recorder. err: %w", recorderErr)
+		}
+		defer func() {
+			if closeErr := recorder.Close(); closeErr != nil {
+				err = errors.Join(err, closeErr)
+			}
+		}()
+		sink = recorder
+	}
+
+	seconds := float64(frames) * console.CPUCyclesPerFrame / apu.CPUClockNTSC
+	return playNSF(n, n.StartingSong, seconds, sink, controls)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/file"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestNewMachine$ github.com/sunjin110/nes_emu/cmd/nes
+func TestNewMachine(t *testing.T) {
+	Convey("TestNewMachine", t, func() {
+		// NES 2.0, mapper 0, PRG-ROM 16KB, CHR-ROM 8KB
+		header := []byte{'N', 'E', 'S', 0x1A, 1, 1, 0, 0x08, 0, 0, 0, 0, 0, 0, 0, 0}
+		newROM := func(timing byte) []byte {
+			rom := append([]byte(nil), header...)
+			rom[12] = timing
+			return append(rom, make([]byte, 0x4000+0x2000)...)
+		}
+
+		for _, tt := range []struct {
+			name      string
+			timing    byte
+			clockRate int
+		}{
+			{name: "NTSC", timing: 0, clockRate: apu.CPUClockNTSC},
+			{name: "PAL", timing: 1, clockRate: apu.CPUClockPAL},
+			{name: "Multi-region", timing: 2, clockRate: apu.CPUClockNTSC},
+			{name: "Dendy", timing: 3, clockRate: apu.CPUClockDendy},
+		} {
+			Convey("ヘッダーのタイミングがWAVに書き出す時のクロック周波数になる: "+tt.name, func() {
+				m, err := newMachine(newROM(tt.timing), file.FormatINES, "", "")
+				So(err, ShouldBeNil)
+				So(m.clockRate, ShouldEqual, tt.clockRate)
+			})
+		}
+	})
+}
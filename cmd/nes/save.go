@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/file"
+)
+
+// batterySave バッテリーバックアップされたRAMを.savファイルに保存する
+type batterySave struct {
+	path string
+	cart *cartridge.Cartridge
+	// saved 最後に保存した内容、変わっていない場合は書き込まない
+	saved []byte
+}
+
+// openBatterySave .savファイルがあればカートリッジに読み込む
+// バッテリーがない、またはmapperに保存するRAMがない場合はnil
+func openBatterySave(path string, cart *cartridge.Cartridge) (*batterySave, error) {
+	if !cart.Battery || cart.SRAM() == nil {
+		return nil, nil
+	}
+	data, err := file.LoadSaveFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		if err := cart.LoadSRAM(data); err != nil {
+			return nil, fmt.Errorf("failed load save file. path: %s, err: %w", path, err)
+		}
+	}
+	return &batterySave{path: path, cart: cart, saved: cart.SRAM()}, nil
+}
+
+// flush RAMが前回の保存から変わっていれば書き込む
+func (s *batterySave) flush() error {
+	data := s.cart.SRAM()
+	if bytes.Equal(data, s.saved) {
+		return nil
+	}
+	if err := file.WriteSaveFile(s.path, data); err != nil {
+		return err
+	}
+	s.saved = data
+	return nil
+}
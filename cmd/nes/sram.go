@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/file"
+	"github.com/sunjin110/nes_emu/pkg/logger"
+)
+
+const sramUsage = "usage: nes sram export|import <rom> <file> [--save <path>] [--gamedb <path>]"
+
+// runSRAM nes sram export|import <rom> <file> [--save <path>] [--gamedb <path>]
+// export: ROMの.savを読み込んだバッテリーバックアップされるRAMをfileに書き出す
+// import: fileのRAMをROMのサイズと照らし合わせて、ROMの.savとして保存する
+func runSRAM(args []string) error {
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		return errors.New(sramUsage)
+	}
+	command := args[0]
+
+	fs := flag.NewFlagSet("nes sram "+command, flag.ContinueOnError)
+	savePath := fs.String("save", "", ".savファイルのパス、省略時はROMと同じ場所")
+	patchFlag := fs.String("patch", "", patchUsage)
+	gameDBPath := fs.String("gamedb", "", gameDBUsage)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), sramUsage)
+		fmt.Fprintln(fs.Output(), "  export: .savのバッテリーバックアップされるRAMをfileに書き出す")
+		fmt.Fprintln(fs.Output(), "  import: fileのRAMを.savとして保存する")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		fs.Usage()
+		return errors.New("rom and file paths are required")
+	}
+	romPath, path := positional[0], positional[1]
+	if *savePath == "" {
+		*savePath = file.SaveFilePath(romPath)
+	}
+
+	if err := loadGameDB(*gameDBPath); err != nil {
+		return err
+	}
+	cart, err := loadBatteryCartridge(romPath, *patchFlag)
+	if err != nil {
+		return err
+	}
+	if command == "export" {
+		return exportSRAM(cart, *savePath, path)
+	}
+	return importSRAM(cart, *savePath, path)
+}
+
+// loadBatteryCartridge バッテリーバックアップされるRAMがないROMはエラー
+func loadBatteryCartridge(romPath, patchFlag string) (*cartridge.Cartridge, error) {
+	data, format, _, err := loadROM(romPath, patchFlag)
+	if err != nil {
+		return nil, err
+	}
+	if !format.IsCartridge() {
+		return nil, fmt.Errorf("not a cartridge. format: %s", format)
+	}
+	cart, err := cartridge.NewCartridge(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed new cartridge. err: %w", err)
+	}
+	if !cart.Battery || cart.SRAM() == nil {
+		return nil, fmt.Errorf("%w. path: %s", cartridge.ErrNoSRAM, romPath)
+	}
+	return cart, nil
+}
+
+// exportSRAM .savのサイズがROMのRAMと合うかを確かめて書き出す、.savがない場合は電源を入れた時のRAMを書き出す
+// LoadSRAMの後のRAMはtrainerが書き込まれているので、.savの内容をそのまま書き出す
+func exportSRAM(cart *cartridge.Cartridge, savePath, outPath string) error {
+	data, err := file.LoadSaveFile(savePath)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data = cart.SRAM()
+	} else if err := cart.LoadSRAM(data); err != nil {
+		return fmt.Errorf("failed load save file. path: %s, err: %w", savePath, err)
+	}
+	if err := os.WriteFile(outPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed write sram. path: %s, err: %w", outPath, err)
+	}
+	logger.Logger.Info("sram exported", "save", savePath, "out", outPath, "size", len(data))
+	return nil
+}
+
+// importSRAM fileのサイズがROMのRAMと合う場合だけ.savに保存する
+func importSRAM(cart *cartridge.Cartridge, savePath, inPath string) error {
+	data, err := os.ReadFile(inPath)
+	if err != nil {
+		return fmt.Errorf("failed read sram. path: %s, err: %w", inPath, err)
+	}
+	if err := cart.LoadSRAM(data); err != nil {
+		return fmt.Errorf("failed load sram. path: %s, err: %w", inPath, err)
+	}
+	if err := file.WriteSaveFile(savePath, data); err != nil {
+		return err
+	}
+	logger.Logger.Info("sram imported", "in", inPath, "save", savePath, "size", len(data))
+	return nil
+}
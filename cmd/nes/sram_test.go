@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestRunSRAM$ github.com/sunjin110/nes_emu/cmd/nes
+func TestRunSRAM(t *testing.T) {
+	Convey("TestRunSRAM", t, func() {
+		// NES 2.0, mapper 1(SOROM), バッテリーあり, PRG-ROM 32KB, PRG-RAM 8KB + PRG-NVRAM 8KB, CHR-RAM 8KB
+		header := []byte{'N', 'E', 'S', 0x1A, 2, 0, 0x12, 0x08, 0, 0, 0x77, 0x07, 0, 0, 0, 0}
+		dir := t.TempDir()
+		romPath := filepath.Join(dir, "game.nes")
+		So(os.WriteFile(romPath, append(header, make([]byte, 0x8000)...), 0o644), ShouldBeNil)
+
+		Convey("importしたRAMがROMと同じ場所の.savになり、exportで同じ内容を書き出す", func() {
+			sram := bytes.Repeat([]byte{0x5A}, 0x2000)
+			inPath := filepath.Join(dir, "in.srm")
+			So(os.WriteFile(inPath, sram, 0o644), ShouldBeNil)
+
+			So(runSRAM([]string{"import", romPath, inPath}), ShouldBeNil)
+			save, err := os.ReadFile(filepath.Join(dir, "game.sav"))
+			So(err, ShouldBeNil)
+			So(save, ShouldResemble, sram)
+
+			outPath := filepath.Join(dir, "out.srm")
+			So(runSRAM([]string{"export", romPath, outPath}), ShouldBeNil)
+			out, err := os.ReadFile(outPath)
+			So(err, ShouldBeNil)
+			So(out, ShouldResemble, sram)
+		})
+
+		Convey("--saveで.savのパスを指定でき、.savがない場合は電源を入れた時のRAMを書き出す", func() {
+			savePath := filepath.Join(dir, "other.sav")
+			outPath := filepath.Join(dir, "out.srm")
+			So(runSRAM([]string{"export", romPath, outPath, "--save", savePath}), ShouldBeNil)
+			out, err := os.ReadFile(outPath)
+			So(err, ShouldBeNil)
+			So(out, ShouldResemble, make([]byte, 0x2000))
+		})
+
+		Convey("不揮発性のRAMとサイズが違う場合は.savを書き換えない", func() {
+			inPath := filepath.Join(dir, "in.srm")
+			So(os.WriteFile(inPath, make([]byte, 0x4000), 0o644), ShouldBeNil)
+			err := runSRAM([]string{"import", romPath, inPath})
+			So(errors.Is(err, cartridge.ErrSRAMSizeMismatch), ShouldBeTrue)
+			_, err = os.Stat(filepath.Join(dir, "game.sav"))
+			So(errors.Is(err, os.ErrNotExist), ShouldBeTrue)
+		})
+
+		Convey("バッテリーのないROMはErrNoSRAM", func() {
+			noBattery := append([]byte(nil), header...)
+			noBattery[6] = 0x10
+			path := filepath.Join(dir, "nobattery.nes")
+			So(os.WriteFile(path, append(noBattery, make([]byte, 0x8000)...), 0o644), ShouldBeNil)
+			err := runSRAM([]string{"export", path, filepath.Join(dir, "out.srm")})
+			So(errors.Is(err, cartridge.ErrNoSRAM), ShouldBeTrue)
+		})
+
+		Convey("--gamedbのゲームデータベースでバッテリーありになったROMは書き出せる", func() {
+			noBattery := append([]byte(nil), header...)
+			noBattery[6] = 0x10
+			prg := make([]byte, 0x8000)
+			prg[0] = 0x01
+			path := filepath.Join(dir, "nobattery.nes")
+			So(os.WriteFile(path, append(noBattery, prg...), 0o644), ShouldBeNil)
+
+			// PRG-ROMだけのハッシュで引く、mapper 1, バッテリーあり, PRG-NVRAM 8KB, CHR-RAM 8KB
+			crc := crc32.ChecksumIEEE(prg)
+			sum := sha1.Sum(prg)
+			db := fmt.Sprintf("%08X\t%X\t1\t0\tM\t1\t0\t8192\t8192\t0\t0\tbattery\n", crc, sum)
+			dbPath := filepath.Join(dir, "gamedb.tsv")
+			So(os.WriteFile(dbPath, []byte(db), 0o644), ShouldBeNil)
+
+			outPath := filepath.Join(dir, "out.srm")
+			So(runSRAM([]string{"export", path, outPath, "--gamedb", dbPath}), ShouldBeNil)
+			out, err := os.ReadFile(outPath)
+			So(err, ShouldBeNil)
+			So(out, ShouldHaveLength, 0x2000)
+
+			So(runSRAM([]string{"export", path, outPath, "--gamedb", filepath.Join(dir, "unknown.tsv")}), ShouldNotBeNil)
+		})
+	})
+}
@@ -1,31 +1,263 @@
 package apu
 
 // Audio Processing Unit
+// doc: https://www.nesdev.org/wiki/APU
 
 type APU struct {
 	registers [0x16]byte
+
+	pulse1       pulse
+	pulse2       pulse
+	triangle     triangle
+	noise        noise
+	dmc          dmc
+	frameCounter frameCounter
+	expansions   []ExpansionAudio
+
+	controls ChannelControls
+	gains    ChannelGains // controlsから計算した倍率
+
+	// pulseのtimerはAPUサイクル(CPUの2サイクル)ごとにclockされる
+	evenCycle bool
+
+	sink SampleSink
 }
 
 const (
 	addrAPUIOStart = 0x4000
 	addrAPUIOEnd   = 0x4015
+
+	addrAPUStatus       = 0x4015
+	addrAPUFrameCounter = 0x4017
+)
+
+// CPUのクロック周波数(Hz)、APUはCPUと同じクロックで動作する
+const (
+	CPUClockNTSC  = 1789773
+	CPUClockPAL   = 1662607
+	CPUClockDendy = 1773448
 )
 
 func NewAPU() *APU {
-	return &APU{
+	a := &APU{
 		registers: [0x16]byte{},
+		pulse1:    newPulse(true),
+		pulse2:    newPulse(false),
+		noise:     newNoise(),
+		dmc:       newDMC(),
 	}
+	a.SetChannelControls(NewChannelControls())
+	return a
+}
+
+// ConnectBus DMCがサンプルを読み込むためのCPUのメモリを接続する
+func (a *APU) ConnectBus(bus Bus) {
+	a.dmc.bus = bus
+}
+
+// SetSampleSink CPUサイクルごとの出力の送り先を設定する、nilの場合は出力しない
+func (a *APU) SetSampleSink(sink SampleSink) {
+	a.sink = sink
 }
 
 func (a *APU) Read(addr uint16) byte {
+	if addr == addrAPUStatus {
+		return a.readStatus()
+	}
 	return a.registers[addr-addrAPUIOStart]
 }
 
 func (a *APU) Write(addr uint16, value byte) {
+	if addr == addrAPUFrameCounter {
+		a.clockFrameEvent(a.frameCounter.write(value))
+		return
+	}
+
 	a.registers[addr-addrAPUIOStart] = value
-	// TODO 必要に応じて音声処理の副作用
+
+	switch {
+	case addr <= 0x4003:
+		a.pulse1.write(addr-0x4000, value)
+	case addr <= 0x4007:
+		a.pulse2.write(addr-0x4004, value)
+	case addr <= 0x400B:
+		a.triangle.write(addr-0x4008, value)
+	case addr <= 0x400F:
+		a.noise.write(addr-0x400C, value)
+	case addr <= 0x4013:
+		a.dmc.write(addr-0x4010, value)
+	case addr == addrAPUStatus:
+		a.writeStatus(value)
+	}
+}
+
+// Step CPUの1サイクル分APUを進める
+func (a *APU) Step() {
+	a.clockFrameEvent(a.frameCounter.clock())
+
+	if a.evenCycle {
+		a.pulse1.clockTimer()
+		a.pulse2.clockTimer()
+	}
+	a.evenCycle = !a.evenCycle
+	a.triangle.clockTimer()
+	a.noise.clockTimer()
+	a.dmc.clockTimer()
+	for _, expansion := range a.expansions {
+		expansion.Step()
+	}
+
+	if a.sink != nil {
+		a.sink.WriteSample(a.sample())
+	}
+}
+
+// IRQ frame counterかDMCがIRQを要求している場合はtrue
+func (a *APU) IRQ() bool {
+	return a.frameCounter.irq || a.dmc.irq
+}
+
+// Output 全チャンネルをミックスした現在の出力(2A03のみの場合は0.0〜1.0)
+func (a *APU) Output() float32 {
+	return a.sample().Mixed
+}
+
+func (a *APU) sample() Sample {
+	p1 := float32(a.pulse1.output()) * a.gains[ChannelPulse1]
+	p2 := float32(a.pulse2.output()) * a.gains[ChannelPulse2]
+	t := float32(a.triangle.output()) * a.gains[ChannelTriangle]
+	n := float32(a.noise.output()) * a.gains[ChannelNoise]
+	d := float32(a.dmc.output()) * a.gains[ChannelDMC]
+	e := a.expansionOutput()
+
+	return Sample{
+		Mixed:     pulseMix(p1, p2) + tndMix(t, n, d) + e,
+		Expansion: e,
+		Pulse1:    pulseMix(p1, 0),
+		Pulse2:    pulseMix(0, p2),
+		Triangle:  tndMix(t, 0, 0),
+		Noise:     tndMix(0, n, 0),
+		DMC:       tndMix(0, 0, d),
+	}
+}
+
+// Channels 2A03のチャンネルと、追加された拡張音源のチャンネル
+func (a *APU) Channels() []Channel {
+	channels := append([]Channel{}, Channels2A03...)
+	for _, expansion := range a.expansions {
+		channels = append(channels, expansion.Channels()...)
+	}
+	return channels
+}
+
+// SetChannelMute チャンネルをミュートする、エミュレーションの状態には影響しない
+func (a *APU) SetChannelMute(channel Channel, mute bool) error {
+	if err := a.controls.SetMute(channel, mute); err != nil {
+		return err
+	}
+	a.gains = a.controls.Gains()
+	return nil
+}
+
+// SetChannelSolo チャンネルをソロにする、ソロのチャンネルがある場合はソロのチャンネルだけが鳴る
+func (a *APU) SetChannelSolo(channel Channel, solo bool) error {
+	if err := a.controls.SetSolo(channel, solo); err != nil {
+		return err
+	}
+	a.gains = a.controls.Gains()
+	return nil
+}
+
+// SetChannelVolume チャンネルの音量の倍率を設定する、1.0で元の音量
+func (a *APU) SetChannelVolume(channel Channel, volume float32) error {
+	if err := a.controls.SetVolume(channel, volume); err != nil {
+		return err
+	}
+	a.gains = a.controls.Gains()
+	return nil
+}
+
+// ChannelControls 現在のミュート・ソロ・音量の設定
+func (a *APU) ChannelControls() ChannelControls {
+	return a.controls
+}
+
+// SetChannelControls ミュート・ソロ・音量の設定をまとめて置き換える
+func (a *APU) SetChannelControls(controls ChannelControls) {
+	a.controls = controls
+	a.gains = controls.Gains()
+}
+
+func (a *APU) clockFrameEvent(event frameEvent) {
+	switch event {
+	case frameEventQuarter:
+		a.clockQuarterFrame()
+	case frameEventHalf:
+		a.clockQuarterFrame()
+		a.clockHalfFrame()
+	}
+}
+
+// clockQuarterFrame envelopeとtriangleのlinear counterをclockする
+func (a *APU) clockQuarterFrame() {
+	a.pulse1.envelope.clock()
+	a.pulse2.envelope.clock()
+	a.triangle.clockLinearCounter()
+	a.noise.envelope.clock()
+}
+
+// clockHalfFrame length counterとsweepをclockする
+func (a *APU) clockHalfFrame() {
+	a.pulse1.lengthCounter.clock()
+	a.pulse2.lengthCounter.clock()
+	a.triangle.lengthCounter.clock()
+	a.noise.lengthCounter.clock()
+	a.pulse1.clockSweep()
+	a.pulse2.clockSweep()
+}
+
+// readStatus $4015の読み込み、frame IRQのフラグはクリアされる
+func (a *APU) readStatus() byte {
+	var status byte
+	if a.pulse1.lengthCounter.active() {
+		status |= 0x01
+	}
+	if a.pulse2.lengthCounter.active() {
+		status |= 0x02
+	}
+	if a.triangle.lengthCounter.active() {
+		status |= 0x04
+	}
+	if a.noise.lengthCounter.active() {
+		status |= 0x08
+	}
+	if a.dmc.bytesRemain > 0 {
+		status |= 0x10
+	}
+	if a.frameCounter.irq {
+		status |= 0x40
+	}
+	if a.dmc.irq {
+		status |= 0x80
+	}
+	a.frameCounter.irq = false
+	return status
+}
+
+// writeStatus $4015の書き込み、各チャンネルの有効/無効を切り替える
+func (a *APU) writeStatus(value byte) {
+	a.pulse1.lengthCounter.setEnabled(value&0x01 != 0)
+	a.pulse2.lengthCounter.setEnabled(value&0x02 != 0)
+	a.triangle.lengthCounter.setEnabled(value&0x04 != 0)
+	a.noise.lengthCounter.setEnabled(value&0x08 != 0)
+	a.dmc.setEnabled(value&0x10 != 0)
 }
 
 func IsAPUAddrRange(addr uint16) bool {
 	return addr >= addrAPUIOStart && addr <= addrAPUIOEnd
 }
+
+// IsFrameCounterAddr $4017は書き込みの場合はAPUのframe counter、読み込みの場合は2Pのコントローラーになる
+func IsFrameCounterAddr(addr uint16) bool {
+	return addr == addrAPUFrameCounter
+}
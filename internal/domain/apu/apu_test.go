@@ -0,0 +1,175 @@
+package apu_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+)
+
+type sampleCollector struct {
+	samples []apu.Sample
+}
+
+func (c *sampleCollector) WriteSample(sample apu.Sample) {
+	c.samples = append(c.samples, sample)
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestAPU_Status$ github.com/sunjin110/nes_emu/internal/domain/apu
+func TestAPU_Status(t *testing.T) {
+	Convey("TestAPU_Status", t, func() {
+		Convey("$4015で有効化したチャンネルはlength counterがロードされる", func() {
+			a := apu.NewAPU()
+			a.Write(0x4015, 0x01)
+			a.Write(0x4003, 0x08) // length index 1 => 254
+			So(a.Read(0x4015)&0x01, ShouldEqual, 0x01)
+
+			a.Write(0x4007, 0x08) // pulse2は無効なのでロードされない
+			So(a.Read(0x4015)&0x02, ShouldEqual, 0x00)
+		})
+
+		Convey("$4015で無効化するとlength counterが0になる", func() {
+			a := apu.NewAPU()
+			a.Write(0x4015, 0x0F)
+			a.Write(0x400B, 0x08)
+			So(a.Read(0x4015)&0x04, ShouldEqual, 0x04)
+			a.Write(0x4015, 0x00)
+			So(a.Read(0x4015)&0x04, ShouldEqual, 0x00)
+		})
+
+		Convey("4-step modeではframe IRQが発生し、$4015の読み込みでクリアされる", func() {
+			a := apu.NewAPU()
+			for i := 0; i < 29830; i++ {
+				a.Step()
+			}
+			So(a.IRQ(), ShouldBeTrue)
+			So(a.Read(0x4015)&0x40, ShouldEqual, 0x40)
+			So(a.IRQ(), ShouldBeFalse)
+		})
+
+		Convey("IRQ inhibitの場合はframe IRQが発生しない", func() {
+			a := apu.NewAPU()
+			a.Write(0x4017, 0x40)
+			for i := 0; i < 29830; i++ {
+				a.Step()
+			}
+			So(a.IRQ(), ShouldBeFalse)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestAPU_Sample$ github.com/sunjin110/nes_emu/internal/domain/apu
+func TestAPU_Sample(t *testing.T) {
+	Convey("TestAPU_Sample", t, func() {
+		a := apu.NewAPU()
+		collector := &sampleCollector{}
+		a.SetSampleSink(collector)
+
+		// pulse1: duty 50%, constant volume 15, period 0x100
+		a.Write(0x4015, 0x01)
+		a.Write(0x4000, 0xBF)
+		a.Write(0x4002, 0x00)
+		a.Write(0x4003, 0x09)
+
+		for i := 0; i < 10000; i++ {
+			a.Step()
+		}
+		So(len(collector.samples), ShouldEqual, 10000)
+
+		var maxPulse1, minPulse1 float32 = 0, 1
+		for _, s := range collector.samples {
+			So(s.Pulse2, ShouldEqual, 0)
+			So(s.Noise, ShouldEqual, 0)
+			So(s.Mixed, ShouldBeGreaterThanOrEqualTo, s.Pulse1)
+			maxPulse1 = max(maxPulse1, s.Pulse1)
+			minPulse1 = min(minPulse1, s.Pulse1)
+		}
+		// pulse1の音量15の場合: 95.88 / (8128/15 + 100)
+		So(maxPulse1, ShouldAlmostEqual, 95.88/(8128.0/15+100), 0.0001)
+		So(minPulse1, ShouldEqual, 0)
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestAPU_ChannelControls$ github.com/sunjin110/nes_emu/internal/domain/apu
+func TestAPU_ChannelControls(t *testing.T) {
+	Convey("TestAPU_ChannelControls", t, func() {
+		a := apu.NewAPU()
+		vrc6 := apu.NewVRC6()
+		a.AddExpansionAudio(vrc6)
+		collector := &sampleCollector{}
+		a.SetSampleSink(collector)
+
+		// pulse1: constant volume 15, VRC6 pulse1: 常に音量15を出力
+		a.Write(0x4015, 0x01)
+		a.Write(0x4000, 0xFF)
+		a.Write(0x4003, 0x09)
+		vrc6.Write(0x9000, 0x8F)
+		vrc6.Write(0x9002, 0x80)
+
+		// step 1000サイクル進めて、各チャンネルの最大の出力を返す
+		step := func() apu.Sample {
+			var peak apu.Sample
+			for i := 0; i < 1000; i++ {
+				a.Step()
+				s := collector.samples[len(collector.samples)-1]
+				peak.Mixed = max(peak.Mixed, s.Mixed)
+				peak.Pulse1 = max(peak.Pulse1, s.Pulse1)
+				peak.Expansion = max(peak.Expansion, s.Expansion)
+			}
+			return peak
+		}
+
+		So(a.Channels(), ShouldResemble, []apu.Channel{
+			apu.ChannelPulse1, apu.ChannelPulse2, apu.ChannelTriangle, apu.ChannelNoise, apu.ChannelDMC,
+			apu.ChannelVRC6Pulse1, apu.ChannelVRC6Pulse2, apu.ChannelVRC6Saw,
+		})
+
+		Convey("ミュートしたチャンネルは出力されない", func() {
+			So(a.SetChannelMute(apu.ChannelPulse1, true), ShouldBeNil)
+			s := step()
+			So(s.Pulse1, ShouldEqual, 0)
+			So(s.Expansion, ShouldBeGreaterThan, 0)
+
+			So(a.SetChannelMute(apu.ChannelPulse1, false), ShouldBeNil)
+			So(step().Pulse1, ShouldBeGreaterThan, 0)
+		})
+
+		Convey("ソロのチャンネルがある場合はソロのチャンネルだけが出力される", func() {
+			So(a.SetChannelSolo(apu.ChannelVRC6Pulse1, true), ShouldBeNil)
+			s := step()
+			So(s.Pulse1, ShouldEqual, 0)
+			So(s.Expansion, ShouldBeGreaterThan, 0)
+			So(s.Mixed, ShouldEqual, s.Expansion)
+		})
+
+		Convey("音量の倍率をかけた出力になる", func() {
+			before := step().Expansion
+			So(a.SetChannelVolume(apu.ChannelVRC6Pulse1, 0.5), ShouldBeNil)
+			So(step().Expansion, ShouldAlmostEqual, before*0.5, 0.0001)
+		})
+
+		Convey("存在しないチャンネルはErrInvalidChannelで、設定は変わらない", func() {
+			before := a.ChannelControls()
+			So(errors.Is(a.SetChannelMute(apu.ChannelCount, true), apu.ErrInvalidChannel), ShouldBeTrue)
+			So(errors.Is(a.SetChannelSolo(apu.Channel(0xFF), true), apu.ErrInvalidChannel), ShouldBeTrue)
+			So(errors.Is(a.SetChannelVolume(apu.ChannelCount, 2), apu.ErrInvalidChannel), ShouldBeTrue)
+			So(a.ChannelControls(), ShouldResemble, before)
+
+			controls := a.ChannelControls()
+			So(controls.Mute(apu.ChannelCount), ShouldBeFalse)
+			So(controls.Solo(apu.ChannelCount), ShouldBeFalse)
+			So(controls.Volume(apu.ChannelCount), ShouldEqual, 0)
+		})
+
+		Convey("チャンネル名から変換できる", func() {
+			channel, err := apu.ParseChannel("vrc6-saw")
+			So(err, ShouldBeNil)
+			So(channel, ShouldEqual, apu.ChannelVRC6Saw)
+			So(channel.String(), ShouldEqual, "vrc6-saw")
+
+			_, err = apu.ParseChannel("unknown")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
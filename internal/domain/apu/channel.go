@@ -0,0 +1,188 @@
+package apu
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidChannel ChannelCount以上のチャンネル
+var ErrInvalidChannel = errors.New("APU: invalid channel")
+
+// Channel APUと拡張音源のチャンネルの識別子
+// 値はファイルや設定に保存されても変わらないように、新しいチャンネルは末尾に追加する
+type Channel byte
+
+const (
+	ChannelPulse1 Channel = iota
+	ChannelPulse2
+	ChannelTriangle
+	ChannelNoise
+	ChannelDMC
+
+	ChannelVRC6Pulse1
+	ChannelVRC6Pulse2
+	ChannelVRC6Saw
+
+	ChannelVRC7FM1
+	ChannelVRC7FM2
+	ChannelVRC7FM3
+	ChannelVRC7FM4
+	ChannelVRC7FM5
+	ChannelVRC7FM6
+
+	ChannelFDS
+
+	ChannelMMC5Pulse1
+	ChannelMMC5Pulse2
+	ChannelMMC5PCM
+
+	ChannelN163Wave1
+	ChannelN163Wave2
+	ChannelN163Wave3
+	ChannelN163Wave4
+	ChannelN163Wave5
+	ChannelN163Wave6
+	ChannelN163Wave7
+	ChannelN163Wave8
+
+	ChannelSunsoft5BA
+	ChannelSunsoft5BB
+	ChannelSunsoft5BC
+
+	ChannelCount // チャンネルの数
+)
+
+var channelNames = [ChannelCount]string{
+	ChannelPulse1:     "pulse1",
+	ChannelPulse2:     "pulse2",
+	ChannelTriangle:   "triangle",
+	ChannelNoise:      "noise",
+	ChannelDMC:        "dmc",
+	ChannelVRC6Pulse1: "vrc6-pulse1",
+	ChannelVRC6Pulse2: "vrc6-pulse2",
+	ChannelVRC6Saw:    "vrc6-saw",
+	ChannelVRC7FM1:    "vrc7-fm1",
+	ChannelVRC7FM2:    "vrc7-fm2",
+	ChannelVRC7FM3:    "vrc7-fm3",
+	ChannelVRC7FM4:    "vrc7-fm4",
+	ChannelVRC7FM5:    "vrc7-fm5",
+	ChannelVRC7FM6:    "vrc7-fm6",
+	ChannelFDS:        "fds",
+	ChannelMMC5Pulse1: "mmc5-pulse1",
+	ChannelMMC5Pulse2: "mmc5-pulse2",
+	ChannelMMC5PCM:    "mmc5-pcm",
+	ChannelN163Wave1:  "n163-1",
+	ChannelN163Wave2:  "n163-2",
+	ChannelN163Wave3:  "n163-3",
+	ChannelN163Wave4:  "n163-4",
+	ChannelN163Wave5:  "n163-5",
+	ChannelN163Wave6:  "n163-6",
+	ChannelN163Wave7:  "n163-7",
+	ChannelN163Wave8:  "n163-8",
+	ChannelSunsoft5BA: "5b-a",
+	ChannelSunsoft5BB: "5b-b",
+	ChannelSunsoft5BC: "5b-c",
+}
+
+// Channels2A03 2A03の内蔵チャンネル
+var Channels2A03 = []Channel{ChannelPulse1, ChannelPulse2, ChannelTriangle, ChannelNoise, ChannelDMC}
+
+func (c Channel) String() string {
+	if c >= ChannelCount {
+		return fmt.Sprintf("Channel(%d)", byte(c))
+	}
+	return channelNames[c]
+}
+
+// ParseChannel "pulse1"や"vrc6-saw"のようなチャンネル名からChannelを返す
+func ParseChannel(name string) (Channel, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, channelName := range channelNames {
+		if channelName == name {
+			return Channel(i), nil
+		}
+	}
+	return 0, fmt.Errorf("APU: unknown channel. name: %s", name)
+}
+
+// ChannelGains チャンネルごとの出力にかける倍率、ミュートされたチャンネルは0
+type ChannelGains [ChannelCount]float32
+
+// ChannelControls チャンネルごとのミュート・ソロ・音量の設定
+// エミュレーションの状態には影響せず、ミキサーに渡す出力だけを変える
+// ソロのチャンネルが1つ以上ある場合は、ソロのチャンネルだけが鳴る
+type ChannelControls struct {
+	mute   [ChannelCount]bool
+	solo   [ChannelCount]bool
+	volume [ChannelCount]float32
+}
+
+// NewChannelControls 全チャンネルがミュートなし・音量1.0の設定
+func NewChannelControls() ChannelControls {
+	var c ChannelControls
+	for i := range c.volume {
+		c.volume[i] = 1
+	}
+	return c
+}
+
+func (c *ChannelControls) SetMute(channel Channel, mute bool) error {
+	if channel >= ChannelCount {
+		return fmt.Errorf("%w. channel: %d", ErrInvalidChannel, channel)
+	}
+	c.mute[channel] = mute
+	return nil
+}
+
+func (c *ChannelControls) SetSolo(channel Channel, solo bool) error {
+	if channel >= ChannelCount {
+		return fmt.Errorf("%w. channel: %d", ErrInvalidChannel, channel)
+	}
+	c.solo[channel] = solo
+	return nil
+}
+
+// SetVolume 音量の倍率を設定する、1.0で元の音量
+func (c *ChannelControls) SetVolume(channel Channel, volume float32) error {
+	if channel >= ChannelCount {
+		return fmt.Errorf("%w. channel: %d", ErrInvalidChannel, channel)
+	}
+	c.volume[channel] = max(volume, 0)
+	return nil
+}
+
+// Mute 存在しないチャンネルはfalse
+func (c *ChannelControls) Mute(channel Channel) bool {
+	return channel < ChannelCount && c.mute[channel]
+}
+
+// Solo 存在しないチャンネルはfalse
+func (c *ChannelControls) Solo(channel Channel) bool {
+	return channel < ChannelCount && c.solo[channel]
+}
+
+// Volume 存在しないチャンネルは0
+func (c *ChannelControls) Volume(channel Channel) float32 {
+	if channel >= ChannelCount {
+		return 0
+	}
+	return c.volume[channel]
+}
+
+// Gains ミュート・ソロ・音量を反映した各チャンネルの倍率
+func (c *ChannelControls) Gains() ChannelGains {
+	soloing := false
+	for _, solo := range c.solo {
+		soloing = soloing || solo
+	}
+
+	var gains ChannelGains
+	for i := range gains {
+		if c.mute[i] || (soloing && !c.solo[i]) {
+			continue
+		}
+		gains[i] = c.volume[i]
+	}
+	return gains
+}
@@ -0,0 +1,155 @@
+package apu
+
+import "github.com/sunjin110/nes_emu/pkg/logger"
+
+// dmcRateTable NTSCのDMCのtimer周期(CPUサイクル)
+// doc: https://www.nesdev.org/wiki/APU_DMC
+var dmcRateTable = [16]uint16{
+	428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84, 72, 54,
+}
+
+// Bus DMCがサンプルを読み込むためのCPUのメモリ
+type Bus interface {
+	Read(addr uint16) (byte, error)
+}
+
+// dmc Delta Modulation Channel($4010-$4013)
+// CPUのメモリ($C000-$FFFF)から1bitのデルタ値を読み込んで7bitの出力レベルを上下させる
+type dmc struct {
+	bus Bus
+
+	irqEnabled bool
+	irq        bool
+	loop       bool
+	timerValue uint16
+	rate       uint16
+
+	outputLevel byte // 7bit
+
+	sampleAddr   uint16
+	sampleLength uint16
+	currentAddr  uint16
+	bytesRemain  uint16
+
+	sampleBuffer      byte
+	sampleBufferEmpty bool
+
+	shiftRegister byte
+	bitsRemain    byte
+	silence       bool
+}
+
+func newDMC() dmc {
+	return dmc{
+		rate:              dmcRateTable[0],
+		sampleBufferEmpty: true,
+		bitsRemain:        8,
+		silence:           true,
+	}
+}
+
+func (d *dmc) write(reg uint16, value byte) {
+	switch reg {
+	case 0:
+		d.irqEnabled = value&0x80 != 0
+		if !d.irqEnabled {
+			d.irq = false
+		}
+		d.loop = value&0x40 != 0
+		d.rate = dmcRateTable[value&0x0F]
+	case 1:
+		d.outputLevel = value & 0x7F
+	case 2:
+		d.sampleAddr = 0xC000 | (uint16(value) << 6)
+	case 3:
+		d.sampleLength = (uint16(value) << 4) | 1
+	}
+}
+
+func (d *dmc) setEnabled(enabled bool) {
+	d.irq = false
+	if !enabled {
+		d.bytesRemain = 0
+		return
+	}
+	if d.bytesRemain == 0 {
+		d.restart()
+	}
+}
+
+func (d *dmc) restart() {
+	d.currentAddr = d.sampleAddr
+	d.bytesRemain = d.sampleLength
+}
+
+// fillSampleBuffer sample bufferが空の場合にメモリから1byte読み込む
+func (d *dmc) fillSampleBuffer() {
+	if !d.sampleBufferEmpty || d.bytesRemain == 0 {
+		return
+	}
+
+	var value byte
+	if d.bus != nil {
+		v, err := d.bus.Read(d.currentAddr)
+		if err != nil {
+			logger.Logger.Warn("APU: DMC: failed read sample", "addr", d.currentAddr, "err", err)
+		}
+		value = v
+	}
+	d.sampleBuffer = value
+	d.sampleBufferEmpty = false
+
+	// $FFFFを超えたら$8000に戻る
+	if d.currentAddr == 0xFFFF {
+		d.currentAddr = 0x8000
+	} else {
+		d.currentAddr++
+	}
+
+	d.bytesRemain--
+	if d.bytesRemain == 0 {
+		if d.loop {
+			d.restart()
+		} else if d.irqEnabled {
+			d.irq = true
+		}
+	}
+}
+
+// clockTimer CPUサイクルごとに呼ばれる
+func (d *dmc) clockTimer() {
+	d.fillSampleBuffer()
+
+	if d.timerValue > 0 {
+		d.timerValue--
+		return
+	}
+	d.timerValue = d.rate - 1
+
+	if !d.silence {
+		if d.shiftRegister&0x01 != 0 {
+			if d.outputLevel <= 125 {
+				d.outputLevel += 2
+			}
+		} else if d.outputLevel >= 2 {
+			d.outputLevel -= 2
+		}
+	}
+	d.shiftRegister >>= 1
+
+	d.bitsRemain--
+	if d.bitsRemain == 0 {
+		d.bitsRemain = 8
+		if d.sampleBufferEmpty {
+			d.silence = true
+		} else {
+			d.silence = false
+			d.shiftRegister = d.sampleBuffer
+			d.sampleBufferEmpty = true
+		}
+	}
+}
+
+func (d *dmc) output() byte {
+	return d.outputLevel
+}
@@ -0,0 +1,88 @@
+package apu
+
+// lengthTable length counterにロードされる値
+// doc: https://www.nesdev.org/wiki/APU_Length_Counter
+var lengthTable = [32]byte{
+	10, 254, 20, 2, 40, 4, 80, 6, 160, 8, 60, 10, 14, 12, 26, 14,
+	12, 16, 24, 18, 48, 20, 96, 22, 192, 24, 72, 26, 16, 28, 32, 30,
+}
+
+// lengthCounter 0になったチャンネルは無音になる
+// half frameごとにデクリメントされる
+type lengthCounter struct {
+	enabled bool // $4015で有効化されているか
+	halt    bool // trueの場合はデクリメントしない
+	value   byte
+}
+
+func (l *lengthCounter) load(index byte) {
+	if !l.enabled {
+		return
+	}
+	l.value = lengthTable[index&0x1F]
+}
+
+func (l *lengthCounter) setEnabled(enabled bool) {
+	l.enabled = enabled
+	if !enabled {
+		l.value = 0
+	}
+}
+
+// clock half frameで呼ばれる
+func (l *lengthCounter) clock() {
+	if l.halt || l.value == 0 {
+		return
+	}
+	l.value--
+}
+
+func (l *lengthCounter) active() bool {
+	return l.value > 0
+}
+
+// envelope pulseとnoiseの音量を制御する
+// doc: https://www.nesdev.org/wiki/APU_Envelope
+type envelope struct {
+	start          bool
+	loop           bool // length counter haltと共用
+	constantVolume bool
+	period         byte // constantVolumeの場合は音量として扱う
+	divider        byte
+	decay          byte
+}
+
+// write $4000/$4004/$400Cの下位6bit
+func (e *envelope) write(value byte) {
+	e.loop = value&0x20 != 0
+	e.constantVolume = value&0x10 != 0
+	e.period = value & 0x0F
+}
+
+// clock quarter frameで呼ばれる
+func (e *envelope) clock() {
+	if e.start {
+		e.start = false
+		e.decay = 15
+		e.divider = e.period
+		return
+	}
+
+	if e.divider > 0 {
+		e.divider--
+		return
+	}
+	e.divider = e.period
+	if e.decay > 0 {
+		e.decay--
+	} else if e.loop {
+		e.decay = 15
+	}
+}
+
+func (e *envelope) volume() byte {
+	if e.constantVolume {
+		return e.period
+	}
+	return e.decay
+}
@@ -0,0 +1,36 @@
+package apu
+
+// ExpansionAudio カートリッジ側の拡張音源
+// doc: https://www.nesdev.org/wiki/Expansion_audio
+//
+// マッパー(またはNSFのプレーヤー)がCPUからの読み書きを転送し、APU.AddExpansionAudioで登録するとAPUが毎サイクルStepを呼んで出力をミックスする
+type ExpansionAudio interface {
+	// Write CPUからの書き込み、拡張音源のレジスタ以外のアドレスは無視する
+	Write(addr uint16, value byte)
+	// Read CPUからの読み込み、拡張音源の読み込み可能なレジスタでない場合はok=false
+	Read(addr uint16) (value byte, ok bool)
+	// Step CPUの1サイクル分進める
+	Step()
+	// Channels 拡張音源が持つチャンネル
+	Channels() []Channel
+	// Output 各チャンネルの出力にgainsの倍率をかけてミックスした現在の出力、2A03の出力(Sample.Mixed)と同じスケール
+	Output(gains *ChannelGains) float32
+}
+
+// squareVolumeUnit 2A03のpulse1チャンネルだけが音量15で鳴っている時の出力を15等分したもの
+// 拡張音源の相対音量はこの値を基準に合わせる
+// doc: https://www.nesdev.org/wiki/Expansion_audio
+var squareVolumeUnit = pulseMix(15, 0) / 15
+
+// AddExpansionAudio 拡張音源をミキサーに追加する
+func (a *APU) AddExpansionAudio(expansion ExpansionAudio) {
+	a.expansions = append(a.expansions, expansion)
+}
+
+func (a *APU) expansionOutput() float32 {
+	var out float32
+	for _, expansion := range a.expansions {
+		out += expansion.Output(&a.gains)
+	}
+	return out
+}
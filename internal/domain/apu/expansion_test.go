@@ -0,0 +1,77 @@
+package apu_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestExpansionAudio$ github.com/sunjin110/nes_emu/internal/domain/apu
+func TestExpansionAudio(t *testing.T) {
+	Convey("TestExpansionAudio", t, func() {
+		Convey("VRC6のpulseの音量15は2A03のpulseの音量15と同じ出力になる", func() {
+			a := apu.NewAPU()
+			vrc6 := apu.NewVRC6()
+			a.AddExpansionAudio(vrc6)
+
+			vrc6.Write(0x9000, 0x8F) // mode=1(常に音量を出力), 音量15
+			vrc6.Write(0x9002, 0x80)
+			a.Step()
+
+			collector := &sampleCollector{}
+			a.SetSampleSink(collector)
+			a.Step()
+			So(collector.samples[0].Expansion, ShouldAlmostEqual, 0.14938, 0.0001) // pulseMix(15, 0)
+			So(collector.samples[0].Mixed, ShouldBeGreaterThanOrEqualTo, collector.samples[0].Expansion)
+		})
+
+		Convey("N163の内部RAMは自動インクリメントで読み書きできる", func() {
+			n163 := apu.NewN163()
+			n163.Write(0xF800, 0x80|0x10)
+			n163.Write(0x4800, 0x12)
+			n163.Write(0x4800, 0x34)
+
+			n163.Write(0xF800, 0x80|0x10)
+			value, ok := n163.Read(0x4800)
+			So(ok, ShouldBeTrue)
+			So(value, ShouldEqual, 0x12)
+			value, _ = n163.Read(0x4800)
+			So(value, ShouldEqual, 0x34)
+
+			_, ok = n163.Read(0x5000)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("FDSの波形メモリは$4089のbit7が立っている時だけ書き込める", func() {
+			fds := apu.NewFDSAudio()
+			fds.Write(0x4040, 0x3F)
+			value, _ := fds.Read(0x4040)
+			So(value, ShouldEqual, 0x00)
+
+			fds.Write(0x4089, 0x80)
+			fds.Write(0x4040, 0x3F)
+			value, _ = fds.Read(0x4040)
+			So(value, ShouldEqual, 0x3F)
+		})
+
+		Convey("VRC7はkey onすると音が出る", func() {
+			vrc7 := apu.NewVRC7()
+			vrc7.Write(0x9010, 0x30)
+			vrc7.Write(0x9030, 0x30) // 音色3, 音量最大
+			vrc7.Write(0x9010, 0x10)
+			vrc7.Write(0x9030, 0xAC)
+			vrc7.Write(0x9010, 0x20)
+			vrc7.Write(0x9030, 0x18) // key on, block 4
+
+			controls := apu.NewChannelControls()
+			gains := controls.Gains()
+			var peak float32
+			for i := 0; i < apu.CPUClockNTSC/20; i++ {
+				vrc7.Step()
+				peak = max(peak, vrc7.Output(&gains))
+			}
+			So(peak, ShouldBeGreaterThan, 0)
+		})
+	})
+}
@@ -0,0 +1,225 @@
+package apu
+
+// FDSAudio ファミコンディスクシステムの拡張音源(波形メモリ音源 x1 + 周波数変調ユニット)
+// doc: https://www.nesdev.org/wiki/FDS_audio
+//
+// $4040-$407Fが64stepの6bit波形、$4080-$408Aが制御レジスタ
+// 相対音量: 音量(gain)32・マスター音量2/2で振幅最大の波形の場合、2A03のpulseの音量15の約2.4倍
+type FDSAudio struct {
+	wave         [64]byte
+	waveWrite    bool // $4089 bit7、波形の書き込み中は出力が止まる
+	masterVolume byte // $4089 bit0-1
+
+	wavePeriod   uint16 // 12bit
+	waveHalt     bool   // $4083 bit7
+	envelopeHalt bool   // $4083 bit6
+	waveAcc      uint32 // 下位16bitが小数部、(waveAcc >> 16) & 0x3Fが波形の位置
+
+	volume     fdsEnvelope
+	modulation fdsEnvelope
+	envSpeed   byte // $408A
+
+	modTable   [64]byte
+	modPos     byte // modTableの書き込み・読み込み位置
+	modPeriod  uint16
+	modHalt    bool
+	modAcc     uint32
+	modCounter int8 // 7bit符号付き
+
+	output byte // 波形の出力(波形の位置が変わった時だけ更新される)
+}
+
+type fdsEnvelope struct {
+	disabled bool // bit7
+	increase bool // bit6
+	speed    byte // bit0-5
+	gain     byte
+	timer    uint32
+}
+
+func (e *fdsEnvelope) write(value byte) {
+	e.disabled = value&0x80 != 0
+	e.increase = value&0x40 != 0
+	e.speed = value & 0x3F
+	if e.disabled {
+		e.gain = e.speed
+	}
+	e.timer = 0
+}
+
+// clock 8 * (speed + 1) * $408A CPUサイクルごとにgainを1増減する(上限32)
+func (e *fdsEnvelope) clock(envSpeed byte) {
+	if e.disabled || envSpeed == 0 {
+		return
+	}
+	e.timer++
+	if e.timer < 8*(uint32(e.speed)+1)*uint32(envSpeed) {
+		return
+	}
+	e.timer = 0
+	switch {
+	case e.increase && e.gain < 32:
+		e.gain++
+	case !e.increase && e.gain > 0:
+		e.gain--
+	}
+}
+
+// fdsModSteps modTableの値ごとのmodCounterの増減、4の場合は0にリセットする
+var fdsModSteps = [8]int8{0, 1, 2, 4, 0, -4, -2, -1}
+
+// fdsMasterVolumes $4089のbit0-1ごとの音量(2/2, 2/3, 2/4, 2/5)
+var fdsMasterVolumes = [4]float32{2.0 / 2, 2.0 / 3, 2.0 / 4, 2.0 / 5}
+
+// fdsGain 波形の最大値63 x gain 32 が2A03のpulse 2.4個分
+const fdsGain = 2.4 * 15 / (63 * 32)
+
+func NewFDSAudio() *FDSAudio {
+	return &FDSAudio{
+		envSpeed: 0xE8,
+	}
+}
+
+var _ ExpansionAudio = (*FDSAudio)(nil)
+
+func (f *FDSAudio) Write(addr uint16, value byte) {
+	switch {
+	case addr >= 0x4040 && addr <= 0x407F:
+		if f.waveWrite {
+			f.wave[addr-0x4040] = value & 0x3F
+		}
+	case addr == 0x4080:
+		f.volume.write(value)
+	case addr == 0x4082:
+		f.wavePeriod = (f.wavePeriod & 0x0F00) | uint16(value)
+	case addr == 0x4083:
+		f.wavePeriod = (f.wavePeriod & 0x00FF) | uint16(value&0x0F)<<8
+		f.waveHalt = value&0x80 != 0
+		f.envelopeHalt = value&0x40 != 0
+		if f.waveHalt {
+			f.waveAcc = 0
+		}
+	case addr == 0x4084:
+		f.modulation.write(value)
+	case addr == 0x4085:
+		f.modCounter = int8(value<<1) >> 1
+	case addr == 0x4086:
+		f.modPeriod = (f.modPeriod & 0x0F00) | uint16(value)
+	case addr == 0x4087:
+		f.modPeriod = (f.modPeriod & 0x00FF) | uint16(value&0x0F)<<8
+		f.modHalt = value&0x80 != 0
+		if f.modHalt {
+			f.modAcc &= 0xFFFF0000
+		}
+	case addr == 0x4088:
+		// 停止中のみ書き込める、1回の書き込みで2step分書き込まれる
+		if f.modHalt {
+			f.modTable[f.modPos] = value & 0x07
+			f.modTable[(f.modPos+1)&0x3F] = value & 0x07
+			f.modPos = (f.modPos + 2) & 0x3F
+		}
+	case addr == 0x4089:
+		f.waveWrite = value&0x80 != 0
+		f.masterVolume = value & 0x03
+	case addr == 0x408A:
+		f.envSpeed = value
+	}
+}
+
+func (f *FDSAudio) Read(addr uint16) (byte, bool) {
+	switch {
+	case addr >= 0x4040 && addr <= 0x407F:
+		// 上位2bitはopen busだが0を返す
+		return f.wave[addr-0x4040], true
+	case addr == 0x4090:
+		return f.volume.gain, true
+	case addr == 0x4092:
+		return f.modulation.gain, true
+	}
+	return 0, false
+}
+
+func (f *FDSAudio) Step() {
+	if !f.waveHalt && !f.envelopeHalt {
+		f.volume.clock(f.envSpeed)
+		f.modulation.clock(f.envSpeed)
+	}
+
+	f.clockModulation()
+
+	if f.waveHalt || f.waveWrite {
+		return
+	}
+	before := f.waveAcc >> 16
+	f.waveAcc = (f.waveAcc + uint32(f.pitch())) & 0x3FFFFF
+	if pos := f.waveAcc >> 16; pos != before {
+		f.output = f.wave[pos&0x3F]
+	}
+}
+
+// clockModulation modAccが16bitを超えるたびにmodTableを1step進めてmodCounterを更新する
+func (f *FDSAudio) clockModulation() {
+	if f.modHalt || f.modPeriod == 0 {
+		return
+	}
+	f.modAcc += uint32(f.modPeriod)
+	if f.modAcc < 0x10000 {
+		return
+	}
+	f.modAcc -= 0x10000
+
+	step := f.modTable[f.modPos]
+	f.modPos = (f.modPos + 1) & 0x3F
+	if step == 4 {
+		f.modCounter = 0
+		return
+	}
+	// 7bitでラップアラウンドする
+	f.modCounter = int8((f.modCounter+fdsModSteps[step])<<1) >> 1
+}
+
+// pitch 周波数変調をかけた波形の周期
+// doc: https://www.nesdev.org/wiki/FDS_audio#Frequency_calculation
+func (f *FDSAudio) pitch() int {
+	pitch := int(f.wavePeriod)
+	if f.modHalt {
+		return pitch
+	}
+
+	temp := int(f.modCounter) * int(f.modulation.gain)
+	remainder := temp & 0x0F
+	temp >>= 4
+	if remainder > 0 && temp&0x80 == 0 {
+		if f.modCounter < 0 {
+			temp--
+		} else {
+			temp += 2
+		}
+	}
+	switch {
+	case temp >= 192:
+		temp -= 256
+	case temp < -64:
+		temp += 256
+	}
+
+	temp = pitch * temp
+	remainder = temp & 0x3F
+	temp >>= 6
+	if remainder >= 32 {
+		temp++
+	}
+	return max(pitch+temp, 0)
+}
+
+func (f *FDSAudio) Channels() []Channel {
+	return []Channel{ChannelFDS}
+}
+
+func (f *FDSAudio) Output(gains *ChannelGains) float32 {
+	if f.waveWrite {
+		return 0
+	}
+	gain := min(f.volume.gain, 32)
+	return float32(f.output) * float32(gain) * fdsMasterVolumes[f.masterVolume] * fdsGain * squareVolumeUnit * gains[ChannelFDS]
+}
@@ -0,0 +1,76 @@
+package apu
+
+// frameCounter envelope, sweep, length counter, linear counterを駆動するシーケンサ($4017)
+// doc: https://www.nesdev.org/wiki/APU_Frame_Counter
+//
+// 4-step mode: quarter, half+quarter, quarter, half+quarter+IRQ
+// 5-step mode: quarter, half+quarter, quarter, (なし), half+quarter
+type frameCounter struct {
+	fiveStep   bool
+	irqInhibit bool
+	irq        bool
+	cycle      uint32
+}
+
+// NTSCのCPUサイクル数でのステップのタイミング
+const (
+	frameStep1       = 7457
+	frameStep2       = 14913
+	frameStep3       = 22371
+	frameStep4       = 29829
+	frameStep5       = 37281
+	frameFourStepEnd = 29830
+	frameFiveStepEnd = 37282
+)
+
+type frameEvent int
+
+const (
+	frameEventNone frameEvent = iota
+	frameEventQuarter
+	frameEventHalf // halfの場合はquarterも同時にclockする
+)
+
+func (f *frameCounter) write(value byte) (immediate frameEvent) {
+	f.fiveStep = value&0x80 != 0
+	f.irqInhibit = value&0x40 != 0
+	if f.irqInhibit {
+		f.irq = false
+	}
+	f.cycle = 0
+
+	// 5-step modeに設定された場合は即座にquarterとhalfをclockする
+	if f.fiveStep {
+		return frameEventHalf
+	}
+	return frameEventNone
+}
+
+// clock CPUサイクルごとに呼ばれる
+func (f *frameCounter) clock() frameEvent {
+	f.cycle++
+
+	switch f.cycle {
+	case frameStep1, frameStep3:
+		return frameEventQuarter
+	case frameStep2:
+		return frameEventHalf
+	case frameStep4:
+		if f.fiveStep {
+			return frameEventNone
+		}
+		if !f.irqInhibit {
+			f.irq = true
+		}
+		return frameEventHalf
+	case frameFourStepEnd:
+		if !f.fiveStep {
+			f.cycle = 0
+		}
+	case frameStep5:
+		return frameEventHalf
+	case frameFiveStepEnd:
+		f.cycle = 0
+	}
+	return frameEventNone
+}
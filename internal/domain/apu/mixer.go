@@ -0,0 +1,43 @@
+package apu
+
+// 各チャンネルの出力を非線形にミックスする
+// doc: https://www.nesdev.org/wiki/APU_Mixer
+
+// pulseMix pulse1とpulse2の出力(0-15)をミックスする
+// 音量の調整のため、出力にChannelGainsの倍率をかけた値も受け付ける
+func pulseMix(pulse1, pulse2 float32) float32 {
+	sum := pulse1 + pulse2
+	if sum == 0 {
+		return 0
+	}
+	return 95.88 / (8128/sum + 100)
+}
+
+// tndMix triangle(0-15), noise(0-15), dmc(0-127)の出力をミックスする
+func tndMix(triangle, noise, dmc float32) float32 {
+	sum := triangle/8227 + noise/12241 + dmc/22638
+	if sum == 0 {
+		return 0
+	}
+	return 159.79 / (1/sum + 100)
+}
+
+// Sample CPUサイクルごとのAPUの出力
+// Mixedは全チャンネルをミックスしたもので、2A03のみの場合は0.0〜1.0の範囲になる
+// 各チャンネルの値は、そのチャンネルだけをミキサーに通した場合の出力
+// いずれもAPU.SetChannelMuteなどのミュート・ソロ・音量の設定を反映した値になる
+// Expansionは拡張音源の出力の合計
+type Sample struct {
+	Mixed     float32
+	Pulse1    float32
+	Pulse2    float32
+	Triangle  float32
+	Noise     float32
+	DMC       float32
+	Expansion float32
+}
+
+// SampleSink APUの出力をCPUサイクルごとに受け取る
+type SampleSink interface {
+	WriteSample(sample Sample)
+}
@@ -0,0 +1,111 @@
+package apu
+
+// MMC5Audio MMC5の拡張音源(pulse x2, PCM x1)
+// doc: https://www.nesdev.org/wiki/MMC5_audio
+//
+// pulseは2A03のpulseと同じだがsweepがなく、envelopeとlength counterは240Hz固定でclockされる
+// PCMは$5011への書き込み(write mode)のみ対応し、$8000-$BFFFの読み込みを監視するread modeは未対応
+// 相対音量: pulseは2A03のpulseと同じ非線形ミキサー、PCMは2A03のDMCと同程度
+type MMC5Audio struct {
+	pulse1 pulse
+	pulse2 pulse
+
+	pcmValue      byte
+	pcmReadMode   bool
+	pcmIRQEnabled bool
+	pcmIRQ        bool
+
+	evenCycle    bool
+	frameCounter uint16
+}
+
+// mmc5FrameRate 240Hzでenvelopeとlength counterをclockするためのCPUサイクル数
+const mmc5FrameRate = CPUClockNTSC / 240
+
+func NewMMC5Audio() *MMC5Audio {
+	m := &MMC5Audio{
+		pulse1: newPulse(true),
+		pulse2: newPulse(false),
+	}
+	m.pulse1.noSweep = true
+	m.pulse2.noSweep = true
+	return m
+}
+
+var _ ExpansionAudio = (*MMC5Audio)(nil)
+
+func (m *MMC5Audio) Write(addr uint16, value byte) {
+	switch {
+	case addr >= 0x5000 && addr <= 0x5003:
+		m.pulse1.write(addr-0x5000, value)
+	case addr >= 0x5004 && addr <= 0x5007:
+		m.pulse2.write(addr-0x5004, value)
+	case addr == 0x5010:
+		m.pcmReadMode = value&0x01 != 0
+		m.pcmIRQEnabled = value&0x80 != 0
+	case addr == 0x5011:
+		// 0の書き込みは無視される
+		if !m.pcmReadMode && value != 0 {
+			m.pcmValue = value
+		}
+	case addr == 0x5015:
+		m.pulse1.lengthCounter.setEnabled(value&0x01 != 0)
+		m.pulse2.lengthCounter.setEnabled(value&0x02 != 0)
+	}
+}
+
+func (m *MMC5Audio) Read(addr uint16) (byte, bool) {
+	switch addr {
+	case 0x5010:
+		var value byte
+		if m.pcmIRQ {
+			value |= 0x80
+		}
+		if m.pcmReadMode {
+			value |= 0x01
+		}
+		m.pcmIRQ = false
+		return value, true
+	case 0x5015:
+		var value byte
+		if m.pulse1.lengthCounter.active() {
+			value |= 0x01
+		}
+		if m.pulse2.lengthCounter.active() {
+			value |= 0x02
+		}
+		return value, true
+	}
+	return 0, false
+}
+
+// IRQ PCMのIRQ、read modeは未対応なので常にfalse
+func (m *MMC5Audio) IRQ() bool {
+	return m.pcmIRQ && m.pcmIRQEnabled
+}
+
+func (m *MMC5Audio) Step() {
+	if m.evenCycle {
+		m.pulse1.clockTimer()
+		m.pulse2.clockTimer()
+	}
+	m.evenCycle = !m.evenCycle
+
+	m.frameCounter++
+	if m.frameCounter >= mmc5FrameRate {
+		m.frameCounter = 0
+		m.pulse1.envelope.clock()
+		m.pulse2.envelope.clock()
+		m.pulse1.lengthCounter.clock()
+		m.pulse2.lengthCounter.clock()
+	}
+}
+
+func (m *MMC5Audio) Channels() []Channel {
+	return []Channel{ChannelMMC5Pulse1, ChannelMMC5Pulse2, ChannelMMC5PCM}
+}
+
+func (m *MMC5Audio) Output(gains *ChannelGains) float32 {
+	return pulseMix(float32(m.pulse1.output())*gains[ChannelMMC5Pulse1], float32(m.pulse2.output())*gains[ChannelMMC5Pulse2]) +
+		tndMix(0, 0, float32(m.pcmValue>>1)*gains[ChannelMMC5PCM])
+}
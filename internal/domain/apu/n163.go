@@ -0,0 +1,135 @@
+package apu
+
+// N163 Namco 163の拡張音源(波形メモリ音源 最大8チャンネル)
+// doc: https://www.nesdev.org/wiki/Namco_163_audio
+//
+// 128byteの内部RAMに波形(4bitサンプル)とチャンネルのレジスタ($40-$7F)が入っている
+// $F800でアドレスを指定し、$4800で読み書きする
+// 15CPUサイクルごとに1チャンネルずつ更新されるので、有効なチャンネル数が多いほど各チャンネルの更新頻度が下がる
+// 実機は時分割で各チャンネルを出力しているが、ここでは有効なチャンネルの平均を出力する
+// 相対音量: 1チャンネルのみ音量15で振幅最大の波形の場合、2A03のpulseの音量15の約1.5倍(ボードによって差がある)
+type N163 struct {
+	ram           [0x80]byte
+	addr          byte
+	autoIncrement bool
+	disabled      bool // $E000 bit6、マッパーから設定する
+
+	cycle          byte
+	currentChannel byte
+	outputs        [8]int
+}
+
+const (
+	n163UpdateCycles = 15
+	n163Gain         = 1.5 * 15 / (15 * 15) // 振幅最大(15段階) x 音量15 が2A03のpulse 1.5個分
+)
+
+func NewN163() *N163 {
+	return &N163{}
+}
+
+var _ ExpansionAudio = (*N163)(nil)
+
+func (n *N163) Write(addr uint16, value byte) {
+	switch {
+	case addr >= 0x4800 && addr <= 0x4FFF:
+		n.ram[n.addr] = value
+		n.incrementAddr()
+	case addr >= 0xF800:
+		n.addr = value & 0x7F
+		n.autoIncrement = value&0x80 != 0
+	}
+}
+
+func (n *N163) Read(addr uint16) (byte, bool) {
+	if addr < 0x4800 || addr > 0x4FFF {
+		return 0, false
+	}
+	value := n.ram[n.addr]
+	n.incrementAddr()
+	return value, true
+}
+
+func (n *N163) incrementAddr() {
+	if n.autoIncrement {
+		n.addr = (n.addr + 1) & 0x7F
+	}
+}
+
+// SetDisabled $E000のbit6で音声の出力を止める
+func (n *N163) SetDisabled(disabled bool) {
+	n.disabled = disabled
+}
+
+// channelCount $7Fのbit4-6 + 1
+func (n *N163) channelCount() byte {
+	return (n.ram[0x7F]>>4)&0x07 + 1
+}
+
+func (n *N163) Step() {
+	if n.disabled {
+		return
+	}
+	n.cycle++
+	if n.cycle < n163UpdateCycles {
+		return
+	}
+	n.cycle = 0
+
+	// チャンネル7から順に有効なチャンネル数だけ更新する
+	count := n.channelCount()
+	if n.currentChannel >= count {
+		n.currentChannel = 0
+	}
+	channel := 7 - n.currentChannel
+	n.outputs[channel] = n.updateChannel(channel)
+	n.currentChannel++
+}
+
+// updateChannel チャンネルのphaseを進めて出力を計算する
+// チャンネルのレジスタ(base = $40 + channel * 8)
+// base+0: 周波数 下位, base+1: phase 下位, base+2: 周波数 中位, base+3: phase 中位
+// base+4: 波形の長さ(256 - 上位6bit) | 周波数 上位2bit, base+5: phase 上位
+// base+6: 波形のアドレス(4bitサンプル単位), base+7: 音量(下位4bit)
+func (n *N163) updateChannel(channel byte) int {
+	base := 0x40 + int(channel)*8
+	freq := int(n.ram[base]) | int(n.ram[base+2])<<8 | int(n.ram[base+4]&0x03)<<16
+	phase := int(n.ram[base+1]) | int(n.ram[base+3])<<8 | int(n.ram[base+5])<<16
+	length := 256 - int(n.ram[base+4]&0xFC)
+
+	phase = (phase + freq) % (length << 16)
+	n.ram[base+1] = byte(phase)
+	n.ram[base+3] = byte(phase >> 8)
+	n.ram[base+5] = byte(phase >> 16)
+
+	sampleAddr := (phase>>16 + int(n.ram[base+6])) & 0xFF
+	sample := n.ram[sampleAddr>>1]
+	if sampleAddr&0x01 != 0 {
+		sample >>= 4
+	}
+	sample &= 0x0F
+
+	volume := int(n.ram[base+7] & 0x0F)
+	return (int(sample) - 8) * volume
+}
+
+func (n *N163) Channels() []Channel {
+	return []Channel{
+		ChannelN163Wave1, ChannelN163Wave2, ChannelN163Wave3, ChannelN163Wave4,
+		ChannelN163Wave5, ChannelN163Wave6, ChannelN163Wave7, ChannelN163Wave8,
+	}
+}
+
+// Output チャンネル1(RAMの$40)〜チャンネル8(RAMの$78)のうち有効なチャンネルの平均
+func (n *N163) Output(gains *ChannelGains) float32 {
+	if n.disabled {
+		return 0
+	}
+	count := n.channelCount()
+	var sum float32
+	for i := byte(0); i < count; i++ {
+		channel := 7 - i
+		sum += float32(n.outputs[channel]) * gains[ChannelN163Wave1+Channel(channel)]
+	}
+	return sum / float32(count) * n163Gain * squareVolumeUnit
+}
@@ -0,0 +1,60 @@
+package apu
+
+// noisePeriodTable NTSCのnoiseのtimer周期(CPUサイクル)
+// doc: https://www.nesdev.org/wiki/APU_Noise
+var noisePeriodTable = [16]uint16{
+	4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034, 4068,
+}
+
+// noise ノイズチャンネル($400C-$400F)
+type noise struct {
+	mode          bool   // trueの場合は短周期(93bit)のノイズになる
+	shift         uint16 // 15bitのLFSR
+	timerPeriod   uint16
+	timerValue    uint16
+	envelope      envelope
+	lengthCounter lengthCounter
+}
+
+func newNoise() noise {
+	return noise{
+		shift: 1, // 電源投入時は1
+	}
+}
+
+func (n *noise) write(reg uint16, value byte) {
+	switch reg {
+	case 0:
+		n.envelope.write(value)
+		n.lengthCounter.halt = value&0x20 != 0
+	case 2:
+		n.mode = value&0x80 != 0
+		n.timerPeriod = noisePeriodTable[value&0x0F]
+	case 3:
+		n.lengthCounter.load(value >> 3)
+		n.envelope.start = true
+	}
+}
+
+// clockTimer CPUサイクルごとに呼ばれる
+func (n *noise) clockTimer() {
+	if n.timerValue > 0 {
+		n.timerValue--
+		return
+	}
+	n.timerValue = n.timerPeriod - 1
+
+	tap := uint16(1)
+	if n.mode {
+		tap = 6
+	}
+	feedback := (n.shift & 0x01) ^ ((n.shift >> tap) & 0x01)
+	n.shift = (n.shift >> 1) | (feedback << 14)
+}
+
+func (n *noise) output() byte {
+	if !n.lengthCounter.active() || n.shift&0x01 != 0 {
+		return 0
+	}
+	return n.envelope.volume()
+}
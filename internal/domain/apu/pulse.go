@@ -0,0 +1,125 @@
+package apu
+
+// dutyTable pulseの波形
+// doc: https://www.nesdev.org/wiki/APU_Pulse
+var dutyTable = [4][8]byte{
+	{0, 1, 0, 0, 0, 0, 0, 0}, // 12.5%
+	{0, 1, 1, 0, 0, 0, 0, 0}, // 25%
+	{0, 1, 1, 1, 1, 0, 0, 0}, // 50%
+	{1, 0, 0, 1, 1, 1, 1, 1}, // 25% negated
+}
+
+// pulse 矩形波チャンネル($4000-$4003, $4004-$4007)
+type pulse struct {
+	// channel1とchannel2ではsweepの減算の仕方が異なる
+	// channel1は1の補数、channel2は2の補数で計算される
+	isChannel1 bool
+	// MMC5のpulseにはsweepがなく、周期による消音もない
+	noSweep bool
+
+	duty          byte
+	sequencePos   byte
+	timerPeriod   uint16
+	timerValue    uint16
+	envelope      envelope
+	lengthCounter lengthCounter
+
+	sweepEnabled bool
+	sweepPeriod  byte
+	sweepNegate  bool
+	sweepShift   byte
+	sweepReload  bool
+	sweepDivider byte
+}
+
+func newPulse(isChannel1 bool) pulse {
+	return pulse{
+		isChannel1: isChannel1,
+	}
+}
+
+func (p *pulse) write(reg uint16, value byte) {
+	switch reg {
+	case 0:
+		p.duty = value >> 6
+		p.envelope.write(value)
+		p.lengthCounter.halt = value&0x20 != 0
+	case 1:
+		if p.noSweep {
+			return
+		}
+		p.sweepEnabled = value&0x80 != 0
+		p.sweepPeriod = (value >> 4) & 0x07
+		p.sweepNegate = value&0x08 != 0
+		p.sweepShift = value & 0x07
+		p.sweepReload = true
+	case 2:
+		p.timerPeriod = (p.timerPeriod & 0x0700) | uint16(value)
+	case 3:
+		p.timerPeriod = (p.timerPeriod & 0x00FF) | (uint16(value&0x07) << 8)
+		p.lengthCounter.load(value >> 3)
+		p.sequencePos = 0
+		p.envelope.start = true
+	}
+}
+
+// clockTimer APUサイクル(CPUの2サイクル)ごとに呼ばれる
+func (p *pulse) clockTimer() {
+	if p.timerValue == 0 {
+		p.timerValue = p.timerPeriod
+		p.sequencePos = (p.sequencePos + 1) & 0x07
+		return
+	}
+	p.timerValue--
+}
+
+// sweepTargetPeriod sweep unitが計算する目標周期
+func (p *pulse) sweepTargetPeriod() uint16 {
+	change := p.timerPeriod >> p.sweepShift
+	if !p.sweepNegate {
+		return p.timerPeriod + change
+	}
+	if p.isChannel1 {
+		// 1の補数
+		if change+1 > p.timerPeriod {
+			return 0
+		}
+		return p.timerPeriod - change - 1
+	}
+	if change > p.timerPeriod {
+		return 0
+	}
+	return p.timerPeriod - change
+}
+
+// clockSweep half frameで呼ばれる
+// doc: https://www.nesdev.org/wiki/APU_Sweep
+func (p *pulse) clockSweep() {
+	target := p.sweepTargetPeriod()
+	if p.sweepDivider == 0 && p.sweepEnabled && p.sweepShift > 0 && !p.isMuted(target) {
+		p.timerPeriod = target
+	}
+	if p.sweepDivider == 0 || p.sweepReload {
+		p.sweepDivider = p.sweepPeriod
+		p.sweepReload = false
+		return
+	}
+	p.sweepDivider--
+}
+
+func (p *pulse) isMuted(target uint16) bool {
+	if p.noSweep {
+		return false
+	}
+	return p.timerPeriod < 8 || target > 0x7FF
+}
+
+func (p *pulse) output() byte {
+	if !p.lengthCounter.active() || p.isMuted(p.sweepTargetPeriod()) {
+		return 0
+	}
+	if dutyTable[p.duty][p.sequencePos] == 0 {
+		return 0
+	}
+	return p.envelope.volume()
+}
@@ -0,0 +1,168 @@
+package apu
+
+import "math"
+
+// Sunsoft5B Sunsoft 5B(FME-7の音源付き版)の拡張音源、YM2149F(AY-3-8910互換)
+// doc: https://www.nesdev.org/wiki/Sunsoft_5B_audio
+//
+// $C000でレジスタ番号を指定し、$E000で書き込む
+// 矩形波x3、ノイズ、エンベロープを持つ
+// 相対音量: 1チャンネルの音量15が2A03のpulseの音量15の約1.5倍
+type Sunsoft5B struct {
+	register byte
+	regs     [16]byte
+
+	tones [3]sunsoft5BTone
+
+	noiseTimer  uint16
+	noiseShift  uint32 // 17bit LFSR
+	noiseOutput bool
+
+	envelopeTimer uint32
+	envelopeStep  byte // 0-31
+	envelopeHold  bool
+	envelopeAlt   bool // 反転中
+}
+
+type sunsoft5BTone struct {
+	timer  uint16
+	output bool
+}
+
+const sunsoft5BGain = 1.5
+
+// sunsoft5BVolumeTable 5bitの音量(エンベロープの値)から出力への変換、1stepあたり1.5dB
+// 4bitの音量は (volume * 2 + 1) で変換する
+var sunsoft5BVolumeTable = func() [32]float32 {
+	var table [32]float32
+	for i := 1; i < 32; i++ {
+		table[i] = float32(math.Pow(10, -1.5*float64(31-i)/20))
+	}
+	return table
+}()
+
+func NewSunsoft5B() *Sunsoft5B {
+	return &Sunsoft5B{
+		noiseShift: 1,
+	}
+}
+
+var _ ExpansionAudio = (*Sunsoft5B)(nil)
+
+func (s *Sunsoft5B) Write(addr uint16, value byte) {
+	switch addr & 0xE000 {
+	case 0xC000:
+		s.register = value & 0x0F
+	case 0xE000:
+		s.regs[s.register] = value
+		if s.register == 0x0D {
+			// エンベロープの形を書き込むとリスタートする
+			s.envelopeStep = 0
+			s.envelopeTimer = 0
+			s.envelopeHold = false
+			s.envelopeAlt = false
+		}
+	}
+}
+
+func (s *Sunsoft5B) Read(addr uint16) (byte, bool) {
+	return 0, false
+}
+
+func (s *Sunsoft5B) tonePeriod(channel int) uint16 {
+	return uint16(s.regs[channel*2]) | uint16(s.regs[channel*2+1]&0x0F)<<8
+}
+
+// Step 矩形波は16*周期CPUサイクルごと、ノイズは32*周期CPUサイクルごとに反転し、エンベロープは16*周期CPUサイクルごとに1step進む
+func (s *Sunsoft5B) Step() {
+	for i := range s.tones {
+		tone := &s.tones[i]
+		tone.timer++
+		if tone.timer >= max(s.tonePeriod(i), 1)*16 {
+			tone.timer = 0
+			tone.output = !tone.output
+		}
+	}
+
+	s.noiseTimer++
+	if s.noiseTimer >= uint16(max(s.regs[6]&0x1F, 1))*32 {
+		s.noiseTimer = 0
+		feedback := (s.noiseShift ^ (s.noiseShift >> 3)) & 0x01
+		s.noiseShift = (s.noiseShift >> 1) | (feedback << 16)
+		s.noiseOutput = s.noiseShift&0x01 != 0
+	}
+
+	envelopePeriod := uint32(s.regs[0x0B]) | uint32(s.regs[0x0C])<<8
+	s.envelopeTimer++
+	if s.envelopeTimer >= max(envelopePeriod, 1)*16 {
+		s.envelopeTimer = 0
+		s.clockEnvelope()
+	}
+}
+
+// clockEnvelope $0Dのbit3: continue, bit2: attack, bit1: alternate, bit0: hold
+func (s *Sunsoft5B) clockEnvelope() {
+	if s.envelopeHold {
+		return
+	}
+	s.envelopeStep++
+	if s.envelopeStep < 32 {
+		return
+	}
+
+	shape := s.regs[0x0D]
+	continueFlag := shape&0x08 != 0
+	alternate := shape&0x02 != 0
+	hold := shape&0x01 != 0
+	switch {
+	case !continueFlag:
+		// 1周期で終わり、0で止まる
+		s.envelopeHold = true
+		s.envelopeAlt = shape&0x04 != 0 // attackの場合は反転させて0にする
+		s.envelopeStep = 31
+	case hold:
+		s.envelopeHold = true
+		s.envelopeAlt = s.envelopeAlt != alternate
+		s.envelopeStep = 31
+	default:
+		s.envelopeStep = 0
+		if alternate {
+			s.envelopeAlt = !s.envelopeAlt
+		}
+	}
+}
+
+func (s *Sunsoft5B) envelopeVolume() byte {
+	volume := s.envelopeStep
+	attack := s.regs[0x0D]&0x04 != 0
+	if attack == s.envelopeAlt {
+		volume = 31 - volume
+	}
+	return volume
+}
+
+func (s *Sunsoft5B) Channels() []Channel {
+	return []Channel{ChannelSunsoft5BA, ChannelSunsoft5BB, ChannelSunsoft5BC}
+}
+
+func (s *Sunsoft5B) Output(gains *ChannelGains) float32 {
+	mixer := s.regs[7]
+	var out float32
+	for i := range s.tones {
+		toneDisabled := mixer&(1<<i) != 0
+		noiseDisabled := mixer&(1<<(i+3)) != 0
+		if !(toneDisabled || s.tones[i].output) || !(noiseDisabled || s.noiseOutput) {
+			continue
+		}
+
+		volumeReg := s.regs[8+i]
+		var volume byte
+		if volumeReg&0x10 != 0 {
+			volume = s.envelopeVolume()
+		} else if volumeReg&0x0F != 0 {
+			volume = (volumeReg&0x0F)*2 + 1
+		}
+		out += sunsoft5BVolumeTable[volume] * gains[ChannelSunsoft5BA+Channel(i)]
+	}
+	return out * sunsoft5BGain * 15 * squareVolumeUnit
+}
@@ -0,0 +1,70 @@
+package apu
+
+// triangleTable 三角波の32ステップのシーケンス
+// doc: https://www.nesdev.org/wiki/APU_Triangle
+var triangleTable = [32]byte{
+	15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// triangle 三角波チャンネル($4008-$400B)
+// 音量の制御はできず、linear counterとlength counterで発音期間を制御する
+type triangle struct {
+	sequencePos   byte
+	timerPeriod   uint16
+	timerValue    uint16
+	lengthCounter lengthCounter
+
+	linearControl bool // length counter haltと共用
+	linearReload  bool
+	linearPeriod  byte
+	linearValue   byte
+}
+
+func (t *triangle) write(reg uint16, value byte) {
+	switch reg {
+	case 0:
+		t.linearControl = value&0x80 != 0
+		t.lengthCounter.halt = t.linearControl
+		t.linearPeriod = value & 0x7F
+	case 2:
+		t.timerPeriod = (t.timerPeriod & 0x0700) | uint16(value)
+	case 3:
+		t.timerPeriod = (t.timerPeriod & 0x00FF) | (uint16(value&0x07) << 8)
+		t.lengthCounter.load(value >> 3)
+		t.linearReload = true
+	}
+}
+
+// clockTimer CPUサイクルごとに呼ばれる
+func (t *triangle) clockTimer() {
+	if t.timerValue > 0 {
+		t.timerValue--
+		return
+	}
+	t.timerValue = t.timerPeriod
+	// 周期が短すぎる場合は超音波になりノイズの原因になるので、実機の出力をそのまま再現せずに止める
+	if t.timerPeriod < 2 {
+		return
+	}
+	if t.lengthCounter.active() && t.linearValue > 0 {
+		t.sequencePos = (t.sequencePos + 1) & 0x1F
+	}
+}
+
+// clockLinearCounter quarter frameで呼ばれる
+func (t *triangle) clockLinearCounter() {
+	if t.linearReload {
+		t.linearValue = t.linearPeriod
+	} else if t.linearValue > 0 {
+		t.linearValue--
+	}
+	if !t.linearControl {
+		t.linearReload = false
+	}
+}
+
+// output 停止中も最後の位置の値を出力し続ける
+func (t *triangle) output() byte {
+	return triangleTable[t.sequencePos]
+}
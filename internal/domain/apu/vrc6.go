@@ -0,0 +1,167 @@
+package apu
+
+// VRC6 KonamiのVRC6の拡張音源(pulse x2, saw x1)
+// doc: https://www.nesdev.org/wiki/VRC6_audio
+//
+// レジスタは$9000-$9003, $A000-$A002, $B000-$B002
+// VRC6b(mapper 26)のようにA0とA1が入れ替わっているボードはマッパー側でアドレスを変換してから渡す
+// 相対音量: VRC6のpulseの音量15は2A03のpulseの音量15とほぼ同じ
+type VRC6 struct {
+	pulse1 vrc6Pulse
+	pulse2 vrc6Pulse
+	saw    vrc6Saw
+
+	halt      bool
+	freqShift byte // $9003 bit1: 4bitシフト, bit2: 8bitシフト
+}
+
+func NewVRC6() *VRC6 {
+	return &VRC6{}
+}
+
+var _ ExpansionAudio = (*VRC6)(nil)
+
+func (v *VRC6) Write(addr uint16, value byte) {
+	switch addr {
+	case 0x9000, 0x9001, 0x9002:
+		v.pulse1.write(addr-0x9000, value)
+	case 0x9003:
+		v.halt = value&0x01 != 0
+		switch {
+		case value&0x04 != 0:
+			v.freqShift = 8
+		case value&0x02 != 0:
+			v.freqShift = 4
+		default:
+			v.freqShift = 0
+		}
+	case 0xA000, 0xA001, 0xA002:
+		v.pulse2.write(addr-0xA000, value)
+	case 0xB000, 0xB001, 0xB002:
+		v.saw.write(addr-0xB000, value)
+	}
+}
+
+func (v *VRC6) Read(addr uint16) (byte, bool) {
+	return 0, false
+}
+
+func (v *VRC6) Step() {
+	if v.halt {
+		return
+	}
+	v.pulse1.clock(v.freqShift)
+	v.pulse2.clock(v.freqShift)
+	v.saw.clock(v.freqShift)
+}
+
+func (v *VRC6) Channels() []Channel {
+	return []Channel{ChannelVRC6Pulse1, ChannelVRC6Pulse2, ChannelVRC6Saw}
+}
+
+func (v *VRC6) Output(gains *ChannelGains) float32 {
+	return (float32(v.pulse1.output())*gains[ChannelVRC6Pulse1] +
+		float32(v.pulse2.output())*gains[ChannelVRC6Pulse2] +
+		float32(v.saw.output())*gains[ChannelVRC6Saw]) * squareVolumeUnit
+}
+
+type vrc6Pulse struct {
+	mode    bool // trueの場合はdutyを無視して常に音量を出力する
+	duty    byte // 0-7
+	volume  byte
+	enabled bool
+	period  uint16 // 12bit
+	timer   uint16
+	step    byte // 0-15
+}
+
+func (p *vrc6Pulse) write(reg uint16, value byte) {
+	switch reg {
+	case 0:
+		p.mode = value&0x80 != 0
+		p.duty = (value >> 4) & 0x07
+		p.volume = value & 0x0F
+	case 1:
+		p.period = (p.period & 0x0F00) | uint16(value)
+	case 2:
+		p.period = (p.period & 0x00FF) | (uint16(value&0x0F) << 8)
+		p.enabled = value&0x80 != 0
+		if !p.enabled {
+			// 無効にするとdutyの位置がリセットされる
+			p.step = 15
+		}
+	}
+}
+
+func (p *vrc6Pulse) clock(shift byte) {
+	if !p.enabled {
+		return
+	}
+	if p.timer > 0 {
+		p.timer--
+		return
+	}
+	p.timer = p.period >> shift
+	p.step = (p.step - 1) & 0x0F
+}
+
+func (p *vrc6Pulse) output() byte {
+	if !p.enabled {
+		return 0
+	}
+	if p.mode || p.step <= p.duty {
+		return p.volume
+	}
+	return 0
+}
+
+type vrc6Saw struct {
+	rate        byte // 6bit
+	enabled     bool
+	period      uint16
+	timer       uint16
+	step        byte // 0-13
+	accumulator byte
+}
+
+func (s *vrc6Saw) write(reg uint16, value byte) {
+	switch reg {
+	case 0:
+		s.rate = value & 0x3F
+	case 1:
+		s.period = (s.period & 0x0F00) | uint16(value)
+	case 2:
+		s.period = (s.period & 0x00FF) | (uint16(value&0x0F) << 8)
+		s.enabled = value&0x80 != 0
+		if !s.enabled {
+			s.accumulator = 0
+			s.step = 0
+		}
+	}
+}
+
+// clock timerが0になるたびにstepが進み、偶数stepでaccumulatorにrateを加算、14stepでリセットする
+func (s *vrc6Saw) clock(shift byte) {
+	if !s.enabled {
+		return
+	}
+	if s.timer > 0 {
+		s.timer--
+		return
+	}
+	s.timer = s.period >> shift
+
+	s.step++
+	switch {
+	case s.step >= 14:
+		s.step = 0
+		s.accumulator = 0
+	case s.step%2 == 0:
+		s.accumulator += s.rate
+	}
+}
+
+// output accumulatorの上位5bit
+func (s *vrc6Saw) output() byte {
+	return s.accumulator >> 3
+}
@@ -0,0 +1,323 @@
+package apu
+
+import "math"
+
+// VRC7 KonamiのVRC7の拡張音源(YM2413(OPLL)のサブセット、2オペレータFM x6チャンネル)
+// doc: https://www.nesdev.org/wiki/VRC7_audio
+//
+// $9010でレジスタ番号を指定し、$9030で書き込む
+// 内蔵音色15種類とユーザー定義音色1種類を持つ
+// 36CPUサイクル(約49.7kHz)ごとに1サンプル計算する
+// 簡略化のため、AM(トレモロ)とVIB(ビブラート)は未対応、エンベロープは実機の整数演算ではなくdB単位の近似で計算する
+// 相対音量: 1チャンネルのみ音量最大のサイン波の場合、振幅は2A03のpulseの音量15とほぼ同じ
+type VRC7 struct {
+	register byte
+	custom   [8]byte
+	disabled bool // $E000 bit6、マッパーから設定する
+
+	channels [6]vrc7Channel
+
+	cycle   byte
+	outputs [6]float32 // チャンネルごとの出力(-1.0〜1.0)
+}
+
+// vrc7Patches 内蔵音色(1-15)、0はユーザー定義音色
+// doc: https://www.nesdev.org/wiki/VRC7_audio#Internal_patch_set
+var vrc7Patches = [16][8]byte{
+	{},
+	{0x03, 0x21, 0x05, 0x06, 0xE8, 0x81, 0x42, 0x27},
+	{0x13, 0x41, 0x14, 0x0D, 0xD8, 0xF6, 0x23, 0x12},
+	{0x11, 0x11, 0x08, 0x08, 0xFA, 0xB2, 0x20, 0x12},
+	{0x31, 0x61, 0x0C, 0x07, 0xA8, 0x64, 0x61, 0x27},
+	{0x32, 0x21, 0x1E, 0x06, 0xE1, 0x76, 0x01, 0x28},
+	{0x02, 0x01, 0x06, 0x00, 0xA3, 0xE2, 0xF4, 0xF4},
+	{0x21, 0x61, 0x1D, 0x07, 0x82, 0x81, 0x11, 0x07},
+	{0x23, 0x21, 0x22, 0x17, 0xA2, 0x72, 0x01, 0x17},
+	{0x35, 0x11, 0x25, 0x00, 0x40, 0x73, 0x72, 0x01},
+	{0xB5, 0x01, 0x0F, 0x0F, 0xA8, 0xA5, 0x51, 0x02},
+	{0x17, 0xC1, 0x24, 0x07, 0xF8, 0xF8, 0x22, 0x12},
+	{0x71, 0x23, 0x11, 0x06, 0x65, 0x74, 0x18, 0x16},
+	{0x01, 0x02, 0xD3, 0x05, 0xC9, 0x95, 0x03, 0x02},
+	{0x61, 0x63, 0x0C, 0x00, 0x94, 0xC0, 0x33, 0xF6},
+	{0x21, 0x72, 0x0D, 0x00, 0xC1, 0xD5, 0x56, 0x06},
+}
+
+const (
+	vrc7UpdateCycles = 36
+	vrc7SampleRate   = float64(CPUClockNTSC) / vrc7UpdateCycles
+
+	vrc7MaxAttenuation = 48.0 // dB、これ以上は無音とみなす
+)
+
+// vrc7Multipliers MULTの値ごとの周波数の倍率
+var vrc7Multipliers = [16]float64{0.5, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 10, 12, 12, 15, 15}
+
+// vrc7KeyScaleLevels F-Numberの上位4bitごとのblock 7でのKSLの減衰量(dB)
+var vrc7KeyScaleLevels = [16]float64{0, 9, 12, 13.875, 15, 16.125, 16.875, 17.625, 18, 18.75, 19.125, 19.5, 19.875, 20.25, 20.625, 21}
+
+func NewVRC7() *VRC7 {
+	return &VRC7{}
+}
+
+var _ ExpansionAudio = (*VRC7)(nil)
+
+func (v *VRC7) Write(addr uint16, value byte) {
+	switch addr {
+	case 0x9010:
+		v.register = value
+	case 0x9030:
+		v.writeRegister(v.register, value)
+	}
+}
+
+// writeRegister
+// $00-$07: ユーザー定義音色, $10-$15: F-Number下位8bit
+// $20-$25: bit5 sustain, bit4 key on, bit1-3 block, bit0 F-Number上位1bit
+// $30-$35: bit4-7 音色, bit0-3 音量(3dB単位の減衰)
+func (v *VRC7) writeRegister(reg byte, value byte) {
+	switch {
+	case reg <= 0x07:
+		v.custom[reg] = value
+	case reg >= 0x10 && reg <= 0x15:
+		ch := &v.channels[reg-0x10]
+		ch.fnum = (ch.fnum & 0x100) | uint16(value)
+	case reg >= 0x20 && reg <= 0x25:
+		ch := &v.channels[reg-0x20]
+		ch.fnum = (ch.fnum & 0xFF) | uint16(value&0x01)<<8
+		ch.block = (value >> 1) & 0x07
+		ch.sustain = value&0x20 != 0
+		ch.setKey(value&0x10 != 0)
+	case reg >= 0x30 && reg <= 0x35:
+		ch := &v.channels[reg-0x30]
+		ch.instrument = value >> 4
+		ch.volume = value & 0x0F
+	}
+}
+
+func (v *VRC7) Read(addr uint16) (byte, bool) {
+	return 0, false
+}
+
+// SetDisabled $E000のbit6で音声をリセットして止める
+func (v *VRC7) SetDisabled(disabled bool) {
+	v.disabled = disabled
+	if disabled {
+		v.channels = [6]vrc7Channel{}
+		v.outputs = [6]float32{}
+	}
+}
+
+func (v *VRC7) Step() {
+	if v.disabled {
+		return
+	}
+	v.cycle++
+	if v.cycle < vrc7UpdateCycles {
+		return
+	}
+	v.cycle = 0
+
+	for i := range v.channels {
+		ch := &v.channels[i]
+		patch := vrc7Patches[ch.instrument]
+		if ch.instrument == 0 {
+			patch = v.custom
+		}
+		v.outputs[i] = float32(ch.update(patch))
+	}
+}
+
+func (v *VRC7) Channels() []Channel {
+	return []Channel{ChannelVRC7FM1, ChannelVRC7FM2, ChannelVRC7FM3, ChannelVRC7FM4, ChannelVRC7FM5, ChannelVRC7FM6}
+}
+
+func (v *VRC7) Output(gains *ChannelGains) float32 {
+	var sum float32
+	for i, output := range v.outputs {
+		sum += output * gains[ChannelVRC7FM1+Channel(i)]
+	}
+	return sum * 15 / 2 * squareVolumeUnit
+}
+
+type vrc7EnvelopeState byte
+
+const (
+	vrc7EnvelopeRelease vrc7EnvelopeState = iota
+	vrc7EnvelopeAttack
+	vrc7EnvelopeDecay
+	vrc7EnvelopeSustain
+)
+
+type vrc7Channel struct {
+	fnum       uint16 // 9bit
+	block      byte
+	sustain    bool
+	key        bool
+	instrument byte
+	volume     byte
+
+	modulator vrc7Operator
+	carrier   vrc7Operator
+
+	feedback [2]float64 // モジュレータの直近2サンプルの出力
+}
+
+type vrc7Operator struct {
+	phase       float64 // 0.0〜1.0
+	attenuation float64 // dB
+	state       vrc7EnvelopeState
+}
+
+func (c *vrc7Channel) setKey(key bool) {
+	if key && !c.key {
+		for _, op := range []*vrc7Operator{&c.modulator, &c.carrier} {
+			op.phase = 0
+			op.state = vrc7EnvelopeAttack
+		}
+	}
+	if !key && c.key {
+		c.modulator.state = vrc7EnvelopeRelease
+		c.carrier.state = vrc7EnvelopeRelease
+	}
+	c.key = key
+}
+
+// update 1サンプル分進めてチャンネルの出力(-1.0〜1.0)を返す
+// patch: 0,1: AM|VIB|EG|KSR|MULT (モジュレータ, キャリア), 2: モジュレータのKSL|TL, 3: キャリアのKSL|DC|DM|FB
+// 4,5: AR|DR, 6,7: SL|RR
+func (c *vrc7Channel) update(patch [8]byte) float64 {
+	ksl := vrc7KeyScaleLevels[c.fnum>>5] - 6*float64(7-c.block)
+	ksl = max(ksl, 0)
+
+	// モジュレータ
+	modTL := float64(patch[2]&0x3F)*0.75 + scaleKeyLevel(ksl, patch[2]>>6)
+	c.modulator.clockEnvelope(patch[0], patch[4], patch[6], c)
+	c.modulator.phase = c.nextPhase(c.modulator.phase, patch[0])
+
+	fb := 0.0
+	if shift := patch[3] & 0x07; shift != 0 {
+		// FB=1でπ/16、1増えるごとに2倍
+		fb = (c.feedback[0] + c.feedback[1]) / 2 * math.Pow(2, float64(shift)-6)
+	}
+	modOut := vrc7Wave(c.modulator.phase+fb, patch[3]&0x08 != 0) * vrc7Amplitude(c.modulator.attenuation+modTL)
+	c.feedback[1] = c.feedback[0]
+	c.feedback[0] = modOut
+
+	// キャリア
+	carTL := float64(c.volume)*3 + scaleKeyLevel(ksl, patch[3]>>6)
+	c.carrier.clockEnvelope(patch[1], patch[5], patch[7], c)
+	c.carrier.phase = c.nextPhase(c.carrier.phase, patch[1])
+
+	// モジュレータの出力が最大の場合、キャリアの位相を±4π(2周期)ずらす
+	return vrc7Wave(c.carrier.phase+modOut*2, patch[3]&0x10 != 0) * vrc7Amplitude(c.carrier.attenuation+carTL)
+}
+
+// nextPhase 周波数 = 49716Hz * F-Number * 2^block / 2^19 * MULT
+func (c *vrc7Channel) nextPhase(phase float64, flags byte) float64 {
+	inc := float64(c.fnum) * math.Pow(2, float64(c.block)) / (1 << 19) * vrc7Multipliers[flags&0x0F]
+	phase += inc
+	return phase - math.Floor(phase)
+}
+
+// keyScaleRate KSRが有効な場合はblockとF-Numberの最上位bitで、無効な場合はblockの上位2bitでエンベロープを速くする
+func (c *vrc7Channel) keyScaleRate(flags byte) int {
+	if flags&0x10 != 0 {
+		return int(c.block)<<1 | int(c.fnum>>8)
+	}
+	return int(c.block >> 1)
+}
+
+// clockEnvelope flags: AM|VIB|EG|KSR|MULT, adr: AR|DR, slr: SL|RR
+func (o *vrc7Operator) clockEnvelope(flags, adr, slr byte, c *vrc7Channel) {
+	ksr := c.keyScaleRate(flags)
+	sustainLevel := float64(slr>>4) * 3
+	percussive := flags&0x20 == 0
+
+	switch o.state {
+	case vrc7EnvelopeAttack:
+		rate := int(adr >> 4)
+		if rate == 15 {
+			o.attenuation = 0
+		} else {
+			o.attenuation -= vrc7AttackStep(rate, ksr) * (o.attenuation + 1) / vrc7MaxAttenuation
+		}
+		if o.attenuation <= 0 {
+			o.attenuation = 0
+			o.state = vrc7EnvelopeDecay
+		}
+	case vrc7EnvelopeDecay:
+		o.attenuation += vrc7DecayStep(int(adr&0x0F), ksr)
+		if o.attenuation >= sustainLevel {
+			o.attenuation = sustainLevel
+			o.state = vrc7EnvelopeSustain
+		}
+	case vrc7EnvelopeSustain:
+		// 減衰音はRRで減衰を続け、持続音はSLで止まる
+		if percussive {
+			o.attenuation += vrc7DecayStep(int(slr&0x0F), ksr)
+		}
+	case vrc7EnvelopeRelease:
+		rate := int(slr & 0x0F)
+		switch {
+		case c.sustain:
+			rate = 5
+		case !percussive:
+			// 持続音はRRで減衰する
+		default:
+			rate = 7
+		}
+		o.attenuation += vrc7DecayStep(rate, ksr)
+	}
+	o.attenuation = min(o.attenuation, vrc7MaxAttenuation)
+}
+
+// vrc7DecayStep 1サンプルあたりの減衰量(dB)
+// 実効レート(rate * 4 + ksr)が4の時に0dBから48dBまで約19.6秒、実効レートが4増えるごとに半分の時間になる
+func vrc7DecayStep(rate, ksr int) float64 {
+	if rate == 0 {
+		return 0
+	}
+	effective := min(rate*4+ksr, 63)
+	seconds := 19.6 / math.Pow(2, float64(effective-4)/4)
+	return vrc7MaxAttenuation / (seconds * vrc7SampleRate)
+}
+
+// vrc7AttackStep 1サンプルあたりのアタックの変化量の係数(dB)
+// アタックは減衰量に比例して変化する(指数カーブ)ので、実効レートが4の時に約2.8秒で最大音量に到達するように係数をかける
+func vrc7AttackStep(rate, ksr int) float64 {
+	if rate == 0 {
+		return 0
+	}
+	effective := min(rate*4+ksr, 63)
+	seconds := 2.8 / math.Pow(2, float64(effective-4)/4)
+	return vrc7MaxAttenuation * math.Log(vrc7MaxAttenuation+1) / (seconds * vrc7SampleRate)
+}
+
+// scaleKeyLevel KSL 0: 0dB/oct, 1: 1.5dB/oct, 2: 3dB/oct, 3: 6dB/oct
+func scaleKeyLevel(ksl float64, level byte) float64 {
+	switch level {
+	case 1:
+		return ksl / 4
+	case 2:
+		return ksl / 2
+	case 3:
+		return ksl
+	}
+	return 0
+}
+
+// vrc7Wave サイン波、rectifiedの場合は負の半分を0にする
+func vrc7Wave(phase float64, rectified bool) float64 {
+	value := math.Sin(2 * math.Pi * phase)
+	if rectified && value < 0 {
+		return 0
+	}
+	return value
+}
+
+func vrc7Amplitude(attenuation float64) float64 {
+	if attenuation >= vrc7MaxAttenuation {
+		return 0
+	}
+	return math.Pow(10, -attenuation/20)
+}
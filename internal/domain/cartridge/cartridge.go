@@ -1,108 +1,263 @@
 package cartridge
 
 import (
+	"crypto/sha1"
 	"errors"
 	"fmt"
 
-	"github.com/sunjin110/nes_emu/pkg/logger"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
 )
 
 const (
 	iNESHeaderSize = 16
 	prgBankSize    = 16 * 1024 // 16KB
 	chrBankSize    = 8 * 1024  // 8KB
+	trainerSize    = 512
+
+	// trainerOffset trainerを置くPRG-RAMの中の位置(0x7000-0x71FF)
+	trainerOffset = 0x7000 - 0x6000
 )
 
 type Cartridge struct {
 	PRG []byte
 	CHR []byte
+	// Format iNES 1.0かNES 2.0かUNIFか、以下のRAMのサイズからDefaultExpansionDeviceまではNES 2.0とUNIFのみ
+	Format Format
 	// mapperの種類 https://www.nesdev.org/wiki/Mapper
-	// 現在はiNES1.0のみ対応
-	MapperNo     int
+	MapperNo int
+	// Submapper NES 2.0のsubmapper(iNES 1.0の場合は0)、同じmapper番号の基板の違いを区別する
+	Submapper    int
 	PRGBankCount int
 	CHRBankCount int
+	// Trainer byte 6のbit2がある場合のヘッダーとPRG-ROMの間の512byte、PRG-RAMの0x7000に読み込む
+	Trainer []byte
+
+	// Mirroring ヘッダーで指定されたミラーリング(mapperで切り替える場合はその初期値)
+	Mirroring ppu.Mirroring
+	// Battery PRG-RAMなどがバッテリーバックアップされているか
+	Battery bool
+	// DirtyHeader byte 7-15に"DiskDude!"などのゴミが書き込まれていたので0として読み込んだ
+	DirtyHeader bool
+
+	// PRG-RAM, CHR-RAMのサイズ(byte)、NVRAMはバッテリーバックアップされたRAMやEEPROM
+	PRGRAMSize   int
+	PRGNVRAMSize int
+	CHRRAMSize   int
+	CHRNVRAMSize int
+
+	Timing      Timing
+	ConsoleType ConsoleType
+	// VSPPUType, VSHardwareType ConsoleVSSystemの場合のPPUと基板の種類
+	VSPPUType      int
+	VSHardwareType int
+	// ExtendedConsoleType ConsoleExtendedの場合の本体の種類
+	ExtendedConsoleType int
+	// MiscROMCount, MiscROM CHR-ROMの後にあるその他のROMの数とデータ
+	MiscROMCount int
+	MiscROM      []byte
+	// BoardName UNIFのMAPRチャンクの基板名
+	BoardName string
+	// Controllers UNIFのCTRLチャンクの対応している入力デバイス
+	// bit0: 標準のコントローラー, bit1: Zapper, bit2: R.O.B., bit3: Arkanoid, bit4: Power Pad, bit5: Four Score
+	Controllers byte
+	// DefaultExpansionDevice 標準の入力デバイス
+	// doc: https://www.nesdev.org/wiki/NES_2.0#Default_Expansion_Device
+	DefaultExpansionDevice int
+
+	// CRC32, SHA1 PRG-ROMとCHR-ROMを続けたデータのハッシュ
+	CRC32 uint32
+	SHA1  [sha1.Size]byte
+	// Header ヘッダーに書かれていた値、Gameがある場合はMapperNoなどはゲームデータベースの値になる
+	Header Board
+	// Game ゲームデータベースで見つかったゲーム、ない場合はnil
+	Game *Game
+
+	// Mapper MapperNoのmapper、CPUとPPUにそのまま接続できる
+	Mapper mapper.Mapper
 }
 
+// NewCartridge iNES(NES 2.0)とUNIFのROMを読み込む、形式は先頭のマジックナンバーで判定する
 func NewCartridge(data []byte) (*Cartridge, error) {
+	if IsUNIF(data) {
+		return newUNIFCartridge(data)
+	}
+	if len(data) < iNESHeaderSize {
+		return nil, fmt.Errorf("%w. ROM size is too short to contain header. size: %d", ErrInvalidHeader, len(data))
+	}
 	if string(data[0:3]) != "NES" {
-		return nil, errors.New("Cartridge: invalid header 0~3")
+		return nil, fmt.Errorf("%w. header 0~2 is not \"NES\". value: %q", ErrInvalidHeader, data[0:3])
 	}
 	if data[3] != 0x1A {
-		return nil, errors.New("Cartridge: invalid header 3")
+		return nil, fmt.Errorf("%w. header 3 is not 0x1A. value: 0x%02X", ErrInvalidHeader, data[3])
 	}
 
-	// PRG-ROM
-	prgBankCount := int(data[4])
+	c := &Cartridge{}
+	prgSize, chrSize, err := c.parseHeader(data[:iNESHeaderSize])
+	if err != nil {
+		return nil, err
+	}
+
+	// byte 6 bit2: trainerがある
 	prgStart := iNESHeaderSize
-	prgEnd := prgStart + prgBankCount*prgBankSize
+	if data[6]&0x04 != 0 {
+		if prgStart+trainerSize > len(data) {
+			return nil, fmt.Errorf("%w. ROM size is too short to contain full trainer. size: %d", ErrInvalidHeader, len(data))
+		}
+		c.Trainer = data[prgStart : prgStart+trainerSize]
+		prgStart += trainerSize
+	}
 
+	// PRG-ROM
+	prgEnd := prgStart + prgSize
 	if prgEnd > len(data) {
-		return nil, errors.New("Cartridge: ROM size is too short to contain full RPG-ROM")
+		return nil, fmt.Errorf("%w. ROM size is too short to contain full PRG-ROM. size: %d, prgEnd: %d", ErrInvalidHeader, len(data), prgEnd)
 	}
-	prgData := data[prgStart:prgEnd]
+	c.PRG = data[prgStart:prgEnd]
+	c.PRGBankCount = prgSize / prgBankSize
 
 	// CHR-ROM
-	chrBankCount := int(data[5])
 	chrStart := prgEnd
-	chrEnd := chrStart + chrBankCount*chrBankSize
+	chrEnd := chrStart + chrSize
 	if chrEnd > len(data) {
-		return nil, errors.New("Cartridge: ROM size is too chort to contain full CHR-ROM")
-	}
-	chrData := data[chrStart:chrEnd]
-
-	// 例: header[6] = 0x31 (下位4ビット=1, 上位4ビット=3)
-	//    header[7] = 0x80 (上位4ビット=8, 下位4ビット=0)
-	//    → mapperNo = 3 | 0x80 = 0x83 = 131
-	mapperNo := (data[6] >> 4) | (data[7] & 0xF0)
-
-	return &Cartridge{
-		PRG:          prgData,
-		CHR:          chrData,
-		MapperNo:     int(mapperNo),
-		PRGBankCount: prgBankCount,
-		CHRBankCount: chrBankCount,
-	}, nil
+		return nil, fmt.Errorf("%w. ROM size is too short to contain full CHR-ROM. size: %d, chrEnd: %d", ErrInvalidHeader, len(data), chrEnd)
+	}
+	c.CHR = data[chrStart:chrEnd]
+	c.CHRBankCount = chrSize / chrBankSize
+
+	// miscellaneous ROM(NES 2.0)はCHR-ROMの後の残り全て
+	if c.MiscROMCount > 0 {
+		if chrEnd == len(data) {
+			return nil, fmt.Errorf("%w. miscellaneous ROM is missing. count: %d", ErrInvalidHeader, c.MiscROMCount)
+		}
+		c.MiscROM = data[chrEnd:]
+	}
+
+	if err := c.setupMapper(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-func (cartridge *Cartridge) ReadPRG(offset, size int) ([]byte, error) {
-	if offset+size <= len(cartridge.PRG) {
-		return cartridge.PRG[offset:size], nil
+// setupMapper ゲームデータベースでヘッダーの値を上書きして、mapperを作る
+func (c *Cartridge) setupMapper() error {
+	c.CRC32, c.SHA1 = hashROM(c.PRG, c.CHR)
+	c.Header = c.Board()
+	game, err := LookupGame(c.CRC32, c.SHA1)
+	switch {
+	case err == nil:
+		c.applyGame(game)
+	case !errors.Is(err, ErrGameNotFound):
+		return err
 	}
 
-	// 実際のデータより大きい場合はミラーリングする
-	data := make([]byte, size)
-	for i := 0; i < size; i++ {
-		idx := offset + i
-		if idx >= len(cartridge.PRG) {
-			idx = idx % len(cartridge.PRG)
-		}
-		data[i] = cartridge.PRG[idx]
+	m, err := mapper.New(mapper.Config{
+		MapperNo:     c.MapperNo,
+		Submapper:    c.Submapper,
+		PRG:          c.PRG,
+		CHR:          c.CHR,
+		CHRRAMSize:   c.CHRRAMSize + c.CHRNVRAMSize,
+		PRGRAMSize:   c.PRGRAMSize + c.PRGNVRAMSize,
+		PRGNVRAMSize: c.PRGNVRAMSize,
+		Mirroring:    c.Mirroring,
+		Battery:      c.Battery,
+	})
+	if err != nil {
+		return fmt.Errorf("Cartridge: failed new mapper. err: %w", err)
+	}
+	c.Mapper = m
+	c.loadTrainer()
+	return nil
+}
+
+// loadTrainer trainerをPRG-RAMの0x7000に読み込む、PRG-RAMがないmapperでは読み込まない
+func (c *Cartridge) loadTrainer() {
+	m, ok := c.Mapper.(mapper.PRGRAMMapper)
+	if len(c.Trainer) == 0 || !ok {
+		return
+	}
+	ram := m.PRGRAM()
+	if len(ram) == 0 {
+		return
+	}
+	// 8KBより小さいRAMはミラーリングされている
+	for i, b := range c.Trainer {
+		ram[(trainerOffset+i)%len(ram)] = b
+	}
+}
+
+// SRAM バッテリーバックアップされるRAM(PRG-RAM, EEPROM)のコピー、ない場合はnil
+func (c *Cartridge) SRAM() []byte {
+	ram := c.saveRAM()
+	if len(ram) == 0 {
+		return nil
+	}
+	return append([]byte(nil), ram...)
+}
+
+// LoadSRAM .savファイルなどのデータをバッテリーバックアップされるRAMに読み込む、サイズが違う場合はエラー
+// trainerは起動時にコピー機が0x7000に書き込むものなので、読み込んだ後にもう一度trainerを書き込む
+func (c *Cartridge) LoadSRAM(data []byte) error {
+	ram := c.saveRAM()
+	if len(ram) == 0 {
+		return fmt.Errorf("%w. mapperNo: %d", ErrNoSRAM, c.MapperNo)
+	}
+	if len(data) != len(ram) {
+		return fmt.Errorf("%w. want: %d, got: %d", ErrSRAMSizeMismatch, len(ram), len(data))
+	}
+	copy(ram, data)
+	c.loadTrainer()
+	return nil
+}
+
+func (c *Cartridge) saveRAM() []byte {
+	m, ok := c.Mapper.(mapper.BatteryMapper)
+	if !ok {
+		return nil
+	}
+	return m.SaveRAM()
+}
+
+// ReadPRG PRG-ROMのoffsetからsizeバイトを読み込む、PRG-ROMより大きい範囲はミラーリングする
+func (cartridge *Cartridge) ReadPRG(offset, size int) ([]byte, error) {
+	data, err := readMirrored(cartridge.PRG, offset, size)
+	if err != nil {
+		return nil, fmt.Errorf("Cartridge: failed read PRG. err: %w", err)
 	}
 	return data, nil
 }
 
 func (cartridge *Cartridge) WritePRG(data []byte, offset int) error {
-	if offset+len(data) > len(cartridge.PRG) {
+	if offset < 0 || offset+len(data) > len(cartridge.PRG) {
 		return fmt.Errorf("Cartridge: offset+size is too large to write PRG. offset: %d, size: %d, prgSize: %d", offset, len(data), len(cartridge.PRG))
 	}
 	copy(cartridge.PRG[offset:], data)
 	return nil
 }
 
+// ReadCHR CHR-ROMのoffsetからsizeバイトを読み込む、CHR-ROMより大きい範囲はミラーリングする
 func (cartridge *Cartridge) ReadCHR(offset, size int) ([]byte, error) {
-	if offset+size <= len(cartridge.CHR) {
-		return cartridge.CHR[offset:size], nil
+	data, err := readMirrored(cartridge.CHR, offset, size)
+	if err != nil {
+		return nil, fmt.Errorf("Cartridge: failed read CHR. err: %w", err)
+	}
+	return data, nil
+}
+
+func readMirrored(rom []byte, offset, size int) ([]byte, error) {
+	if offset < 0 || size < 0 {
+		return nil, fmt.Errorf("invalid range. offset: %d, size: %d", offset, size)
+	}
+	if offset+size <= len(rom) {
+		return rom[offset : offset+size], nil
+	}
+	if len(rom) == 0 {
+		return nil, errors.New("rom is empty")
 	}
 
-	// 実際のデータより大きい場合はミラーリングする
 	data := make([]byte, size)
-	for i := 0; i < size; i++ {
-		idx := offset + i
-		if idx >= len(cartridge.CHR) {
-			logger.Logger.Warn("Cartridge: ReadCHR: didn't expect mirroring")
-			idx = idx % len(cartridge.CHR)
-		}
-		data[i] = cartridge.CHR[idx]
+	for i := range data {
+		data[i] = rom[(offset+i)%len(rom)]
 	}
 	return data, nil
 }
@@ -1,21 +1,49 @@
 package cartridge_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"testing"
 
 	_ "embed"
 
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
 	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
 )
 
 var (
 	//go:embed testdata/hello.nes
 	helloNesRom []byte
+
+	//go:embed testdata/color-bars-mapper0.nes
+	colorBarsNesRom []byte
+	//go:embed testdata/gamedb.tsv
+	testGameDB []byte
 )
 
+// helloNesSHA1 hello.nesのPRG-ROMとCHR-ROMのSHA-1
+var helloNesSHA1 = [20]byte{
+	0x44, 0xF3, 0x87, 0x11, 0x3C, 0xFA, 0x77, 0x94, 0xF5, 0xA2,
+	0x19, 0xDA, 0x6F, 0x8E, 0x35, 0xC1, 0x0C, 0x3D, 0x48, 0x2E,
+}
+
+func newMapper(config mapper.Config) mapper.Mapper {
+	m, err := mapper.New(config)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
 // go test -v -count=1 -timeout 30s -run ^TestNewCartridge$ github.com/sunjin110/nes_emu/internal/domain/cartridge
 func TestNewCartridge(t *testing.T) {
+	// ヘッダーの読み込みだけを見るので、空のゲームデータベースを使う
+	defer cartridge.SetGameDBForTest(nil)()
+
 	Convey("TestNewCartridge", t, func() {
 		type test struct {
 			name    string
@@ -34,6 +62,16 @@ func TestNewCartridge(t *testing.T) {
 					MapperNo:     0,
 					PRGBankCount: 2,
 					CHRBankCount: 1,
+					Mirroring:    ppu.MirroringVertical,
+					CRC32:        0xEAA62BF4,
+					SHA1:         helloNesSHA1,
+					Header:       cartridge.Board{Mirroring: ppu.MirroringVertical},
+					Mapper: newMapper(mapper.Config{
+						MapperNo:  0,
+						PRG:       helloNesRom[16 : 16+2*16*1024],
+						CHR:       helloNesRom[16+2*16*1024 : (16+2*16*1024)+8*1024],
+						Mirroring: ppu.MirroringVertical,
+					}),
 				},
 			},
 		}
@@ -52,3 +90,531 @@ func TestNewCartridge(t *testing.T) {
 		}
 	})
 }
+
+// go test -v -count=1 -timeout 30s -run ^TestCartridge_NROM$ github.com/sunjin110/nes_emu/internal/domain/cartridge
+func TestCartridge_NROM(t *testing.T) {
+	Convey("TestCartridge_NROM", t, func() {
+		Convey("NROM-128(16KB)は0xC000-0xFFFFに0x8000-0xBFFFがミラーリングされる", func() {
+			cart, err := cartridge.NewCartridge(colorBarsNesRom)
+			So(err, ShouldBeNil)
+			So(cart.PRGBankCount, ShouldEqual, 1)
+
+			for addr := uint16(0x8000); addr < 0xC000; addr++ {
+				if cart.Mapper.ReadPRG(addr) != cart.Mapper.ReadPRG(addr+0x4000) {
+					t.Fatalf("not mirrored. addr: 0x%04X", addr)
+				}
+			}
+			So(cart.Mapper.ReadPRG(0xFFFC), ShouldEqual, cart.PRG[0x3FFC])
+			So(cart.Mapper.ReadPRG(0xFFFD), ShouldEqual, cart.PRG[0x3FFD])
+			So(cart.Mapper.ReadCHR(0x1FFF), ShouldEqual, cart.CHR[0x1FFF])
+		})
+
+		Convey("NROM-256(32KB)はミラーリングせずに0x8000-0xFFFFにそのまま配置される", func() {
+			cart, err := cartridge.NewCartridge(helloNesRom)
+			So(err, ShouldBeNil)
+			So(cart.PRGBankCount, ShouldEqual, 2)
+
+			So(cart.Mapper.ReadPRG(0x8000), ShouldEqual, cart.PRG[0x0000])
+			So(cart.Mapper.ReadPRG(0xC000), ShouldEqual, cart.PRG[0x4000])
+			So(cart.Mapper.ReadPRG(0xFFFC), ShouldEqual, cart.PRG[0x7FFC])
+			So(cart.Mapper.ReadPRG(0xFFFD), ShouldEqual, cart.PRG[0x7FFD])
+			So(cart.Mapper.ReadCHR(0x0010), ShouldEqual, cart.CHR[0x0010])
+
+			// CHR-ROMには書き込めない
+			before := cart.Mapper.ReadCHR(0x0010)
+			cart.Mapper.WriteCHR(0x0010, ^before)
+			So(cart.Mapper.ReadCHR(0x0010), ShouldEqual, before)
+		})
+
+		Convey("ReadPRGはoffsetから読み込み、PRG-ROMより大きい範囲はミラーリングする", func() {
+			cart, err := cartridge.NewCartridge(colorBarsNesRom)
+			So(err, ShouldBeNil)
+
+			data, err := cart.ReadPRG(0x1000, 0x10)
+			So(err, ShouldBeNil)
+			So(data, ShouldResemble, cart.PRG[0x1000:0x1010])
+
+			data, err = cart.ReadPRG(0x2000, 0x8000-0x2000)
+			So(err, ShouldBeNil)
+			So(data[0x4000-0x2000], ShouldEqual, cart.PRG[0])
+			So(data[len(data)-1], ShouldEqual, cart.PRG[0x3FFF])
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestCartridge_Submapper$ github.com/sunjin110/nes_emu/internal/domain/cartridge
+func TestCartridge_Submapper(t *testing.T) {
+	Convey("TestCartridge_Submapper", t, func() {
+		// mapper 23, PRG-ROM 128KB, CHR-ROM 128KB
+		newROM := func(flags7, byte8 byte) []byte {
+			header := []byte{'N', 'E', 'S', 0x1A, 8, 16, 0x70, flags7, byte8, 0, 0, 0, 0, 0, 0, 0}
+			prg := make([]byte, 0x20000)
+			for i := range prg {
+				prg[i] = byte(i / 0x2000)
+			}
+			return append(append(header, prg...), make([]byte, 0x20000)...)
+		}
+
+		Convey("NES 2.0のヘッダーはbyte 8からsubmapperを読み込み、mapperの基板を選ぶ", func() {
+			got, err := cartridge.NewCartridge(newROM(0x18, 0x20))
+			So(err, ShouldBeNil)
+			So(got.MapperNo, ShouldEqual, 23)
+			So(got.Submapper, ShouldEqual, 2)
+
+			// VRC4eはA2, A3でレジスタを選ぶので0x9008はPRGのswapモード
+			got.Mapper.WritePRG(0x8000, 3)
+			got.Mapper.WritePRG(0x9008, 0x02)
+			So(got.Mapper.ReadPRG(0xC000), ShouldEqual, 3)
+			So(got.Mapper.ReadPRG(0x8000), ShouldEqual, 14)
+		})
+
+		Convey("iNES 1.0のヘッダーはbyte 8を使わない", func() {
+			got, err := cartridge.NewCartridge(newROM(0x10, 0x21))
+			So(err, ShouldBeNil)
+			So(got.MapperNo, ShouldEqual, 23)
+			So(got.Submapper, ShouldEqual, 0)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestCartridge_NES20$ github.com/sunjin110/nes_emu/internal/domain/cartridge
+func TestCartridge_NES20(t *testing.T) {
+	Convey("TestCartridge_NES20", t, func() {
+		// mapper 4(MMC3)のNES 2.0のヘッダー、PRG-ROMは指数表記で32KB(2^15 * 1)、CHR-ROMは8KB
+		newHeader := func() []byte {
+			return []byte{'N', 'E', 'S', 0x1A, 15 << 2, 1, 0x40, 0x08, 0x00, 0x0F, 0, 0, 0, 0, 0, 0}
+		}
+		newROM := func(header []byte, extra ...byte) []byte {
+			data := append(header, make([]byte, 0x8000+0x2000)...)
+			return append(data, extra...)
+		}
+
+		Convey("NES 2.0のヘッダーの各フィールドを読み込む", func() {
+			header := newHeader()
+			header[7] |= 0x01 // VS. System
+			header[10] = 0x70 // PRG-NVRAM 8KB
+			header[11] = 0x07 // CHR-RAM 8KB
+			header[12] = 0x01 // PAL
+			header[13] = 0x21 // PPU: 1, 基板: 2
+			header[14] = 0x01 // miscellaneous ROMが1つ
+			header[15] = 0x01 // 標準のコントローラー
+			got, err := cartridge.NewCartridge(newROM(header, 0xAA, 0xBB))
+			So(err, ShouldBeNil)
+			So(got.Format, ShouldEqual, cartridge.FormatNES20)
+			So(got.MapperNo, ShouldEqual, 4)
+			So(len(got.PRG), ShouldEqual, 0x8000)
+			So(got.PRGBankCount, ShouldEqual, 2)
+			So(len(got.CHR), ShouldEqual, 0x2000)
+			So(got.PRGRAMSize, ShouldEqual, 0)
+			So(got.PRGNVRAMSize, ShouldEqual, 0x2000)
+			So(got.CHRRAMSize, ShouldEqual, 0x2000)
+			So(got.CHRNVRAMSize, ShouldEqual, 0)
+			So(got.Timing, ShouldEqual, cartridge.TimingPAL)
+			So(got.Timing.CPUClock(), ShouldEqual, apu.CPUClockPAL)
+			So(got.ConsoleType, ShouldEqual, cartridge.ConsoleVSSystem)
+			So(got.VSPPUType, ShouldEqual, 1)
+			So(got.VSHardwareType, ShouldEqual, 2)
+			So(got.MiscROMCount, ShouldEqual, 1)
+			So(got.MiscROM, ShouldResemble, []byte{0xAA, 0xBB})
+			So(got.DefaultExpansionDevice, ShouldEqual, 1)
+		})
+
+		Convey("指数表記のバンクより小さいサイズやバンクの倍数でないサイズでもpanicせずに読み込める", func() {
+			tests := []struct {
+				name    string
+				prg     byte // 上位6bitが指数、下位2bitが倍率(*2+1)
+				chr     byte
+				prgSize int
+				chrSize int
+			}{
+				{name: "PRG-ROM 4KB, CHR-ROM 1KB", prg: 12 << 2, chr: 10 << 2, prgSize: 0x1000, chrSize: 0x0400},
+				{name: "PRG-ROM 12KB, CHR-ROM 3KB", prg: 12<<2 | 1, chr: 10<<2 | 1, prgSize: 0x3000, chrSize: 0x0C00},
+			}
+			for _, tt := range tests {
+				for _, mapperNo := range []byte{0, 1, 2, 3, 4, 7} {
+					Convey(fmt.Sprintf("%s, mapper %d", tt.name, mapperNo), func() {
+						header := []byte{'N', 'E', 'S', 0x1A, tt.prg, tt.chr, mapperNo << 4, 0x08, 0x00, 0xFF, 0, 0, 0, 0, 0, 0}
+						prg := make([]byte, tt.prgSize)
+						prg[tt.prgSize-1] = 0x12
+						data := append(append(header, prg...), make([]byte, tt.chrSize)...)
+
+						got, err := cartridge.NewCartridge(data)
+						So(err, ShouldBeNil)
+						So(len(got.PRG), ShouldEqual, tt.prgSize)
+						So(len(got.CHR), ShouldEqual, tt.chrSize)
+						So(func() {
+							for addr := 0x8000; addr <= 0xFFFF; addr++ {
+								got.Mapper.ReadPRG(uint16(addr))
+							}
+							for addr := 0x0000; addr < 0x2000; addr++ {
+								got.Mapper.ReadCHR(uint16(addr))
+							}
+						}, ShouldNotPanic)
+					})
+				}
+			}
+		})
+
+		Convey("mapper番号はbyte 8の下位4bitを含めて12bit", func() {
+			header := newHeader()
+			header[8] = 0x31
+			_, err := cartridge.NewCartridge(newROM(header))
+			So(errors.Is(err, mapper.ErrUnsupportedMapper), ShouldBeTrue)
+			So(err.Error(), ShouldContainSubstring, "mapperNo: 260")
+		})
+
+		Convey("ヘッダーの内容が不正な場合はErrInvalidHeader", func() {
+			tests := map[string]func(header []byte){
+				"miscellaneous ROMがない":     func(header []byte) { header[14] = 0x01 },
+				"指数表記のサイズが大きすぎる":           func(header []byte) { header[4] = 0xFC },
+				"予約されている拡張の本体の種類":          func(header []byte) { header[7] |= 0x03; header[13] = 0x0F },
+				"予約されているVS. SystemのPPUの種類": func(header []byte) { header[7] |= 0x01; header[13] = 0x0D },
+			}
+			for name, modify := range tests {
+				Convey(name, func() {
+					header := newHeader()
+					modify(header)
+					_, err := cartridge.NewCartridge(newROM(header))
+					So(errors.Is(err, cartridge.ErrInvalidHeader), ShouldBeTrue)
+				})
+			}
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestCartridge_INES$ github.com/sunjin110/nes_emu/internal/domain/cartridge
+func TestCartridge_INES(t *testing.T) {
+	Convey("TestCartridge_INES", t, func() {
+		// mapper 0, PRG-ROM 16KB, CHR-ROM 8KB
+		newHeader := func() []byte {
+			return []byte{'N', 'E', 'S', 0x1A, 1, 1, 0x00, 0x00, 0, 0, 0, 0, 0, 0, 0, 0}
+		}
+		newPRG := func() []byte {
+			prg := make([]byte, 0x4000)
+			prg[0] = 0x4C
+			return prg
+		}
+
+		Convey("byte 6からミラーリングとバッテリーを読み込む", func() {
+			header := newHeader()
+			header[6] = 0x0A // バッテリー、4画面
+			got, err := cartridge.NewCartridge(append(append(header, newPRG()...), make([]byte, 0x2000)...))
+			So(err, ShouldBeNil)
+			So(got.Mirroring, ShouldEqual, ppu.MirroringFourScreen)
+			So(got.Battery, ShouldBeTrue)
+			So(got.Mapper.Mirroring(), ShouldEqual, ppu.MirroringFourScreen)
+		})
+
+		Convey("trainerはPRG-ROMの前の512byteで、PRG-RAMの0x7000に読み込む", func() {
+			header := newHeader()
+			header[6] = 0x04
+			trainer := make([]byte, 512)
+			trainer[0] = 0x12
+			trainer[511] = 0x34
+			data := append(append(header, trainer...), newPRG()...)
+			got, err := cartridge.NewCartridge(append(data, make([]byte, 0x2000)...))
+			So(err, ShouldBeNil)
+			So(got.Trainer, ShouldResemble, trainer)
+			So(got.PRG[0], ShouldEqual, 0x4C)
+			So(got.Mapper.ReadPRG(0x7000), ShouldEqual, 0x12)
+			So(got.Mapper.ReadPRG(0x71FF), ShouldEqual, 0x34)
+			So(got.Mapper.ReadPRG(0x8000), ShouldEqual, 0x4C)
+		})
+
+		Convey("byte 7-15に\"DiskDude!\"が書き込まれている場合は0として読む", func() {
+			header := append(newHeader()[:7], []byte("DiskDude!")...)
+			header[6] = 0x01
+			got, err := cartridge.NewCartridge(append(append(header, newPRG()...), make([]byte, 0x2000)...))
+			So(err, ShouldBeNil)
+			So(got.DirtyHeader, ShouldBeTrue)
+			So(got.MapperNo, ShouldEqual, 0)
+			So(got.Format, ShouldEqual, cartridge.FormatINES)
+			So(got.Mirroring, ShouldEqual, ppu.MirroringVertical)
+		})
+
+		Convey("データが短い場合やマジックナンバーが違う場合はpanicせずにErrInvalidHeaderを返す", func() {
+			tests := map[string][]byte{
+				"空":                   {},
+				"3byte":               []byte("NES"),
+				"ヘッダーの途中まで":           newHeader()[:8],
+				"trainerがない":          append(func() []byte { h := newHeader(); h[6] = 0x04; return h }(), make([]byte, 100)...),
+				"PRG-ROMがない":          newHeader(),
+				"CHR-ROMがない":          append(newHeader(), newPRG()...),
+				"byte 0-2が\"NES\"でない": append([]byte("NEZ\x1A"), newHeader()[4:]...),
+				"byte 3が0x1Aでない":      append([]byte("NES\x00"), newHeader()[4:]...),
+			}
+			for name, data := range tests {
+				Convey(name, func() {
+					So(func() { _, _ = cartridge.NewCartridge(data) }, ShouldNotPanic)
+					_, err := cartridge.NewCartridge(data)
+					So(errors.Is(err, cartridge.ErrInvalidHeader), ShouldBeTrue)
+				})
+			}
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestCartridge_SRAM$ github.com/sunjin110/nes_emu/internal/domain/cartridge
+func TestCartridge_SRAM(t *testing.T) {
+	Convey("TestCartridge_SRAM", t, func() {
+		// mapper 1(MMC1), バッテリーあり, PRG-ROM 32KB, CHR-RAM
+		newROM := func(flags6 byte) []byte {
+			header := []byte{'N', 'E', 'S', 0x1A, 2, 0, 0x10 | flags6, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+			return append(header, make([]byte, 0x8000)...)
+		}
+
+		Convey("PRG-RAMへの書き込みを書き出し、別のカートリッジに読み込める", func() {
+			cart, err := cartridge.NewCartridge(newROM(0x02))
+			So(err, ShouldBeNil)
+			So(cart.Battery, ShouldBeTrue)
+			cart.Mapper.WritePRG(0x6000, 0x12)
+			cart.Mapper.WritePRG(0x7FFF, 0x34)
+
+			sram := cart.SRAM()
+			So(len(sram), ShouldEqual, 0x2000)
+			So(sram[0], ShouldEqual, 0x12)
+			So(sram[0x1FFF], ShouldEqual, 0x34)
+			// コピーなので書き換えてもmapperのRAMは変わらない
+			sram[0] = 0x56
+			So(cart.Mapper.ReadPRG(0x6000), ShouldEqual, 0x12)
+
+			other, err := cartridge.NewCartridge(newROM(0x02))
+			So(err, ShouldBeNil)
+			So(other.LoadSRAM(sram), ShouldBeNil)
+			So(other.Mapper.ReadPRG(0x6000), ShouldEqual, 0x56)
+			So(other.Mapper.ReadPRG(0x7FFF), ShouldEqual, 0x34)
+		})
+
+		Convey("NES 2.0で揮発性のRAMもある場合は、不揮発性のRAMのサイズだけを保存する", func() {
+			data := newROM(0x02)
+			data[7] = 0x08  // NES 2.0
+			data[10] = 0x77 // PRG-RAM 8KB, PRG-NVRAM 8KB
+			data[11] = 0x07 // CHR-RAM 8KB
+			cart, err := cartridge.NewCartridge(data)
+			So(err, ShouldBeNil)
+			So(len(cart.SRAM()), ShouldEqual, 0x2000)
+			So(errors.Is(cart.LoadSRAM(make([]byte, 0x4000)), cartridge.ErrSRAMSizeMismatch), ShouldBeTrue)
+			So(cart.LoadSRAM(bytes.Repeat([]byte{0xAA}, 0x2000)), ShouldBeNil)
+		})
+
+		Convey("trainerがある場合は.savを読み込んだ後も0x7000-0x71FFはtrainerのまま", func() {
+			trainer := bytes.Repeat([]byte{0x77}, 512)
+			data := newROM(0x02 | 0x04)
+			data = append(append(data[:16:16], trainer...), data[16:]...)
+			cart, err := cartridge.NewCartridge(data)
+			So(err, ShouldBeNil)
+			So(cart.Mapper.ReadPRG(0x7000), ShouldEqual, 0x77)
+
+			So(cart.LoadSRAM(bytes.Repeat([]byte{0xAA}, 0x2000)), ShouldBeNil)
+			So(cart.Mapper.ReadPRG(0x6FFF), ShouldEqual, 0xAA)
+			So(cart.Mapper.ReadPRG(0x7000), ShouldEqual, 0x77)
+			So(cart.Mapper.ReadPRG(0x71FF), ShouldEqual, 0x77)
+			So(cart.Mapper.ReadPRG(0x7200), ShouldEqual, 0xAA)
+		})
+
+		Convey("サイズが違う場合はErrSRAMSizeMismatch", func() {
+			cart, err := cartridge.NewCartridge(newROM(0x02))
+			So(err, ShouldBeNil)
+			err = cart.LoadSRAM(make([]byte, 0x100))
+			So(errors.Is(err, cartridge.ErrSRAMSizeMismatch), ShouldBeTrue)
+		})
+
+		Convey("RAMがないmapperはErrNoSRAM", func() {
+			// mapper 2(UxROM)
+			header := []byte{'N', 'E', 'S', 0x1A, 2, 0, 0x22, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+			cart, err := cartridge.NewCartridge(append(header, make([]byte, 0x8000)...))
+			So(err, ShouldBeNil)
+			So(cart.SRAM(), ShouldBeNil)
+			err = cart.LoadSRAM(make([]byte, 0x2000))
+			So(errors.Is(err, cartridge.ErrNoSRAM), ShouldBeTrue)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestCartridge_GameDB$ github.com/sunjin110/nes_emu/internal/domain/cartridge
+func TestCartridge_GameDB(t *testing.T) {
+	defer cartridge.SetGameDBForTest(testGameDB)()
+
+	Convey("TestCartridge_GameDB", t, func() {
+		Convey("ヘッダーが間違っていてもゲームデータベースの値でmapperを作る", func() {
+			data := append([]byte(nil), helloNesRom...)
+			data[6] = 0x32 // mapper 3, 水平ミラーリング, バッテリー
+			data[7] = 0x00
+			got, err := cartridge.NewCartridge(data)
+			So(err, ShouldBeNil)
+			So(got.Game, ShouldNotBeNil)
+			So(got.Game.Name, ShouldEqual, "hello")
+			So(got.MapperNo, ShouldEqual, 0)
+			So(got.Mirroring, ShouldEqual, ppu.MirroringVertical)
+			So(got.Battery, ShouldBeFalse)
+			So(got.Mapper.Mirroring(), ShouldEqual, ppu.MirroringVertical)
+			So(got.Header.MapperNo, ShouldEqual, 3)
+			So(got.Header.Mirroring, ShouldEqual, ppu.MirroringHorizontal)
+			So(got.Header.Battery, ShouldBeTrue)
+		})
+
+		Convey("ミラーリングをmapperが切り替えるゲームはヘッダーのミラーリングを使う", func() {
+			game, err := cartridge.LookupGame(0xB62743B8, [20]byte{
+				0x69, 0xD9, 0x2C, 0x7E, 0xC5, 0x32, 0xD5, 0xF0, 0x81, 0x14,
+				0x35, 0x82, 0xF5, 0x70, 0x65, 0xF5, 0x1A, 0xB7, 0x59, 0x24,
+			})
+			So(err, ShouldBeNil)
+			So(game.Name, ShouldEqual, "megaari")
+			So(game.Board.MapperNo, ShouldEqual, 4)
+			So(game.MapperMirroring, ShouldBeTrue)
+		})
+
+		Convey("データベースにないROMはヘッダーの値を使う", func() {
+			data := append([]byte(nil), helloNesRom...)
+			data[16] ^= 0xFF
+			got, err := cartridge.NewCartridge(data)
+			So(err, ShouldBeNil)
+			So(got.Game, ShouldBeNil)
+			So(got.Header, ShouldResemble, cartridge.Board{Mirroring: ppu.MirroringVertical})
+
+			_, err = cartridge.LookupGame(got.CRC32, got.SHA1)
+			So(errors.Is(err, cartridge.ErrGameNotFound), ShouldBeTrue)
+		})
+
+		Convey("データベースが不正な場合はNewCartridgeもエラーにする", func() {
+			defer cartridge.SetGameDBForTest([]byte("EAA62BF4\tinvalid\n"))()
+			_, err := cartridge.NewCartridge(helloNesRom)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("SetGameDBで読み込んだデータベースを使い、不正なデータは読み込まない", func() {
+			defer cartridge.SetGameDBForTest(testGameDB)()
+			custom := "EAA62BF4\t44F387113CFA7794F5A219DA6F8E35C10C3D482E\t2\t0\tH\t0\t0\t0\t0\t0\t1\thello (PAL)\n"
+			So(cartridge.SetGameDB([]byte(custom)), ShouldBeNil)
+			got, err := cartridge.NewCartridge(helloNesRom)
+			So(err, ShouldBeNil)
+			So(got.Game.Name, ShouldEqual, "hello (PAL)")
+			So(got.MapperNo, ShouldEqual, 2)
+			So(got.Timing, ShouldEqual, cartridge.TimingPAL)
+
+			So(cartridge.SetGameDB([]byte("EAA62BF4\tinvalid\n")), ShouldNotBeNil)
+			got, err = cartridge.NewCartridge(helloNesRom)
+			So(err, ShouldBeNil)
+			So(got.Game.Name, ShouldEqual, "hello (PAL)")
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestCartridge_UNIF$ github.com/sunjin110/nes_emu/internal/domain/cartridge
+func TestCartridge_UNIF(t *testing.T) {
+	Convey("TestCartridge_UNIF", t, func() {
+		chunk := func(id string, data []byte) []byte {
+			length := len(data)
+			header := append([]byte(id), byte(length), byte(length>>8), byte(length>>16), byte(length>>24))
+			return append(header, data...)
+		}
+		newUNIF := func(chunks ...[]byte) []byte {
+			data := append([]byte("UNIF"), make([]byte, 28)...)
+			data[4] = 7 // リビジョン
+			for _, c := range chunks {
+				data = append(data, c...)
+			}
+			return data
+		}
+		fill := func(size int, value byte) []byte {
+			return bytes.Repeat([]byte{value}, size)
+		}
+
+		Convey("基板名からmapperを選び、PRG, CHRのチャンクを番号順に繋げる", func() {
+			data := newUNIF(
+				chunk("MAPR", []byte("NES-SNROM\x00")),
+				chunk("PRG1", fill(0x4000, 0x11)),
+				chunk("PRG0", fill(0x4000, 0x00)),
+				chunk("CHR0", fill(0x2000, 0x22)),
+				chunk("MIRR", []byte{1}),
+				chunk("BATR", []byte{1}),
+				chunk("TVCI", []byte{1}),
+				chunk("CTRL", []byte{0x03}),
+				chunk("NAME", []byte("test\x00")),
+			)
+			got, err := cartridge.NewCartridge(data)
+			So(err, ShouldBeNil)
+			So(got.Format, ShouldEqual, cartridge.FormatUNIF)
+			So(got.BoardName, ShouldEqual, "NES-SNROM")
+			So(got.MapperNo, ShouldEqual, 1)
+			So(len(got.PRG), ShouldEqual, 0x8000)
+			So(got.PRG[0], ShouldEqual, 0x00)
+			So(got.PRG[0x4000], ShouldEqual, 0x11)
+			So(got.PRGBankCount, ShouldEqual, 2)
+			So(got.CHRBankCount, ShouldEqual, 1)
+			So(got.Mirroring, ShouldEqual, ppu.MirroringVertical)
+			So(got.Battery, ShouldBeTrue)
+			So(got.Timing, ShouldEqual, cartridge.TimingPAL)
+			So(got.Controllers, ShouldEqual, 0x03)
+			So(got.Mapper.ReadCHR(0x0000), ShouldEqual, 0x22)
+		})
+
+		Convey("CHRのチャンクがない場合はCHR-RAM、SXROMは32KBのPRG-RAM", func() {
+			got, err := cartridge.NewCartridge(newUNIF(
+				chunk("MAPR", []byte("NES-SXROM\x00")),
+				chunk("PRG0", fill(0x8000, 0x00)),
+			))
+			So(err, ShouldBeNil)
+			So(got.CHR, ShouldBeEmpty)
+			So(got.PRGRAMSize, ShouldEqual, 0x8000)
+			got.Mapper.WriteCHR(0x0010, 0x56)
+			So(got.Mapper.ReadCHR(0x0010), ShouldEqual, 0x56)
+		})
+
+		Convey("バンクより小さいCHRはバンクの中でミラーリングする", func() {
+			chr := fill(0x1000, 0x00)
+			chr[0x0FFF] = 0x33
+			got, err := cartridge.NewCartridge(newUNIF(
+				chunk("MAPR", []byte("NES-CNROM\x00")),
+				chunk("PRG0", fill(0x4000, 0x00)),
+				chunk("CHR0", chr),
+			))
+			So(err, ShouldBeNil)
+			So(got.Mapper.ReadCHR(0x0FFF), ShouldEqual, 0x33)
+			So(got.Mapper.ReadCHR(0x1FFF), ShouldEqual, 0x33)
+		})
+
+		Convey("対応していない基板はErrUnsupportedBoard", func() {
+			_, err := cartridge.NewCartridge(newUNIF(
+				chunk("MAPR", []byte("UNL-UNKNOWN\x00")),
+				chunk("PRG0", fill(0x8000, 0x00)),
+			))
+			So(errors.Is(err, cartridge.ErrUnsupportedBoard), ShouldBeTrue)
+		})
+
+		Convey("チャンクが不正な場合はErrInvalidUNIF", func() {
+			tests := map[string][]byte{
+				"ヘッダーが短い":     []byte("UNIF"),
+				"チャンクが途中で終わる": newUNIF(chunk("MAPR", []byte("NES-NROM\x00")))[:40],
+				"MAPRがない":     newUNIF(chunk("PRG0", fill(0x8000, 0x00))),
+				"PRGがない":      newUNIF(chunk("MAPR", []byte("NES-NROM\x00"))),
+				"PRGがバンクの倍数でない": newUNIF(
+					chunk("MAPR", []byte("NES-UNROM\x00")),
+					chunk("PRG0", fill(0x8000, 0x00)),
+					chunk("PRG1", fill(0x0123, 0x00)),
+				),
+				"PRGがバンクより小さくミラーリングできない": newUNIF(
+					chunk("MAPR", []byte("NES-NROM\x00")),
+					chunk("PRG0", fill(0x0C00, 0x00)),
+				),
+				"CHRがバンクの倍数でない": newUNIF(
+					chunk("MAPR", []byte("NES-CNROM\x00")),
+					chunk("PRG0", fill(0x8000, 0x00)),
+					chunk("CHR0", fill(0x2000, 0x00)),
+					chunk("CHR1", fill(0x1000, 0x00)),
+				),
+				"CHRがバンクより小さくミラーリングできない": newUNIF(
+					chunk("MAPR", []byte("NES-TLROM\x00")),
+					chunk("PRG0", fill(0x8000, 0x00)),
+					chunk("CHR0", fill(0x0300, 0x00)),
+				),
+			}
+			for name, data := range tests {
+				Convey(name, func() {
+					_, err := cartridge.NewCartridge(data)
+					So(errors.Is(err, cartridge.ErrInvalidUNIF), ShouldBeTrue)
+				})
+			}
+		})
+	})
+}
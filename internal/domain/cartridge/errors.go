@@ -1 +1,18 @@
 package cartridge
+
+import "errors"
+
+var (
+	// ErrInvalidHeader ヘッダーの内容が不正、またはヘッダーとROMのサイズが合わない
+	ErrInvalidHeader = errors.New("Cartridge: invalid header")
+	// ErrInvalidUNIF UNIFのチャンクが不正
+	ErrInvalidUNIF = errors.New("Cartridge: invalid UNIF")
+	// ErrUnsupportedBoard UNIFの基板名に対応するmapperがない
+	ErrUnsupportedBoard = errors.New("Cartridge: unsupported board")
+	// ErrNoSRAM mapperにバッテリーバックアップされるRAMがない
+	ErrNoSRAM = errors.New("Cartridge: no SRAM")
+	// ErrSRAMSizeMismatch 読み込むSRAMのサイズがmapperのRAMと違う
+	ErrSRAMSizeMismatch = errors.New("Cartridge: SRAM size mismatch")
+	// ErrGameNotFound ゲームデータベースにROMがない
+	ErrGameNotFound = errors.New("Cartridge: game is not found in database")
+)
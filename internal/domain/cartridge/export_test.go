@@ -0,0 +1,12 @@
+package cartridge
+
+// SetGameDBForTest 埋め込みのgamedb.tsvの代わりにdataをゲームデータベースとして使う、戻り値で元に戻す
+func SetGameDBForTest(data []byte) (restore func()) {
+	original := loadGameDB
+	loadGameDB = func() (gameDB, error) {
+		return parseGameDB(data)
+	}
+	return func() {
+		loadGameDB = original
+	}
+}
@@ -0,0 +1,242 @@
+package cartridge
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// ゲームデータベース
+// ヘッダーが間違っているROMが多いので、PRG-ROMとCHR-ROMのハッシュでデータベースを引いてmapperなどを上書きする
+//
+// gamedb.tsvはnes20db(https://forums.nesdev.org/viewtopic.php?t=19940)のXMLから
+// go run ./cmd/gamedb nes20db.xml > internal/domain/cartridge/gamedb.tsv で作る、今はstatic/romsのROMだけが入っている
+// nes20db全体から作ったTSVはSetGameDBで埋め込みの代わりに使える(nes --gamedb)
+// テストはtestdata/gamedb.tsvを使うので、作り直してもテストは変わらない
+
+//go:embed gamedb.tsv
+var gameDBData []byte
+
+// Board ヘッダーかゲームデータベースで指定されるmapperとRAMの設定
+type Board struct {
+	MapperNo     int
+	Submapper    int
+	Mirroring    ppu.Mirroring
+	Battery      bool
+	PRGRAMSize   int
+	PRGNVRAMSize int
+	CHRRAMSize   int
+	CHRNVRAMSize int
+	Timing       Timing
+}
+
+// Game ゲームデータベースの1件
+type Game struct {
+	Name  string
+	CRC32 uint32
+	SHA1  [sha1.Size]byte
+	Board Board
+	// MapperMirroring ミラーリングはmapperが切り替えるので、ヘッダーの値を上書きしない
+	MapperMirroring bool
+}
+
+// gameDB CRC32ごとのゲーム、CRC32が同じ別のゲームはSHA-1で区別する
+type gameDB map[uint32][]Game
+
+// gamedb.tsvの列
+const (
+	gameDBColumnCRC32 = iota
+	gameDBColumnSHA1
+	gameDBColumnMapper
+	gameDBColumnSubmapper
+	gameDBColumnMirroring
+	gameDBColumnBattery
+	gameDBColumnPRGRAM
+	gameDBColumnPRGNVRAM
+	gameDBColumnCHRRAM
+	gameDBColumnCHRNVRAM
+	gameDBColumnTiming
+	gameDBColumnName
+	gameDBColumns
+)
+
+var loadGameDB = sync.OnceValues(func() (gameDB, error) {
+	return parseGameDB(gameDBData)
+})
+
+// SetGameDB 埋め込みのgamedb.tsvの代わりにdataをゲームデータベースとして使う、NewCartridgeより前に呼ぶこと
+// dataが読み込めない場合はエラーを返して、今のゲームデータベースをそのまま使う
+func SetGameDB(data []byte) error {
+	db, err := parseGameDB(data)
+	if err != nil {
+		return err
+	}
+	loadGameDB = func() (gameDB, error) {
+		return db, nil
+	}
+	return nil
+}
+
+// parseGameDB タブ区切りで1行1件、#から始まる行と空行は読み飛ばす
+// 列: crc32, sha1, mapper, submapper, mirroring(H, V, 4, M: mapperが切り替える), battery(0, 1),
+// prgram, prgnvram, chrram, chrnvram(byte), timing(0: NTSC, 1: PAL, 2: Multi-region, 3: Dendy), name
+func parseGameDB(data []byte) (gameDB, error) {
+	db := gameDB{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		game, err := parseGame(strings.Split(line, "\t"))
+		if err != nil {
+			return nil, fmt.Errorf("Cartridge: invalid game database. line: %d, err: %w", lineNo, err)
+		}
+		db[game.CRC32] = append(db[game.CRC32], game)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Cartridge: failed read game database. err: %w", err)
+	}
+	return db, nil
+}
+
+func parseGame(columns []string) (Game, error) {
+	if len(columns) != gameDBColumns {
+		return Game{}, fmt.Errorf("invalid column count. want: %d, got: %d", gameDBColumns, len(columns))
+	}
+
+	var game Game
+	crc, err := strconv.ParseUint(columns[gameDBColumnCRC32], 16, 32)
+	if err != nil {
+		return Game{}, fmt.Errorf("invalid crc32. err: %w", err)
+	}
+	game.CRC32 = uint32(crc)
+	sum, err := hex.DecodeString(columns[gameDBColumnSHA1])
+	if err != nil || len(sum) != sha1.Size {
+		return Game{}, fmt.Errorf("invalid sha1. value: %s", columns[gameDBColumnSHA1])
+	}
+	copy(game.SHA1[:], sum)
+
+	switch columns[gameDBColumnMirroring] {
+	case "H":
+		game.Board.Mirroring = ppu.MirroringHorizontal
+	case "V":
+		game.Board.Mirroring = ppu.MirroringVertical
+	case "4":
+		game.Board.Mirroring = ppu.MirroringFourScreen
+	case "M":
+		game.MapperMirroring = true
+	default:
+		return Game{}, fmt.Errorf("invalid mirroring. value: %s", columns[gameDBColumnMirroring])
+	}
+
+	numbers := []struct {
+		column int
+		target *int
+	}{
+		{column: gameDBColumnMapper, target: &game.Board.MapperNo},
+		{column: gameDBColumnSubmapper, target: &game.Board.Submapper},
+		{column: gameDBColumnPRGRAM, target: &game.Board.PRGRAMSize},
+		{column: gameDBColumnPRGNVRAM, target: &game.Board.PRGNVRAMSize},
+		{column: gameDBColumnCHRRAM, target: &game.Board.CHRRAMSize},
+		{column: gameDBColumnCHRNVRAM, target: &game.Board.CHRNVRAMSize},
+	}
+	for _, number := range numbers {
+		value, err := strconv.Atoi(columns[number.column])
+		if err != nil || value < 0 {
+			return Game{}, fmt.Errorf("invalid number. value: %s", columns[number.column])
+		}
+		*number.target = value
+	}
+
+	battery, err := strconv.ParseBool(columns[gameDBColumnBattery])
+	if err != nil {
+		return Game{}, fmt.Errorf("invalid battery. err: %w", err)
+	}
+	game.Board.Battery = battery
+
+	timing, err := strconv.Atoi(columns[gameDBColumnTiming])
+	if err != nil || timing < int(TimingNTSC) || timing > int(TimingDendy) {
+		return Game{}, fmt.Errorf("invalid timing. value: %s", columns[gameDBColumnTiming])
+	}
+	game.Board.Timing = Timing(timing)
+
+	game.Name = columns[gameDBColumnName]
+	return game, nil
+}
+
+// find CRC32とSHA-1が両方一致するゲーム
+func (db gameDB) find(crc uint32, sum [sha1.Size]byte) (Game, bool) {
+	for _, game := range db[crc] {
+		if game.SHA1 == sum {
+			return game, true
+		}
+	}
+	return Game{}, false
+}
+
+// LookupGame PRG-ROMとCHR-ROMのハッシュでゲームデータベースを引く
+func LookupGame(crc uint32, sum [sha1.Size]byte) (Game, error) {
+	db, err := loadGameDB()
+	if err != nil {
+		return Game{}, err
+	}
+	game, ok := db.find(crc, sum)
+	if !ok {
+		return Game{}, fmt.Errorf("%w. crc32: %08X", ErrGameNotFound, crc)
+	}
+	return game, nil
+}
+
+// hashROM PRG-ROMとCHR-ROMを続けたデータのCRC32とSHA-1
+func hashROM(prg, chr []byte) (uint32, [sha1.Size]byte) {
+	crc := crc32.NewIEEE()
+	sum := sha1.New()
+	for _, data := range [][]byte{prg, chr} {
+		crc.Write(data)
+		sum.Write(data)
+	}
+	var digest [sha1.Size]byte
+	copy(digest[:], sum.Sum(nil))
+	return crc.Sum32(), digest
+}
+
+// Board mapperの作成に使う値、Gameがある場合はゲームデータベースで上書きした値
+func (c *Cartridge) Board() Board {
+	return Board{
+		MapperNo:     c.MapperNo,
+		Submapper:    c.Submapper,
+		Mirroring:    c.Mirroring,
+		Battery:      c.Battery,
+		PRGRAMSize:   c.PRGRAMSize,
+		PRGNVRAMSize: c.PRGNVRAMSize,
+		CHRRAMSize:   c.CHRRAMSize,
+		CHRNVRAMSize: c.CHRNVRAMSize,
+		Timing:       c.Timing,
+	}
+}
+
+// applyGame ゲームデータベースの値でヘッダーの値を上書きする
+func (c *Cartridge) applyGame(game Game) {
+	c.Game = &game
+	c.MapperNo = game.Board.MapperNo
+	c.Submapper = game.Board.Submapper
+	if !game.MapperMirroring {
+		c.Mirroring = game.Board.Mirroring
+	}
+	c.Battery = game.Board.Battery
+	c.PRGRAMSize = game.Board.PRGRAMSize
+	c.PRGNVRAMSize = game.Board.PRGNVRAMSize
+	c.CHRRAMSize = game.Board.CHRRAMSize
+	c.CHRNVRAMSize = game.Board.CHRNVRAMSize
+	c.Timing = game.Board.Timing
+}
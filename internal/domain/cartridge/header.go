@@ -0,0 +1,213 @@
+package cartridge
+
+import (
+	"fmt"
+
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// iNES, NES 2.0のヘッダー(16byte)
+// doc: https://www.nesdev.org/wiki/INES
+// doc: https://www.nesdev.org/wiki/NES_2.0
+
+// Format ROMの形式
+type Format int
+
+const (
+	FormatINES  Format = iota // iNES 1.0
+	FormatNES20               // NES 2.0
+	FormatUNIF                // UNIF
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatNES20:
+		return "NES 2.0"
+	case FormatUNIF:
+		return "UNIF"
+	}
+	return "iNES"
+}
+
+// Timing CPU/PPUのタイミング(NES 2.0のbyte 12)
+type Timing int
+
+const (
+	TimingNTSC        Timing = iota // RP2C02
+	TimingPAL                       // RP2C07
+	TimingMultiRegion               // NTSCとPALの両方で動く
+	TimingDendy                     // UA6538
+)
+
+func (t Timing) String() string {
+	switch t {
+	case TimingNTSC:
+		return "NTSC"
+	case TimingPAL:
+		return "PAL"
+	case TimingMultiRegion:
+		return "Multi-region"
+	case TimingDendy:
+		return "Dendy"
+	}
+	return fmt.Sprintf("Timing(%d)", int(t))
+}
+
+// CPUClock タイミングのCPUのクロック周波数(Hz)、Multi-regionはNTSCとして扱う
+func (t Timing) CPUClock() int {
+	switch t {
+	case TimingPAL:
+		return apu.CPUClockPAL
+	case TimingDendy:
+		return apu.CPUClockDendy
+	}
+	return apu.CPUClockNTSC
+}
+
+// ConsoleType 本体の種類(byte 7のbit0-1)
+type ConsoleType int
+
+const (
+	ConsoleNES          ConsoleType = iota // NES/ファミコン
+	ConsoleVSSystem                        // VS. System
+	ConsolePlayChoice10                    // PlayChoice-10
+	ConsoleExtended                        // byte 13のExtendedConsoleType
+)
+
+func (t ConsoleType) String() string {
+	switch t {
+	case ConsoleNES:
+		return "NES"
+	case ConsoleVSSystem:
+		return "VS. System"
+	case ConsolePlayChoice10:
+		return "PlayChoice-10"
+	case ConsoleExtended:
+		return "Extended"
+	}
+	return fmt.Sprintf("ConsoleType(%d)", int(t))
+}
+
+const (
+	// maxVSPPUType, maxVSHardwareType, maxExtendedConsoleType 定義されている値の最大、それより大きい値は予約されている
+	maxVSPPUType           = 0x0C
+	maxVSHardwareType      = 0x06
+	maxExtendedConsoleType = 0x0B
+
+	// maxSizeExponent 指数表記のROMのサイズの指数の最大(1GB)、それより大きいROMは読み込めない
+	maxSizeExponent = 30
+)
+
+// isNES20 byte 7のbit2, 3が10ならNES 2.0
+func isNES20(header []byte) bool {
+	return header[7]&0x0C == 0x08
+}
+
+// isDirty iNES 1.0でbyte 7のbit2, 3が00でない、またはbyte 12-15が0でない場合は
+// 古いツールが"DiskDude!"などのゴミを書き込んだヘッダー
+func isDirty(header []byte) bool {
+	if isNES20(header) {
+		return false
+	}
+	if header[7]&0x0C != 0 {
+		return true
+	}
+	for _, b := range header[12:iNESHeaderSize] {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHeader ヘッダー(16byte)の内容をcartridgeに入れて、PRG-ROMとCHR-ROMのサイズ(byte)を返す
+func (c *Cartridge) parseHeader(header []byte) (prgSize, chrSize int, err error) {
+	if isDirty(header) {
+		// byte 7-15は0として読む
+		c.DirtyHeader = true
+		header = append(header[:7:7], make([]byte, iNESHeaderSize-7)...)
+	}
+
+	// byte 6 bit0: 0 水平ミラーリング, 1: 垂直ミラーリング、bit1: バッテリー、bit3: 4画面
+	c.Mirroring = ppu.MirroringHorizontal
+	if header[6]&0x01 != 0 {
+		c.Mirroring = ppu.MirroringVertical
+	}
+	if header[6]&0x08 != 0 {
+		c.Mirroring = ppu.MirroringFourScreen
+	}
+	c.Battery = header[6]&0x02 != 0
+
+	// 例: header[6] = 0x31 (下位4ビット=1, 上位4ビット=3)
+	//    header[7] = 0x80 (上位4ビット=8, 下位4ビット=0)
+	//    → mapperNo = 3 | 0x80 = 0x83 = 131
+	c.MapperNo = int(header[6]>>4) | int(header[7]&0xF0)
+	prgSize = int(header[4]) * prgBankSize
+	chrSize = int(header[5]) * chrBankSize
+
+	if !isNES20(header) {
+		c.Format = FormatINES
+		return prgSize, chrSize, nil
+	}
+	c.Format = FormatNES20
+	// byte 8: 下位4bitがmapper番号のbit8-11、上位4bitがsubmapper
+	c.MapperNo |= int(header[8]&0x0F) << 8
+	c.Submapper = int(header[8] >> 4)
+
+	// byte 9: 下位4bitがPRG-ROM、上位4bitがCHR-ROMのサイズの上位bit
+	if prgSize, err = romSize(header[4], header[9]&0x0F, prgBankSize); err != nil {
+		return 0, 0, fmt.Errorf("%w. invalid PRG-ROM size. err: %w", ErrInvalidHeader, err)
+	}
+	if chrSize, err = romSize(header[5], header[9]>>4, chrBankSize); err != nil {
+		return 0, 0, fmt.Errorf("%w. invalid CHR-ROM size. err: %w", ErrInvalidHeader, err)
+	}
+
+	// byte 10, 11: 下位4bitが揮発性、上位4bitが不揮発性のRAMのサイズ
+	c.PRGRAMSize = ramSize(header[10] & 0x0F)
+	c.PRGNVRAMSize = ramSize(header[10] >> 4)
+	c.CHRRAMSize = ramSize(header[11] & 0x0F)
+	c.CHRNVRAMSize = ramSize(header[11] >> 4)
+
+	c.Timing = Timing(header[12] & 0x03)
+
+	c.ConsoleType = ConsoleType(header[7] & 0x03)
+	switch c.ConsoleType {
+	case ConsoleVSSystem:
+		c.VSPPUType = int(header[13] & 0x0F)
+		c.VSHardwareType = int(header[13] >> 4)
+		if c.VSPPUType > maxVSPPUType || c.VSHardwareType > maxVSHardwareType {
+			return 0, 0, fmt.Errorf("%w. invalid VS. System type. ppuType: %d, hardwareType: %d", ErrInvalidHeader, c.VSPPUType, c.VSHardwareType)
+		}
+	case ConsoleExtended:
+		c.ExtendedConsoleType = int(header[13] & 0x0F)
+		if c.ExtendedConsoleType > maxExtendedConsoleType {
+			return 0, 0, fmt.Errorf("%w. invalid extended console type. type: %d", ErrInvalidHeader, c.ExtendedConsoleType)
+		}
+	}
+
+	c.MiscROMCount = int(header[14] & 0x03)
+	c.DefaultExpansionDevice = int(header[15] & 0x3F)
+	return prgSize, chrSize, nil
+}
+
+// romSize NES 2.0のROMのサイズ、上位4bitが0xFの場合はLSBが指数表記(EEEEEEMM: 2^E * (MM*2+1) byte)
+// 指数表記では4KBのPRG-ROMなどバンクより小さいサイズもあるが、mapperがROMの中でミラーリングする
+func romSize(lsb, msb byte, unit int) (int, error) {
+	if msb != 0x0F {
+		return (int(msb)<<8 | int(lsb)) * unit, nil
+	}
+	exponent := int(lsb >> 2)
+	if exponent > maxSizeExponent {
+		return 0, fmt.Errorf("size is too large. exponent: %d", exponent)
+	}
+	return (1 << exponent) * (int(lsb&0x03)*2 + 1), nil
+}
+
+// ramSize RAMのサイズ(64 << shift byte)、0の場合はRAMなし
+func ramSize(shift byte) int {
+	if shift == 0 {
+		return 0
+	}
+	return 64 << shift
+}
@@ -0,0 +1,276 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// UNIF(Universal NES Image Format)
+// doc: https://www.nesdev.org/wiki/UNIF
+//
+// 32byteのヘッダー("UNIF", リビジョン, 予約)の後に、ID(4byte), 長さ(4byte, リトルエンディアン), データのチャンクが続く
+// mapper番号の代わりにMAPRチャンクの基板名で基板を指定する
+
+const unifHeaderSize = 32
+
+var (
+	unifMagic = []byte("UNIF")
+	iNESMagic = []byte("NES\x1A")
+)
+
+// IsUNIF UNIFのデータかどうか
+func IsUNIF(data []byte) bool {
+	return bytes.HasPrefix(data, unifMagic)
+}
+
+// IsINES iNES(NES 2.0)のデータかどうか
+func IsINES(data []byte) bool {
+	return bytes.HasPrefix(data, iNESMagic)
+}
+
+// unifBoard 基板名に対応するmapper
+type unifBoard struct {
+	mapperNo  int
+	submapper int
+	// prgRAMSize 0の場合はmapperの標準のサイズ
+	prgRAMSize int
+}
+
+// unifBoards 基板名(NES-, UNL-などを除く)とmapperの対応
+// doc: https://www.nesdev.org/wiki/UNIF_to_NES_2.0_Mapping
+var unifBoards = map[string]unifBoard{
+	"NROM":     {mapperNo: 0},
+	"NROM-128": {mapperNo: 0},
+	"NROM-256": {mapperNo: 0},
+	"HROM":     {mapperNo: 0},
+	"RROM":     {mapperNo: 0},
+	"RROM-128": {mapperNo: 0},
+	"SROM":     {mapperNo: 0},
+	"RTROM":    {mapperNo: 0},
+	"STROM":    {mapperNo: 0},
+
+	"SAROM":  {mapperNo: 1},
+	"SBROM":  {mapperNo: 1},
+	"SCROM":  {mapperNo: 1},
+	"SEROM":  {mapperNo: 1},
+	"SFROM":  {mapperNo: 1},
+	"SGROM":  {mapperNo: 1},
+	"SHROM":  {mapperNo: 1},
+	"SJROM":  {mapperNo: 1},
+	"SKROM":  {mapperNo: 1},
+	"SLROM":  {mapperNo: 1},
+	"SL1ROM": {mapperNo: 1},
+	"SNROM":  {mapperNo: 1},
+	"SOROM":  {mapperNo: 1, prgRAMSize: 0x4000},
+	"SUROM":  {mapperNo: 1},
+	"SXROM":  {mapperNo: 1, prgRAMSize: 0x8000},
+
+	"UNROM": {mapperNo: 2},
+	"UOROM": {mapperNo: 2},
+
+	"CNROM": {mapperNo: 3},
+
+	"TBROM":  {mapperNo: 4},
+	"TEROM":  {mapperNo: 4},
+	"TFROM":  {mapperNo: 4},
+	"TGROM":  {mapperNo: 4},
+	"TKROM":  {mapperNo: 4},
+	"TLROM":  {mapperNo: 4},
+	"TL1ROM": {mapperNo: 4},
+	"TNROM":  {mapperNo: 4},
+	"TR1ROM": {mapperNo: 4},
+	"TSROM":  {mapperNo: 4},
+	"TVROM":  {mapperNo: 4},
+	"HKROM":  {mapperNo: 4, submapper: 1},
+	"TKSROM": {mapperNo: 118},
+	"TLSROM": {mapperNo: 118},
+
+	"EKROM": {mapperNo: 5},
+	"ELROM": {mapperNo: 5},
+	"ETROM": {mapperNo: 5},
+	"EWROM": {mapperNo: 5},
+
+	"AMROM":  {mapperNo: 7},
+	"ANROM":  {mapperNo: 7},
+	"AN1ROM": {mapperNo: 7},
+	"AOROM":  {mapperNo: 7},
+
+	"GNROM": {mapperNo: 66},
+	"MHROM": {mapperNo: 66},
+
+	"BTR":   {mapperNo: 69},
+	"JLROM": {mapperNo: 69},
+	"JSROM": {mapperNo: 69},
+}
+
+// unifBankSize mapperが切り替えるPRG, CHRのバンクの一番小さい単位(byte)
+type unifBankSize struct {
+	prg int
+	chr int
+}
+
+// unifBankSizes unifBoardsのmapper番号ごとのバンクの単位
+var unifBankSizes = map[int]unifBankSize{
+	0:   {prg: 0x4000, chr: 0x2000},
+	1:   {prg: 0x4000, chr: 0x1000},
+	2:   {prg: 0x4000, chr: 0x2000},
+	3:   {prg: 0x4000, chr: 0x2000},
+	4:   {prg: 0x2000, chr: 0x0400},
+	5:   {prg: 0x2000, chr: 0x0400},
+	7:   {prg: 0x8000, chr: 0x2000},
+	66:  {prg: 0x8000, chr: 0x2000},
+	69:  {prg: 0x2000, chr: 0x0400},
+	118: {prg: 0x2000, chr: 0x0400},
+}
+
+// mappableSize sizeをbankSize単位で切り替えられるか
+// bankSizeの倍数か、bankSizeより小さくてもbankSizeを割り切れるサイズならバンクの中でミラーリングできる
+func mappableSize(size, bankSize int) bool {
+	if size >= bankSize {
+		return size%bankSize == 0
+	}
+	return bankSize%size == 0
+}
+
+// unifBoardPrefixes 基板名の前に付く製造元など
+var unifBoardPrefixes = []string{"NES-", "HVC-", "UNL-", "BTL-", "BMC-"}
+
+// lookupUNIFBoard 基板名(MAPR)に対応するmapper
+func lookupUNIFBoard(name string) (unifBoard, error) {
+	key := strings.ToUpper(name)
+	for _, prefix := range unifBoardPrefixes {
+		key = strings.TrimPrefix(key, prefix)
+	}
+	board, ok := unifBoards[key]
+	if !ok {
+		return unifBoard{}, fmt.Errorf("%w. board: %s", ErrUnsupportedBoard, name)
+	}
+	return board, nil
+}
+
+// newUNIFCartridge UNIFのチャンクを読み込む、PRG0-PRGF, CHR0-CHRFは番号順に繋げる
+func newUNIFCartridge(data []byte) (*Cartridge, error) {
+	if len(data) < unifHeaderSize {
+		return nil, fmt.Errorf("%w. data is too short to contain header. size: %d", ErrInvalidUNIF, len(data))
+	}
+
+	c := &Cartridge{Format: FormatUNIF}
+	var prgChunks, chrChunks [16][]byte
+	for offset := unifHeaderSize; offset < len(data); {
+		if offset+8 > len(data) {
+			return nil, fmt.Errorf("%w. chunk header is truncated. offset: %d", ErrInvalidUNIF, offset)
+		}
+		id := string(data[offset : offset+4])
+		length := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		offset += 8
+		if length > len(data)-offset {
+			return nil, fmt.Errorf("%w. chunk is truncated. id: %q, length: %d", ErrInvalidUNIF, id, length)
+		}
+		chunk := data[offset : offset+length]
+		offset += length
+
+		if err := c.readUNIFChunk(id, chunk, &prgChunks, &chrChunks); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.BoardName == "" {
+		return nil, fmt.Errorf("%w. MAPR chunk is missing", ErrInvalidUNIF)
+	}
+	for _, chunk := range prgChunks {
+		c.PRG = append(c.PRG, chunk...)
+	}
+	if len(c.PRG) == 0 {
+		return nil, fmt.Errorf("%w. PRG chunk is missing", ErrInvalidUNIF)
+	}
+	for _, chunk := range chrChunks {
+		c.CHR = append(c.CHR, chunk...)
+	}
+	c.PRGBankCount = len(c.PRG) / prgBankSize
+	c.CHRBankCount = len(c.CHR) / chrBankSize
+
+	board, err := lookupUNIFBoard(c.BoardName)
+	if err != nil {
+		return nil, err
+	}
+	c.MapperNo = board.mapperNo
+	c.Submapper = board.submapper
+	c.PRGRAMSize = board.prgRAMSize
+
+	if bankSize, ok := unifBankSizes[board.mapperNo]; ok {
+		if !mappableSize(len(c.PRG), bankSize.prg) {
+			return nil, fmt.Errorf("%w. PRG size is not mappable. board: %s, size: %d, bankSize: %d", ErrInvalidUNIF, c.BoardName, len(c.PRG), bankSize.prg)
+		}
+		if len(c.CHR) > 0 && !mappableSize(len(c.CHR), bankSize.chr) {
+			return nil, fmt.Errorf("%w. CHR size is not mappable. board: %s, size: %d, bankSize: %d", ErrInvalidUNIF, c.BoardName, len(c.CHR), bankSize.chr)
+		}
+	}
+
+	if err := c.setupMapper(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Cartridge) readUNIFChunk(id string, chunk []byte, prgChunks, chrChunks *[16][]byte) error {
+	switch {
+	case id == "MAPR":
+		// NULで終わる文字列
+		name, _, _ := bytes.Cut(chunk, []byte{0})
+		c.BoardName = strings.TrimSpace(string(name))
+	case strings.HasPrefix(id, "PRG") || strings.HasPrefix(id, "CHR"):
+		// PRG0-PRGF, CHR0-CHRF、PCK0, CCK0などのCRCのチャンクは読み飛ばす
+		no, err := strconv.ParseUint(id[3:], 16, 4)
+		if err != nil {
+			return fmt.Errorf("%w. invalid chunk id: %q", ErrInvalidUNIF, id)
+		}
+		if id[:3] == "PRG" {
+			prgChunks[no] = chunk
+		} else {
+			chrChunks[no] = chunk
+		}
+	case id == "MIRR":
+		if len(chunk) < 1 {
+			return fmt.Errorf("%w. MIRR chunk is empty", ErrInvalidUNIF)
+		}
+		// 0: 水平, 1: 垂直, 2, 3: 1画面, 4: 4画面, 5: mapperが切り替える
+		switch chunk[0] {
+		case 1:
+			c.Mirroring = ppu.MirroringVertical
+		case 2:
+			c.Mirroring = ppu.MirroringSingleScreenLower
+		case 3:
+			c.Mirroring = ppu.MirroringSingleScreenUpper
+		case 4:
+			c.Mirroring = ppu.MirroringFourScreen
+		default:
+			c.Mirroring = ppu.MirroringHorizontal
+		}
+	case id == "BATR":
+		c.Battery = true
+	case id == "TVCI":
+		if len(chunk) < 1 {
+			return fmt.Errorf("%w. TVCI chunk is empty", ErrInvalidUNIF)
+		}
+		// 0: NTSC, 1: PAL, 2: 両方
+		switch chunk[0] {
+		case 1:
+			c.Timing = TimingPAL
+		case 2:
+			c.Timing = TimingMultiRegion
+		default:
+			c.Timing = TimingNTSC
+		}
+	case id == "CTRL":
+		if len(chunk) < 1 {
+			return fmt.Errorf("%w. CTRL chunk is empty", ErrInvalidUNIF)
+		}
+		c.Controllers = chunk[0]
+	}
+	return nil
+}
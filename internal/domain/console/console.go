@@ -0,0 +1,173 @@
+package console
+
+import (
+	"fmt"
+
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+	"github.com/sunjin110/nes_emu/internal/domain/cpu"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+	"github.com/sunjin110/nes_emu/internal/domain/prgrom"
+)
+
+// CPUCyclesPerFrame NTSCの1フレームあたりのCPUサイクル数(341 * 262 / 3)
+const CPUCyclesPerFrame = 29781
+
+// Console CPU, PPU, APUを同期させながら動かす本体
+type Console struct {
+	prgROM     prgrom.PRGROM
+	mapper     mapper.Mapper
+	cpu        *cpu.CPU
+	ppu        ppu.PPU
+	apu        *apu.APU
+	controller *controller.Controller
+}
+
+func NewConsole(cart *cartridge.Cartridge) (*Console, error) {
+	if cart.Mapper == nil {
+		return nil, fmt.Errorf("Console: mapper is not set. mapperNo: %d", cart.MapperNo)
+	}
+	return NewConsoleWithMapper(cart.Mapper)
+}
+
+// NewConsoleWithMapper cartridge.Cartridge以外(ディスクシステムなど)をカートリッジのスロットに接続する
+func NewConsoleWithMapper(m mapper.Mapper) (*Console, error) {
+	p := ppu.NewPPU(m)
+	a := apu.NewAPU()
+	if m, ok := m.(mapper.AudioMapper); ok {
+		a.AddExpansionAudio(m.ExpansionAudio())
+	}
+	ctrl := controller.NewController()
+	// Zapperは画面の明るさを見る
+	ctrl.SetLightSource(p)
+	prgROM := mapper.NewCPUBus(m)
+	c, err := cpu.NewCPU(prgROM, p, a, ctrl)
+	if err != nil {
+		return nil, fmt.Errorf("Console: failed new cpu. err: %w", err)
+	}
+
+	return &Console{
+		prgROM:     prgROM,
+		mapper:     m,
+		cpu:        c,
+		ppu:        p,
+		apu:        a,
+		controller: ctrl,
+	}, nil
+}
+
+// APU 音声の出力先の設定などに使う
+func (c *Console) APU() *apu.APU {
+	return c.apu
+}
+
+// Controller frontendからボタンの状態を設定するのに使う
+func (c *Console) Controller() *controller.Controller {
+	return c.controller
+}
+
+// Frame 描画が終わったフレーム数
+func (c *Console) Frame() uint64 {
+	return c.ppu.Frame()
+}
+
+// FrameBuffer 256x240のパレットのインデックス
+func (c *Console) FrameBuffer() []byte {
+	return c.ppu.FrameBuffer()
+}
+
+// Reset リセットボタンを押す
+func (c *Console) Reset() error {
+	c.ppu.Reset()
+	c.silenceAPU()
+	c.resetMapper(mapper.ResetTypeSoft)
+	if err := c.cpu.Interrupt(cpu.InterruptTypeReset); err != nil {
+		return fmt.Errorf("Console: failed reset cpu. err: %w", err)
+	}
+	return nil
+}
+
+// PowerCycle 電源を入れ直す、WRAMとCPUのレジスタ、mapperのレジスタとバッテリーバックアップされていないPRG-RAMは初期化される
+// APUとコントローラーは出力先や接続しているデバイスの設定を残すため同じものを使う
+func (c *Console) PowerCycle() error {
+	c.ppu.Reset()
+	c.silenceAPU()
+	c.resetMapper(mapper.ResetTypeHard)
+	newCPU, err := cpu.NewCPU(c.prgROM, c.ppu, c.apu, c.controller)
+	if err != nil {
+		return fmt.Errorf("Console: failed new cpu. err: %w", err)
+	}
+	c.cpu = newCPU
+	return nil
+}
+
+// resetMapper MMC1のシフトレジスタなど、mapperのリセットされる部分を初期化する
+func (c *Console) resetMapper(resetType mapper.ResetType) {
+	if m, ok := c.mapper.(mapper.ResetMapper); ok {
+		m.Reset(resetType)
+	}
+}
+
+// silenceAPU リセット時は全てのチャンネルが無効になる
+func (c *Console) silenceAPU() {
+	c.apu.Write(0x4015, 0x00)
+}
+
+// Step CPUの1命令を実行し、かかったサイクル数だけAPUとPPUを進める
+func (c *Console) Step() (cycles int, err error) {
+	cpuCycles, err := c.cpu.Run()
+	if err != nil {
+		return 0, fmt.Errorf("Console: failed run cpu. err: %w", err)
+	}
+	cycles = int(cpuCycles) + c.cpu.TakeStallCycles()
+	if err := c.clock(cycles); err != nil {
+		return 0, err
+	}
+
+	if c.ppu.PollNMI() {
+		if err := c.cpu.Interrupt(cpu.InterruptTypeNMI); err != nil {
+			return 0, fmt.Errorf("Console: failed interrupt NMI. err: %w", err)
+		}
+		if err := c.clock(interruptCycles); err != nil {
+			return 0, err
+		}
+		cycles += interruptCycles
+	}
+
+	if c.apu.IRQ() || c.mapper.IRQ() {
+		if err := c.cpu.Interrupt(cpu.InterruptTypeIRQ); err != nil {
+			return 0, fmt.Errorf("Console: failed interrupt IRQ. err: %w", err)
+		}
+	}
+	return cycles, nil
+}
+
+// interruptCycles 割り込みの処理にかかるCPUサイクル数
+const interruptCycles = 7
+
+// clock CPUの1サイクルごとにAPUとmapperを1回、PPUを3回進める
+func (c *Console) clock(cycles int) error {
+	for i := 0; i < cycles; i++ {
+		c.apu.Step()
+		c.mapper.Step()
+		for j := 0; j < 3; j++ {
+			if err := c.ppu.Step(); err != nil {
+				return fmt.Errorf("Console: failed step ppu. err: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// RunFrame PPUが次のフレームの描画を終えるまで実行する
+func (c *Console) RunFrame() error {
+	frame := c.ppu.Frame()
+	for c.ppu.Frame() == frame {
+		if _, err := c.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package console_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/domain/console"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// mmc3TestDir blarggのmmc3_test(1.clocking.nes - 6-MMC3_alt.nes)を置くディレクトリ
+// ROMはリポジトリに含めていないので、ない場合はスキップする(IRQのタイミングはTestConsole_MMC3IRQTimingで常に確認する)
+// doc: https://github.com/christopherpow/nes-test-roms/tree/master/mmc3_test_2
+const mmc3TestDir = "../../../static/roms/mmc3_test"
+
+// blarggTestTimeoutFrames テストが終わるのを待つ最大のフレーム数
+const blarggTestTimeoutFrames = 60 * 60
+
+// runBlarggTest blarggのテストROMを実行して、$6000の結果と$6004からのメッセージを返す
+// doc: https://github.com/christopherpow/nes-test-roms/blob/master/mmc3_test_2/readme.txt
+//
+// $6001-$6003が DE B0 61 になった後、$6000が0x80の間は実行中、0x81はリセットが必要、それ以外は結果(0: 成功)
+func runBlarggTest(path string) (byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, "", err
+	}
+	if strings.Contains(filepath.Base(path), "MMC3_alt") {
+		data = withMMC3ASubmapper(data)
+	}
+	cart, err := cartridge.NewCartridge(data)
+	if err != nil {
+		return 0, "", err
+	}
+	nes, err := console.NewConsole(cart)
+	if err != nil {
+		return 0, "", err
+	}
+
+	read := func(addr uint16) byte {
+		return cart.Mapper.ReadPRG(addr)
+	}
+	status := byte(0xFF)
+	for frame := 0; frame < blarggTestTimeoutFrames; frame++ {
+		if err := nes.RunFrame(); err != nil {
+			return 0, "", err
+		}
+		if read(0x6001) != 0xDE || read(0x6002) != 0xB0 || read(0x6003) != 0x61 {
+			continue
+		}
+		status = read(0x6000)
+		if status == 0x81 {
+			// リセットボタンを押すまで少し待つ
+			for i := 0; i < 6; i++ {
+				if err := nes.RunFrame(); err != nil {
+					return 0, "", err
+				}
+			}
+			if err := nes.Reset(); err != nil {
+				return 0, "", err
+			}
+			continue
+		}
+		if status < 0x80 {
+			break
+		}
+	}
+
+	var message strings.Builder
+	for addr := uint16(0x6004); addr < 0x7000 && read(addr) != 0; addr++ {
+		message.WriteByte(read(addr))
+	}
+	return status, message.String(), nil
+}
+
+// withMMC3ASubmapper 6-MMC3_alt.nesはMMC3Aの動作を見るので、NES 2.0のsubmapper 4にする
+// PRG-RAMは8KB、CHR-ROMがない場合はCHR-RAM 8KB
+func withMMC3ASubmapper(data []byte) []byte {
+	data = append([]byte(nil), data...)
+	data[7] = data[7]&0xF0 | 0x08
+	data[8] = 0x40
+	data[9] = 0x00
+	data[10] = 0x07
+	data[11] = 0x00
+	if data[5] == 0 {
+		data[11] = 0x07
+	}
+	clear(data[12:16])
+	return data
+}
+
+// go test -v -count=1 -timeout 600s -run ^TestConsole_MMC3Test$ github.com/sunjin110/nes_emu/internal/domain/console
+func TestConsole_MMC3Test(t *testing.T) {
+	paths, _ := filepath.Glob(filepath.Join(mmc3TestDir, "*.nes"))
+	if len(paths) == 0 {
+		t.Skipf("mmc3_test ROMs are not found. dir: %s", mmc3TestDir)
+	}
+
+	Convey("TestConsole_MMC3Test", t, func() {
+		for _, path := range paths {
+			Convey(filepath.Base(path), func() {
+				status, message, err := runBlarggTest(path)
+				So(err, ShouldBeNil)
+				So(message, ShouldNotBeEmpty)
+				So(status, ShouldEqual, 0)
+			})
+		}
+	})
+}
+
+// newMMC3IRQTimingPRG 0xE000からのプログラムで、描画を有効にしてMMC3のIRQをlatchのscanlineごとに出させる
+// IRQはフレームごとに1回だけ出す、NMIで$6001、IRQで$6000を1増やすので、NMIからIRQまでのCPUサイクル数を数えられる
+func newMMC3IRQTimingPRG(latch byte) []byte {
+	program := []byte{
+		0x78,             // SEI
+		0xA2, 0xFF, 0x9A, // LDX #$FF, TXS
+		0xA9, 0x40, 0x8D, 0x17, 0x40, // LDA #$40, STA $4017 (APUのフレームIRQを止める)
+		0xA9, 0x00, 0x8D, 0x00, 0x20, 0x8D, 0x01, 0x20, // LDA #0, STA $2000, STA $2001
+		0x2C, 0x02, 0x20, 0x10, 0xFB, // BIT $2002, BPL -5 (VBlankを2回待つ)
+		0x2C, 0x02, 0x20, 0x10, 0xFB,
+		0xA9, latch, 0x8D, 0x00, 0xC0, // LDA #latch, STA $C000 (ラッチ)
+		0x8D, 0x01, 0xC0, // STA $C001 (リロード)
+		0x8D, 0x01, 0xE0, // STA $E001 (IRQを有効)
+		0xA9, 0x18, 0x8D, 0x01, 0x20, // LDA #$18, STA $2001 (BGとスプライトを描画)
+		0xA9, 0x88, 0x8D, 0x00, 0x20, // LDA #$88, STA $2000 (NMIを有効、スプライトは$1000)
+		0x58, // CLI
+	}
+	loop := 0xE000 + len(program)
+	program = append(program, 0x4C, byte(loop), byte(loop>>8)) // JMP loop
+	nmi := 0xE000 + len(program)
+	program = append(program,
+		0x8D, 0x01, 0xC0, 0x8D, 0x01, 0xE0, // STA $C001, STA $E001 (次のフレームのためにリロードして有効にする)
+		0xEE, 0x01, 0x60, // INC $6001
+		0x40, // RTI
+	)
+	irq := 0xE000 + len(program)
+	program = append(program,
+		0x8D, 0x00, 0xE0, // STA $E000 (IRQを取り下げて無効にする)
+		0xEE, 0x00, 0x60, // INC $6000
+		0x40, // RTI
+	)
+
+	prg := make([]byte, 0x8000)
+	bank := prg[0x6000:]
+	copy(bank, program)
+	for i, vector := range []int{nmi, 0xE000, irq} {
+		bank[0x1FFA+i*2] = byte(vector)
+		bank[0x1FFB+i*2] = byte(vector >> 8)
+	}
+	return prg
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestConsole_MMC3IRQTiming$ github.com/sunjin110/nes_emu/internal/domain/console
+func TestConsole_MMC3IRQTiming(t *testing.T) {
+	Convey("TestConsole_MMC3IRQTiming", t, func() {
+		// measure NMIからIRQが処理されるまでのCPUサイクル数
+		measure := func(latch byte) int {
+			m, err := mapper.New(mapper.Config{MapperNo: 4, PRG: newMMC3IRQTimingPRG(latch), CHR: make([]byte, 0x2000)})
+			So(err, ShouldBeNil)
+			nes, err := console.NewConsoleWithMapper(m)
+			So(err, ShouldBeNil)
+
+			nmiCount, irqCount := m.ReadPRG(0x6001), m.ReadPRG(0x6000)
+			cycles := -1
+			for i := 0; i < 1_000_000; i++ {
+				c, err := nes.Step()
+				if err != nil {
+					return -1
+				}
+				if cycles >= 0 {
+					cycles += c
+				}
+				if m.ReadPRG(0x6001) != nmiCount {
+					nmiCount = m.ReadPRG(0x6001)
+					cycles = 0
+				}
+				if m.ReadPRG(0x6000) != irqCount {
+					irqCount = m.ReadPRG(0x6000)
+					// 2フレーム目以降は前のフレームのIRQでカウンターが0になっている
+					if cycles >= 0 && nmiCount >= 2 {
+						return cycles
+					}
+				}
+			}
+			return -1
+		}
+
+		// VBlank(scanline 241)からpre-render(261)でカウンターがリロードされ、
+		// scanline 0からlatch回数えた scanline latch-1 のdot 260でIRQが出る
+		for _, latch := range []byte{1, 10, 100} {
+			Convey(fmt.Sprintf("latch %dのIRQはscanline %dで処理される", latch, int(latch)-1), func() {
+				dots := (261-241+1+int(latch)-1)*341 + 260 - 1
+				So(measure(latch), ShouldAlmostEqual, dots/3, 30)
+			})
+		}
+	})
+}
+
+// newLoopPRG 16KBのバンクごとに先頭がJMP $8000で、リセットベクタが0x8000のPRG-ROM
+func newLoopPRG(size int) []byte {
+	prg := make([]byte, size)
+	for bank := 0; bank < size; bank += 0x4000 {
+		copy(prg[bank:], []byte{0x4C, 0x00, 0x80})
+		prg[bank+0x3FFC] = 0x00
+		prg[bank+0x3FFD] = 0x80
+	}
+	return prg
+}
+
+// writeMMC1 シフトレジスタに下位bitから5回書き込む
+func writeMMC1(m mapper.Mapper, addr uint16, value byte) {
+	for i := 0; i < 5; i++ {
+		m.WritePRG(addr, value>>i&0x01)
+		m.Step()
+	}
+}
+
+// clockMMC3 PPUのA12を下げてから上げて、IRQのカウンターを1回数えさせる
+func clockMMC3(m mapper.Mapper) {
+	m.PPUAddress(0x0000)
+	for i := 0; i < 4; i++ {
+		m.Step()
+	}
+	m.PPUAddress(0x1000)
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestConsole_Reset$ github.com/sunjin110/nes_emu/internal/domain/console
+func TestConsole_Reset(t *testing.T) {
+	Convey("TestConsole_Reset", t, func() {
+		newConsole := func(config mapper.Config) (*console.Console, mapper.Mapper) {
+			m, err := mapper.New(config)
+			So(err, ShouldBeNil)
+			nes, err := console.NewConsoleWithMapper(m)
+			So(err, ShouldBeNil)
+			return nes, m
+		}
+
+		Convey("リセットボタンでMMC1のシフトレジスタとcontrolがリセットされる", func() {
+			nes, m := newConsole(mapper.Config{MapperNo: 1, PRG: newLoopPRG(0x20000)})
+			// PRGモード0(32KB)、垂直ミラーリング
+			writeMMC1(m, 0x8000, 0x02)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringVertical)
+
+			// 2bitだけ書き込んだ途中でリセットする
+			m.WritePRG(0x8000, 0x00)
+			m.Step()
+			m.WritePRG(0x8000, 0x00)
+			m.Step()
+			So(nes.Reset(), ShouldBeNil)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringSingleScreenLower)
+
+			// PRGモード3に戻っているので、0xC000は最後のバンク
+			writeMMC1(m, 0xE000, 0x01)
+			So(m.ReadPRG(0x8000), ShouldEqual, 0x4C)
+			So(m.ReadPRG(0xFFFD), ShouldEqual, 0x80)
+
+			// シフトレジスタは空なので、5回の書き込みでレジスタが決まる
+			writeMMC1(m, 0x8000, 0x02)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringVertical)
+		})
+
+		Convey("リセットボタンでMMC3のIRQが取り下げられて無効になる", func() {
+			nes, m := newConsole(mapper.Config{MapperNo: 4, PRG: newLoopPRG(0x8000)})
+			m.WritePRG(0xC000, 0x00) // ラッチ
+			m.WritePRG(0xC001, 0x00) // リロード
+			m.WritePRG(0xE001, 0x00) // 有効
+			clockMMC3(m)
+			So(m.IRQ(), ShouldBeTrue)
+
+			So(nes.Reset(), ShouldBeNil)
+			So(m.IRQ(), ShouldBeFalse)
+			clockMMC3(m)
+			clockMMC3(m)
+			So(m.IRQ(), ShouldBeFalse)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestConsole_PowerCycle$ github.com/sunjin110/nes_emu/internal/domain/console
+func TestConsole_PowerCycle(t *testing.T) {
+	Convey("TestConsole_PowerCycle", t, func() {
+		newConsole := func(config mapper.Config) (*console.Console, mapper.Mapper) {
+			m, err := mapper.New(config)
+			So(err, ShouldBeNil)
+			nes, err := console.NewConsoleWithMapper(m)
+			So(err, ShouldBeNil)
+			return nes, m
+		}
+		// 8KBのバンクごとに0x0100にバンク番号を書いておく
+		newMarkedPRG := func(size int) []byte {
+			prg := newLoopPRG(size)
+			for bank := 0; bank < size/0x2000; bank++ {
+				prg[bank*0x2000+0x0100] = byte(bank)
+			}
+			return prg
+		}
+
+		Convey("MMC3のバンク、ミラーリング、IRQ、PRG-RAMが電源を入れた時の状態に戻る", func() {
+			nes, m := newConsole(mapper.Config{MapperNo: 4, PRG: newMarkedPRG(0x10000), Mirroring: ppu.MirroringVertical})
+			m.WritePRG(0x8000, 0x46) // PRGモード1、R6
+			m.WritePRG(0x8001, 0x03)
+			m.WritePRG(0xA000, 0x01) // 水平ミラーリング
+			m.WritePRG(0xA001, 0x40) // PRG-RAMの書き込み禁止
+			m.WritePRG(0x6000, 0x12)
+			m.WritePRG(0xC000, 0x00)
+			m.WritePRG(0xC001, 0x00)
+			m.WritePRG(0xE001, 0x00)
+			clockMMC3(m)
+			So(m.ReadPRG(0x8100), ShouldEqual, 6)
+			So(m.ReadPRG(0xC100), ShouldEqual, 3)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringHorizontal)
+			So(m.IRQ(), ShouldBeTrue)
+
+			So(nes.PowerCycle(), ShouldBeNil)
+			So(m.ReadPRG(0x8100), ShouldEqual, 0)
+			So(m.ReadPRG(0xC100), ShouldEqual, 6)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringVertical)
+			So(m.IRQ(), ShouldBeFalse)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x00)
+			// PRG-RAMは有効に戻っている
+			m.WritePRG(0x6000, 0x34)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x34)
+		})
+
+		Convey("バッテリーバックアップされるPRG-RAMは残る", func() {
+			nes, m := newConsole(mapper.Config{MapperNo: 4, PRG: newMarkedPRG(0x10000), Battery: true})
+			m.WritePRG(0x6000, 0x12)
+			So(nes.PowerCycle(), ShouldBeNil)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x12)
+		})
+
+		Convey("SOROMは揮発性の前半8KBだけ消えて、バッテリーバックアップされる後半8KBは残る", func() {
+			nes, m := newConsole(mapper.Config{
+				MapperNo:     1,
+				PRG:          newMarkedPRG(0x20000),
+				PRGRAMSize:   0x4000,
+				PRGNVRAMSize: 0x2000,
+				Battery:      true,
+			})
+			writeMMC1(m, 0xA000, 0x00) // 前半のバンク
+			m.WritePRG(0x6000, 0x12)
+			writeMMC1(m, 0xA000, 0x08) // 後半のバンク
+			m.WritePRG(0x6000, 0x34)
+			writeMMC1(m, 0xE000, 0x02) // PRGバンク2
+			So(m.ReadPRG(0x8100), ShouldEqual, 4)
+
+			So(nes.PowerCycle(), ShouldBeNil)
+			So(m.ReadPRG(0x8100), ShouldEqual, 0)
+			writeMMC1(m, 0xA000, 0x08)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x34)
+			writeMMC1(m, 0xA000, 0x00)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x00)
+		})
+	})
+}
@@ -0,0 +1,65 @@
+package controller
+
+// ArkanoidController アルカノイドのパドルコントローラー(Vaus)
+// doc: https://www.nesdev.org/wiki/Arkanoid_controller
+//
+// strobeでつまみの位置(8bit)をラッチし、読み込むたびに上位bitから1bitずつ反転して出力する
+// NES版: ポートに接続し、D3がボタン、D4がつまみの位置
+// ファミコン版: 拡張端子に接続し、$4016のD1がボタン、$4017のD1がつまみの位置
+type ArkanoidController struct {
+	famicom  bool
+	position byte // つまみの位置、アルカノイドでは概ね0x62-0xF2の範囲
+	button   bool
+	shift    byte
+	strobe   bool
+}
+
+func NewArkanoidController(famicom bool) *ArkanoidController {
+	return &ArkanoidController{
+		famicom:  famicom,
+		position: 0x62,
+	}
+}
+
+var _ InputDevice = (*ArkanoidController)(nil)
+
+// SetPosition つまみの位置を設定する、次のstrobeで反映される
+func (a *ArkanoidController) SetPosition(position byte) {
+	a.position = position
+}
+
+func (a *ArkanoidController) SetButton(pressed bool) {
+	a.button = pressed
+}
+
+func (a *ArkanoidController) Write(out byte) {
+	strobe := out&0x01 != 0
+	if strobe {
+		a.shift = a.position
+	}
+	a.strobe = strobe
+}
+
+func (a *ArkanoidController) Read(port Port) byte {
+	var button byte
+	if a.button {
+		button = 1
+	}
+
+	if a.famicom {
+		if port == Port1 {
+			return button << 1
+		}
+		return a.readBit() << 1
+	}
+	return button<<3 | a.readBit()<<4
+}
+
+// readBit 上位bitから反転して出力する、8bit読んだ後は0を返す
+func (a *ArkanoidController) readBit() byte {
+	bit := (^a.shift >> 7) & 0x01
+	if !a.strobe {
+		a.shift = a.shift<<1 | 0x01
+	}
+	return bit
+}
@@ -2,40 +2,175 @@ package controller
 
 import "github.com/sunjin110/nes_emu/pkg/logger"
 
+// Controller コントローラーポート x2 ($4016, $4017)とファミコンの拡張端子
+// doc: https://www.nesdev.org/wiki/Input_devices
+//
+// 各ポートにInputDeviceを接続し、$4016への書き込み(OUT0-OUT2)は全てのデバイスに送られる
+// $4016の読み込みはポート1と拡張端子、$4017の読み込みはポート2と拡張端子のデバイスのbitを合わせたものになる
 type Controller struct {
-	state [2]byte // 0: 1P, 1: 2P
+	devices   [2]InputDevice // 0: 1P, 1: 2P
+	expansion InputDevice    // ファミコンの拡張端子
+	light     LightSource    // ApplyInputConfigでZapperを作る時に使う
 }
 
+// Port コントローラーのポート
+type Port int
+
+const (
+	Port1 Port = iota // $4016
+	Port2             // $4017
+)
+
+// NewController ポート1, 2に標準コントローラーを接続した状態で作る
 func NewController() *Controller {
 	return &Controller{
-		state: [2]byte{},
+		devices: [2]InputDevice{NewStandardController(), NewStandardController()},
 	}
 }
 
 const (
 	addrController1P = 0x4016
 	addrController2P = 0x4017
+
+	// openBus $4016, $4017の上位bitはデータバスに残っている値(直前に読んだアドレスの上位バイト0x40)になる
+	openBus = 0x40
+	// dataBits デバイスから読み込めるD0-D4
+	dataBits = 0x1F
+)
+
+// Connect ポートにデバイスを接続する、nilの場合は何も接続されていない状態になる
+func (c *Controller) Connect(port Port, device InputDevice) {
+	if port != Port1 && port != Port2 {
+		logger.Logger.Error("Controller: Connect: invalid port", "port", port)
+		return
+	}
+	c.devices[port] = device
+}
+
+// ConnectExpansion ファミコンの拡張端子にデバイスを接続する
+func (c *Controller) ConnectExpansion(device InputDevice) {
+	c.expansion = device
+}
+
+// SetLightSource ApplyInputConfigで作るZapperが明るさを見る画面を設定する
+func (c *Controller) SetLightSource(light LightSource) {
+	c.light = light
+}
+
+// Device ポートに接続されているデバイス
+func (c *Controller) Device(port Port) InputDevice {
+	if port != Port1 && port != Port2 {
+		return nil
+	}
+	return c.devices[port]
+}
+
+// Expansion 拡張端子に接続されているデバイス
+func (c *Controller) Expansion() InputDevice {
+	return c.expansion
+}
+
+// Player SetButtonsで指定するプレイヤー
+type Player int
+
+const (
+	Player1 Player = iota
+	Player2
+	Player3 // Four Score, Hori 4 Players Adapterを接続している場合のみ
+	Player4
 )
 
+// SetButtons playerの標準コントローラーのボタンの状態を設定する、次のstrobeでシフトレジスタに反映される
+func (c *Controller) SetButtons(player Player, state ButtonState) {
+	pads := c.pads(player)
+	if len(pads) == 0 {
+		logger.Logger.Error("Controller: SetButtons: standard controller is not connected", "player", player)
+		return
+	}
+	for _, pad := range pads {
+		pad.SetButtons(state)
+	}
+}
+
+// Buttons playerの標準コントローラーに設定されているボタンの状態
+func (c *Controller) Buttons(player Player) ButtonState {
+	pads := c.pads(player)
+	if len(pads) == 0 {
+		return 0
+	}
+	return pads[0].Buttons()
+}
+
+// Players SetButtonsで反映できるプレイヤー数、Four Score, Hori 4 Players Adapterを接続している場合は4
+func (c *Controller) Players() int {
+	for _, device := range []InputDevice{c.devices[Port1], c.devices[Port2], c.expansion} {
+		if _, ok := device.(*FourPlayerAdapter); ok {
+			return int(Player4) + 1
+		}
+	}
+	return int(Player2) + 1
+}
+
+// pads playerのボタンを反映する標準コントローラー
+// 1P, 2Pはポートのコントローラー(Four Scoreの場合はその1P, 2P)
+// Hori 4 Players Adapterの場合、1P, 2Pは本体のコントローラーとアダプターの両方に反映する(D0とD1のどちらを読むかはゲームによるため)
+func (c *Controller) pads(player Player) []*StandardController {
+	if player < Player1 || player > Player4 {
+		return nil
+	}
+
+	var pads []*StandardController
+	port := Port(player % 2)
+	switch device := c.devices[port].(type) {
+	case *StandardController:
+		if player <= Player2 {
+			pads = append(pads, device)
+		}
+	case *FourPlayerAdapter:
+		pads = append(pads, device.Pad(player))
+	}
+	if adapter, ok := c.expansion.(*FourPlayerAdapter); ok {
+		pads = append(pads, adapter.Pad(player))
+	}
+	return pads
+}
+
 func (c *Controller) Read(addr uint16) byte {
+	var port Port
 	switch addr {
 	case addrController1P:
-		return c.state[0]
+		port = Port1
 	case addrController2P:
-		return c.state[1]
+		port = Port2
+	default:
+		logger.Logger.Error("Controller: Read: invalid addr", "addr", addr)
+		return 0xFF
+	}
+
+	var value byte
+	if device := c.devices[port]; device != nil {
+		value |= device.Read(port)
+	}
+	if c.expansion != nil {
+		value |= c.expansion.Read(port)
 	}
-	logger.Logger.Error("Controller: Read: invalid addr", "addr", addr)
-	return 0xFF
+	return openBus | value&dataBits
 }
 
+// Write $4016への書き込み、$4017への書き込みはAPUのframe counterなのでここには来ない
 func (c *Controller) Write(addr uint16, value byte) {
-	switch addr {
-	case addrController1P:
-		c.state[0] = value
-	case addrController2P:
-		c.state[1] = value
-	default:
+	if addr != addrController1P {
 		logger.Logger.Error("Controller: Write: invalid addr", "addr", addr)
+		return
+	}
+	out := value & 0x07
+	for _, device := range c.devices {
+		if device != nil {
+			device.Write(out)
+		}
+	}
+	if c.expansion != nil {
+		c.expansion.Write(out)
 	}
 }
 
@@ -0,0 +1,112 @@
+package controller_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestController$ github.com/sunjin110/nes_emu/internal/domain/controller
+func TestController(t *testing.T) {
+	Convey("TestController", t, func() {
+		c := controller.NewController()
+		c.SetButtons(controller.Player1, controller.ButtonA|controller.ButtonStart|controller.ButtonRight)
+		c.SetButtons(controller.Player2, controller.ButtonB)
+
+		Convey("strobeの後はA, B, Select, Start, Up, Down, Left, Rightの順に読み出され、その後は1になる", func() {
+			c.Write(0x4016, 1)
+			c.Write(0x4016, 0)
+
+			var bits []byte
+			for i := 0; i < 10; i++ {
+				bits = append(bits, c.Read(0x4016)&0x01)
+			}
+			So(bits, ShouldResemble, []byte{1, 0, 0, 1, 0, 0, 0, 1, 1, 1})
+
+			So(c.Read(0x4017)&0x01, ShouldEqual, 0)
+			So(c.Read(0x4017)&0x01, ShouldEqual, 1)
+		})
+
+		Convey("上位bitはopen busになる", func() {
+			c.Write(0x4016, 1)
+			c.Write(0x4016, 0)
+			So(c.Read(0x4016)&0xE0, ShouldEqual, 0x40)
+		})
+
+		Convey("strobe中は常にAボタンの状態を返す", func() {
+			c.Write(0x4016, 1)
+			So(c.Read(0x4016)&0x01, ShouldEqual, 1)
+			So(c.Read(0x4016)&0x01, ShouldEqual, 1)
+			So(c.Read(0x4017)&0x01, ShouldEqual, 0)
+		})
+
+		Convey("strobeの後にボタンを変えても次のstrobeまで反映されない", func() {
+			c.Write(0x4016, 1)
+			c.Write(0x4016, 0)
+			c.SetButtons(controller.Player1, 0)
+			So(c.Read(0x4016)&0x01, ShouldEqual, 1)
+		})
+	})
+}
+
+// readBits addrからn回読み込んでbitの位置の値を並べる
+func readBits(c *controller.Controller, addr uint16, n int, bit uint) []byte {
+	var bits []byte
+	for i := 0; i < n; i++ {
+		bits = append(bits, (c.Read(addr)>>bit)&0x01)
+	}
+	return bits
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestFourPlayerAdapter$ github.com/sunjin110/nes_emu/internal/domain/controller
+func TestFourPlayerAdapter(t *testing.T) {
+	Convey("TestFourPlayerAdapter", t, func() {
+		Convey("Four Scoreは1P, 3P, signatureの順に$4016のD0から読み込める", func() {
+			c := controller.NewController()
+			So(c.ApplyInputConfig(controller.InputConfig{Port1: controller.DeviceFourScore, Port2: controller.DeviceFourScore}), ShouldBeNil)
+			c.SetButtons(controller.Player1, controller.ButtonA)
+			c.SetButtons(controller.Player2, controller.ButtonB)
+			c.SetButtons(controller.Player3, controller.ButtonStart)
+			c.SetButtons(controller.Player4, controller.ButtonRight)
+
+			c.Write(0x4016, 1)
+			c.Write(0x4016, 0)
+			So(readBits(c, 0x4016, 26, 0), ShouldResemble, []byte{
+				1, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 1, 0, 0, 0, 0,
+				0, 0, 0, 1, 0, 0, 0, 0,
+				1, 1,
+			})
+			So(readBits(c, 0x4017, 24, 0), ShouldResemble, []byte{
+				0, 1, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 1,
+				0, 0, 1, 0, 0, 0, 0, 0,
+			})
+		})
+
+		Convey("4人用のアダプターを接続している場合だけPlayersが4になる", func() {
+			c := controller.NewController()
+			So(c.Players(), ShouldEqual, 2)
+			So(c.ApplyInputConfig(controller.InputConfig{Port1: controller.DeviceFourScore, Port2: controller.DeviceFourScore}), ShouldBeNil)
+			So(c.Players(), ShouldEqual, 4)
+			So(c.ApplyInputConfig(controller.InputConfig{Port1: controller.DeviceStandard, Port2: controller.DeviceStandard, Expansion: controller.DeviceHori4Player}), ShouldBeNil)
+			So(c.Players(), ShouldEqual, 4)
+		})
+
+		Convey("Hori 4 Players Adapterは拡張端子からD1に出力する", func() {
+			c := controller.NewController()
+			So(c.ApplyInputConfig(controller.InputConfig{Port1: controller.DeviceStandard, Port2: controller.DeviceStandard, Expansion: controller.DeviceHori4Player}), ShouldBeNil)
+			c.SetButtons(controller.Player1, controller.ButtonA)
+			c.SetButtons(controller.Player3, controller.ButtonB)
+
+			c.Write(0x4016, 1)
+			c.Write(0x4016, 0)
+			So(readBits(c, 0x4016, 24, 1), ShouldResemble, []byte{
+				1, 0, 0, 0, 0, 0, 0, 0,
+				0, 1, 0, 0, 0, 0, 0, 0,
+				0, 0, 1, 0, 0, 0, 0, 0,
+			})
+		})
+	})
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InputDevice コントローラーポートや拡張端子に接続する入力デバイス
+// doc: https://www.nesdev.org/wiki/Input_devices
+type InputDevice interface {
+	// Write $4016への書き込み(bit0: OUT0(strobe), bit1: OUT1, bit2: OUT2)
+	Write(out byte)
+	// Read portのレジスタ($4016 or $4017)を読み込んだ時のD0-D4
+	// ポートのデバイスは自分のポートの読み込みの時だけ呼ばれ、拡張端子のデバイスは両方の読み込みで呼ばれる
+	Read(port Port) byte
+}
+
+// DeviceType 入力デバイスの種類
+type DeviceType int
+
+const (
+	DeviceNone DeviceType = iota
+	DeviceStandard
+	DeviceZapper
+	DeviceFourScore
+	DeviceHori4Player
+	DeviceArkanoidNES
+	DeviceArkanoidFamicom
+	DevicePowerPadA
+	DevicePowerPadB
+	DeviceFamilyBASICKeyboard
+	DeviceSNESMouse
+)
+
+var deviceTypeNames = map[DeviceType]string{
+	DeviceNone:                "none",
+	DeviceStandard:            "pad",
+	DeviceZapper:              "zapper",
+	DeviceFourScore:           "fourscore",
+	DeviceHori4Player:         "hori",
+	DeviceArkanoidNES:         "vaus",
+	DeviceArkanoidFamicom:     "vaus-famicom",
+	DevicePowerPadA:           "powerpad-a",
+	DevicePowerPadB:           "powerpad-b",
+	DeviceFamilyBASICKeyboard: "keyboard",
+	DeviceSNESMouse:           "snes-mouse",
+}
+
+func (t DeviceType) String() string {
+	if name, ok := deviceTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("DeviceType(%d)", int(t))
+}
+
+// ParseDeviceType "pad"や"zapper"のような名前からDeviceTypeを返す
+func ParseDeviceType(name string) (DeviceType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for t, typeName := range deviceTypeNames {
+		if typeName == name {
+			return t, nil
+		}
+	}
+	return DeviceNone, fmt.Errorf("Controller: unknown device type. name: %s", name)
+}
+
+// InputConfig 各ポートと拡張端子に接続するデバイス
+type InputConfig struct {
+	Port1     DeviceType
+	Port2     DeviceType
+	Expansion DeviceType
+}
+
+// DefaultInputConfig 標準コントローラー x2
+func DefaultInputConfig() InputConfig {
+	return InputConfig{
+		Port1: DeviceStandard,
+		Port2: DeviceStandard,
+	}
+}
+
+// InputConfigFromExpansionDevice NES 2.0ヘッダーのbyte 15(Default Expansion Device)から接続するデバイスを決める
+// 対応していないデバイスや未指定(0)の場合はDefaultInputConfigを返す
+// doc: https://www.nesdev.org/wiki/NES_2.0#Default_Expansion_Device
+func InputConfigFromExpansionDevice(device byte) InputConfig {
+	config := DefaultInputConfig()
+	switch device {
+	case 0x02:
+		config.Port1 = DeviceFourScore
+		config.Port2 = DeviceFourScore
+	case 0x03:
+		config.Expansion = DeviceHori4Player
+	case 0x08:
+		config.Port2 = DeviceZapper
+	case 0x09:
+		config.Port1 = DeviceZapper
+		config.Port2 = DeviceZapper
+	case 0x0B:
+		config.Port2 = DevicePowerPadA
+	case 0x0C:
+		config.Port2 = DevicePowerPadB
+	case 0x0F:
+		config.Port2 = DeviceArkanoidNES
+	case 0x10:
+		config.Expansion = DeviceArkanoidFamicom
+	case 0x23:
+		config.Expansion = DeviceFamilyBASICKeyboard
+	case 0x29:
+		config.Port2 = DeviceSNESMouse
+	}
+	return config
+}
+
+// NewInputDevice デバイスの種類からInputDeviceを作る、DeviceNoneの場合はnil
+// lightはZapperが画面の明るさを見るのに使う
+func NewInputDevice(t DeviceType, light LightSource) (InputDevice, error) {
+	switch t {
+	case DeviceNone:
+		return nil, nil
+	case DeviceStandard:
+		return NewStandardController(), nil
+	case DeviceZapper:
+		return NewZapper(light), nil
+	case DeviceFourScore:
+		return NewFourScore(), nil
+	case DeviceHori4Player:
+		return NewHori4PlayerAdapter(), nil
+	case DeviceArkanoidNES:
+		return NewArkanoidController(false), nil
+	case DeviceArkanoidFamicom:
+		return NewArkanoidController(true), nil
+	case DevicePowerPadA, DevicePowerPadB:
+		return NewPowerPad(), nil
+	case DeviceFamilyBASICKeyboard:
+		return NewFamilyBASICKeyboard(), nil
+	case DeviceSNESMouse:
+		return NewSNESMouse(), nil
+	}
+	return nil, fmt.Errorf("Controller: unsupported device type. type: %s", t)
+}
+
+// ApplyInputConfig 設定に従ってデバイスを作り、各ポートと拡張端子に接続する
+func (c *Controller) ApplyInputConfig(config InputConfig) error {
+	port1, err := NewInputDevice(config.Port1, c.light)
+	if err != nil {
+		return fmt.Errorf("Controller: failed new port1 device. err: %w", err)
+	}
+	port2, err := NewInputDevice(config.Port2, c.light)
+	if err != nil {
+		return fmt.Errorf("Controller: failed new port2 device. err: %w", err)
+	}
+	// Four Scoreは1つのアダプターを両方のポートに接続する
+	if config.Port1 == DeviceFourScore && config.Port2 == DeviceFourScore {
+		port2 = port1
+	}
+	expansion, err := NewInputDevice(config.Expansion, c.light)
+	if err != nil {
+		return fmt.Errorf("Controller: failed new expansion device. err: %w", err)
+	}
+	c.Connect(Port1, port1)
+	c.Connect(Port2, port2)
+	c.ConnectExpansion(expansion)
+	return nil
+}
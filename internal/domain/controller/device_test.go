@@ -0,0 +1,69 @@
+package controller_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestInputDevice$ github.com/sunjin110/nes_emu/internal/domain/controller
+func TestInputDevice(t *testing.T) {
+	Convey("TestInputDevice", t, func() {
+		Convey("ファミリーベーシックのキーボードは行と列を切り替えながら拡張端子から読み込める", func() {
+			c := controller.NewController()
+			keyboard := controller.NewFamilyBASICKeyboard()
+			c.ConnectExpansion(keyboard)
+			keyboard.SetKey(controller.KeyReturn, true) // 行0 列0 D3
+			keyboard.SetKey(controller.KeyF, true)      // 行5 列1 D4
+
+			c.Write(0x4016, 0x05) // 行0にリセット
+			c.Write(0x4016, 0x04) // 列0
+			So(c.Read(0x4017)&0x1E, ShouldEqual, 0x1E&^0x08)
+			c.Write(0x4016, 0x06) // 列1
+			So(c.Read(0x4017)&0x1E, ShouldEqual, 0x1E)
+
+			for row := 1; row <= 5; row++ {
+				c.Write(0x4016, 0x04)
+				c.Write(0x4016, 0x06)
+			}
+			So(c.Read(0x4017)&0x1E, ShouldEqual, 0x1E&^0x10)
+		})
+
+		Convey("NES版のアルカノイドコントローラーはD4につまみの位置を反転して出力する", func() {
+			c := controller.NewController()
+			vaus := controller.NewArkanoidController(false)
+			c.Connect(controller.Port2, vaus)
+			vaus.SetPosition(0xA5)
+			vaus.SetButton(true)
+
+			c.Write(0x4016, 1)
+			c.Write(0x4016, 0)
+			var position byte
+			for i := 0; i < 8; i++ {
+				value := c.Read(0x4017)
+				So(value&0x08, ShouldEqual, 0x08)
+				position = position<<1 | (^value>>4)&0x01
+			}
+			So(position, ShouldEqual, 0xA5)
+		})
+
+		Convey("NES 2.0のDefault Expansion Deviceから接続するデバイスを決める", func() {
+			So(controller.InputConfigFromExpansionDevice(0x00), ShouldResemble, controller.DefaultInputConfig())
+			So(controller.InputConfigFromExpansionDevice(0x08).Port2, ShouldEqual, controller.DeviceZapper)
+			So(controller.InputConfigFromExpansionDevice(0x23).Expansion, ShouldEqual, controller.DeviceFamilyBASICKeyboard)
+		})
+
+		Convey("デバイス名から設定を作って接続できる", func() {
+			deviceType, err := controller.ParseDeviceType("snes-mouse")
+			So(err, ShouldBeNil)
+
+			c := controller.NewController()
+			err = c.ApplyInputConfig(controller.InputConfig{Port1: controller.DeviceStandard, Port2: deviceType})
+			So(err, ShouldBeNil)
+			_, ok := c.Device(controller.Port2).(*controller.SNESMouse)
+			So(ok, ShouldBeTrue)
+			So(c.Expansion(), ShouldBeNil)
+		})
+	})
+}
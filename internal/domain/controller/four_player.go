@@ -0,0 +1,98 @@
+package controller
+
+// FourPlayerAdapter 4人用のアダプター
+// doc: https://www.nesdev.org/wiki/Four_player_adapters
+//
+// NES Four Score: 両方のポートに接続し、D0で24bitを出力する
+// $4016: 1P(8bit), 3P(8bit), signature(8bit)、$4017: 2P(8bit), 4P(8bit), signature(8bit)
+// ファミコンのHori 4 Players Adapter(4人モード): 拡張端子に接続し、同じ順番でD1に出力する(signatureは$4016と$4017が逆)
+// 24bit読んだ後は1を返す
+type FourPlayerAdapter struct {
+	hori   bool
+	pads   [4]*StandardController // 0: 1P, 1: 2P, 2: 3P, 3: 4P
+	reads  [2]int                 // strobeの後にポートごとに読み込んだbit数
+	strobe bool
+}
+
+const fourPlayerReportBits = 24
+
+// 17-24bit目に読み込まれるsignature(読み込む順)
+var (
+	fourScoreSignature = [2][8]byte{
+		{0, 0, 0, 1, 0, 0, 0, 0}, // $4016
+		{0, 0, 1, 0, 0, 0, 0, 0}, // $4017
+	}
+	horiSignature = [2][8]byte{
+		{0, 0, 1, 0, 0, 0, 0, 0},
+		{0, 0, 0, 1, 0, 0, 0, 0},
+	}
+)
+
+// NewFourScore NESのFour Score、同じインスタンスをポート1, 2の両方に接続する
+func NewFourScore() *FourPlayerAdapter {
+	return newFourPlayerAdapter(false)
+}
+
+// NewHori4PlayerAdapter ファミコンのHori 4 Players Adapter、拡張端子に接続する
+func NewHori4PlayerAdapter() *FourPlayerAdapter {
+	return newFourPlayerAdapter(true)
+}
+
+func newFourPlayerAdapter(hori bool) *FourPlayerAdapter {
+	a := &FourPlayerAdapter{hori: hori}
+	for i := range a.pads {
+		a.pads[i] = NewStandardController()
+	}
+	return a
+}
+
+var _ InputDevice = (*FourPlayerAdapter)(nil)
+
+// Pad アダプターに接続されているplayerのコントローラー
+func (a *FourPlayerAdapter) Pad(player Player) *StandardController {
+	if player < Player1 || player > Player4 {
+		return nil
+	}
+	return a.pads[player]
+}
+
+func (a *FourPlayerAdapter) Write(out byte) {
+	for _, pad := range a.pads {
+		pad.Write(out)
+	}
+	a.strobe = out&0x01 != 0
+	if a.strobe {
+		a.reads = [2]int{}
+	}
+}
+
+func (a *FourPlayerAdapter) Read(port Port) byte {
+	if port != Port1 && port != Port2 {
+		return 0
+	}
+
+	var bit byte
+	n := a.reads[port]
+	switch {
+	case a.strobe || n < 8:
+		bit = a.pads[port].Read(port)
+	case n < 16:
+		bit = a.pads[int(port)+2].Read(port)
+	case n < fourPlayerReportBits:
+		if a.hori {
+			bit = horiSignature[port][n-16]
+		} else {
+			bit = fourScoreSignature[port][n-16]
+		}
+	default:
+		bit = 1
+	}
+	if !a.strobe && n < fourPlayerReportBits {
+		a.reads[port]++
+	}
+
+	if a.hori {
+		return bit << 1
+	}
+	return bit
+}
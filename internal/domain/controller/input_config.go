@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseInputConfig ROMごとの入力デバイスの設定を読み込み、baseを上書きする
+//
+// 1行に1つの設定を書き、#以降はコメント、書いていないポートはbaseのまま
+//
+//	port1 = pad
+//	port2 = zapper
+//	expansion = keyboard
+func ParseInputConfig(r io.Reader, base InputConfig) (InputConfig, error) {
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return base, fmt.Errorf("Controller: invalid input config. expected <port> = <device>. line: %d", lineNo)
+		}
+		deviceType, err := ParseDeviceType(value)
+		if err != nil {
+			return base, fmt.Errorf("Controller: invalid input config. line: %d, err: %w", lineNo, err)
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "port1":
+			base.Port1 = deviceType
+		case "port2":
+			base.Port2 = deviceType
+		case "expansion":
+			base.Expansion = deviceType
+		default:
+			return base, fmt.Errorf("Controller: invalid input config. unknown port. line: %d, port: %s", lineNo, strings.TrimSpace(key))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return base, fmt.Errorf("Controller: failed read input config. err: %w", err)
+	}
+	return base, nil
+}
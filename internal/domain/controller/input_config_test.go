@@ -0,0 +1,39 @@
+package controller_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestParseInputConfig$ github.com/sunjin110/nes_emu/internal/domain/controller
+func TestParseInputConfig(t *testing.T) {
+	Convey("TestParseInputConfig", t, func() {
+		// Zapperのヘッダー(0x08)の設定を上書きする
+		base := controller.InputConfigFromExpansionDevice(0x08)
+		So(base.Port2, ShouldEqual, controller.DeviceZapper)
+
+		Convey("書いたポートだけを上書きする", func() {
+			config, err := controller.ParseInputConfig(strings.NewReader(`
+# 2Pはパッドにして、キーボードをつなぐ
+Port2 = pad
+expansion = keyboard # コメント
+`), base)
+			So(err, ShouldBeNil)
+			So(config, ShouldResemble, controller.InputConfig{
+				Port1:     controller.DeviceStandard,
+				Port2:     controller.DeviceStandard,
+				Expansion: controller.DeviceFamilyBASICKeyboard,
+			})
+		})
+
+		Convey("不正な行はエラー", func() {
+			for _, text := range []string{"port2 zapper", "port3 = pad", "port1 = gamepad"} {
+				_, err := controller.ParseInputConfig(strings.NewReader(text), base)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
@@ -0,0 +1,145 @@
+package controller
+
+// FamilyBASICKeyboard ファミリーベーシックのキーボード、拡張端子に接続する
+// doc: https://www.nesdev.org/wiki/Family_BASIC_Keyboard
+//
+// 9行 x 2列 x 4キーのマトリクスになっている
+// $4016への書き込み bit0: 行を0にリセット, bit1: 列の選択(列が1から0に変わると次の行に進む), bit2: キーボードを有効にする
+// $4017の読み込みのD1-D4で選択中の行と列の4キーの状態を返す(0が押されている)
+type FamilyBASICKeyboard struct {
+	keys    [keyboardRows * 8]bool
+	row     int
+	column  byte
+	enabled bool
+}
+
+// Key キーボードのキー、値は 行 * 8 + 列 * 4 + bit(D1-D4)
+type Key int
+
+const keyboardRows = 9
+
+const (
+	KeyRightBracket Key = iota // 行0 列0
+	KeyLeftBracket
+	KeyReturn
+	KeyF8
+	KeyStop // 行0 列1
+	KeyYen
+	KeyRightShift
+	KeyKana
+
+	KeySemicolon // 行1 列0
+	KeyColon
+	KeyAt
+	KeyF7
+	KeyCaret // 行1 列1
+	KeyMinus
+	KeySlash
+	KeyUnderscore
+
+	KeyK // 行2 列0
+	KeyL
+	KeyO
+	KeyF6
+	Key0 // 行2 列1
+	KeyP
+	KeyComma
+	KeyPeriod
+
+	KeyJ // 行3 列0
+	KeyU
+	KeyI
+	KeyF5
+	Key8 // 行3 列1
+	Key9
+	KeyN
+	KeyM
+
+	KeyH // 行4 列0
+	KeyG
+	KeyY
+	KeyF4
+	Key6 // 行4 列1
+	Key7
+	KeyV
+	KeyB
+
+	KeyD // 行5 列0
+	KeyR
+	KeyT
+	KeyF3
+	Key4 // 行5 列1
+	Key5
+	KeyC
+	KeyF
+
+	KeyA // 行6 列0
+	KeyS
+	KeyW
+	KeyF2
+	Key3 // 行6 列1
+	KeyE
+	KeyZ
+	KeyX
+
+	KeyCtrl // 行7 列0
+	KeyQ
+	KeyEscape
+	KeyF1
+	Key2 // 行7 列1
+	Key1
+	KeyGraph
+	KeyLeftShift
+
+	KeyLeft // 行8 列0
+	KeyRight
+	KeyUp
+	KeyClearHome
+	KeyInsert // 行8 列1
+	KeyDelete
+	KeySpace
+	KeyDown
+)
+
+func NewFamilyBASICKeyboard() *FamilyBASICKeyboard {
+	return &FamilyBASICKeyboard{}
+}
+
+var _ InputDevice = (*FamilyBASICKeyboard)(nil)
+
+func (k *FamilyBASICKeyboard) SetKey(key Key, pressed bool) {
+	if key < 0 || int(key) >= len(k.keys) {
+		return
+	}
+	k.keys[key] = pressed
+}
+
+func (k *FamilyBASICKeyboard) Write(out byte) {
+	k.enabled = out&0x04 != 0
+	column := (out >> 1) & 0x01
+	if k.column == 1 && column == 0 {
+		k.row++
+	}
+	k.column = column
+	if out&0x01 != 0 {
+		k.row = 0
+	}
+}
+
+func (k *FamilyBASICKeyboard) Read(port Port) byte {
+	if port != Port2 || !k.enabled {
+		return 0
+	}
+	// 押されていないキーは1
+	value := byte(0x1E)
+	if k.row >= keyboardRows {
+		return value
+	}
+	base := k.row*8 + int(k.column)*4
+	for i := 0; i < 4; i++ {
+		if k.keys[base+i] {
+			value &^= 1 << (i + 1)
+		}
+	}
+	return value
+}
@@ -0,0 +1,67 @@
+package controller
+
+// PowerPad パワーパッド(ファミリートレーナー)、12個のボタンを持つマット
+// doc: https://www.nesdev.org/wiki/Power_Pad
+//
+// strobeでボタンの状態をラッチし、読み込むたびにD3とD4から1bitずつ出力する
+// D3: 2, 1, 5, 9, 6, 10, 11, 7 の順、D4: 4, 3, 12, 8 の順で、その後は1を返す
+// ボタンの番号はマットのスイッチの番号(サイドBの印刷の番号)で、サイドAとサイドBは同じスイッチをゲーム側で読み分けているだけなので区別しない
+type PowerPad struct {
+	buttons [13]bool // 1-12
+	shiftD3 byte
+	shiftD4 byte
+	strobe  bool
+}
+
+var (
+	powerPadD3Order = [8]int{2, 1, 5, 9, 6, 10, 11, 7}
+	powerPadD4Order = [4]int{4, 3, 12, 8}
+)
+
+func NewPowerPad() *PowerPad {
+	return &PowerPad{}
+}
+
+var _ InputDevice = (*PowerPad)(nil)
+
+// SetButton ボタン(1-12)の状態を設定する
+func (p *PowerPad) SetButton(button int, pressed bool) {
+	if button < 1 || button > 12 {
+		return
+	}
+	p.buttons[button] = pressed
+}
+
+func (p *PowerPad) Write(out byte) {
+	p.strobe = out&0x01 != 0
+	if p.strobe {
+		p.latch()
+	}
+}
+
+func (p *PowerPad) latch() {
+	p.shiftD3 = 0
+	for i, button := range powerPadD3Order {
+		if p.buttons[button] {
+			p.shiftD3 |= 1 << i
+		}
+	}
+	p.shiftD4 = 0xF0
+	for i, button := range powerPadD4Order {
+		if p.buttons[button] {
+			p.shiftD4 |= 1 << i
+		}
+	}
+}
+
+func (p *PowerPad) Read(port Port) byte {
+	if p.strobe {
+		p.latch()
+	}
+	value := (p.shiftD3&0x01)<<3 | (p.shiftD4&0x01)<<4
+	if !p.strobe {
+		p.shiftD3 = p.shiftD3>>1 | 0x80
+		p.shiftD4 = p.shiftD4>>1 | 0x80
+	}
+	return value
+}
@@ -0,0 +1,81 @@
+package controller
+
+// SNESMouse スーパーファミコンのマウス、ポートに接続する
+// doc: https://www.nesdev.org/wiki/Super_NES_Mouse
+//
+// strobeで移動量とボタンの状態をラッチし、読み込むたびにD0から32bitを上位bitから出力する
+// byte0: 0, byte1: 右ボタン, 左ボタン, 感度(2bit), シグネチャ(0001)
+// byte2: Yの移動量(bit7が符号(1が上), 下位7bitが大きさ), byte3: Xの移動量(bit7が符号(1が左), 下位7bitが大きさ)
+// strobe中に読み込むと感度が切り替わる
+type SNESMouse struct {
+	dx, dy      int // 前回のラッチからの移動量
+	left, right bool
+	sensitivity byte // 0-2
+
+	report uint32
+	strobe bool
+}
+
+func NewSNESMouse() *SNESMouse {
+	return &SNESMouse{}
+}
+
+var _ InputDevice = (*SNESMouse)(nil)
+
+// Move マウスの移動量を加算する、右と下が正
+func (m *SNESMouse) Move(dx, dy int) {
+	m.dx += dx
+	m.dy += dy
+}
+
+func (m *SNESMouse) SetButtons(left, right bool) {
+	m.left = left
+	m.right = right
+}
+
+func (m *SNESMouse) Sensitivity() byte {
+	return m.sensitivity
+}
+
+func (m *SNESMouse) Write(out byte) {
+	strobe := out&0x01 != 0
+	if strobe && !m.strobe {
+		m.latch()
+	}
+	m.strobe = strobe
+}
+
+func (m *SNESMouse) latch() {
+	var buttons uint32 = 0x01 // シグネチャ
+	if m.right {
+		buttons |= 0x80
+	}
+	if m.left {
+		buttons |= 0x40
+	}
+	buttons |= uint32(m.sensitivity) << 4
+
+	m.report = buttons<<16 | uint32(mouseDelta(m.dy))<<8 | uint32(mouseDelta(m.dx))
+	m.dx = 0
+	m.dy = 0
+}
+
+// mouseDelta 符号と7bitの大きさに変換する
+func mouseDelta(delta int) byte {
+	var sign byte
+	if delta < 0 {
+		sign = 0x80
+		delta = -delta
+	}
+	return sign | byte(min(delta, 0x7F))
+}
+
+func (m *SNESMouse) Read(port Port) byte {
+	if m.strobe {
+		m.sensitivity = (m.sensitivity + 1) % 3
+		return 0
+	}
+	bit := byte(m.report>>31) & 0x01
+	m.report = m.report<<1 | 0x01
+	return bit
+}
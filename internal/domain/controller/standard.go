@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// StandardController 標準コントローラー
+// doc: https://www.nesdev.org/wiki/Standard_controller
+//
+// OUT0に1を書き込んでいる間(strobe)はボタンの状態を読み込み続け、0を書き込むとその時点の状態がシフトレジスタに固定される
+// 読み込むたびに A, B, Select, Start, Up, Down, Left, Right の順に1bitずつD0に出力し、8回読んだ後は1を返す
+type StandardController struct {
+	buttons ButtonState // frontendから設定された現在の状態
+	shift   byte
+	strobe  bool
+}
+
+// ButtonState ボタンの押下状態、bitの並びはシフトレジスタから読み出される順番と同じ
+type ButtonState byte
+
+const (
+	ButtonA ButtonState = 1 << iota
+	ButtonB
+	ButtonSelect
+	ButtonStart
+	ButtonUp
+	ButtonDown
+	ButtonLeft
+	ButtonRight
+)
+
+// buttonNames ButtonStateのbitの順番の名前
+var buttonNames = [8]string{"a", "b", "select", "start", "up", "down", "left", "right"}
+
+// String "a+start"のように押しているボタンを+で繋げる、何も押していない場合は"none"
+func (b ButtonState) String() string {
+	var names []string
+	for i, name := range buttonNames {
+		if b&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "+")
+}
+
+// ParseButtons "a+start"のような+で繋げたボタン名からButtonStateを返す、"none"は何も押していない状態
+func ParseButtons(value string) (ButtonState, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "none" || value == "" {
+		return 0, nil
+	}
+	var state ButtonState
+	for _, name := range strings.Split(value, "+") {
+		index := slices.Index(buttonNames[:], strings.TrimSpace(name))
+		if index < 0 {
+			return 0, fmt.Errorf("Controller: unknown button. name: %s", name)
+		}
+		state |= 1 << index
+	}
+	return state, nil
+}
+
+func NewStandardController() *StandardController {
+	return &StandardController{}
+}
+
+var _ InputDevice = (*StandardController)(nil)
+
+// SetButtons ボタンの状態を設定する、次のstrobeでシフトレジスタに反映される
+func (s *StandardController) SetButtons(state ButtonState) {
+	s.buttons = state
+	if s.strobe {
+		s.shift = byte(s.buttons)
+	}
+}
+
+func (s *StandardController) Buttons() ButtonState {
+	return s.buttons
+}
+
+func (s *StandardController) Write(out byte) {
+	s.strobe = out&0x01 != 0
+	if s.strobe {
+		s.shift = byte(s.buttons)
+	}
+}
+
+// Read シフトレジスタから1bit読み出してD0に出力する、空いたbitには1が入る
+func (s *StandardController) Read(port Port) byte {
+	if s.strobe {
+		// strobe中は常にAボタンの状態を返す
+		return byte(s.buttons & ButtonA)
+	}
+	bit := s.shift & 0x01
+	s.shift = s.shift>>1 | 0x80
+	return bit
+}
@@ -0,0 +1,93 @@
+package controller
+
+// LightSource Zapperが光を検出するための画面の情報(PPU)
+type LightSource interface {
+	// Position 現在描画しているscanlineとdot
+	Position() (scanline, dot int)
+	// Brightness 画面の(x, y)のピクセルの明るさ(0-1)
+	Brightness(x, y int) float32
+}
+
+// Zapper 光線銃
+// doc: https://www.nesdev.org/wiki/Zapper
+//
+// D3: 光センサー(0: 光を検出, 1: 検出していない), D4: トリガー(1: 引いている)
+// 狙っている位置の周辺が明るく描画された直後の一定期間だけ光を検出する
+type Zapper struct {
+	light     LightSource
+	x, y      int
+	offscreen bool
+	trigger   bool
+}
+
+const (
+	// zapperRadius 光センサーが見る範囲(狙っている位置からのピクセル数)
+	zapperRadius = 2
+	// zapperSenseScanlines 明るいピクセルが描画されてから光を検出し続けるscanline数
+	zapperSenseScanlines = 20
+	// zapperThreshold 光として検出する明るさ
+	zapperThreshold = 0.85
+)
+
+func NewZapper(light LightSource) *Zapper {
+	return &Zapper{
+		light:     light,
+		offscreen: true,
+	}
+}
+
+var _ InputDevice = (*Zapper)(nil)
+
+// Aim 画面の(x, y)を狙う
+func (z *Zapper) Aim(x, y int) {
+	z.x = x
+	z.y = y
+	z.offscreen = false
+}
+
+// AimOffscreen 画面の外を狙う(光を検出しない)
+func (z *Zapper) AimOffscreen() {
+	z.offscreen = true
+}
+
+func (z *Zapper) SetTrigger(pulled bool) {
+	z.trigger = pulled
+}
+
+// Write Zapperはstrobeを使わない
+func (z *Zapper) Write(out byte) {}
+
+func (z *Zapper) Read(port Port) byte {
+	var value byte
+	if !z.detectLight() {
+		value |= 1 << 3
+	}
+	if z.trigger {
+		value |= 1 << 4
+	}
+	return value
+}
+
+// detectLight 狙っている位置の周辺に、直近に描画された明るいピクセルがあるか
+func (z *Zapper) detectLight() bool {
+	if z.offscreen || z.light == nil {
+		return false
+	}
+	scanline, dot := z.light.Position()
+	for y := z.y - zapperRadius; y <= z.y+zapperRadius; y++ {
+		// 描画が終わってからzapperSenseScanlinesの間だけ検出する
+		if y > scanline || scanline-y >= zapperSenseScanlines {
+			continue
+		}
+		for x := z.x - zapperRadius; x <= z.x+zapperRadius; x++ {
+			if y == scanline && x >= dot {
+				// まだ描画されていない
+				continue
+			}
+			if z.light.Brightness(x, y) >= zapperThreshold {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ZapperScript フレームごとのZapperの操作(自動テスト用)
+//
+// 1行に1つの操作を書き、#以降はコメント
+//
+//	<frame> <x> <y> [trigger]  (x, y)を狙う、triggerが1の場合はトリガーを引く
+//	<frame> off [trigger]      画面の外を狙う
+//
+// 指定したフレームから次の操作までその状態が続く
+type ZapperScript struct {
+	entries []zapperScriptEntry
+}
+
+type zapperScriptEntry struct {
+	frame     int
+	x, y      int
+	offscreen bool
+	trigger   bool
+}
+
+func ParseZapperScript(r io.Reader) (*ZapperScript, error) {
+	var entries []zapperScriptEntry
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		entry, err := parseZapperScriptEntry(fields)
+		if err != nil {
+			return nil, fmt.Errorf("Controller: invalid zapper script. line: %d, err: %w", lineNo, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Controller: failed read zapper script. err: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].frame < entries[j].frame
+	})
+	return &ZapperScript{entries: entries}, nil
+}
+
+func parseZapperScriptEntry(fields []string) (zapperScriptEntry, error) {
+	var entry zapperScriptEntry
+	frame, err := strconv.Atoi(fields[0])
+	if err != nil || frame < 0 {
+		return entry, fmt.Errorf("invalid frame. frame: %s", fields[0])
+	}
+	entry.frame = frame
+
+	rest := fields[1:]
+	switch {
+	case len(rest) >= 1 && rest[0] == "off":
+		entry.offscreen = true
+		rest = rest[1:]
+	case len(rest) >= 2:
+		if entry.x, err = strconv.Atoi(rest[0]); err != nil {
+			return entry, fmt.Errorf("invalid x. x: %s", rest[0])
+		}
+		if entry.y, err = strconv.Atoi(rest[1]); err != nil {
+			return entry, fmt.Errorf("invalid y. y: %s", rest[1])
+		}
+		rest = rest[2:]
+	default:
+		return entry, fmt.Errorf("x y or off is required")
+	}
+
+	switch {
+	case len(rest) == 0:
+	case len(rest) == 1 && (rest[0] == "0" || rest[0] == "1"):
+		entry.trigger = rest[0] == "1"
+	default:
+		return entry, fmt.Errorf("invalid trigger. trigger: %s", strings.Join(rest, " "))
+	}
+	return entry, nil
+}
+
+// Apply frameの時点の操作をZapperに反映する
+func (s *ZapperScript) Apply(frame int, zapper *Zapper) {
+	i := sort.Search(len(s.entries), func(i int) bool {
+		return s.entries[i].frame > frame
+	})
+	if i == 0 {
+		return
+	}
+	entry := s.entries[i-1]
+	if entry.offscreen {
+		zapper.AimOffscreen()
+	} else {
+		zapper.Aim(entry.x, entry.y)
+	}
+	zapper.SetTrigger(entry.trigger)
+}
@@ -0,0 +1,80 @@
+package controller_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+)
+
+// screen 指定した範囲だけ明るい画面
+type screen struct {
+	scanline, dot int
+	brightY       int
+}
+
+func (s *screen) Position() (int, int) {
+	return s.scanline, s.dot
+}
+
+func (s *screen) Brightness(x, y int) float32 {
+	if y == s.brightY && x >= 100 && x < 140 {
+		return 1
+	}
+	return 0
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestZapper$ github.com/sunjin110/nes_emu/internal/domain/controller
+func TestZapper(t *testing.T) {
+	Convey("TestZapper", t, func() {
+		light := &screen{brightY: 100}
+		c := controller.NewController()
+		c.SetLightSource(light)
+		So(c.ApplyInputConfig(controller.InputConfig{Port1: controller.DeviceStandard, Port2: controller.DeviceZapper}), ShouldBeNil)
+		zapper, ok := c.Device(controller.Port2).(*controller.Zapper)
+		So(ok, ShouldBeTrue)
+
+		Convey("明るいピクセルが描画された直後だけ光を検出する", func() {
+			zapper.Aim(120, 100)
+
+			light.scanline, light.dot = 99, 200
+			So(c.Read(0x4017)&0x08, ShouldEqual, 0x08) // まだ描画されていない
+			light.scanline, light.dot = 100, 150
+			So(c.Read(0x4017)&0x08, ShouldEqual, 0)
+			light.scanline, light.dot = 110, 0
+			So(c.Read(0x4017)&0x08, ShouldEqual, 0)
+			light.scanline, light.dot = 130, 0
+			So(c.Read(0x4017)&0x08, ShouldEqual, 0x08) // 時間が経つと検出しなくなる
+		})
+
+		Convey("暗い位置や画面外を狙うと検出しない", func() {
+			light.scanline, light.dot = 101, 0
+			zapper.Aim(20, 100)
+			So(c.Read(0x4017)&0x08, ShouldEqual, 0x08)
+			zapper.AimOffscreen()
+			So(c.Read(0x4017)&0x08, ShouldEqual, 0x08)
+		})
+
+		Convey("スクリプトでフレームごとに狙う位置とトリガーを変える", func() {
+			script, err := controller.ParseZapperScript(strings.NewReader(`
+# frame x y trigger
+10 120 100 1
+0 off
+20 off 0
+`))
+			So(err, ShouldBeNil)
+			light.scanline, light.dot = 101, 0
+
+			script.Apply(5, zapper)
+			So(c.Read(0x4017)&0x18, ShouldEqual, 0x08)
+			script.Apply(15, zapper)
+			So(c.Read(0x4017)&0x18, ShouldEqual, 0x10)
+			script.Apply(20, zapper)
+			So(c.Read(0x4017)&0x18, ShouldEqual, 0x08)
+
+			_, err = controller.ParseZapperScript(strings.NewReader("10 120"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
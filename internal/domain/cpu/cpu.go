@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
 	"github.com/sunjin110/nes_emu/internal/domain/cpu/internal/memory"
 	"github.com/sunjin110/nes_emu/internal/domain/ppu"
 	"github.com/sunjin110/nes_emu/internal/domain/prgrom"
@@ -16,8 +18,8 @@ type CPU struct {
 	register Register
 }
 
-func NewCPU(prgROM prgrom.PRGROM, ppu ppu.PPU) (*CPU, error) {
-	memory := memory.NewMemory(prgROM, ppu)
+func NewCPU(prgROM prgrom.PRGROM, ppu ppu.PPU, apu *apu.APU, controller *controller.Controller) (*CPU, error) {
+	memory := memory.NewMemory(prgROM, ppu, apu, controller)
 
 	register, err := NewRegister(prgROM)
 	if err != nil {
@@ -168,9 +170,6 @@ func (cpu *CPU) Interrupt(t InterruptType) error {
 		return nil
 	}
 
-	// 割り込むフラグを追加する
-	cpu.setFlag(interruptFlag, true)
-
 	switch t {
 	case InterruptTypeNMI:
 
@@ -188,6 +187,8 @@ func (cpu *CPU) Interrupt(t InterruptType) error {
 		if err := cpu.pushStack(pushData); err != nil {
 			return fmt.Errorf("CPU: Interrupt: NMI: failed push stack. err: %w", err)
 		}
+		// 割り込み禁止フラグはPをpushした後に立てる(RTIで割り込み前の状態に戻るように)
+		cpu.setFlag(interruptFlag, true)
 
 		interruptLowerPC, err := cpu.memory.Read(memory.NMIInterruptLowerPCAddr)
 		if err != nil {
@@ -203,6 +204,7 @@ func (cpu *CPU) Interrupt(t InterruptType) error {
 		cpu.setPC(pc)
 		return nil
 	case InterruptTypeReset:
+		cpu.setFlag(interruptFlag, true)
 		interruptLowerPC, err := cpu.memory.Read(memory.ResetInterruptLowerPCAddr)
 		if err != nil {
 			return fmt.Errorf("CPU: Interrupt: Reset: failed read memory. err: %w", err)
@@ -234,6 +236,8 @@ func (cpu *CPU) Interrupt(t InterruptType) error {
 		if err := cpu.pushStack(pushData); err != nil {
 			return fmt.Errorf("CPU: Interrupt: IRQ: failed push stack. err: %w", err)
 		}
+		// 割り込み禁止フラグはPをpushした後に立てる(RTIで割り込み前の状態に戻るように)
+		cpu.setFlag(interruptFlag, true)
 
 		interruptLowerPC, err := cpu.memory.Read(memory.IRQInterruptLowerPCAddr)
 		if err != nil {
@@ -260,6 +264,7 @@ func (cpu *CPU) Interrupt(t InterruptType) error {
 		}
 
 		cpu.setFlag(breakFlag, true)
+		cpu.setFlag(interruptFlag, true)
 		pushData := cpu.register.p | (1 << 5) // 5bit目(未使用)を必ず1にする
 		if err := cpu.pushStack(pushData); err != nil {
 			return fmt.Errorf("CPU: Interrupt: BRK: failed push stack. err: %w", err)
@@ -292,6 +297,35 @@ func (cpu *CPU) Reset() error {
 	return nil
 }
 
+// CallSubroutine NSFのINIT/PLAYのように外部からサブルーチンを呼び出す
+// JSRと同様にreturnAddr-1をスタックにpushするので、RTSで戻ってくるとPC()がreturnAddrになる
+func (cpu *CPU) CallSubroutine(addr uint16, returnAddr uint16, a byte, x byte) error {
+	// jsrと同じくlower -> upperの順にpush
+	lower, upper := bit_helper.Uint16ToBytes(returnAddr - 1)
+	if err := cpu.pushStack(lower); err != nil {
+		return fmt.Errorf("CPU: CallSubroutine: failed push lower returnAddr to stack. err: %w", err)
+	}
+	if err := cpu.pushStack(upper); err != nil {
+		return fmt.Errorf("CPU: CallSubroutine: failed push upper returnAddr to stack. err: %w", err)
+	}
+
+	cpu.setA(a)
+	cpu.setX(x)
+	cpu.setY(0)
+	cpu.setPC(addr)
+	return nil
+}
+
+// TakeStallCycles 直前の命令のOAM DMAなどでCPUが止まっていたサイクル数を返す
+func (cpu *CPU) TakeStallCycles() int {
+	return cpu.memory.TakeStallCycles()
+}
+
+// PC 次に実行する命令のアドレス
+func (cpu *CPU) PC() uint16 {
+	return cpu.register.pc
+}
+
 // fetchOpcode PCから実行コードを取得する
 func (cpu *CPU) fetchOpcode() (Opcode, error) {
 	pc := cpu.register.pc
@@ -720,8 +754,8 @@ func (cpu *CPU) bit(opcode Opcode) (cycles uint8, err error) {
 	result := cpu.register.a & arg
 
 	cpu.setFlag(zeroFlag, result == 0)
-	cpu.setFlag(overflowFlag, (arg&0x40) == 0x40) // 6bit目が1ならoverflowをtrue
-	cpu.setFlag(negativeFlag, cpu.isNegative(result))
+	cpu.setFlag(overflowFlag, (arg&0x40) == 0x40)  // 6bit目が1ならoverflowをtrue
+	cpu.setFlag(negativeFlag, cpu.isNegative(arg)) // negativeはAとのANDではなくメモリの値の7bit目
 
 	cpu.incrementPC(uint16(opcode.Length))
 	return opcode.Cycles + additionalCycles, nil
@@ -1188,7 +1222,7 @@ func (cpu *CPU) lda(opcode Opcode) (cycles uint8, err error) {
 
 // ldx: Load X
 func (cpu *CPU) ldx(opcode Opcode) (cycles uint8, err error) {
-	if opcode.Mnemonic != LDA {
+	if opcode.Mnemonic != LDX {
 		return 0, fmt.Errorf("invalid mnemonic was specified. mnemonic: %v", opcode.Mnemonic)
 	}
 
@@ -1208,7 +1242,7 @@ func (cpu *CPU) ldx(opcode Opcode) (cycles uint8, err error) {
 
 // ldy: Load Y
 func (cpu *CPU) ldy(opcode Opcode) (cycles uint8, err error) {
-	if opcode.Mnemonic != LDA {
+	if opcode.Mnemonic != LDY {
 		return 0, fmt.Errorf("invalid mnemonic was specified. mnemonic: %v", opcode.Mnemonic)
 	}
 
@@ -1746,13 +1780,14 @@ func (cpu *CPU) pushStack(b byte) error {
 	return nil
 }
 
+// popStack pushStackとは逆に、SPをincrementしてから読み込む
 func (cpu *CPU) popStack() (byte, error) {
+	cpu.register.sp += 1
 	spAddr := uint16(cpu.register.sp) | uint16(0x0100)
 	b, err := cpu.memory.Read(spAddr)
 	if err != nil {
 		return 0, fmt.Errorf("CPU: popStack: failed read memory. addr: %x, err: %w", spAddr, err)
 	}
-	cpu.register.sp += 1
 	return b, nil
 }
 
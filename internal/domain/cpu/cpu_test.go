@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
 	"github.com/sunjin110/nes_emu/internal/domain/prgrom"
 	"github.com/sunjin110/nes_emu/pkg/bit_helper"
 )
@@ -28,6 +30,10 @@ func (m *dummyMemory) GetPRGROM() prgrom.PRGROM {
 	}
 }
 
+func (m *dummyMemory) TakeStallCycles() int {
+	return 0
+}
+
 type dummyPRGROM struct {
 	data map[uint16]byte
 }
@@ -535,6 +541,27 @@ func Test_CPU_Run(t *testing.T) {
 				},
 				expectedCycles: 4,
 			},
+			{
+				name: "BIT Absolute NとVはAに関係なくメモリの値から決まる",
+				initialMemory: map[uint16]byte{
+					0x8000: 0x2C, // BIT Absolute opcode
+					0x8001: 0x10, // Low byte of address
+					0x8002: 0x00, // High byte of address
+					0x0010: 0xC0, // Value at address 0x0010
+				},
+				initalRegs: Register{
+					a:  0x00,
+					pc: 0x8000,
+					p:  0x00,
+				},
+				expectedMemory: map[uint16]byte{},
+				expectedRegs: Register{
+					a:  0x00,
+					pc: 0x8003,
+					p:  0xC2, // Negative, Overflow and Zero flags set
+				},
+				expectedCycles: 4,
+			},
 			{
 				name: "BMI - Branch if Minus",
 				initialMemory: map[uint16]byte{
@@ -643,6 +670,119 @@ func Test_CPU_Run(t *testing.T) {
 				},
 				expectedCycles: 3,
 			},
+			{
+				name: "LDX Immediate - X = Operand",
+				initialMemory: map[uint16]byte{
+					0x8000: 0xA2, // LDX Immediate opcode
+					0x8001: 0x80, // Operand
+				},
+				initalRegs: Register{
+					pc: 0x8000,
+				},
+				expectedMemory: map[uint16]byte{},
+				expectedRegs: Register{
+					x:  0x80,
+					pc: 0x8002,
+					p:  0x80, // Negative flag set
+				},
+				expectedCycles: 2,
+			},
+			{
+				name: "LDY Immediate - Y = Operand",
+				initialMemory: map[uint16]byte{
+					0x8000: 0xA0, // LDY Immediate opcode
+					0x8001: 0x00, // Operand
+				},
+				initalRegs: Register{
+					y:  0x10,
+					pc: 0x8000,
+				},
+				expectedMemory: map[uint16]byte{},
+				expectedRegs: Register{
+					pc: 0x8002,
+					p:  0x02, // Zero flag set
+				},
+				expectedCycles: 2,
+			},
+			{
+				name: "PLA - SPを1増やしてから読み込む",
+				initialMemory: map[uint16]byte{
+					0x8000: 0x68, // PLA opcode
+					0x01FC: 0x11, // SPの位置(pushStackで次に書き込む場所)
+					0x01FD: 0x80, // 最後にpushされた値
+				},
+				initalRegs: Register{
+					pc: 0x8000,
+					sp: 0xFC,
+				},
+				expectedMemory: map[uint16]byte{},
+				expectedRegs: Register{
+					a:  0x80,
+					pc: 0x8001,
+					sp: 0xFD,
+					p:  0x80, // Negative flag set
+				},
+				expectedCycles: 4,
+			},
+			{
+				name: "PLP - SPを1増やしてから読み込み、B4とB5は変えない",
+				initialMemory: map[uint16]byte{
+					0x8000: 0x28, // PLP opcode
+					0x01FC: 0x11,
+					0x01FD: 0xF3, // 最後にpushされた値
+				},
+				initalRegs: Register{
+					pc: 0x8000,
+					sp: 0xFC,
+				},
+				expectedMemory: map[uint16]byte{},
+				expectedRegs: Register{
+					pc: 0x8001,
+					sp: 0xFD,
+					p:  0xC3,
+				},
+				expectedCycles: 4,
+			},
+			{
+				name: "RTS - JSRでpushしたアドレス+1に戻る",
+				initialMemory: map[uint16]byte{
+					0x8000: 0x60, // RTS opcode
+					0x01FB: 0x11,
+					0x01FC: 0x90, // PC high byte (JSRで後にpush)
+					0x01FD: 0x02, // PC low byte (JSRで先にpush)
+				},
+				initalRegs: Register{
+					pc: 0x8000,
+					sp: 0xFB,
+				},
+				expectedMemory: map[uint16]byte{},
+				expectedRegs: Register{
+					pc: 0x9003,
+					sp: 0xFD,
+				},
+				expectedCycles: 6,
+			},
+			{
+				name: "RTI - Pとpushしたアドレスに戻る",
+				initialMemory: map[uint16]byte{
+					0x8000: 0x40, // RTI opcode
+					0x01FA: 0x11,
+					0x01FB: 0xF1, // P (B4, B5は変えない)
+					0x01FC: 0x90, // PC high byte
+					0x01FD: 0x10, // PC low byte
+				},
+				initalRegs: Register{
+					pc: 0x8000,
+					sp: 0xFA,
+				},
+				expectedMemory: map[uint16]byte{},
+				expectedRegs: Register{
+					pc: 0x9010,
+					sp: 0xFD,
+					p:  0xC1,
+				},
+				expectedCycles: 6,
+			},
 		}
 
 		for _, tt := range tests {
@@ -652,7 +792,7 @@ func Test_CPU_Run(t *testing.T) {
 					data: tt.initialMemory,
 				}
 
-				cpu, err := NewCPU(m.GetPRGROM(), nil)
+				cpu, err := NewCPU(m.GetPRGROM(), nil, apu.NewAPU(), controller.NewController())
 				cpu.memory = m
 				So(err, ShouldBeNil)
 
@@ -677,3 +817,40 @@ func Test_CPU_Run(t *testing.T) {
 		}
 	})
 }
+
+// go test -v -count=1 -timeout 30s -run ^Test_CPU_Interrupt$ github.com/sunjin110/nes_emu/internal/domain/cpu
+func Test_CPU_Interrupt(t *testing.T) {
+	Convey("Test_CPU_Interrupt", t, func() {
+		m := &dummyMemory{
+			data: map[uint16]byte{
+				0xFFFA: 0x00, 0xFFFB: 0x90, // NMI
+				0xFFFE: 0x00, 0xFFFF: 0xA0, // IRQ
+			},
+		}
+		cpu, err := NewCPU(m.GetPRGROM(), nil, apu.NewAPU(), controller.NewController())
+		So(err, ShouldBeNil)
+		cpu.memory = m
+
+		tests := map[InterruptType]uint16{
+			InterruptTypeNMI: 0x9000,
+			InterruptTypeIRQ: 0xA000,
+		}
+		for interruptType, vector := range tests {
+			Convey(fmt.Sprintf("割り込み前のPをpushしてからIフラグを立てる. type: %v", interruptType), func() {
+				cpu.register = Register{pc: 0x8000, sp: 0xFD, p: 0x01}
+				So(cpu.Interrupt(interruptType), ShouldBeNil)
+				So(cpu.register.pc, ShouldEqual, vector)
+				So(cpu.register.sp, ShouldEqual, 0xFA)
+				So(cpu.register.p, ShouldEqual, 0x05)
+				// 5bit目は1、Iフラグは立っていない
+				So(m.data[0x01FB], ShouldEqual, 0x21)
+			})
+		}
+
+		Convey("Iフラグが立っている場合はIRQを無視する", func() {
+			cpu.register = Register{pc: 0x8000, sp: 0xFD, p: 0x04}
+			So(cpu.Interrupt(InterruptTypeIRQ), ShouldBeNil)
+			So(cpu.register, ShouldResemble, Register{pc: 0x8000, sp: 0xFD, p: 0x04})
+		})
+	})
+}
@@ -27,24 +27,34 @@ type Memory interface {
 	Read(addr uint16) (byte, error)
 	Write(addr uint16, value byte) error
 	GetPRGROM() prgrom.PRGROM
+	// TakeStallCycles OAM DMAなどでCPUが止まるサイクル数を返してクリアする
+	TakeStallCycles() int
 }
 
 type memory struct {
-	ram        ram.WorkRAM           // RAM:ワーキングメモリ(0x0000-0x07ff) 0x0800-0x1fffはミラー
-	ppu        ppu.PPU               // PPUレジスタ(0x2000〜0x2007)　0x2008-0x3fffはミラー
-	apu        apu.APU               // APU(0x4000-0x4015)
-	controller controller.Controller // Controller(0x4016-4017)
-	prgROM     prgrom.PRGROM         // PRG-ROM(0x8000〜0xFFFF)
+	ram        ram.WorkRAM            // RAM:ワーキングメモリ(0x0000-0x07ff) 0x0800-0x1fffはミラー
+	ppu        ppu.PPU                // PPUレジスタ(0x2000〜0x2007)　0x2008-0x3fffはミラー
+	apu        *apu.APU               // APU(0x4000-0x4015), 0x4017の書き込み
+	controller *controller.Controller // Controller(0x4016-4017), 0x4017は読み込みのみ
+	prgROM     prgrom.PRGROM          // PRG-ROM(0x8000〜0xFFFF), ExpansionPRGROMの場合は0x4020〜0x7FFFも
+
+	stallCycles int
 }
 
-func NewMemory(prgROM prgrom.PRGROM, ppu ppu.PPU) Memory {
-	return &memory{
+// oamDMACycles OAM DMAでCPUが止まるサイクル数(奇数サイクルで始まった場合は+1だが考慮しない)
+const oamDMACycles = 513
+
+func NewMemory(prgROM prgrom.PRGROM, ppu ppu.PPU, apu *apu.APU, controller *controller.Controller) Memory {
+	m := &memory{
 		ram:        *ram.NewWorkRAM(),
 		ppu:        ppu,
-		apu:        *apu.NewAPU(),
-		controller: *controller.NewController(),
+		apu:        apu,
+		controller: controller,
 		prgROM:     prgROM,
 	}
+	// DMCはCPUのメモリからサンプルを読み込む
+	apu.ConnectBus(m)
+	return m
 }
 
 func (memory *memory) Read(addr uint16) (byte, error) {
@@ -63,6 +73,12 @@ func (memory *memory) Read(addr uint16) (byte, error) {
 		return memory.controller.Read(addr), nil
 	case prgrom.IsPRGRomRange(addr):
 		return memory.prgROM.Read(addr), nil
+	case prgrom.IsExpansionRange(addr):
+		if expansion, ok := memory.prgROM.(prgrom.ExpansionPRGROM); ok {
+			return expansion.Read(addr), nil
+		}
+		logger.Logger.Error("invalid addr is specified", "addr", addr)
+		return 0, fmt.Errorf("Memory: invalid addr is specified. addr: %b", addr)
 	default:
 		logger.Logger.Error("invalid addr is specified", "addr", addr)
 		return 0, fmt.Errorf("Memory: invalid addr is specified. addr: %b", addr)
@@ -77,12 +93,23 @@ func (memory *memory) Write(addr uint16, value byte) error {
 		if err := memory.ppu.Write(addr, value); err != nil {
 			return fmt.Errorf("failed write ppu. addr: %x, value: %x, err: %w", addr, value, err)
 		}
-	case apu.IsAPUAddrRange(addr): // APU
+	case ppu.IsOAMDMAAddr(addr) && memory.ppu != nil: // OAMDMA、APUの範囲と重なるので先に判定する
+		if err := memory.oamDMA(value); err != nil {
+			return fmt.Errorf("failed oam dma. value: %x, err: %w", value, err)
+		}
+	case apu.IsAPUAddrRange(addr), apu.IsFrameCounterAddr(addr): // APU
 		memory.apu.Write(addr, value)
 	case controller.IsControllerAddr(addr):
 		memory.controller.Write(addr, value)
-	case prgrom.IsPRGRomRange(addr):
-		return fmt.Errorf("Memory: PRGROM is not allowed write. addr: %b", addr)
+	case prgrom.IsPRGRomRange(addr), prgrom.IsExpansionRange(addr):
+		expansion, ok := memory.prgROM.(prgrom.ExpansionPRGROM)
+		if !ok {
+			if prgrom.IsPRGRomRange(addr) {
+				return fmt.Errorf("Memory: PRGROM is not allowed write. addr: %b", addr)
+			}
+			return fmt.Errorf("Memory: invalid addr is specified. addr: %b", addr)
+		}
+		expansion.Write(addr, value)
 	default:
 		return fmt.Errorf("Memory: invalid addr is specified. addr: %b", addr)
 	}
@@ -92,3 +119,26 @@ func (memory *memory) Write(addr uint16, value byte) error {
 func (memory *memory) GetPRGROM() prgrom.PRGROM {
 	return memory.prgROM
 }
+
+func (memory *memory) TakeStallCycles() int {
+	cycles := memory.stallCycles
+	memory.stallCycles = 0
+	return cycles
+}
+
+// oamDMA $XX00-$XXFFの256byteをOAMDATA($2004)に順に書き込む
+// doc: https://www.nesdev.org/wiki/PPU_registers#OAMDMA
+func (memory *memory) oamDMA(page byte) error {
+	start := uint16(page) << 8
+	for i := uint16(0); i < 0x100; i++ {
+		value, err := memory.Read(start + i)
+		if err != nil {
+			return fmt.Errorf("Memory: oamDMA: failed read. addr: %x, err: %w", start+i, err)
+		}
+		if err := memory.ppu.Write(0x2004, value); err != nil {
+			return fmt.Errorf("Memory: oamDMA: failed write OAMDATA. err: %w", err)
+		}
+	}
+	memory.stallCycles += oamDMACycles
+	return nil
+}
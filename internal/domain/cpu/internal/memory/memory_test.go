@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
 	"github.com/sunjin110/nes_emu/internal/domain/cpu/internal/memory"
 	"github.com/sunjin110/nes_emu/internal/domain/ppu/mock_ppu"
 	"github.com/sunjin110/nes_emu/internal/domain/prgrom"
@@ -23,7 +25,7 @@ func TestMemory_ReadWrite(t *testing.T) {
 	// メモリの初期化
 	prgRom := [prgrom.PRGROMSize]byte{}
 	prgRom[0] = 0x99
-	mem := memory.NewMemory(prgrom.NewFixedPRGROM(prgRom), ppu)
+	mem := memory.NewMemory(prgrom.NewFixedPRGROM(prgRom), ppu, apu.NewAPU(), controller.NewController())
 
 	// RAMの書き込みと読み込み
 	addrRAM := uint16(0x0000)
@@ -71,7 +73,7 @@ func TestMemory_ReadWrite(t *testing.T) {
 
 func TestMemory_InvalidAddress(t *testing.T) {
 	// メモリの初期化
-	mem := memory.NewMemory(prgrom.NewFixedPRGROM([32 * 1024]byte{}), nil)
+	mem := memory.NewMemory(prgrom.NewFixedPRGROM([32 * 1024]byte{}), nil, apu.NewAPU(), controller.NewController())
 
 	// 無効なアドレスの読み込み
 	invalidAddr := uint16(0x8000 - 1)
@@ -82,3 +84,25 @@ func TestMemory_InvalidAddress(t *testing.T) {
 	err = mem.Write(invalidAddr, 0x55)
 	assert.Error(t, err, "無効なアドレス: エラーが発生しませんでした")
 }
+
+func TestMemory_OAMDMA(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// $0200-$02FFの256byteが順にOAMDATAに書き込まれる
+	ppu := mock_ppu.NewMockPPU(ctrl)
+	var calls []any
+	for i := 0; i < 0x100; i++ {
+		calls = append(calls, ppu.EXPECT().Write(uint16(0x2004), byte(i)).Return(nil))
+	}
+	gomock.InOrder(calls...)
+
+	mem := memory.NewMemory(prgrom.NewFixedPRGROM([prgrom.PRGROMSize]byte{}), ppu, apu.NewAPU(), controller.NewController())
+	for i := 0; i < 0x100; i++ {
+		assert.NoError(t, mem.Write(uint16(0x0200+i), byte(i)))
+	}
+
+	assert.NoError(t, mem.Write(0x4014, 0x02))
+	assert.Equal(t, 513, mem.TakeStallCycles(), "OAMDMA: CPUが止まるサイクル数が正しくありません")
+	assert.Equal(t, 0, mem.TakeStallCycles(), "OAMDMA: 止まるサイクル数がクリアされていません")
+}
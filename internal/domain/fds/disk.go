@@ -0,0 +1,214 @@
+package fds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ディスクのイメージ
+// doc: https://www.nesdev.org/wiki/FDS_disk_format
+//
+// .fds: 16byteのfwNESのヘッダー(省略可能)の後に、1面65500byteのブロックが続く
+// .qd:  1面65536byte、各ブロックの後に2byteのCRCが付く
+//
+// ブロック 1: ディスク情報(56byte), 2: ファイル数(2byte), 3: ファイルヘッダー(16byte), 4: ファイルのデータ(1 + ファイルヘッダーのサイズ)
+
+var (
+	// ErrInvalidDisk ディスクのイメージが不正
+	ErrInvalidDisk = errors.New("FDS: invalid disk image")
+	// ErrInvalidSide 存在しない面
+	ErrInvalidSide = errors.New("FDS: invalid disk side")
+)
+
+// ImageFormat ディスクのイメージの形式
+type ImageFormat int
+
+const (
+	ImageFDS ImageFormat = iota // .fds
+	ImageQD                     // .qd
+)
+
+const (
+	headerSize   = 16
+	fdsSideSize  = 65500
+	qdSideSize   = 0x10000
+	diskInfoSize = 56
+	crcSize      = 2
+
+	blockDiskInfo   = 1
+	blockFileAmount = 2
+	blockFileHeader = 3
+	blockFileData   = 4
+	fileHeaderSize  = 16
+)
+
+var (
+	headerMagic = []byte("FDS\x1A")
+	// diskInfoMagic 各面の先頭のブロック
+	diskInfoMagic = []byte("\x01*NINTENDO-HVC*")
+)
+
+// Disk ディスクの全ての面、各面はCRCのない.fdsの形式(65500byte)で持つ
+type Disk struct {
+	Format ImageFormat
+	// Header fwNESのヘッダーがあったか(.fdsのみ)
+	Header bool
+	Sides  [][]byte
+}
+
+// IsDisk .fds, .qdのデータかどうか
+func IsDisk(data []byte) bool {
+	return bytes.HasPrefix(data, headerMagic) || bytes.HasPrefix(data, diskInfoMagic)
+}
+
+// ParseDisk .fdsか.qdのイメージを読み込む、形式は中身から判定する
+func ParseDisk(data []byte) (*Disk, error) {
+	d := &Disk{Format: ImageFDS}
+	if bytes.HasPrefix(data, headerMagic) {
+		if len(data) < headerSize {
+			return nil, fmt.Errorf("%w. header is truncated. size: %d", ErrInvalidDisk, len(data))
+		}
+		d.Header = true
+		data = data[headerSize:]
+	}
+	if !bytes.HasPrefix(data, diskInfoMagic) {
+		return nil, fmt.Errorf("%w. disk info block is missing", ErrInvalidDisk)
+	}
+
+	sideSize := fdsSideSize
+	// .qdはディスク情報の後にCRCがあるので、ファイル数のブロックが2byte後ろにある
+	if !d.Header && len(data)%qdSideSize == 0 && len(data) > diskInfoSize+crcSize && data[diskInfoSize+crcSize] == blockFileAmount {
+		d.Format = ImageQD
+		sideSize = qdSideSize
+	}
+	if len(data) < sideSize {
+		return nil, fmt.Errorf("%w. image is too short. size: %d", ErrInvalidDisk, len(data))
+	}
+
+	for offset := 0; offset+sideSize <= len(data); offset += sideSize {
+		side := data[offset : offset+sideSize]
+		if !bytes.HasPrefix(side, diskInfoMagic) {
+			return nil, fmt.Errorf("%w. side %d has no disk info block", ErrInvalidDisk, len(d.Sides))
+		}
+		if d.Format == ImageQD {
+			side = stripCRC(side)
+		} else {
+			side = append([]byte(nil), side...)
+		}
+		d.Sides = append(d.Sides, side)
+	}
+	return d, nil
+}
+
+// Bytes 読み込んだ時と同じ形式のイメージ
+func (d *Disk) Bytes() []byte {
+	var data []byte
+	if d.Header {
+		header := make([]byte, headerSize)
+		copy(header, headerMagic)
+		header[4] = byte(len(d.Sides))
+		data = append(data, header...)
+	}
+	for _, side := range d.Sides {
+		if d.Format == ImageQD {
+			data = append(data, addCRC(side)...)
+			continue
+		}
+		data = append(data, side...)
+	}
+	return data
+}
+
+// blockLength ブロックの種類ごとの長さ、ファイルのデータはその前のファイルヘッダーのサイズで決まる
+// 不明な種類の場合は0
+func blockLength(blockType byte, fileSize int) int {
+	switch blockType {
+	case blockDiskInfo:
+		return diskInfoSize
+	case blockFileAmount:
+		return 2
+	case blockFileHeader:
+		return fileHeaderSize
+	case blockFileData:
+		return 1 + fileSize
+	}
+	return 0
+}
+
+// fileSize ファイルヘッダーのbyte 13, 14のファイルのサイズ
+func fileSize(header []byte) int {
+	return int(header[13]) | int(header[14])<<8
+}
+
+// eachBlock sideのブロックを順に呼ぶ、gapはブロックの後の読み飛ばすbyte数(.qdのCRC)
+// 不明な種類のブロックか、sideの最後まで来たら終わる
+func eachBlock(side []byte, gap int, f func(block []byte)) {
+	var size int
+	for offset := 0; offset < len(side); {
+		length := blockLength(side[offset], size)
+		if length == 0 || offset+length > len(side) {
+			return
+		}
+		block := side[offset : offset+length]
+		if block[0] == blockFileHeader {
+			size = fileSize(block)
+		}
+		f(block)
+		offset += length + gap
+	}
+}
+
+// stripCRC .qdの面から各ブロックのCRCを取り除く
+func stripCRC(side []byte) []byte {
+	stripped := make([]byte, 0, fdsSideSize)
+	eachBlock(side, crcSize, func(block []byte) {
+		stripped = append(stripped, block...)
+	})
+	return pad(stripped, fdsSideSize)
+}
+
+// addCRC .qdの面にするため各ブロックの後にCRCを付ける
+func addCRC(side []byte) []byte {
+	data := make([]byte, 0, qdSideSize)
+	eachBlock(side, 0, func(block []byte) {
+		crc := blockCRC(block)
+		data = append(data, block...)
+		data = append(data, byte(crc), byte(crc>>8))
+	})
+	return pad(data, qdSideSize)
+}
+
+// pad sizeまで0で埋める、sizeより長い場合は切り詰める
+func pad(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data[:size]
+	}
+	return append(data, make([]byte, size-len(data))...)
+}
+
+// blockCRC ブロックの開始マーク(0x80)からブロックの最後までのCRC-16
+func blockCRC(block []byte) uint16 {
+	var crc uint16
+	crc = updateCRC(crc, blockStartMark)
+	for _, b := range block {
+		crc = updateCRC(crc, b)
+	}
+	crc = updateCRC(crc, 0)
+	return updateCRC(crc, 0)
+}
+
+// updateCRC 下位bitから1bitずつ計算する(多項式 0x8408)
+func updateCRC(crc uint16, value byte) uint16 {
+	for bit := byte(0x01); bit != 0; bit <<= 1 {
+		carry := crc&0x01 != 0
+		crc >>= 1
+		if carry {
+			crc ^= 0x8408
+		}
+		if value&bit != 0 {
+			crc ^= 0x8000
+		}
+	}
+	return crc
+}
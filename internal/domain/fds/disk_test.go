@@ -0,0 +1,85 @@
+package fds_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/fds"
+)
+
+// testBlocks ディスク情報、ファイル数、ファイルヘッダー、4byteのファイルのデータ
+func testBlocks(fileData ...byte) [][]byte {
+	info := make([]byte, 56)
+	copy(info, "\x01*NINTENDO-HVC*")
+	header := make([]byte, 16)
+	header[0] = 0x03
+	copy(header[3:11], "FILE0000")
+	header[13] = byte(len(fileData))
+	return [][]byte{info, {0x02, 0x01}, header, append([]byte{0x04}, fileData...)}
+}
+
+// newFDSSide .fdsの1面(65500byte)
+func newFDSSide(blocks [][]byte) []byte {
+	side := bytes.Join(blocks, nil)
+	return append(side, make([]byte, 65500-len(side))...)
+}
+
+// newQDSide .qdの1面(65536byte)、CRCは読み込みで使わないので適当な値
+func newQDSide(blocks [][]byte) []byte {
+	var side []byte
+	for _, block := range blocks {
+		side = append(append(side, block...), 0xAA, 0xBB)
+	}
+	return append(side, make([]byte, 0x10000-len(side))...)
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestParseDisk$ github.com/sunjin110/nes_emu/internal/domain/fds
+func TestParseDisk(t *testing.T) {
+	Convey("TestParseDisk", t, func() {
+		Convey("fwNESのヘッダーがある.fdsを面ごとに読み込み、同じ形式で書き出せる", func() {
+			header := append([]byte("FDS\x1A\x02"), make([]byte, 11)...)
+			data := append(header, newFDSSide(testBlocks(1, 2, 3, 4))...)
+			data = append(data, newFDSSide(testBlocks(5, 6, 7, 8))...)
+			So(fds.IsDisk(data), ShouldBeTrue)
+
+			disk, err := fds.ParseDisk(data)
+			So(err, ShouldBeNil)
+			So(disk.Format, ShouldEqual, fds.ImageFDS)
+			So(disk.Header, ShouldBeTrue)
+			So(len(disk.Sides), ShouldEqual, 2)
+			So(disk.Sides[1][56+2+16+1], ShouldEqual, 5)
+			So(disk.Bytes(), ShouldResemble, data)
+		})
+
+		Convey(".qdはCRCを取り除いて.fdsと同じ面にする", func() {
+			qd, err := fds.ParseDisk(newQDSide(testBlocks(1, 2, 3, 4)))
+			So(err, ShouldBeNil)
+			So(qd.Format, ShouldEqual, fds.ImageQD)
+			So(qd.Sides[0], ShouldResemble, newFDSSide(testBlocks(1, 2, 3, 4)))
+
+			headerless, err := fds.ParseDisk(newFDSSide(testBlocks(1, 2, 3, 4)))
+			So(err, ShouldBeNil)
+			So(headerless.Format, ShouldEqual, fds.ImageFDS)
+			So(headerless.Header, ShouldBeFalse)
+			So(headerless.Sides, ShouldResemble, qd.Sides)
+			So(len(qd.Bytes()), ShouldEqual, 0x10000)
+		})
+
+		Convey("ディスク情報のブロックがない場合はErrInvalidDisk", func() {
+			tests := map[string][]byte{
+				"空":             {},
+				"ヘッダーのみ":        []byte("FDS\x1A"),
+				"面が短い":          newFDSSide(testBlocks(1))[:1000],
+				"2面目のディスク情報がない": append(newFDSSide(testBlocks(1)), make([]byte, 65500)...),
+			}
+			for name, data := range tests {
+				Convey(name, func() {
+					_, err := fds.ParseDisk(data)
+					So(errors.Is(err, fds.ErrInvalidDisk), ShouldBeTrue)
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,431 @@
+package fds
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// FDS ファミコンディスクシステムのRAMアダプターとディスクドライブ
+// doc: https://www.nesdev.org/wiki/Family_Computer_Disk_System
+//
+// 0x6000-0xDFFF: 32KBのPRG-RAM、0xE000-0xFFFF: BIOS(disksys.rom)、CHRは8KBのCHR-RAM
+// 0x4020, 0x4021: タイマーIRQのリロード値の下位・上位、0x4022: タイマーIRQの制御(bit0: 繰り返し, bit1: 有効化)
+// 0x4023: bit0 ディスクのレジスタ, bit1 音源のレジスタの有効化、0x4024: 書き込むデータ
+// 0x4025: bit0 モーター, bit1 転送のリセット, bit2 読み込み(1)/書き込み(0), bit3 ミラーリング(1: 水平),
+// bit4 CRCの転送, bit6 ブロックの開始, bit7 1byteの転送ごとにIRQを出す
+// 0x4030: IRQなどの状態、0x4031: 読み込んだデータ、0x4032: ドライブの状態、0x4033: 外部端子(bit7 バッテリー)
+// 0x4040-0x408A: 音源(apu.FDSAudio)
+//
+// mapper.Mapperとしてconsoleに接続する
+type FDS struct {
+	bios []byte
+	ram  [ramSize]byte
+	chr  [chrSize]byte
+
+	audio     *apu.FDSAudio
+	mirroring ppu.Mirroring
+
+	diskRegEnabled  bool
+	soundRegEnabled bool
+
+	// タイマーIRQ、CPUの1サイクルごとに減り、0になったらIRQを出してリロードする
+	irqReload  uint16
+	irqCounter uint16
+	irqRepeat  bool
+	irqEnabled bool
+	timerIRQ   bool
+
+	disk *Disk
+	// tracks 面ごとのトラック、書き込むとdirtyになる
+	tracks [][]byte
+	dirty  []bool
+	side   int // ejectedの場合は挿入されていない
+
+	motorOn        bool
+	resetTransfer  bool
+	readMode       bool
+	crcControl     bool
+	blockStart     bool // 0x4025 bit6
+	diskIRQEnabled bool
+
+	position    int
+	delay       int
+	endOfHead   bool
+	scanning    bool
+	gapEnded    bool
+	crc         uint16
+	previousCRC bool
+
+	readData         byte
+	writeData        byte
+	transferComplete bool
+	diskIRQ          bool
+}
+
+const (
+	ramSize  = 0x8000 // 32KB
+	chrSize  = 0x2000 // 8KB
+	biosSize = 0x2000 // 8KB
+
+	addrRAMStart  = 0x6000
+	addrBIOSStart = 0xE000
+
+	ejected = -1
+
+	// byteCycles 1byteの転送にかかるCPUサイクル数(96.4kHz / 8bit)
+	byteCycles = 150
+	// rewindCycles ヘッドが面の最後から先頭に戻るまでのCPUサイクル数
+	rewindCycles = 50000
+)
+
+// ErrInvalidBIOS BIOSのサイズが8KBでない
+var ErrInvalidBIOS = errors.New("FDS: invalid BIOS")
+
+var (
+	_ mapper.Mapper      = (*FDS)(nil)
+	_ mapper.AudioMapper = (*FDS)(nil)
+	_ mapper.ResetMapper = (*FDS)(nil)
+)
+
+// New biosはdisksys.rom(8KB)、ディスクの1面目を挿入した状態で始める
+func New(bios []byte, disk *Disk) (*FDS, error) {
+	if len(bios) != biosSize {
+		return nil, fmt.Errorf("%w. size must be %d. size: %d", ErrInvalidBIOS, biosSize, len(bios))
+	}
+	if len(disk.Sides) == 0 {
+		return nil, fmt.Errorf("%w. disk has no side", ErrInvalidDisk)
+	}
+	f := &FDS{
+		bios:      bios,
+		audio:     apu.NewFDSAudio(),
+		mirroring: ppu.MirroringHorizontal,
+		disk:      disk,
+		dirty:     make([]bool, len(disk.Sides)),
+		endOfHead: true,
+	}
+	for _, side := range disk.Sides {
+		f.tracks = append(f.tracks, newTrack(side))
+	}
+	return f, nil
+}
+
+// SideCount ディスクの面の数(ディスク1のA面, B面, ディスク2のA面...)
+func (f *FDS) SideCount() int {
+	return len(f.tracks)
+}
+
+// Side 挿入されている面、挿入されていない場合は-1
+func (f *FDS) Side() int {
+	return f.side
+}
+
+// Eject ディスクを取り出す、面を入れ替える時はBIOSが取り出されたことに気付くまで待ってから挿入する
+func (f *FDS) Eject() {
+	f.side = ejected
+}
+
+// Insert sideの面を挿入する
+func (f *FDS) Insert(side int) error {
+	if side < 0 || side >= len(f.tracks) {
+		return fmt.Errorf("%w. side: %d, count: %d", ErrInvalidSide, side, len(f.tracks))
+	}
+	f.side = side
+	f.endOfHead = true
+	return nil
+}
+
+// Modified ディスクに書き込まれたか
+func (f *FDS) Modified() bool {
+	for _, dirty := range f.dirty {
+		if dirty {
+			return true
+		}
+	}
+	return false
+}
+
+// Image 書き込んだ内容を反映した、読み込んだ時と同じ形式のディスクのイメージ
+func (f *FDS) Image() []byte {
+	disk := &Disk{Format: f.disk.Format, Header: f.disk.Header}
+	for i, side := range f.disk.Sides {
+		if f.dirty[i] {
+			side = trackToSide(f.tracks[i])
+		}
+		disk.Sides = append(disk.Sides, side)
+	}
+	return disk.Bytes()
+}
+
+func (f *FDS) ExpansionAudio() apu.ExpansionAudio {
+	return f.audio
+}
+
+func (f *FDS) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= addrBIOSStart:
+		return f.bios[addr-addrBIOSStart]
+	case addr >= addrRAMStart:
+		return f.ram[addr-addrRAMStart]
+	case addr >= 0x4040:
+		if value, ok := f.audio.Read(addr); ok && f.soundRegEnabled {
+			return value
+		}
+	case addr == 0x4030:
+		return f.readStatus()
+	case addr == 0x4031:
+		f.transferComplete = false
+		f.diskIRQ = false
+		return f.readData
+	case addr == 0x4032:
+		return f.readDriveStatus()
+	case addr == 0x4033:
+		// バッテリーは十分にある
+		return 0x80
+	}
+	return openBus
+}
+
+// openBus 何もつながっていないアドレスの値
+const openBus = 0xFF
+
+// readStatus bit0 タイマーIRQ, bit1 1byteの転送の完了, bit6 面の最後、読み込むとIRQを止める
+func (f *FDS) readStatus() byte {
+	var value byte
+	if f.timerIRQ {
+		value |= 0x01
+	}
+	if f.transferComplete {
+		value |= 0x02
+	}
+	if f.endOfHead {
+		value |= 0x40
+	}
+	f.transferComplete = false
+	f.timerIRQ = false
+	f.diskIRQ = false
+	return value
+}
+
+// readDriveStatus bit0 ディスクがない, bit1 読み書きできない, bit2 書き込み禁止
+func (f *FDS) readDriveStatus() byte {
+	value := byte(0x40)
+	if f.side == ejected {
+		return value | 0x07
+	}
+	if !f.scanning {
+		value |= 0x02
+	}
+	return value
+}
+
+func (f *FDS) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= addrBIOSStart:
+		// BIOSには書き込めない
+	case addr >= addrRAMStart:
+		f.ram[addr-addrRAMStart] = value
+	case addr >= 0x4040:
+		if f.soundRegEnabled {
+			f.audio.Write(addr, value)
+		}
+	case addr >= 0x4024 && addr <= 0x4026 && !f.diskRegEnabled:
+		// 0x4023のbit0が0の場合は書き込めない
+	default:
+		f.writeRegister(addr, value)
+	}
+}
+
+func (f *FDS) writeRegister(addr uint16, value byte) {
+	switch addr {
+	case 0x4020:
+		f.irqReload = f.irqReload&0xFF00 | uint16(value)
+	case 0x4021:
+		f.irqReload = f.irqReload&0x00FF | uint16(value)<<8
+	case 0x4022:
+		f.irqRepeat = value&0x01 != 0
+		f.irqEnabled = value&0x02 != 0 && f.diskRegEnabled
+		if f.irqEnabled {
+			f.irqCounter = f.irqReload
+		} else {
+			f.timerIRQ = false
+		}
+	case 0x4023:
+		f.diskRegEnabled = value&0x01 != 0
+		f.soundRegEnabled = value&0x02 != 0
+		if !f.diskRegEnabled {
+			f.irqEnabled = false
+			f.timerIRQ = false
+			f.diskIRQ = false
+		}
+	case 0x4024:
+		f.writeData = value
+		f.transferComplete = false
+		f.diskIRQ = false
+	case 0x4025:
+		f.motorOn = value&0x01 != 0
+		f.resetTransfer = value&0x02 != 0
+		f.readMode = value&0x04 != 0
+		f.mirroring = ppu.MirroringVertical
+		if value&0x08 != 0 {
+			f.mirroring = ppu.MirroringHorizontal
+		}
+		f.crcControl = value&0x10 != 0
+		f.blockStart = value&0x40 != 0
+		f.diskIRQEnabled = value&0x80 != 0
+		f.diskIRQ = false
+	}
+}
+
+func (f *FDS) ReadCHR(addr uint16) byte {
+	return f.chr[addr%chrSize]
+}
+
+func (f *FDS) WriteCHR(addr uint16, value byte) {
+	f.chr[addr%chrSize] = value
+}
+
+func (f *FDS) Mirroring() ppu.Mirroring {
+	return f.mirroring
+}
+
+func (f *FDS) IRQ() bool {
+	return f.timerIRQ || f.diskIRQ
+}
+
+func (f *FDS) PPUAddress(addr uint16) {}
+
+func (f *FDS) Scanline() {}
+
+// Reset 電源の入れ直しでRAMとレジスタを消して、ディスクの転送を止める、挿入されている面と書き込んだ内容は残す
+func (f *FDS) Reset(resetType mapper.ResetType) {
+	if resetType != mapper.ResetTypeHard {
+		return
+	}
+	*f = FDS{
+		bios:      f.bios,
+		audio:     f.audio,
+		mirroring: ppu.MirroringHorizontal,
+		disk:      f.disk,
+		tracks:    f.tracks,
+		dirty:     f.dirty,
+		side:      f.side,
+		endOfHead: true,
+	}
+}
+
+// Step タイマーIRQとディスクの転送をCPUの1サイクル分進める
+func (f *FDS) Step() {
+	f.stepTimer()
+	f.stepDisk()
+}
+
+func (f *FDS) stepTimer() {
+	if !f.irqEnabled {
+		return
+	}
+	if f.irqCounter > 0 {
+		f.irqCounter--
+		return
+	}
+	f.timerIRQ = true
+	f.irqCounter = f.irqReload
+	if !f.irqRepeat {
+		f.irqEnabled = false
+	}
+}
+
+// stepDisk モーターが回っている間、byteCyclesごとに1byte読み書きする
+// 面の最後まで来るとモーターが止まり、次にモーターを回すとrewindCycles後に先頭から読み始める
+func (f *FDS) stepDisk() {
+	if f.side == ejected || !f.motorOn {
+		f.endOfHead = true
+		f.scanning = false
+		return
+	}
+	if f.resetTransfer && !f.scanning {
+		return
+	}
+	if f.endOfHead {
+		f.delay = rewindCycles
+		f.endOfHead = false
+		f.position = 0
+		f.gapEnded = false
+		return
+	}
+	if f.delay > 0 {
+		f.delay--
+		return
+	}
+
+	f.scanning = true
+	if f.readMode {
+		f.readByte()
+	} else {
+		f.writeByte()
+	}
+	f.previousCRC = f.crcControl
+
+	f.position++
+	if f.position >= len(f.tracks[f.side]) {
+		f.motorOn = false
+		return
+	}
+	f.delay = byteCycles
+}
+
+// readByte ブロックの開始(0x4025 bit6)の後、ギャップを読み飛ばして開始マークの次のbyteから転送する
+func (f *FDS) readByte() {
+	data := f.tracks[f.side][f.position]
+	if !f.previousCRC {
+		f.crc = updateCRC(f.crc, data)
+	}
+	irq := f.diskIRQEnabled
+	switch {
+	case !f.blockStart:
+		f.gapEnded = false
+		f.crc = 0
+	case data != 0 && !f.gapEnded:
+		// 開始マークは転送しない
+		f.gapEnded = true
+		irq = false
+	}
+	if f.gapEnded {
+		f.transferComplete = true
+		f.readData = data
+		if irq {
+			f.diskIRQ = true
+		}
+	}
+}
+
+// writeByte ブロックの開始(0x4025 bit6)の前はギャップ(0)を書き込み、CRCの転送ではCRCを下位から書き込む
+func (f *FDS) writeByte() {
+	var data byte
+	if !f.crcControl {
+		f.transferComplete = true
+		data = f.writeData
+		if f.diskIRQEnabled {
+			f.diskIRQ = true
+		}
+	}
+	if !f.blockStart {
+		data = 0
+		f.crc = 0
+	}
+	if !f.crcControl {
+		f.crc = updateCRC(f.crc, data)
+	} else {
+		if !f.previousCRC {
+			f.crc = updateCRC(updateCRC(f.crc, 0), 0)
+		}
+		data = byte(f.crc)
+		f.crc >>= 8
+	}
+	f.tracks[f.side][f.position] = data
+	f.dirty[f.side] = true
+	f.gapEnded = false
+}
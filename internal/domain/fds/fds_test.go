@@ -0,0 +1,137 @@
+package fds_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/fds"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+func newFDS(sides ...[]byte) *fds.FDS {
+	bios := make([]byte, 0x2000)
+	bios[0x1FFC] = 0x34
+	disk, err := fds.ParseDisk(bytes.Join(sides, nil))
+	if err != nil {
+		panic(err)
+	}
+	f, err := fds.New(bios, disk)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// transfer 1byteの転送が終わるまで進める、終わらない場合はfalse
+func transfer(f *fds.FDS) bool {
+	for i := 0; i < 1000000; i++ {
+		f.Step()
+		if f.IRQ() {
+			return f.ReadPRG(0x4030)&0x02 != 0
+		}
+	}
+	return false
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestFDS$ github.com/sunjin110/nes_emu/internal/domain/fds
+func TestFDS(t *testing.T) {
+	Convey("TestFDS", t, func() {
+		Convey("0x6000-0xDFFFはRAM、0xE000-0xFFFFはBIOS", func() {
+			f := newFDS(newFDSSide(testBlocks(1)))
+			f.WritePRG(0x6000, 0x12)
+			f.WritePRG(0xDFFF, 0x34)
+			f.WritePRG(0xFFFC, 0x56)
+			So(f.ReadPRG(0x6000), ShouldEqual, 0x12)
+			So(f.ReadPRG(0xDFFF), ShouldEqual, 0x34)
+			So(f.ReadPRG(0xFFFC), ShouldEqual, 0x34)
+
+			_, err := fds.New(make([]byte, 0x1000), &fds.Disk{Sides: [][]byte{{}}})
+			So(errors.Is(err, fds.ErrInvalidBIOS), ShouldBeTrue)
+		})
+
+		Convey("タイマーIRQはリロード値から数えて0になったら出し、0x4030の読み込みで止まる", func() {
+			f := newFDS(newFDSSide(testBlocks(1)))
+			f.WritePRG(0x4023, 0x01)
+			f.WritePRG(0x4020, 0x02)
+			f.WritePRG(0x4021, 0x00)
+			f.WritePRG(0x4022, 0x02)
+			f.Step()
+			f.Step()
+			So(f.IRQ(), ShouldBeFalse)
+			f.Step()
+			So(f.IRQ(), ShouldBeTrue)
+			So(f.ReadPRG(0x4030)&0x01, ShouldEqual, 0x01)
+			So(f.IRQ(), ShouldBeFalse)
+
+			// 繰り返さない場合は1回で止まる
+			for i := 0; i < 10; i++ {
+				f.Step()
+			}
+			So(f.IRQ(), ShouldBeFalse)
+		})
+
+		Convey("0x4025のbit3でミラーリングを切り替え、0x4023のbit0が0の場合は書き込めない", func() {
+			f := newFDS(newFDSSide(testBlocks(1)))
+			f.WritePRG(0x4025, 0x2E)
+			So(f.Mirroring(), ShouldEqual, ppu.MirroringHorizontal)
+			f.WritePRG(0x4023, 0x01)
+			f.WritePRG(0x4025, 0x26)
+			So(f.Mirroring(), ShouldEqual, ppu.MirroringVertical)
+		})
+
+		Convey("ギャップと開始マークを読み飛ばして、ブロックを1byteずつ読み込む", func() {
+			f := newFDS(newFDSSide(testBlocks(1, 2, 3, 4)))
+			f.WritePRG(0x4023, 0x01)
+			// モーター, 読み込み, ブロックの開始, IRQ
+			f.WritePRG(0x4025, 0xE5)
+			var got []byte
+			for i := 0; i < 15; i++ {
+				So(transfer(f), ShouldBeTrue)
+				got = append(got, f.ReadPRG(0x4031))
+			}
+			So(string(got), ShouldEqual, "\x01*NINTENDO-HVC*")
+		})
+
+		Convey("ディスクの状態は0x4032、取り出すと0x4032のbit0が1になる", func() {
+			f := newFDS(newFDSSide(testBlocks(1)), newFDSSide(testBlocks(2)))
+			So(f.SideCount(), ShouldEqual, 2)
+			So(f.ReadPRG(0x4032)&0x01, ShouldEqual, 0)
+			f.Eject()
+			So(f.Side(), ShouldEqual, -1)
+			So(f.ReadPRG(0x4032)&0x07, ShouldEqual, 0x07)
+			So(f.Insert(1), ShouldBeNil)
+			So(f.Side(), ShouldEqual, 1)
+			So(errors.Is(f.Insert(2), fds.ErrInvalidSide), ShouldBeTrue)
+		})
+
+		Convey("書き込んだブロックは元の形式のイメージに反映される", func() {
+			original := newFDSSide(testBlocks(1, 2, 3, 4))
+			f := newFDS(original)
+			So(f.Modified(), ShouldBeFalse)
+			f.WritePRG(0x4023, 0x01)
+			// モーター, 書き込み, IRQ、ブロックの開始の前はギャップ(0)を書く
+			f.WritePRG(0x4025, 0xA1)
+			for i := 0; i < 28300/8; i++ {
+				So(transfer(f), ShouldBeTrue)
+			}
+			// 開始マークとディスク情報のブロック
+			f.WritePRG(0x4025, 0xE1)
+			info := append([]byte(nil), original[:56]...)
+			info[20] = 0x99
+			for _, value := range append([]byte{0x80}, info...) {
+				f.WritePRG(0x4024, value)
+				So(transfer(f), ShouldBeTrue)
+			}
+			// CRC
+			f.WritePRG(0x4025, 0xF1)
+			f.WritePRG(0x4025, 0x61)
+			So(f.Modified(), ShouldBeTrue)
+
+			want := append([]byte(nil), original...)
+			want[20] = 0x99
+			So(f.Image(), ShouldResemble, want)
+		})
+	})
+}
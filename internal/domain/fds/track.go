@@ -0,0 +1,59 @@
+package fds
+
+// ドライブが読み書きするトラック
+// doc: https://www.nesdev.org/wiki/FDS_disk_format#Physical_format
+//
+// 面の先頭は28300bitのギャップ、各ブロックは開始マーク(0x80)、ブロック、CRC(2byte)の順で、ブロックの後に976bitのギャップがある
+// ギャップは0として、1byteずつ読み書きする
+
+const (
+	blockStartMark = 0x80
+	leadInGap      = 28300 / 8
+	blockGap       = 976 / 8
+	// trackSize 最低限のトラックの長さ、ブロックが少ない面でも最後まで読んでから先頭に戻る
+	trackSize = leadInGap + fdsSideSize
+)
+
+// newTrack .fdsの形式の面にギャップ、開始マーク、CRCを入れる
+func newTrack(side []byte) []byte {
+	track := make([]byte, leadInGap, trackSize)
+	eachBlock(side, 0, func(block []byte) {
+		crc := blockCRC(block)
+		track = append(track, blockStartMark)
+		track = append(track, block...)
+		track = append(track, byte(crc), byte(crc>>8))
+		track = append(track, make([]byte, blockGap)...)
+	})
+	if len(track) < trackSize {
+		track = append(track, make([]byte, trackSize-len(track))...)
+	}
+	return track
+}
+
+// trackToSide トラックからギャップ、開始マーク、CRCを取り除いて.fdsの形式の面に戻す
+// 開始マークがない場所や不明な種類のブロックで終わる
+func trackToSide(track []byte) []byte {
+	side := make([]byte, 0, fdsSideSize)
+	var size int
+	for offset := 0; offset < len(track); {
+		if track[offset] == 0 {
+			offset++
+			continue
+		}
+		if track[offset] != blockStartMark || offset+1 >= len(track) {
+			break
+		}
+		offset++
+		length := blockLength(track[offset], size)
+		if length == 0 || offset+length > len(track) {
+			break
+		}
+		block := track[offset : offset+length]
+		if block[0] == blockFileHeader {
+			size = fileSize(block)
+		}
+		side = append(side, block...)
+		offset += length + crcSize
+	}
+	return pad(side, fdsSideSize)
+}
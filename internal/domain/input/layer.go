@@ -0,0 +1,180 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+)
+
+// players Four Scoreを含めた最大のプレイヤー数
+const players = 4
+
+// Layer frontendの入力にリマップ・ターボ・マクロを適用してからコントローラーに反映する
+//
+// Applyをフレームの開始時(コントローラーのラッチより前)に1回だけ呼ぶことで、
+// 結果がフレーム数とfrontendの入力だけで決まるようにする(ムービーの記録はApplyの後に行う)
+type Layer struct {
+	frame   int
+	players [players]playerInput
+	macros  map[string]Macro
+}
+
+type playerInput struct {
+	held    controller.ButtonState // frontendで押されているボタン(リマップ前)
+	profile Profile
+
+	turbo      [8]int                 // ボタンごとのターボの間隔(フレーム)、0の場合は無効
+	turboStart [8]int                 // ボタンを押し始めたフレーム
+	lastHeld   controller.ButtonState // 前のフレームのリマップ後のボタン
+	output     controller.ButtonState // 前のフレームにコントローラーに反映したボタン
+
+	macro     Macro
+	macroPos  int
+	recording Macro
+	isRecord  bool
+}
+
+func NewLayer() *Layer {
+	return &Layer{
+		macros: map[string]Macro{},
+	}
+}
+
+func (l *Layer) player(player controller.Player) (*playerInput, error) {
+	if player < controller.Player1 || player > controller.Player4 {
+		return nil, fmt.Errorf("Input: invalid player. player: %d", player)
+	}
+	return &l.players[player], nil
+}
+
+// SetHeld frontendで押されているボタン(リマップ前)を設定する、次のApplyで反映される
+func (l *Layer) SetHeld(player controller.Player, state controller.ButtonState) error {
+	p, err := l.player(player)
+	if err != nil {
+		return err
+	}
+	p.held = state
+	return nil
+}
+
+// SetProfile リマップの設定、nilの場合はそのまま
+func (l *Layer) SetProfile(player controller.Player, profile Profile) error {
+	p, err := l.player(player)
+	if err != nil {
+		return err
+	}
+	p.profile = profile
+	return nil
+}
+
+// SetTurbo リマップ後のbuttonsを押している間、frames毎に押す・離すを繰り返す
+// framesが0の場合はターボを無効にする
+func (l *Layer) SetTurbo(player controller.Player, buttons controller.ButtonState, frames int) error {
+	p, err := l.player(player)
+	if err != nil {
+		return err
+	}
+	if frames < 0 {
+		return fmt.Errorf("Input: invalid turbo frames. frames: %d", frames)
+	}
+	for i := range p.turbo {
+		if buttons&(1<<i) != 0 {
+			p.turbo[i] = frames
+		}
+	}
+	return nil
+}
+
+// BindMacro nameでマクロを登録する
+func (l *Layer) BindMacro(name string, macro Macro) {
+	l.macros[name] = macro
+}
+
+// PlayMacro 次のApplyからnameのマクロの入力をfrontendの入力に重ねる
+func (l *Layer) PlayMacro(player controller.Player, name string) error {
+	p, err := l.player(player)
+	if err != nil {
+		return err
+	}
+	macro, ok := l.macros[name]
+	if !ok {
+		return fmt.Errorf("Input: macro is not found. name: %s", name)
+	}
+	p.macro = macro
+	p.macroPos = 0
+	return nil
+}
+
+// StartRecording 次のApplyからコントローラーに反映した入力をマクロとして記録する
+func (l *Layer) StartRecording(player controller.Player) error {
+	p, err := l.player(player)
+	if err != nil {
+		return err
+	}
+	p.recording = nil
+	p.isRecord = true
+	return nil
+}
+
+// StopRecording 記録を終了して記録したマクロを返す
+func (l *Layer) StopRecording(player controller.Player) (Macro, error) {
+	p, err := l.player(player)
+	if err != nil {
+		return nil, err
+	}
+	macro := p.recording
+	p.recording = nil
+	p.isRecord = false
+	return macro, nil
+}
+
+// Buttons 直前のApplyでplayerに反映したボタン
+func (l *Layer) Buttons(player controller.Player) controller.ButtonState {
+	p, err := l.player(player)
+	if err != nil {
+		return 0
+	}
+	return p.output
+}
+
+// Apply 1フレーム分の入力を計算してコントローラーに反映する
+// playersはコントローラーに反映するプレイヤー数(標準コントローラー x2の場合は2)
+func (l *Layer) Apply(c *controller.Controller, players int) {
+	for i := 0; i < players && i < len(l.players); i++ {
+		p := &l.players[i]
+		state := p.buttons(l.frame)
+		if p.isRecord {
+			p.recording = append(p.recording, state)
+		}
+		c.SetButtons(controller.Player(i), state)
+	}
+	l.frame++
+}
+
+// buttons frameのボタン: リマップ -> ターボ -> マクロの順に適用する
+func (p *playerInput) buttons(frame int) controller.ButtonState {
+	held := p.profile.Apply(p.held)
+
+	state := held
+	for i, interval := range p.turbo {
+		bit := controller.ButtonState(1 << i)
+		if held&bit == 0 {
+			continue
+		}
+		if p.lastHeld&bit == 0 {
+			// 押し始めたフレームから数える
+			p.turboStart[i] = frame
+		}
+		if interval > 0 && (frame-p.turboStart[i])/interval%2 == 1 {
+			state &^= bit
+		}
+	}
+	p.lastHeld = held
+
+	if p.macroPos < len(p.macro) {
+		state |= p.macro[p.macroPos]
+		p.macroPos++
+	}
+	p.output = state
+	return state
+}
@@ -0,0 +1,88 @@
+package input_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+	"github.com/sunjin110/nes_emu/internal/domain/input"
+)
+
+// applyFrames framesフレーム分Applyして、1Pに反映されたボタンを並べる
+func applyFrames(l *input.Layer, c *controller.Controller, frames int) []controller.ButtonState {
+	var states []controller.ButtonState
+	for i := 0; i < frames; i++ {
+		l.Apply(c, 2)
+		states = append(states, c.Buttons(controller.Player1))
+	}
+	return states
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestLayer$ github.com/sunjin110/nes_emu/internal/domain/input
+func TestLayer(t *testing.T) {
+	Convey("TestLayer", t, func() {
+		c := controller.NewController()
+		l := input.NewLayer()
+		a := controller.ButtonA
+		b := controller.ButtonB
+
+		Convey("ターボは押し始めたフレームから指定したフレーム毎に押す・離すを繰り返す", func() {
+			So(l.SetTurbo(controller.Player1, a, 2), ShouldBeNil)
+			applyFrames(l, c, 3)
+			So(l.SetHeld(controller.Player1, a|b), ShouldBeNil)
+			So(applyFrames(l, c, 6), ShouldResemble, []controller.ButtonState{a | b, a | b, b, b, a | b, a | b})
+		})
+
+		Convey("リマップした後のボタンにターボが適用される", func() {
+			profile, err := input.ParseProfile("a=b, b=a+start, select=none")
+			So(err, ShouldBeNil)
+			So(l.SetProfile(controller.Player1, profile), ShouldBeNil)
+			So(l.SetTurbo(controller.Player1, controller.ButtonStart, 1), ShouldBeNil)
+			So(l.SetHeld(controller.Player1, b|controller.ButtonSelect|controller.ButtonUp), ShouldBeNil)
+			So(applyFrames(l, c, 2), ShouldResemble, []controller.ButtonState{
+				a | controller.ButtonStart | controller.ButtonUp,
+				a | controller.ButtonUp,
+			})
+
+			_, err = input.ParseProfile("a+b=start")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("記録したマクロを再生すると同じ入力になり、frontendの入力と重なる", func() {
+			So(l.StartRecording(controller.Player1), ShouldBeNil)
+			So(l.SetHeld(controller.Player1, controller.ButtonRight), ShouldBeNil)
+			applyFrames(l, c, 2)
+			So(l.SetHeld(controller.Player1, controller.ButtonRight|a), ShouldBeNil)
+			applyFrames(l, c, 1)
+			macro, err := l.StopRecording(controller.Player1)
+			So(err, ShouldBeNil)
+
+			var buf bytes.Buffer
+			So(macro.Write(&buf), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "2 right\n1 a+right\n")
+			parsed, err := input.ParseMacro(strings.NewReader(buf.String()))
+			So(err, ShouldBeNil)
+			So(parsed, ShouldResemble, macro)
+
+			l.BindMacro("dash", parsed)
+			So(l.SetHeld(controller.Player1, b), ShouldBeNil)
+			So(l.PlayMacro(controller.Player1, "dash"), ShouldBeNil)
+			So(applyFrames(l, c, 4), ShouldResemble, []controller.ButtonState{
+				b | controller.ButtonRight, b | controller.ButtonRight, b | controller.ButtonRight | a, b,
+			})
+			So(l.PlayMacro(controller.Player1, "unknown"), ShouldNotBeNil)
+		})
+
+		Convey("Four Scoreを接続している場合は3Pのリマップしたボタンもコントローラーに反映される", func() {
+			So(c.ApplyInputConfig(controller.InputConfig{Port1: controller.DeviceFourScore, Port2: controller.DeviceFourScore}), ShouldBeNil)
+			profile, err := input.ParseProfile("a=b")
+			So(err, ShouldBeNil)
+			So(l.SetProfile(controller.Player3, profile), ShouldBeNil)
+			So(l.SetHeld(controller.Player3, a|controller.ButtonUp), ShouldBeNil)
+			l.Apply(c, c.Players())
+			So(c.Buttons(controller.Player3), ShouldEqual, b|controller.ButtonUp)
+		})
+	})
+}
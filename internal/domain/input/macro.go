@@ -0,0 +1,67 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+)
+
+// Macro 1フレームに1つのボタンの状態を並べた入力の列
+type Macro []controller.ButtonState
+
+// ParseMacro 1行に"<フレーム数> <ボタン>"を書いたマクロを読み込む、#以降はコメント
+//
+//	10 right
+//	2 right+a
+//	30 none
+func ParseMacro(r io.Reader) (Macro, error) {
+	var macro Macro
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Input: invalid macro. line: %d", lineNo)
+		}
+		frames, err := strconv.Atoi(fields[0])
+		if err != nil || frames <= 0 {
+			return nil, fmt.Errorf("Input: invalid macro frames. line: %d, frames: %s", lineNo, fields[0])
+		}
+		buttons, err := controller.ParseButtons(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("Input: invalid macro buttons. line: %d, err: %w", lineNo, err)
+		}
+		for i := 0; i < frames; i++ {
+			macro = append(macro, buttons)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Input: failed read macro. err: %w", err)
+	}
+	return macro, nil
+}
+
+// Write ParseMacroで読み込める形式で書き出す、同じボタンが続くフレームは1行にまとめる
+func (m Macro) Write(w io.Writer) error {
+	for i := 0; i < len(m); {
+		j := i
+		for j < len(m) && m[j] == m[i] {
+			j++
+		}
+		if _, err := fmt.Fprintf(w, "%d %s\n", j-i, m[i]); err != nil {
+			return fmt.Errorf("Input: failed write macro. err: %w", err)
+		}
+		i = j
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+)
+
+// Profile リマップの設定、frontendのボタン(1bit)から実際に押すボタンへの対応
+// 設定されていないボタンはそのまま
+type Profile map[controller.ButtonState]controller.ButtonState
+
+// ParseProfile "a=b,b=a,select=none"のような設定を読み込む
+func ParseProfile(value string) (Profile, error) {
+	profile := Profile{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("Input: invalid profile. item: %s", item)
+		}
+		fromButton, err := controller.ParseButtons(from)
+		if err != nil {
+			return nil, fmt.Errorf("Input: invalid profile. item: %s, err: %w", item, err)
+		}
+		if fromButton == 0 || fromButton&(fromButton-1) != 0 {
+			return nil, fmt.Errorf("Input: profile must map a single button. item: %s", item)
+		}
+		toButtons, err := controller.ParseButtons(to)
+		if err != nil {
+			return nil, fmt.Errorf("Input: invalid profile. item: %s, err: %w", item, err)
+		}
+		profile[fromButton] = toButtons
+	}
+	return profile, nil
+}
+
+// Apply frontendのボタンをリマップする
+func (p Profile) Apply(state controller.ButtonState) controller.ButtonState {
+	if p == nil {
+		return state
+	}
+	var mapped controller.ButtonState
+	for i := 0; i < 8; i++ {
+		bit := controller.ButtonState(1 << i)
+		if state&bit == 0 {
+			continue
+		}
+		if to, ok := p[bit]; ok {
+			mapped |= to
+		} else {
+			mapped |= bit
+		}
+	}
+	return mapped
+}
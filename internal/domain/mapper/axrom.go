@@ -0,0 +1,67 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/ppu"
+
+func init() {
+	register(7, "AxROM", newAxROM)
+}
+
+// AxROM mapper 7、0x8000-0xFFFFの32KBを切り替えて、bit4で1画面ミラーリングのページを選ぶ
+// doc: https://www.nesdev.org/wiki/AxROM
+//
+// バス競合があるのはAMROMだけで、ANROM/AOROM向けのゲームは競合を気にせずに書き込むため、submapperで指定された時だけ再現する
+type AxROM struct {
+	base
+	busConflicts bool
+	prgBank      int
+}
+
+const axromPRGBankSize = 0x8000 // 32KB
+
+func newAxROM(config Config) (Mapper, error) {
+	m := &AxROM{
+		base:         newBase(config, 0),
+		busConflicts: hasBusConflicts(config, false),
+	}
+	m.Reset(ResetTypeHard)
+	return m, nil
+}
+
+// Reset 電源を入れた時はバンク0で、1画面ミラーリングの下側のページ
+func (m *AxROM) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		m.prgBank = 0
+		m.mirroring = ppu.MirroringSingleScreenLower
+	}
+}
+
+func (m *AxROM) ReadPRG(addr uint16) byte {
+	if addr < addrPRGROMStart {
+		return openBus
+	}
+	return m.readPRGBank(axromPRGBankSize, m.prgBank, addr)
+}
+
+func (m *AxROM) WritePRG(addr uint16, value byte) {
+	if addr < addrPRGROMStart {
+		return
+	}
+	if m.busConflicts {
+		value &= m.ReadPRG(addr)
+	}
+	m.prgBank = int(value & 0x07)
+	if value&0x10 != 0 {
+		m.mirroring = ppu.MirroringSingleScreenUpper
+	} else {
+		m.mirroring = ppu.MirroringSingleScreenLower
+	}
+}
+
+func (m *AxROM) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(chrSize, 0, addr)
+}
+
+func (m *AxROM) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(chrSize, 0, addr, value)
+}
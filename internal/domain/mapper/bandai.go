@@ -0,0 +1,213 @@
+package mapper
+
+func init() {
+	register(16, "Bandai FCG", newBandaiFCG)
+	register(153, "Bandai LZ93D50 with SRAM", newBandaiFCG)
+	register(159, "Bandai LZ93D50 with 24C01", newBandaiFCG)
+}
+
+// BandaiFCG mapper 16, 153, 159 (FCG-1/2, LZ93D50)
+// doc: https://www.nesdev.org/wiki/Bandai_FCG_board
+//
+// レジスタはaddrの下位4bitで選ぶ、FCG-1/2は0x6000-0x7FFF、LZ93D50は0x8000-0xFFFF
+// 0x0-0x7: 1KBのCHRバンク、0x8: 0x8000の16KBのPRGバンク(0xC000は最後のバンクに固定)、0x9: ミラーリング
+// 0xA: IRQの有効化、0xB, 0xC: IRQのカウンター(LZ93D50はラッチ)の下位・上位、0xD: EEPROMのSCL(bit5), SDA(bit6)
+//
+// mapper 16はsubmapper 4がFCG-1/2、5がLZ93D50と24C02、submapperがない場合は両方のアドレスでレジスタを受け付ける
+// mapper 153はCHRバンクのbit0で256KBの上位のPRGバンクを選び、0xDのbit5でPRG-RAMを有効にする
+// mapper 159は24C01を使う
+type BandaiFCG struct {
+	base
+
+	fcg     bool // 0x6000-0x7FFFのレジスタ
+	lz93d50 bool // 0x8000-0xFFFFのレジスタ
+	sram    bool // mapper 153
+
+	chrBanks [8]int
+	prgBank  int
+
+	irqEnabled bool
+	irqCounter uint16
+	irqLatch   uint16
+	irq        bool
+
+	eeprom       *eeprom
+	prgRAMEnable bool
+}
+
+const (
+	bandaiPRGBankSize   = 0x4000 // 16KB
+	bandaiCHRBankSize   = 0x0400 // 1KB
+	bandaiOuterPRGBanks = 16     // mapper 153の上位バンク(256KB)あたりの16KBのバンクの数
+
+	bandaiSubmapperFCG     = 4
+	bandaiSubmapperLZ93D50 = 5
+
+	bandaiEEPROMSCL    = 0x20
+	bandaiEEPROMSDA    = 0x40
+	bandaiPRGRAMEnable = 0x20
+	// bandaiEEPROMOut 0x6000-0x7FFFの読み込みでEEPROMのSDAが出るbit
+	bandaiEEPROMOut = 0x10
+)
+
+func newBandaiFCG(config Config) (Mapper, error) {
+	m := &BandaiFCG{fcg: true, lz93d50: true}
+	switch config.MapperNo {
+	case 153:
+		m.fcg = false
+		m.sram = true
+		m.base = newBase(config, defaultPRGRAM)
+		return m, nil
+	case 159:
+		m.fcg = false
+		m.eeprom = newEEPROM24C01()
+	default:
+		switch config.Submapper {
+		case bandaiSubmapperFCG:
+			m.lz93d50 = false
+		case bandaiSubmapperLZ93D50:
+			m.fcg = false
+			m.eeprom = newEEPROM24C02()
+		default:
+			m.eeprom = newEEPROM24C02()
+		}
+	}
+	m.base = newBase(config, 0)
+	return m, nil
+}
+
+// Reset 電源の入れ直しでバンクとIRQのレジスタを0に戻して、EEPROMの通信を止める(EEPROMの内容は残す)
+func (m *BandaiFCG) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType != ResetTypeHard {
+		return
+	}
+	*m = BandaiFCG{base: m.base, fcg: m.fcg, lz93d50: m.lz93d50, sram: m.sram, eeprom: m.eeprom}
+	if m.eeprom != nil {
+		m.eeprom.reset()
+	}
+}
+
+func (m *BandaiFCG) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= 0xC000:
+		return m.readPRGBank(bandaiPRGBankSize, m.outerPRGBank()|(bandaiOuterPRGBanks-1), addr)
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(bandaiPRGBankSize, m.outerPRGBank()|m.prgBank, addr)
+	case addr >= addrPRGRAMStart:
+		if m.sram {
+			if !m.prgRAMEnable {
+				return openBus
+			}
+			return m.readPRGRAM(addr)
+		}
+		if m.eeprom != nil && m.eeprom.read() {
+			return openBus
+		}
+		return openBus &^ bandaiEEPROMOut
+	}
+	return openBus
+}
+
+// SaveRAM EEPROMがある基板はEEPROMの内容を保存する
+func (m *BandaiFCG) SaveRAM() []byte {
+	if m.eeprom != nil {
+		return m.eeprom.data
+	}
+	return m.prgRAM
+}
+
+// outerPRGBank mapper 153の上位のPRGバンク、CHRバンクの0-3のbit0
+func (m *BandaiFCG) outerPRGBank() int {
+	if !m.sram {
+		return 0
+	}
+	var outer int
+	for _, bank := range m.chrBanks[:4] {
+		outer |= bank & 0x01
+	}
+	return outer * bandaiOuterPRGBanks
+}
+
+func (m *BandaiFCG) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= addrPRGROMStart:
+		if m.lz93d50 {
+			m.writeRegister(addr, value, true)
+		}
+	case addr >= addrPRGRAMStart:
+		if m.fcg {
+			m.writeRegister(addr, value, false)
+			return
+		}
+		if m.sram && m.prgRAMEnable {
+			m.writePRGRAM(addr, value)
+		}
+	}
+}
+
+// writeRegister latchがtrueの場合(LZ93D50)は0xB, 0xCでラッチに書き込み、0xAでカウンターにコピーする
+func (m *BandaiFCG) writeRegister(addr uint16, value byte, latch bool) {
+	switch reg := addr & 0x0F; {
+	case reg < 8:
+		m.chrBanks[reg] = int(value)
+	case reg == 0x8:
+		m.prgBank = int(value & 0x0F)
+	case reg == 0x9:
+		m.mirroring = vrcMirroring(value)
+	case reg == 0xA:
+		m.irqEnabled = value&0x01 != 0
+		m.irq = false
+		if latch {
+			m.irqCounter = m.irqLatch
+		}
+	case reg == 0xB, reg == 0xC:
+		target := &m.irqCounter
+		if latch {
+			target = &m.irqLatch
+		}
+		if reg == 0xB {
+			*target = *target&0xFF00 | uint16(value)
+		} else {
+			*target = *target&0x00FF | uint16(value)<<8
+		}
+	case reg == 0xD:
+		if m.sram {
+			m.prgRAMEnable = value&bandaiPRGRAMEnable != 0
+		}
+		if m.eeprom != nil {
+			m.eeprom.write(value&bandaiEEPROMSCL != 0, value&bandaiEEPROMSDA != 0)
+		}
+	}
+}
+
+func (m *BandaiFCG) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(bandaiCHRBankSize, m.chrBankNo(addr), addr)
+}
+
+func (m *BandaiFCG) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(bandaiCHRBankSize, m.chrBankNo(addr), addr, value)
+}
+
+// chrBankNo mapper 153はCHR-RAMでバンクを切り替えない
+func (m *BandaiFCG) chrBankNo(addr uint16) int {
+	if m.sram {
+		return int(addr / bandaiCHRBankSize)
+	}
+	return m.chrBanks[addr/bandaiCHRBankSize]
+}
+
+func (m *BandaiFCG) IRQ() bool {
+	return m.irq
+}
+
+// Step カウンターはCPUの1サイクルごとに減り、減らす前に0ならIRQを出す
+func (m *BandaiFCG) Step() {
+	if !m.irqEnabled {
+		return
+	}
+	if m.irqCounter == 0 {
+		m.irq = true
+	}
+	m.irqCounter--
+}
@@ -0,0 +1,194 @@
+package mapper_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+)
+
+// i2c BandaiのLZ93D50の0x800DでEEPROMのSCLとSDAを操作する
+type i2c struct {
+	m mapper.Mapper
+	// lsbFirst X24C01はLSBから送る
+	lsbFirst bool
+}
+
+func (c *i2c) set(scl, sda int) {
+	c.m.WritePRG(0x800D, byte(scl<<5|sda<<6))
+}
+
+// sda EEPROMが出力しているSDA(0x6000のbit4)
+func (c *i2c) sda() int {
+	return int(c.m.ReadPRG(0x6000)>>4) & 0x01
+}
+
+func (c *i2c) start() {
+	c.set(0, 1)
+	c.set(1, 1)
+	c.set(1, 0)
+	c.set(0, 0)
+}
+
+func (c *i2c) stop() {
+	c.set(0, 0)
+	c.set(1, 0)
+	c.set(1, 1)
+}
+
+// send 1byte送ってEEPROMがACKを返したか
+func (c *i2c) send(value byte) bool {
+	for i := 0; i < 8; i++ {
+		bit := int(value>>(7-i)) & 0x01
+		if c.lsbFirst {
+			bit = int(value>>i) & 0x01
+		}
+		c.set(0, bit)
+		c.set(1, bit)
+		c.set(0, bit)
+	}
+	c.set(0, 1)
+	ack := c.sda() == 0
+	c.set(1, 1)
+	c.set(0, 1)
+	return ack
+}
+
+// receive 1byte受け取って、ackがfalseの場合はNACKを返す
+func (c *i2c) receive(ack bool) byte {
+	var value byte
+	for i := 0; i < 8; i++ {
+		bit := byte(c.sda())
+		if c.lsbFirst {
+			value |= bit << i
+		} else {
+			value = value<<1 | bit
+		}
+		c.set(1, 1)
+		c.set(0, 1)
+	}
+	sda := 1
+	if ack {
+		sda = 0
+	}
+	c.set(0, sda)
+	c.set(1, sda)
+	c.set(0, sda)
+	return value
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestBandaiFCG$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestBandaiFCG(t *testing.T) {
+	Convey("TestBandaiFCG", t, func() {
+		newBandai := func(mapperNo, submapper, prgSize int) mapper.Mapper {
+			config := mapper.Config{
+				MapperNo:  mapperNo,
+				Submapper: submapper,
+				PRG:       newBankedROM(prgSize, 0x4000),
+			}
+			if mapperNo != 153 {
+				config.CHR = newBankedROM(0x40000, 0x0400)
+			}
+			m, err := mapper.New(config)
+			So(err, ShouldBeNil)
+			return m
+		}
+
+		Convey("LZ93D50は0x8000、FCG-1/2は0x6000でレジスタを書き込む", func() {
+			m := newBandai(16, 5, 0x40000)
+			So(m.ReadPRG(0xC000), ShouldEqual, 15)
+			m.WritePRG(0x8008, 3)
+			m.WritePRG(0x8003, 0x42)
+			So(m.ReadPRG(0x8000), ShouldEqual, 3)
+			So(m.ReadCHR(0x0C00), ShouldEqual, 0x42)
+			m.WritePRG(0x6008, 4)
+			So(m.ReadPRG(0x8000), ShouldEqual, 3)
+
+			m = newBandai(16, 4, 0x40000)
+			m.WritePRG(0x6008, 4)
+			m.WritePRG(0x8008, 5)
+			So(m.ReadPRG(0x8000), ShouldEqual, 4)
+		})
+
+		Convey("IRQのカウンターはCPUの1サイクルごとに減り、LZ93D50は0xAの書き込みでラッチからコピーする", func() {
+			m := newBandai(16, 5, 0x40000)
+			m.WritePRG(0x800B, 0x03)
+			m.WritePRG(0x800C, 0x00)
+			m.WritePRG(0x800A, 0x01)
+			for i := 0; i < 3; i++ {
+				m.Step()
+			}
+			So(m.IRQ(), ShouldBeFalse)
+			m.Step()
+			So(m.IRQ(), ShouldBeTrue)
+			m.WritePRG(0x800A, 0x00)
+			So(m.IRQ(), ShouldBeFalse)
+
+			// FCG-1/2はカウンターに直接書き込む
+			m = newBandai(16, 4, 0x40000)
+			m.WritePRG(0x600A, 0x01)
+			m.WritePRG(0x600B, 0x00)
+			m.Step()
+			So(m.IRQ(), ShouldBeTrue)
+		})
+
+		Convey("24C02にワードアドレスを指定して書き込み、読み込める", func() {
+			m := newBandai(16, 5, 0x40000)
+			c := &i2c{m: m}
+			c.start()
+			So(c.send(0xA0), ShouldBeTrue)
+			So(c.send(0x10), ShouldBeTrue)
+			So(c.send(0x5A), ShouldBeTrue)
+			So(c.send(0xC3), ShouldBeTrue)
+			c.stop()
+
+			c.start()
+			So(c.send(0xA0), ShouldBeTrue)
+			So(c.send(0x10), ShouldBeTrue)
+			c.start()
+			So(c.send(0xA1), ShouldBeTrue)
+			So(c.receive(true), ShouldEqual, 0x5A)
+			So(c.receive(false), ShouldEqual, 0xC3)
+			c.stop()
+
+			// デバイスアドレスが違う場合は応答しない
+			c.start()
+			So(c.send(0x50), ShouldBeFalse)
+			c.stop()
+		})
+
+		Convey("X24C01(mapper 159)はアドレスとR/WをLSBから送る", func() {
+			m := newBandai(159, 0, 0x40000)
+			c := &i2c{m: m, lsbFirst: true}
+			c.start()
+			So(c.send(0x05), ShouldBeTrue)
+			So(c.send(0x81), ShouldBeTrue)
+			c.stop()
+
+			c.start()
+			So(c.send(0x80|0x05), ShouldBeTrue)
+			So(c.receive(false), ShouldEqual, 0x81)
+			c.stop()
+		})
+
+		Convey("mapper 153はCHRバンクのbit0で256KBの上位のPRGバンクを選び、0xDのbit5でPRG-RAMを有効にする", func() {
+			m := newBandai(153, 0, 0x80000)
+			for i := uint16(0); i < 4; i++ {
+				m.WritePRG(0x8000+i, 0x01)
+			}
+			m.WritePRG(0x8008, 0x02)
+			So(m.ReadPRG(0x8000), ShouldEqual, 18)
+			So(m.ReadPRG(0xC000), ShouldEqual, 31)
+
+			m.WritePRG(0x6000, 0x12)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0xFF)
+			m.WritePRG(0x800D, 0x20)
+			m.WritePRG(0x6000, 0x12)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x12)
+
+			// CHR-RAM
+			m.WriteCHR(0x1234, 0x56)
+			So(m.ReadCHR(0x1234), ShouldEqual, 0x56)
+		})
+	})
+}
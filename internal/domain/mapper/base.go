@@ -0,0 +1,166 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/ppu"
+
+const (
+	chrSize       = 0x2000 // 8KB
+	defaultPRGRAM = 0x2000 // 8KB (0x6000-0x7FFF)
+
+	addrPRGRAMStart = 0x6000
+	addrPRGRAMEnd   = 0x7FFF
+	addrPRGROMStart = 0x8000
+
+	// openBus 何もつながっていないアドレスを読み込んだ時の値(直前のバスの値の代わり)
+	openBus = 0xFF
+)
+
+// base 各mapperで共通の部分、IRQなどを使わないmapperはこのままで良い
+type base struct {
+	prg         []byte
+	chr         []byte
+	chrWritable bool // CHR-RAM
+	prgRAM      []byte
+	// nvramSize prgRAMの後ろ側のバッテリーバックアップされるサイズ
+	nvramSize int
+	battery   bool
+	mirroring ppu.Mirroring
+	// headerMirroring 電源を入れた時のミラーリング
+	headerMirroring ppu.Mirroring
+}
+
+// newBase prgRAMSizeはconfigで指定されていない場合のPRG-RAMのサイズ(0の場合はPRG-RAMなし)
+func newBase(config Config, prgRAMSize int) base {
+	b := base{
+		prg:             config.PRG,
+		chr:             config.CHR,
+		battery:         config.Battery,
+		mirroring:       config.Mirroring,
+		headerMirroring: config.Mirroring,
+	}
+	if len(b.chr) == 0 {
+		size := config.CHRRAMSize
+		if size == 0 {
+			size = chrSize
+		}
+		b.chr = make([]byte, size)
+		b.chrWritable = true
+	}
+	if config.PRGRAMSize > 0 {
+		prgRAMSize = config.PRGRAMSize
+	}
+	if prgRAMSize > 0 {
+		b.prgRAM = make([]byte, prgRAMSize)
+	}
+	b.nvramSize = len(b.prgRAM)
+	if config.PRGNVRAMSize > 0 && config.PRGNVRAMSize < len(b.prgRAM) {
+		b.nvramSize = config.PRGNVRAMSize
+	}
+	return b
+}
+
+func (b *base) Mirroring() ppu.Mirroring {
+	return b.mirroring
+}
+
+func (*base) IRQ() bool {
+	return false
+}
+
+// Reset リセットボタンでは変わらない
+// 電源の入れ直しではミラーリングをヘッダーの値に戻して、バッテリーバックアップされていないPRG-RAMを消す
+func (b *base) Reset(resetType ResetType) {
+	if resetType != ResetTypeHard {
+		return
+	}
+	b.mirroring = b.headerMirroring
+	clear(b.volatilePRGRAM())
+}
+
+// volatilePRGRAM PRG-RAMのうちバッテリーバックアップされていない前側
+func (b *base) volatilePRGRAM() []byte {
+	if !b.battery {
+		return b.prgRAM
+	}
+	return b.prgRAM[:len(b.prgRAM)-b.nvramSize]
+}
+
+func (*base) Step() {}
+
+func (*base) PPUAddress(addr uint16) {}
+
+func (*base) Scanline() {}
+
+// readPRGBank bankSize単位のbank番目のバンクのoffsetを読み込む、バンク番号はROMのサイズでミラーリングする
+func (b *base) readPRGBank(bankSize int, bank int, offset uint16) byte {
+	return b.prg[bankOffset(len(b.prg), bankSize, bank, offset)]
+}
+
+func (b *base) readCHRBank(bankSize int, bank int, offset uint16) byte {
+	return b.chr[bankOffset(len(b.chr), bankSize, bank, offset)]
+}
+
+func (b *base) writeCHRBank(bankSize int, bank int, offset uint16, value byte) {
+	if !b.chrWritable {
+		return
+	}
+	b.chr[bankOffset(len(b.chr), bankSize, bank, offset)] = value
+}
+
+// bankOffset バンクのoffsetのdataの中の位置
+// dataがbankSizeより小さい場合やbankSizeの倍数でない場合も、dataの中でミラーリングしてdataの外を指さないようにする
+func bankOffset(dataSize int, bankSize int, bank int, offset uint16) int {
+	return (bankIndex(dataSize, bankSize, bank) + int(offset)%bankSize) % dataSize
+}
+
+// bankIndex バンクの先頭の位置、dataがbankSizeより小さい場合はdataの中でミラーリングする
+func bankIndex(dataSize int, bankSize int, bank int) int {
+	if dataSize <= bankSize {
+		return 0
+	}
+	count := dataSize / bankSize
+	bank %= count
+	if bank < 0 {
+		bank += count
+	}
+	return bank * bankSize
+}
+
+// readPRGRAM PRG-RAMがない場合はopen bus
+func (b *base) readPRGRAM(addr uint16) byte {
+	if len(b.prgRAM) == 0 {
+		return openBus
+	}
+	return b.prgRAM[int(addr-addrPRGRAMStart)%len(b.prgRAM)]
+}
+
+func (b *base) writePRGRAM(addr uint16, value byte) {
+	if len(b.prgRAM) == 0 {
+		return
+	}
+	b.prgRAM[int(addr-addrPRGRAMStart)%len(b.prgRAM)] = value
+}
+
+// PRGRAM PRG-RAMの全体(バンク切り替えされるRAMは0番目のバンクから)、PRG-RAMがない場合はnil
+func (b *base) PRGRAM() []byte {
+	return b.prgRAM
+}
+
+// SaveRAM バッテリーバックアップされるのは基本的にPRG-RAM、揮発性のRAMもある基板は後ろ側の不揮発性のRAMだけ
+func (b *base) SaveRAM() []byte {
+	if len(b.prgRAM) == 0 {
+		return nil
+	}
+	return b.prgRAM[len(b.prgRAM)-b.nvramSize:]
+}
+
+// hasBusConflicts ROMへの書き込みでバス競合が起きる基板か、NES 2.0のsubmapperがない場合はdefaultValue
+// doc: https://www.nesdev.org/wiki/Bus_conflict
+func hasBusConflicts(config Config, defaultValue bool) bool {
+	switch config.Submapper {
+	case 1:
+		return false
+	case 2:
+		return true
+	}
+	return defaultValue
+}
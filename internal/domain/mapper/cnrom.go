@@ -0,0 +1,58 @@
+package mapper
+
+func init() {
+	register(3, "CNROM", newCNROM)
+}
+
+// CNROM mapper 3、PRG-ROMはNROMと同じで、CHR-ROMの8KBを切り替える
+// doc: https://www.nesdev.org/wiki/CNROM
+type CNROM struct {
+	base
+	busConflicts bool
+	chrBank      int
+}
+
+func newCNROM(config Config) (Mapper, error) {
+	return &CNROM{
+		base:         newBase(config, 0),
+		busConflicts: hasBusConflicts(config, true),
+	}, nil
+}
+
+// Reset 電源の入れ直しでCHRのバンクを0に戻す
+func (m *CNROM) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		m.chrBank = 0
+	}
+}
+
+func (m *CNROM) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= addrPRGROMStart:
+		return m.prg[int(addr-addrPRGROMStart)%len(m.prg)]
+	case addr >= addrPRGRAMStart:
+		return m.readPRGRAM(addr)
+	}
+	return openBus
+}
+
+func (m *CNROM) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= addrPRGROMStart:
+		if m.busConflicts {
+			value &= m.ReadPRG(addr)
+		}
+		m.chrBank = int(value)
+	case addr >= addrPRGRAMStart:
+		m.writePRGRAM(addr, value)
+	}
+}
+
+func (m *CNROM) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(chrSize, m.chrBank, addr)
+}
+
+func (m *CNROM) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(chrSize, m.chrBank, addr, value)
+}
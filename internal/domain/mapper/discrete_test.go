@@ -0,0 +1,83 @@
+package mapper_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestDiscreteMapper$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestDiscreteMapper(t *testing.T) {
+	Convey("TestDiscreteMapper", t, func() {
+		newMapper := func(config mapper.Config) mapper.Mapper {
+			m, err := mapper.New(config)
+			So(err, ShouldBeNil)
+			return m
+		}
+
+		Convey("UxROMは0x8000のバンクを切り替えて、0xC000は最後のバンクに固定される", func() {
+			m := newMapper(mapper.Config{MapperNo: 2, Submapper: 1, PRG: newBankedROM(0x20000, 0x4000)})
+			So(m.ReadPRG(0xC000), ShouldEqual, 7)
+
+			m.WritePRG(0x8000, 5)
+			So(m.ReadPRG(0x8000), ShouldEqual, 5)
+			So(m.ReadPRG(0xFFFF), ShouldEqual, 7)
+
+			// CHR-RAM
+			m.WriteCHR(0x0100, 0xAB)
+			So(m.ReadCHR(0x0100), ShouldEqual, 0xAB)
+		})
+
+		Convey("UxROMのバス競合では書き込んだ値とROMの値のANDになる", func() {
+			m := newMapper(mapper.Config{MapperNo: 2, PRG: newBankedROM(0x20000, 0x4000)})
+			// 0xC000のROMの値は7
+			m.WritePRG(0xC000, 0x0E)
+			So(m.ReadPRG(0x8000), ShouldEqual, 6)
+		})
+
+		Convey("CNROMはCHR-ROMの8KBを切り替える", func() {
+			// バス競合で書き込んだ値がそのまま残るようにPRG-ROMは0xFFで埋める
+			prg := bytes.Repeat([]byte{0xFF}, 0x8000)
+			m := newMapper(mapper.Config{MapperNo: 3, PRG: prg, CHR: newBankedROM(0x8000, 0x2000)})
+			So(m.ReadCHR(0x1FFF), ShouldEqual, 0)
+
+			m.WritePRG(0x8000, 3)
+			So(m.ReadCHR(0x0000), ShouldEqual, 3)
+			So(m.ReadCHR(0x1FFF), ShouldEqual, 3)
+			So(m.ReadPRG(0x8000), ShouldEqual, 0xFF)
+		})
+
+		Convey("AxROMは32KBを切り替えて、bit4で1画面ミラーリングのページを選ぶ", func() {
+			m := newMapper(mapper.Config{MapperNo: 7, PRG: newBankedROM(0x40000, 0x8000)})
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringSingleScreenLower)
+
+			m.WritePRG(0x8000, 0x13)
+			So(m.ReadPRG(0x8000), ShouldEqual, 3)
+			So(m.ReadPRG(0xFFFF), ShouldEqual, 3)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringSingleScreenUpper)
+
+			// ANROM/AOROMはバス競合がない
+			m.WritePRG(0x8000, 0x05)
+			So(m.ReadPRG(0x8000), ShouldEqual, 5)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringSingleScreenLower)
+		})
+
+		Convey("AMROM(submapper 2)はバス競合がある", func() {
+			m := newMapper(mapper.Config{MapperNo: 7, Submapper: 2, PRG: newBankedROM(0x40000, 0x8000)})
+			m.WritePRG(0x8000, 0x13)
+			So(m.ReadPRG(0x8000), ShouldEqual, 0)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringSingleScreenLower)
+		})
+
+		Convey("GxROMはbit4, 5でPRG-ROM、bit0, 1でCHR-ROMを切り替える", func() {
+			m := newMapper(mapper.Config{MapperNo: 66, Submapper: 1, PRG: newBankedROM(0x20000, 0x8000), CHR: newBankedROM(0x8000, 0x2000)})
+
+			m.WritePRG(0x8000, 0x32)
+			So(m.ReadPRG(0x8000), ShouldEqual, 3)
+			So(m.ReadCHR(0x0000), ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,190 @@
+package mapper
+
+// eeprom BandaiのFCGボードのシリアルEEPROM(24C01, 24C02)
+// doc: https://www.nesdev.org/wiki/Bandai_FCG_board#Serial_EEPROM
+//
+// SCLがHighの間にSDAがHighからLowになるとstart、LowからHighになるとstop
+// SCLの立ち上がりでSDAの1bitを受け取り、8bit受け取ったら次のSCLのLowの間にSDAをLowにして応答(ACK)する
+//
+// 24C02(256byte)は標準のI2Cで、デバイスアドレス(1010xxxR)、ワードアドレス、データの順にMSBから送る
+// X24C01(128byte)はデバイスアドレスがなく、7bitのアドレスとR/Wを1byteにしてLSBから送る
+type eeprom struct {
+	data []byte
+	// x24c01 X24C01の手順で読み書きする
+	x24c01 bool
+	// pageSize 連続して書き込む時にアドレスが一周する単位
+	pageSize int
+
+	mode     eepromMode
+	nextMode eepromMode
+	bit      int // 0-7: データのbit, 8: ACKのbit
+	buffer   byte
+	addr     int
+
+	scl bool
+	sda bool
+	out bool // EEPROMが出力しているSDA
+}
+
+type eepromMode int
+
+const (
+	eepromIdle eepromMode = iota
+	eepromDeviceAddress
+	eepromWordAddress
+	eepromWrite
+	eepromRead
+)
+
+const (
+	eeprom24C01Size = 0x80
+	eeprom24C02Size = 0x100
+)
+
+func newEEPROM24C01() *eeprom {
+	return &eeprom{data: make([]byte, eeprom24C01Size), x24c01: true, pageSize: 4, out: true}
+}
+
+// reset 通信の途中の状態を消す、dataはそのまま
+func (e *eeprom) reset() {
+	*e = eeprom{data: e.data, x24c01: e.x24c01, pageSize: e.pageSize, out: true}
+}
+
+func newEEPROM24C02() *eeprom {
+	return &eeprom{data: make([]byte, eeprom24C02Size), pageSize: 8, out: true}
+}
+
+// write SCLとSDAの状態を変える
+func (e *eeprom) write(scl, sda bool) {
+	switch {
+	case e.scl && scl && e.sda && !sda:
+		e.start()
+	case e.scl && scl && !e.sda && sda:
+		e.mode = eepromIdle
+		e.out = true
+	case !e.scl && scl:
+		e.rise(sda)
+	case e.scl && !scl:
+		e.fall()
+	}
+	e.scl = scl
+	e.sda = sda
+}
+
+// read EEPROMが出力しているSDA
+func (e *eeprom) read() bool {
+	return e.out
+}
+
+func (e *eeprom) start() {
+	e.mode = eepromDeviceAddress
+	if e.x24c01 {
+		e.mode = eepromWordAddress
+	}
+	e.bit = 0
+	e.out = true
+}
+
+// rise SCLの立ち上がり、受け取るモードではSDAの1bitを、読み込みのモードではACKのbitを受け取る
+func (e *eeprom) rise(sda bool) {
+	switch e.mode {
+	case eepromIdle:
+		return
+	case eepromRead:
+		if e.bit < 8 {
+			e.bit++
+			return
+		}
+		// ACKがなければ読み込みを終わる
+		e.bit = 0
+		if sda {
+			e.mode = eepromIdle
+			return
+		}
+		e.addr = (e.addr + 1) % len(e.data)
+		e.buffer = e.data[e.addr]
+		return
+	}
+
+	if e.bit < 8 {
+		var value byte
+		if sda {
+			value = 1
+		}
+		if e.x24c01 {
+			e.buffer = e.buffer>>1 | value<<7
+		} else {
+			e.buffer = e.buffer<<1 | value
+		}
+		e.bit++
+		return
+	}
+	e.bit = 0
+	e.mode = e.nextMode
+	if e.mode == eepromRead {
+		e.buffer = e.data[e.addr]
+	}
+}
+
+// fall SCLの立ち下がり、EEPROMがSDAに出力する値を変える
+func (e *eeprom) fall() {
+	switch e.mode {
+	case eepromIdle:
+		return
+	case eepromRead:
+		e.out = true
+		if e.bit < 8 {
+			e.out = e.dataBit(e.bit)
+		}
+		return
+	}
+
+	if e.bit < 8 {
+		e.out = true
+		return
+	}
+	if !e.receive() {
+		e.mode = eepromIdle
+		e.out = true
+		return
+	}
+	e.out = false
+}
+
+func (e *eeprom) dataBit(bit int) bool {
+	if e.x24c01 {
+		return e.buffer>>bit&0x01 != 0
+	}
+	return e.buffer>>(7-bit)&0x01 != 0
+}
+
+// receive 受け取った1byteを処理してACKの後のモードを決める、応答しない場合はfalse
+func (e *eeprom) receive() bool {
+	switch e.mode {
+	case eepromDeviceAddress:
+		if e.buffer>>4 != 0x0A {
+			return false
+		}
+		e.nextMode = eepromWordAddress
+		if e.buffer&0x01 != 0 {
+			e.nextMode = eepromRead
+		}
+	case eepromWordAddress:
+		if e.x24c01 {
+			e.addr = int(e.buffer & 0x7F)
+			e.nextMode = eepromWrite
+			if e.buffer&0x80 != 0 {
+				e.nextMode = eepromRead
+			}
+			return true
+		}
+		e.addr = int(e.buffer)
+		e.nextMode = eepromWrite
+	case eepromWrite:
+		e.data[e.addr] = e.buffer
+		// ページの中でアドレスを進める
+		e.addr = e.addr&^(e.pageSize-1) | (e.addr+1)&(e.pageSize-1)
+		e.nextMode = eepromWrite
+	}
+	return true
+}
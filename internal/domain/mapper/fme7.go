@@ -0,0 +1,142 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/apu"
+
+func init() {
+	register(69, "Sunsoft FME-7", newFME7)
+}
+
+// FME7 mapper 69 (Sunsoft FME-7, 5A, 5B)
+// doc: https://www.nesdev.org/wiki/Sunsoft_FME-7
+//
+// 0x8000-0x9FFFでコマンド番号を選び、0xA000-0xBFFFでパラメーターを書き込む
+// コマンド 0x0-0x7: 1KBのCHRバンク、0x8: 0x6000のPRGバンク(bit7: RAMの有効化, bit6: RAMを選ぶ)
+// 0x9-0xB: 0x8000, 0xA000, 0xC000の8KBのPRGバンク(0xE000は最後のバンクに固定)、0xC: ミラーリング
+// 0xD: IRQのコントロール(bit0: IRQの有効化, bit7: カウンターの有効化)、0xE, 0xF: IRQのカウンターの下位・上位
+//
+// 0xC000-0xFFFFへの書き込みはSunsoft 5Bの拡張音源に渡す(5Bを持たないボードでは何も起きない)
+type FME7 struct {
+	base
+
+	command  byte
+	chrBanks [8]int
+	prgBanks [4]byte // 0x6000, 0x8000, 0xA000, 0xC000
+
+	irqEnabled     bool
+	counterEnabled bool
+	irqCounter     uint16
+	irq            bool
+
+	audio *apu.Sunsoft5B
+}
+
+const (
+	fme7PRGBankSize = 0x2000 // 8KB
+	fme7CHRBankSize = 0x0400 // 1KB
+
+	fme7PRGRAMEnable = 0x80
+	fme7PRGRAMSelect = 0x40
+)
+
+var _ AudioMapper = (*FME7)(nil)
+
+func newFME7(config Config) (Mapper, error) {
+	return &FME7{
+		base:  newBase(config, defaultPRGRAM),
+		audio: apu.NewSunsoft5B(),
+	}, nil
+}
+
+func (m *FME7) ExpansionAudio() apu.ExpansionAudio {
+	return m.audio
+}
+
+// Reset 電源の入れ直しでバンクとIRQのレジスタを0に戻す
+func (m *FME7) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		*m = FME7{base: m.base, audio: m.audio}
+	}
+}
+
+func (m *FME7) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= 0xE000:
+		return m.readPRGBank(fme7PRGBankSize, -1, addr)
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(fme7PRGBankSize, int(m.prgBanks[(addr-addrPRGRAMStart)/fme7PRGBankSize]&0x3F), addr)
+	case addr >= addrPRGRAMStart:
+		bank := m.prgBanks[0]
+		if bank&fme7PRGRAMSelect == 0 {
+			return m.readPRGBank(fme7PRGBankSize, int(bank&0x3F), addr)
+		}
+		if bank&fme7PRGRAMEnable == 0 || len(m.prgRAM) == 0 {
+			return openBus
+		}
+		return m.prgRAM[m.prgRAMIndex(addr)]
+	}
+	return openBus
+}
+
+// prgRAMIndex 0x6000のバンクでPRG-RAMを選んだ時の位置
+func (m *FME7) prgRAMIndex(addr uint16) int {
+	return bankIndex(len(m.prgRAM), fme7PRGBankSize, int(m.prgBanks[0]&0x3F)) + int(addr-addrPRGRAMStart)%min(len(m.prgRAM), fme7PRGBankSize)
+}
+
+func (m *FME7) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= 0xC000:
+		m.audio.Write(addr, value)
+	case addr >= 0xA000:
+		m.writeParameter(value)
+	case addr >= addrPRGROMStart:
+		m.command = value & 0x0F
+	case addr >= addrPRGRAMStart:
+		bank := m.prgBanks[0]
+		if bank&fme7PRGRAMSelect != 0 && bank&fme7PRGRAMEnable != 0 && len(m.prgRAM) > 0 {
+			m.prgRAM[m.prgRAMIndex(addr)] = value
+		}
+	}
+}
+
+func (m *FME7) writeParameter(value byte) {
+	switch c := m.command; {
+	case c < 8:
+		m.chrBanks[c] = int(value)
+	case c < 0xC:
+		m.prgBanks[c-8] = value
+	case c == 0xC:
+		m.mirroring = vrcMirroring(value)
+	case c == 0xD:
+		m.irqEnabled = value&0x01 != 0
+		m.counterEnabled = value&0x80 != 0
+		m.irq = false
+	case c == 0xE:
+		m.irqCounter = m.irqCounter&0xFF00 | uint16(value)
+	default:
+		m.irqCounter = m.irqCounter&0x00FF | uint16(value)<<8
+	}
+}
+
+func (m *FME7) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(fme7CHRBankSize, m.chrBanks[addr/fme7CHRBankSize], addr)
+}
+
+func (m *FME7) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(fme7CHRBankSize, m.chrBanks[addr/fme7CHRBankSize], addr, value)
+}
+
+func (m *FME7) IRQ() bool {
+	return m.irq
+}
+
+// Step カウンターはCPUの1サイクルごとに減り、0から0xFFFFになる時にIRQを出す
+func (m *FME7) Step() {
+	if !m.counterEnabled {
+		return
+	}
+	m.irqCounter--
+	if m.irqCounter == 0xFFFF && m.irqEnabled {
+		m.irq = true
+	}
+}
@@ -0,0 +1,66 @@
+package mapper_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestFME7$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestFME7(t *testing.T) {
+	Convey("TestFME7", t, func() {
+		m, err := mapper.New(mapper.Config{
+			MapperNo: 69,
+			PRG:      newBankedROM(0x40000, 0x2000),
+			CHR:      newBankedROM(0x40000, 0x0400),
+		})
+		So(err, ShouldBeNil)
+		command := func(c, value byte) {
+			m.WritePRG(0x8000, c)
+			m.WritePRG(0xA000, value)
+		}
+
+		Convey("コマンドでPRG, CHRのバンクとミラーリングを切り替える", func() {
+			command(0x9, 3)
+			command(0xA, 4)
+			command(0xB, 5)
+			command(0x7, 0x21)
+			command(0xC, 0x01)
+			So(m.ReadPRG(0x8000), ShouldEqual, 3)
+			So(m.ReadPRG(0xA000), ShouldEqual, 4)
+			So(m.ReadPRG(0xC000), ShouldEqual, 5)
+			So(m.ReadPRG(0xE000), ShouldEqual, 31)
+			So(m.ReadCHR(0x1C00), ShouldEqual, 0x21)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringHorizontal)
+		})
+
+		Convey("0x6000はbit6でPRG-ROMとPRG-RAMを選び、bit7でPRG-RAMを有効にする", func() {
+			command(0x8, 0x06)
+			So(m.ReadPRG(0x6000), ShouldEqual, 6)
+			command(0x8, 0x40)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0xFF)
+			command(0x8, 0xC0)
+			m.WritePRG(0x6000, 0x12)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x12)
+		})
+
+		Convey("IRQのカウンターは0から0xFFFFになる時にIRQを出す", func() {
+			command(0xE, 0x01)
+			command(0xF, 0x00)
+			command(0xD, 0x81)
+			m.Step()
+			So(m.IRQ(), ShouldBeFalse)
+			m.Step()
+			So(m.IRQ(), ShouldBeTrue)
+			command(0xD, 0x81)
+			So(m.IRQ(), ShouldBeFalse)
+		})
+
+		Convey("Sunsoft 5Bの拡張音源を持つ", func() {
+			_, ok := m.(mapper.AudioMapper)
+			So(ok, ShouldBeTrue)
+		})
+	})
+}
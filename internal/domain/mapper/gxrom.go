@@ -0,0 +1,58 @@
+package mapper
+
+func init() {
+	register(66, "GxROM", newGxROM)
+}
+
+// GxROM mapper 66、bit4, 5で32KBのPRG-ROM、bit0, 1で8KBのCHR-ROMを切り替える
+// doc: https://www.nesdev.org/wiki/GxROM
+type GxROM struct {
+	base
+	busConflicts bool
+	prgBank      int
+	chrBank      int
+}
+
+const gxromPRGBankSize = 0x8000 // 32KB
+
+func newGxROM(config Config) (Mapper, error) {
+	return &GxROM{
+		base:         newBase(config, 0),
+		busConflicts: hasBusConflicts(config, true),
+	}, nil
+}
+
+// Reset 電源の入れ直しでPRG, CHRのバンクを0に戻す
+func (m *GxROM) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		m.prgBank = 0
+		m.chrBank = 0
+	}
+}
+
+func (m *GxROM) ReadPRG(addr uint16) byte {
+	if addr < addrPRGROMStart {
+		return openBus
+	}
+	return m.readPRGBank(gxromPRGBankSize, m.prgBank, addr)
+}
+
+func (m *GxROM) WritePRG(addr uint16, value byte) {
+	if addr < addrPRGROMStart {
+		return
+	}
+	if m.busConflicts {
+		value &= m.ReadPRG(addr)
+	}
+	m.prgBank = int(value>>4) & 0x03
+	m.chrBank = int(value) & 0x03
+}
+
+func (m *GxROM) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(chrSize, m.chrBank, addr)
+}
+
+func (m *GxROM) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(chrSize, m.chrBank, addr, value)
+}
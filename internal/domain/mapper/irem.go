@@ -0,0 +1,202 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/ppu"
+
+func init() {
+	register(32, "Irem G-101", newIremG101)
+	register(65, "Irem H3001", newIremH3001)
+}
+
+// IremG101 mapper 32
+// doc: https://www.nesdev.org/wiki/INES_Mapper_032
+//
+// 0x8000: 0x8000(swapモードでは0xC000)の8KBのPRGバンク、0x9000: bit0 ミラーリング, bit1 PRGのswapモード
+// 0xA000: 0xA000の8KBのPRGバンク、0xB000-0xB007: 1KBのCHRバンク
+// submapper 1(Major League)はswapモードがなく、1画面のミラーリングに固定
+type IremG101 struct {
+	base
+
+	prgBanks [2]int
+	prgSwap  bool
+	chrBanks [8]int
+	// fixedMirroring submapper 1
+	fixedMirroring bool
+}
+
+const (
+	iremPRGBankSize = 0x2000 // 8KB
+	iremCHRBankSize = 0x0400 // 1KB
+
+	iremG101SubmapperMajorLeague = 1
+)
+
+func newIremG101(config Config) (Mapper, error) {
+	m := &IremG101{
+		base:           newBase(config, defaultPRGRAM),
+		fixedMirroring: config.Submapper == iremG101SubmapperMajorLeague,
+	}
+	m.Reset(ResetTypeHard)
+	return m, nil
+}
+
+// Reset 電源の入れ直しでバンクのレジスタを0に戻す、submapper 1は1画面ミラーリングに固定
+func (m *IremG101) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType != ResetTypeHard {
+		return
+	}
+	*m = IremG101{base: m.base, fixedMirroring: m.fixedMirroring}
+	if m.fixedMirroring {
+		m.mirroring = ppu.MirroringSingleScreenLower
+	}
+}
+
+func (m *IremG101) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(iremPRGBankSize, m.prgBankNo(addr), addr)
+	case addr >= addrPRGRAMStart:
+		return m.readPRGRAM(addr)
+	}
+	return openBus
+}
+
+// prgBankNo addrの8KBのバンク番号、-1, -2は最後と最後から2番目のバンク
+func (m *IremG101) prgBankNo(addr uint16) int {
+	switch slot := (addr - addrPRGROMStart) / iremPRGBankSize; {
+	case slot == 0 && !m.prgSwap, slot == 2 && m.prgSwap:
+		return m.prgBanks[0]
+	case slot == 1:
+		return m.prgBanks[1]
+	case slot == 3:
+		return -1
+	}
+	return -2
+}
+
+func (m *IremG101) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= 0xC000:
+		// 0xC000-0xFFFFにレジスタはない
+	case addr >= 0xB000:
+		m.chrBanks[addr&0x07] = int(value)
+	case addr >= 0xA000:
+		m.prgBanks[1] = int(value & 0x1F)
+	case addr >= 0x9000:
+		if m.fixedMirroring {
+			return
+		}
+		m.prgSwap = value&0x02 != 0
+		m.mirroring = ppu.MirroringVertical
+		if value&0x01 != 0 {
+			m.mirroring = ppu.MirroringHorizontal
+		}
+	case addr >= addrPRGROMStart:
+		m.prgBanks[0] = int(value & 0x1F)
+	case addr >= addrPRGRAMStart:
+		m.writePRGRAM(addr, value)
+	}
+}
+
+func (m *IremG101) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(iremCHRBankSize, m.chrBanks[addr/iremCHRBankSize], addr)
+}
+
+func (m *IremG101) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(iremCHRBankSize, m.chrBanks[addr/iremCHRBankSize], addr, value)
+}
+
+// IremH3001 mapper 65
+// doc: https://www.nesdev.org/wiki/INES_Mapper_065
+//
+// 0x8000, 0xA000, 0xC000: 8KBのPRGバンク(0xE000は最後のバンクに固定)、0x9001: ミラーリング(bit7)
+// 0x9003: IRQの有効化(bit7)、0x9004: カウンターにリロード値を入れる、0x9005, 0x9006: リロード値の上位・下位
+// 0xB000-0xB007: 1KBのCHRバンク
+// カウンターはCPUの1サイクルごとに減り、0になったらIRQを出して止まる
+type IremH3001 struct {
+	base
+
+	prgBanks [3]int
+	chrBanks [8]int
+
+	irqEnabled bool
+	irqCounter uint16
+	irqReload  uint16
+	irq        bool
+}
+
+func newIremH3001(config Config) (Mapper, error) {
+	m := &IremH3001{base: newBase(config, 0)}
+	m.Reset(ResetTypeHard)
+	return m, nil
+}
+
+// Reset 電源を入れた時は0x8000, 0xA000, 0xC000にバンク0, 1, 最後から2番目を置く
+func (m *IremH3001) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		*m = IremH3001{base: m.base, prgBanks: [3]int{0, 1, -2}}
+	}
+}
+
+func (m *IremH3001) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= 0xE000:
+		return m.readPRGBank(iremPRGBankSize, -1, addr)
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(iremPRGBankSize, m.prgBanks[(addr-addrPRGROMStart)/iremPRGBankSize], addr)
+	case addr >= addrPRGRAMStart:
+		return m.readPRGRAM(addr)
+	}
+	return openBus
+}
+
+func (m *IremH3001) WritePRG(addr uint16, value byte) {
+	switch addr & 0xF007 {
+	case 0x8000, 0xA000, 0xC000:
+		m.prgBanks[(addr-addrPRGROMStart)/iremPRGBankSize] = int(value)
+	case 0x9001:
+		m.mirroring = ppu.MirroringVertical
+		if value&0x80 != 0 {
+			m.mirroring = ppu.MirroringHorizontal
+		}
+	case 0x9003:
+		m.irqEnabled = value&0x80 != 0
+		m.irq = false
+	case 0x9004:
+		m.irqCounter = m.irqReload
+		m.irq = false
+	case 0x9005:
+		m.irqReload = m.irqReload&0x00FF | uint16(value)<<8
+	case 0x9006:
+		m.irqReload = m.irqReload&0xFF00 | uint16(value)
+	case 0xB000, 0xB001, 0xB002, 0xB003, 0xB004, 0xB005, 0xB006, 0xB007:
+		m.chrBanks[addr&0x07] = int(value)
+	default:
+		if addr >= addrPRGRAMStart && addr < addrPRGROMStart {
+			m.writePRGRAM(addr, value)
+		}
+	}
+}
+
+func (m *IremH3001) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(iremCHRBankSize, m.chrBanks[addr/iremCHRBankSize], addr)
+}
+
+func (m *IremH3001) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(iremCHRBankSize, m.chrBanks[addr/iremCHRBankSize], addr, value)
+}
+
+func (m *IremH3001) IRQ() bool {
+	return m.irq
+}
+
+func (m *IremH3001) Step() {
+	if !m.irqEnabled || m.irqCounter == 0 {
+		return
+	}
+	m.irqCounter--
+	if m.irqCounter == 0 {
+		m.irq = true
+	}
+}
@@ -0,0 +1,68 @@
+package mapper_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestIrem$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestIrem(t *testing.T) {
+	Convey("TestIrem", t, func() {
+		newIrem := func(mapperNo, submapper int) mapper.Mapper {
+			m, err := mapper.New(mapper.Config{
+				MapperNo:  mapperNo,
+				Submapper: submapper,
+				PRG:       newBankedROM(0x40000, 0x2000),
+				CHR:       newBankedROM(0x40000, 0x0400),
+			})
+			So(err, ShouldBeNil)
+			return m
+		}
+
+		Convey("G-101は0x9000のbit1で0x8000と0xC000を入れ替える", func() {
+			m := newIrem(32, 0)
+			m.WritePRG(0x8000, 4)
+			m.WritePRG(0xA000, 5)
+			m.WritePRG(0xB007, 0x33)
+			So(m.ReadPRG(0x8000), ShouldEqual, 4)
+			So(m.ReadPRG(0xA000), ShouldEqual, 5)
+			So(m.ReadPRG(0xC000), ShouldEqual, 30)
+			So(m.ReadCHR(0x1C00), ShouldEqual, 0x33)
+
+			m.WritePRG(0x9000, 0x03)
+			So(m.ReadPRG(0x8000), ShouldEqual, 30)
+			So(m.ReadPRG(0xC000), ShouldEqual, 4)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringHorizontal)
+
+			// Major Leagueは1画面に固定
+			m = newIrem(32, 1)
+			m.WritePRG(0x9000, 0x03)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringSingleScreenLower)
+			So(m.ReadPRG(0xC000), ShouldEqual, 30)
+		})
+
+		Convey("H3001のIRQのカウンターはCPUの1サイクルごとに減り、0になったらIRQを出す", func() {
+			m := newIrem(65, 0)
+			m.WritePRG(0xC000, 6)
+			So(m.ReadPRG(0xC000), ShouldEqual, 6)
+			m.WritePRG(0x9001, 0x80)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringHorizontal)
+
+			m.WritePRG(0x9005, 0x00)
+			m.WritePRG(0x9006, 0x02)
+			m.WritePRG(0x9004, 0)
+			m.WritePRG(0x9003, 0x80)
+			m.Step()
+			So(m.IRQ(), ShouldBeFalse)
+			m.Step()
+			So(m.IRQ(), ShouldBeTrue)
+			m.WritePRG(0x9003, 0x80)
+			So(m.IRQ(), ShouldBeFalse)
+			m.Step()
+			So(m.IRQ(), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,155 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/ppu"
+
+func init() {
+	register(18, "Jaleco SS88006", newJalecoSS88006)
+}
+
+// JalecoSS88006 mapper 18
+// doc: https://www.nesdev.org/wiki/Jaleco_SS88006
+//
+// レジスタはaddrの0xF003で選び、バンク番号は2つのレジスタに下位4bitと上位4bitを書き込む
+// 0x8000-0x9001: 0x8000, 0xA000, 0xC000の8KBのPRGバンク(0xE000は最後のバンクに固定)、0x9002: PRG-RAMの有効化(bit0)と書き込み許可(bit1)
+// 0xA000-0xD003: 1KBのCHRバンク、0xE000-0xE003: IRQのリロード値(4bitずつ)
+// 0xF000: IRQのリロード、0xF001: IRQのコントロール(bit0: 有効化, bit1-3: カウンターのbit数)、0xF002: ミラーリング
+// 0xF003の音声(uPD7756)は未対応
+type JalecoSS88006 struct {
+	base
+
+	prgBanks [3]int
+	chrBanks [8]int
+
+	prgRAMEnabled  bool
+	prgRAMWritable bool
+
+	irqReload  uint16
+	irqCounter uint16
+	irqMask    uint16 // カウンターのうち数えるbit
+	irqEnabled bool
+	irq        bool
+}
+
+const (
+	jalecoPRGBankSize = 0x2000 // 8KB
+	jalecoCHRBankSize = 0x0400 // 1KB
+)
+
+func newJalecoSS88006(config Config) (Mapper, error) {
+	m := &JalecoSS88006{base: newBase(config, defaultPRGRAM)}
+	m.Reset(ResetTypeHard)
+	return m, nil
+}
+
+// Reset 電源の入れ直しでバンクとIRQのレジスタを0に戻す、IRQのカウンターは16bitで数える
+func (m *JalecoSS88006) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		*m = JalecoSS88006{base: m.base, irqMask: 0xFFFF}
+	}
+}
+
+func (m *JalecoSS88006) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= 0xE000:
+		return m.readPRGBank(jalecoPRGBankSize, -1, addr)
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(jalecoPRGBankSize, m.prgBanks[(addr-addrPRGROMStart)/jalecoPRGBankSize], addr)
+	case addr >= addrPRGRAMStart:
+		if !m.prgRAMEnabled {
+			return openBus
+		}
+		return m.readPRGRAM(addr)
+	}
+	return openBus
+}
+
+func (m *JalecoSS88006) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= addrPRGROMStart:
+		m.writeRegister(addr&0xF003, value)
+	case addr >= addrPRGRAMStart:
+		if m.prgRAMEnabled && m.prgRAMWritable {
+			m.writePRGRAM(addr, value)
+		}
+	}
+}
+
+func (m *JalecoSS88006) writeRegister(addr uint16, value byte) {
+	// 0x8000から2つのレジスタごとに1つのバンク
+	index := int(addr-addrPRGROMStart)/0x1000*2 + int(addr&0x03)/2
+	high := addr&0x01 != 0
+	switch {
+	case addr < 0x9002:
+		m.prgBanks[index] = setNibble(m.prgBanks[index], value, high)
+	case addr == 0x9002:
+		m.prgRAMEnabled = value&0x01 != 0
+		m.prgRAMWritable = value&0x02 != 0
+	case addr < 0xA000:
+		// 0x9003は使わない
+	case addr < 0xE000:
+		m.chrBanks[index-4] = setNibble(m.chrBanks[index-4], value, high)
+	case addr < 0xF000:
+		shift := (addr & 0x03) * 4
+		m.irqReload = m.irqReload&^(0x0F<<shift) | uint16(value&0x0F)<<shift
+	case addr == 0xF000:
+		m.irq = false
+		m.irqCounter = m.irqReload
+	case addr == 0xF001:
+		m.irq = false
+		m.irqEnabled = value&0x01 != 0
+		switch {
+		case value&0x08 != 0:
+			m.irqMask = 0x000F
+		case value&0x04 != 0:
+			m.irqMask = 0x00FF
+		case value&0x02 != 0:
+			m.irqMask = 0x0FFF
+		default:
+			m.irqMask = 0xFFFF
+		}
+	case addr == 0xF002:
+		switch value & 0x03 {
+		case 0:
+			m.mirroring = ppu.MirroringHorizontal
+		case 1:
+			m.mirroring = ppu.MirroringVertical
+		case 2:
+			m.mirroring = ppu.MirroringSingleScreenLower
+		default:
+			m.mirroring = ppu.MirroringSingleScreenUpper
+		}
+	}
+}
+
+// setNibble highがtrueの場合はbankのbit4-7、falseの場合はbit0-3をvalueの下位4bitにする
+func setNibble(bank int, value byte, high bool) int {
+	if high {
+		return bank&0x0F | int(value&0x0F)<<4
+	}
+	return bank&^0x0F | int(value&0x0F)
+}
+
+func (m *JalecoSS88006) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(jalecoCHRBankSize, m.chrBanks[addr/jalecoCHRBankSize], addr)
+}
+
+func (m *JalecoSS88006) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(jalecoCHRBankSize, m.chrBanks[addr/jalecoCHRBankSize], addr, value)
+}
+
+func (m *JalecoSS88006) IRQ() bool {
+	return m.irq
+}
+
+// Step カウンターはCPUの1サイクルごとに、irqMaskのbitだけ減り、0から戻る時にIRQを出す
+func (m *JalecoSS88006) Step() {
+	if !m.irqEnabled {
+		return
+	}
+	counter := m.irqCounter & m.irqMask
+	m.irqCounter = m.irqCounter&^m.irqMask | (counter-1)&m.irqMask
+	if counter == 0 {
+		m.irq = true
+	}
+}
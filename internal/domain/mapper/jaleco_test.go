@@ -0,0 +1,59 @@
+package mapper_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestJalecoSS88006$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestJalecoSS88006(t *testing.T) {
+	Convey("TestJalecoSS88006", t, func() {
+		m, err := mapper.New(mapper.Config{
+			MapperNo: 18,
+			PRG:      newBankedROM(0x40000, 0x2000),
+			CHR:      newBankedROM(0x40000, 0x0400),
+		})
+		So(err, ShouldBeNil)
+
+		Convey("バンク番号は下位4bitと上位4bitを別のレジスタに書き込む", func() {
+			m.WritePRG(0x8002, 0x05)
+			m.WritePRG(0x8003, 0x01)
+			m.WritePRG(0x9000, 0x07)
+			m.WritePRG(0xD002, 0x0C)
+			m.WritePRG(0xD003, 0x0A)
+			So(m.ReadPRG(0xA000), ShouldEqual, 0x15)
+			So(m.ReadPRG(0xC000), ShouldEqual, 7)
+			So(m.ReadPRG(0xE000), ShouldEqual, 31)
+			So(m.ReadCHR(0x1C00), ShouldEqual, 0xAC)
+
+			m.WritePRG(0xF002, 0x01)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringVertical)
+		})
+
+		Convey("PRG-RAMは0x9002で有効化と書き込みの許可をする", func() {
+			m.WritePRG(0x9002, 0x01)
+			m.WritePRG(0x6000, 0x12)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x00)
+			m.WritePRG(0x9002, 0x03)
+			m.WritePRG(0x6000, 0x12)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x12)
+		})
+
+		Convey("IRQのカウンターは選んだbit数だけ減り、0から戻る時にIRQを出す", func() {
+			// リロード値0x0101、4bitのカウンター
+			m.WritePRG(0xE000, 0x01)
+			m.WritePRG(0xE002, 0x01)
+			m.WritePRG(0xF000, 0)
+			m.WritePRG(0xF001, 0x09)
+			m.Step()
+			So(m.IRQ(), ShouldBeFalse)
+			m.Step()
+			So(m.IRQ(), ShouldBeTrue)
+			m.WritePRG(0xF000, 0)
+			So(m.IRQ(), ShouldBeFalse)
+		})
+	})
+}
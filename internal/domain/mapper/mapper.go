@@ -0,0 +1,96 @@
+package mapper
+
+import (
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+	"github.com/sunjin110/nes_emu/internal/domain/prgrom"
+)
+
+// Mapper カートリッジのmapper、CPUとPPUのアドレス空間のうちカートリッジの部分を担当する
+// doc: https://www.nesdev.org/wiki/Mapper
+type Mapper interface {
+	// ReadPRG CPUの0x4020-0xFFFFの読み込み
+	ReadPRG(addr uint16) byte
+	// WritePRG CPUの0x4020-0xFFFFの書き込み(バンク切り替えのレジスタなど)
+	WritePRG(addr uint16, value byte)
+
+	// ReadCHR PPUの0x0000-0x1FFFの読み込み
+	ReadCHR(addr uint16) byte
+	// WriteCHR PPUの0x0000-0x1FFFの書き込み、CHR-ROMの場合は無視する
+	WriteCHR(addr uint16, value byte)
+
+	// Mirroring 現在のネームテーブルのミラーリング
+	Mirroring() ppu.Mirroring
+
+	// IRQ mapperがIRQを出しているか
+	IRQ() bool
+
+	// Step CPUの1サイクルごとに呼ばれる(CPUサイクルで数えるIRQカウンターなど)
+	Step()
+	// PPUAddress PPUがアドレスバスに出したアドレス(0x0000-0x3EFF)、MMC3などがA12の変化を見るのに使う
+	PPUAddress(addr uint16)
+	// Scanline 描画中のscanlineごとに(dot 260で)呼ばれる
+	Scanline()
+}
+
+// AudioMapper 拡張音源を持つmapper、consoleがAPUのミキサーに追加する
+// 拡張音源のレジスタへの読み書きはmapperのReadPRG, WritePRGから転送する
+type AudioMapper interface {
+	ExpansionAudio() apu.ExpansionAudio
+}
+
+// PRGRAMMapper PRG-RAMを持つmapper、trainerの読み込みなどでRAMに直接アクセスする
+// 返すsliceはmapperのRAMそのもので、書き込むとmapperから見える
+type PRGRAMMapper interface {
+	PRGRAM() []byte
+}
+
+// BatteryMapper バッテリーバックアップされるRAMやEEPROMを持つmapper、.savファイルの読み書きに使う
+// 返すsliceはmapperのデータそのもので、ない場合はnil
+type BatteryMapper interface {
+	SaveRAM() []byte
+}
+
+// ResetType リセットの種類
+type ResetType int
+
+const (
+	// ResetTypeSoft 本体のリセットボタン
+	ResetTypeSoft ResetType = iota
+	// ResetTypeHard 電源の入れ直し
+	ResetTypeHard
+)
+
+// ResetMapper リセットボタンや電源の入れ直しでレジスタを初期化するmapper、consoleのReset, PowerCycleから呼ぶ
+type ResetMapper interface {
+	Reset(resetType ResetType)
+}
+
+var (
+	_ ppu.Cartridge        = Mapper(nil)
+	_ ppu.BusObserver      = Mapper(nil)
+	_ ppu.ScanlineObserver = Mapper(nil)
+)
+
+// cpuBus mapperをCPUのメモリ(0x4020-0xFFFF)に接続する
+type cpuBus struct {
+	mapper Mapper
+}
+
+// NewCPUBus mapperをprgrom.ExpansionPRGROMとしてCPUのメモリに渡せるようにする
+func NewCPUBus(m Mapper) prgrom.ExpansionPRGROM {
+	return &cpuBus{mapper: m}
+}
+
+func (b *cpuBus) Read(addr uint16) byte {
+	return b.mapper.ReadPRG(addr)
+}
+
+func (b *cpuBus) Write(addr uint16, value byte) {
+	b.mapper.WritePRG(addr, value)
+}
+
+// InitPC リセットベクタ(0xFFFC, 0xFFFD)
+func (b *cpuBus) InitPC() uint16 {
+	return uint16(b.mapper.ReadPRG(0xFFFC)) | uint16(b.mapper.ReadPRG(0xFFFD))<<8
+}
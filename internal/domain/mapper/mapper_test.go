@@ -0,0 +1,126 @@
+package mapper_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestNew$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestNew(t *testing.T) {
+	Convey("TestNew", t, func() {
+		Convey("登録されていないmapper番号はErrUnsupportedMapperになる", func() {
+			_, err := mapper.New(mapper.Config{MapperNo: 255, PRG: make([]byte, 0x4000)})
+			So(errors.Is(err, mapper.ErrUnsupportedMapper), ShouldBeTrue)
+			So(mapper.Name(255), ShouldBeEmpty)
+		})
+
+		Convey("mapper番号からmapperを作ってCPUのメモリに接続できる", func() {
+			prg := make([]byte, 0x8000)
+			prg[0x7FFC] = 0x34
+			prg[0x7FFD] = 0x82
+			m, err := mapper.New(mapper.Config{MapperNo: 0, PRG: prg})
+			So(err, ShouldBeNil)
+			So(mapper.Name(0), ShouldEqual, "NROM")
+			So(mapper.Supported(), ShouldContain, 0)
+
+			bus := mapper.NewCPUBus(m)
+			So(bus.InitPC(), ShouldEqual, 0x8234)
+			So(func() { bus.Write(0x8000, 0x01) }, ShouldNotPanic)
+
+			// CHR-ROMがない場合はCHR-RAM
+			m.WriteCHR(0x1234, 0x56)
+			So(m.ReadCHR(0x1234), ShouldEqual, 0x56)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestNROM$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestNROM(t *testing.T) {
+	Convey("TestNROM", t, func() {
+		Convey("Family BASICの4KBのPRG-RAMは0x6000-0x7FFFにミラーリングされる", func() {
+			m, err := mapper.New(mapper.Config{MapperNo: 0, PRG: make([]byte, 0x8000), PRGRAMSize: 0x1000})
+			So(err, ShouldBeNil)
+
+			m.WritePRG(0x6000, 0x12)
+			So(m.ReadPRG(0x7000), ShouldEqual, 0x12)
+			m.WritePRG(0x7FFF, 0x34)
+			So(m.ReadPRG(0x6FFF), ShouldEqual, 0x34)
+		})
+
+		Convey("PRG-ROMへの書き込みは無視され、0x4020-0x5FFFは何もつながっていない", func() {
+			prg := make([]byte, 0x4000)
+			prg[0] = 0x99
+			m, err := mapper.New(mapper.Config{MapperNo: 0, PRG: prg})
+			So(err, ShouldBeNil)
+
+			m.WritePRG(0x8000, 0x55)
+			So(m.ReadPRG(0x8000), ShouldEqual, 0x99)
+			So(m.ReadPRG(0xC000), ShouldEqual, 0x99)
+			So(m.ReadPRG(0x5000), ShouldEqual, 0xFF)
+		})
+
+		Convey("バンクより小さい4KBのPRG-ROMと1KBのCHR-ROMはミラーリングされる", func() {
+			prg := make([]byte, 0x1000)
+			prg[0x0FFF] = 0x12
+			chr := make([]byte, 0x0400)
+			chr[0x03FF] = 0x34
+			m, err := mapper.New(mapper.Config{MapperNo: 0, PRG: prg, CHR: chr})
+			So(err, ShouldBeNil)
+
+			So(m.ReadPRG(0x8FFF), ShouldEqual, 0x12)
+			So(m.ReadPRG(0xFFFF), ShouldEqual, 0x12)
+			So(m.ReadCHR(0x03FF), ShouldEqual, 0x34)
+			So(m.ReadCHR(0x1FFF), ShouldEqual, 0x34)
+		})
+
+		Convey("32KBより大きいPRG-ROMはNROMでは使えない", func() {
+			_, err := mapper.New(mapper.Config{MapperNo: 0, PRG: make([]byte, 0x10000)})
+			So(err, ShouldBeError)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestNew_OddSize$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestNew_OddSize(t *testing.T) {
+	Convey("TestNew_OddSize", t, func() {
+		// バンクのサイズより小さいROMと、バンクのサイズの倍数でないROM
+		prgSizes := []int{0x0001, 0x1000, 0x3001, 0x5000}
+		chrSizes := []int{0x0001, 0x0400, 0x1000, 0x2403}
+
+		for _, no := range mapper.Supported() {
+			for _, prgSize := range prgSizes {
+				for _, chrSize := range chrSizes {
+					name := fmt.Sprintf("mapper %d(%s), PRG: 0x%X, CHR: 0x%X", no, mapper.Name(no), prgSize, chrSize)
+					Convey(name+"でもバンクを切り替えて読み書きしてもpanicしない", func() {
+						m, err := mapper.New(mapper.Config{MapperNo: no, PRG: make([]byte, prgSize), CHR: make([]byte, chrSize)})
+						if err != nil {
+							// NROMの32KBより大きいROMなど、mapperが作れないサイズはエラーで良い
+							return
+						}
+						So(func() {
+							for round := 0; round < 8; round++ {
+								for addr := 0x4020; addr <= 0xFFFF; addr += 0x1F7 {
+									m.WritePRG(uint16(addr), byte(addr>>round)^byte(round*0x35))
+								}
+								for addr := 0x6000; addr <= 0xFFFF; addr += 0x0D {
+									m.ReadPRG(uint16(addr))
+								}
+								for addr := 0x0000; addr < 0x2000; addr += 0x07 {
+									m.PPUAddress(uint16(addr))
+									m.WriteCHR(uint16(addr), 0x5A)
+									m.ReadCHR(uint16(addr))
+								}
+								m.Step()
+								m.Scanline()
+							}
+						}, ShouldNotPanic)
+					})
+				}
+			}
+		}
+	})
+}
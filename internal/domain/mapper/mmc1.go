@@ -0,0 +1,212 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/ppu"
+
+func init() {
+	register(1, "MMC1", newMMC1)
+}
+
+// MMC1 mapper 1 (SxROM)
+// doc: https://www.nesdev.org/wiki/MMC1
+//
+// 0x8000-0xFFFFへの書き込みでbit0を1bitずつシフトレジスタに送り、5回目の書き込みのアドレス(bit13, 14)でレジスタを選ぶ
+// bit7が立った値を書き込むとシフトレジスタがリセットされ、PRGのモードが3(0xC000に最後のバンクを固定)になる
+//
+// SUROM/SXROM(512KBのPRG-ROM)はCHRバンクのbit4で256KBの上位バンクを選び、
+// SOROM/SXROM(16KB/32KBのPRG-RAM)はCHRバンクのbit2, 3でPRG-RAMのバンクを選ぶ
+type MMC1 struct {
+	base
+
+	shift      byte // 5bit目まで入ったら書き込むので、入った数をshiftCountで数える
+	shiftCount int
+
+	control byte // 0x8000-0x9FFF
+	chrBank [2]byte
+	prgBank byte // 0xE000-0xFFFF
+
+	// 4KBのCHRモードの時に、SUROMなどの上位bitをどちらのCHRバンクから取るかを決める
+	a12 bool
+
+	// 連続したCPUサイクルの書き込みは無視する(INCなどのRMW命令のダミー書き込み)
+	cycle          uint64
+	lastWriteCycle uint64
+	written        bool
+}
+
+const (
+	mmc1PRGBankSize = 0x4000 // 16KB
+	mmc1CHRBankSize = 0x1000 // 4KB
+
+	// mmc1OuterPRGSize SUROM/SXROMの上位バンクの単位
+	mmc1OuterPRGSize = 0x40000 // 256KB
+
+	mmc1ControlReset = 0x0C
+)
+
+func newMMC1(config Config) (Mapper, error) {
+	return &MMC1{
+		base:    newBase(config, defaultPRGRAM),
+		control: mmc1ControlReset,
+	}, nil
+}
+
+// Reset シフトレジスタを空にして、controlを起動時の値(PRGモード3)にする
+// 電源の入れ直しではバンクのレジスタも0に戻す
+func (m *MMC1) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		*m = MMC1{base: m.base}
+	}
+	m.shift = 0
+	m.shiftCount = 0
+	m.control = mmc1ControlReset
+}
+
+func (m *MMC1) Step() {
+	m.cycle++
+}
+
+func (m *MMC1) PPUAddress(addr uint16) {
+	if addr < 0x2000 {
+		m.a12 = addr&0x1000 != 0
+	}
+}
+
+func (m *MMC1) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(mmc1PRGBankSize, m.prgBankNo(addr), addr)
+	case addr >= addrPRGRAMStart:
+		if !m.prgRAMEnabled() {
+			return openBus
+		}
+		return m.prgRAM[m.prgRAMIndex(addr)]
+	}
+	return openBus
+}
+
+func (m *MMC1) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= addrPRGROMStart:
+		m.writeRegister(addr, value)
+	case addr >= addrPRGRAMStart:
+		if m.prgRAMEnabled() {
+			m.prgRAM[m.prgRAMIndex(addr)] = value
+		}
+	}
+}
+
+func (m *MMC1) writeRegister(addr uint16, value byte) {
+	consecutive := m.written && m.lastWriteCycle == m.cycle
+	m.written = true
+	m.lastWriteCycle = m.cycle
+	if consecutive {
+		return
+	}
+
+	if value&0x80 != 0 {
+		m.shift = 0
+		m.shiftCount = 0
+		m.control |= mmc1ControlReset
+		return
+	}
+
+	m.shift |= (value & 0x01) << m.shiftCount
+	m.shiftCount++
+	if m.shiftCount < 5 {
+		return
+	}
+
+	switch (addr >> 13) & 0x03 {
+	case 0:
+		m.control = m.shift
+	case 1:
+		m.chrBank[0] = m.shift
+	case 2:
+		m.chrBank[1] = m.shift
+	case 3:
+		m.prgBank = m.shift
+	}
+	m.shift = 0
+	m.shiftCount = 0
+}
+
+// prgBankNo addrの16KBのバンク番号
+func (m *MMC1) prgBankNo(addr uint16) int {
+	bank := int(m.prgBank & 0x0F)
+	switch (m.control >> 2) & 0x03 {
+	case 0, 1:
+		// 32KB単位で切り替える、下位bitは無視
+		bank = bank&^0x01 | int(addr-addrPRGROMStart)/mmc1PRGBankSize
+	case 2:
+		// 0x8000に最初のバンクを固定、0xC000を切り替える
+		if addr < 0xC000 {
+			bank = 0
+		}
+	case 3:
+		// 0xC000に最後のバンクを固定、0x8000を切り替える
+		if addr >= 0xC000 {
+			bank = 0x0F
+		}
+	}
+	if len(m.prg) > mmc1OuterPRGSize {
+		bank |= int(m.outerBank() & 0x10) // 256KB = 16KB * 16
+	}
+	return bank
+}
+
+// outerBank SUROM/SOROM/SXROMの上位bitに使うCHRバンクの値
+func (m *MMC1) outerBank() byte {
+	if m.control&0x10 != 0 && m.a12 {
+		return m.chrBank[1]
+	}
+	return m.chrBank[0]
+}
+
+// prgRAMEnabled MMC1Bはprgバンクのbit4が0の時に有効
+func (m *MMC1) prgRAMEnabled() bool {
+	return len(m.prgRAM) > 0 && m.prgBank&0x10 == 0
+}
+
+func (m *MMC1) prgRAMIndex(addr uint16) int {
+	offset := int(addr-addrPRGRAMStart) % len(m.prgRAM)
+	var bank int
+	switch len(m.prgRAM) {
+	case 0x8000: // SXROM
+		bank = int(m.outerBank()>>2) & 0x03
+	case 0x4000: // SOROM
+		bank = int(m.outerBank()>>3) & 0x01
+	default:
+		return offset
+	}
+	return bank*defaultPRGRAM + offset%defaultPRGRAM
+}
+
+func (m *MMC1) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(mmc1CHRBankSize, m.chrBankNo(addr), addr)
+}
+
+func (m *MMC1) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(mmc1CHRBankSize, m.chrBankNo(addr), addr, value)
+}
+
+// chrBankNo addrの4KBのバンク番号
+func (m *MMC1) chrBankNo(addr uint16) int {
+	if m.control&0x10 == 0 {
+		// 8KB単位で切り替える、下位bitは無視
+		return int(m.chrBank[0]&^0x01) + int(addr/mmc1CHRBankSize)
+	}
+	return int(m.chrBank[addr/mmc1CHRBankSize])
+}
+
+func (m *MMC1) Mirroring() ppu.Mirroring {
+	switch m.control & 0x03 {
+	case 0:
+		return ppu.MirroringSingleScreenLower
+	case 1:
+		return ppu.MirroringSingleScreenUpper
+	case 2:
+		return ppu.MirroringVertical
+	}
+	return ppu.MirroringHorizontal
+}
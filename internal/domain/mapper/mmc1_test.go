@@ -0,0 +1,141 @@
+package mapper_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// writeMMC1 シフトレジスタに下位bitから5回書き込む、連続した書き込みにならないよう間にStepを挟む
+func writeMMC1(m mapper.Mapper, addr uint16, value byte) {
+	for i := 0; i < 5; i++ {
+		m.WritePRG(addr, value>>i&0x01)
+		m.Step()
+	}
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestMMC1$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestMMC1(t *testing.T) {
+	Convey("TestMMC1", t, func() {
+		newMMC1 := func(config mapper.Config) mapper.Mapper {
+			config.MapperNo = 1
+			m, err := mapper.New(config)
+			So(err, ShouldBeNil)
+			return m
+		}
+
+		Convey("起動時はPRGモード3で0xC000に最後のバンクが固定される", func() {
+			m := newMMC1(mapper.Config{PRG: newBankedROM(0x20000, 0x4000)})
+			So(m.ReadPRG(0x8000), ShouldEqual, 0)
+			So(m.ReadPRG(0xC000), ShouldEqual, 7)
+
+			writeMMC1(m, 0xE000, 3)
+			So(m.ReadPRG(0x8000), ShouldEqual, 3)
+			So(m.ReadPRG(0xC000), ShouldEqual, 7)
+		})
+
+		Convey("PRGモード0, 1は32KB単位、モード2は0x8000に最初のバンクを固定する", func() {
+			m := newMMC1(mapper.Config{PRG: newBankedROM(0x20000, 0x4000)})
+			writeMMC1(m, 0xE000, 5)
+
+			writeMMC1(m, 0x8000, 0x00)
+			So(m.ReadPRG(0x8000), ShouldEqual, 4)
+			So(m.ReadPRG(0xC000), ShouldEqual, 5)
+
+			writeMMC1(m, 0x8000, 0x08)
+			So(m.ReadPRG(0x8000), ShouldEqual, 0)
+			So(m.ReadPRG(0xC000), ShouldEqual, 5)
+		})
+
+		Convey("bit7を書き込むとシフトレジスタがリセットされてPRGモード3に戻る", func() {
+			m := newMMC1(mapper.Config{PRG: newBankedROM(0x20000, 0x4000)})
+			writeMMC1(m, 0x8000, 0x00)
+			writeMMC1(m, 0xE000, 2)
+			So(m.ReadPRG(0xC000), ShouldEqual, 3)
+
+			m.WritePRG(0xE000, 0x01)
+			m.Step()
+			m.WritePRG(0x8000, 0x80)
+			m.Step()
+			So(m.ReadPRG(0xC000), ShouldEqual, 7)
+
+			// 途中まで入っていた1bitは捨てられている
+			writeMMC1(m, 0xE000, 1)
+			So(m.ReadPRG(0x8000), ShouldEqual, 1)
+		})
+
+		Convey("連続したサイクルの書き込みは2回目が無視される", func() {
+			m := newMMC1(mapper.Config{PRG: newBankedROM(0x20000, 0x4000)})
+			// INC $E000のようなRMW命令は同じ値を2回書き込む
+			for _, bit := range []byte{1, 0, 0, 0, 0} {
+				m.WritePRG(0xE000, bit)
+				m.WritePRG(0xE000, 0)
+				m.Step()
+			}
+			So(m.ReadPRG(0x8000), ShouldEqual, 1)
+		})
+
+		Convey("CHRは8KBモードと4KBモードで切り替えられ、ミラーリングも切り替えられる", func() {
+			m := newMMC1(mapper.Config{PRG: newBankedROM(0x8000, 0x4000), CHR: newBankedROM(0x8000, 0x1000)})
+			writeMMC1(m, 0xA000, 3)
+			So(m.ReadCHR(0x0000), ShouldEqual, 2)
+			So(m.ReadCHR(0x1000), ShouldEqual, 3)
+
+			writeMMC1(m, 0x8000, 0x10|0x02)
+			writeMMC1(m, 0xC000, 6)
+			So(m.ReadCHR(0x0000), ShouldEqual, 3)
+			So(m.ReadCHR(0x1000), ShouldEqual, 6)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringVertical)
+
+			writeMMC1(m, 0x8000, 0x01)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringSingleScreenUpper)
+		})
+
+		Convey("PRG-RAMはprgバンクのbit4で無効にできる", func() {
+			m := newMMC1(mapper.Config{PRG: newBankedROM(0x8000, 0x4000)})
+			m.WritePRG(0x6000, 0x42)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x42)
+
+			writeMMC1(m, 0xE000, 0x10)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0xFF)
+			m.WritePRG(0x6000, 0x00)
+
+			writeMMC1(m, 0xE000, 0x00)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x42)
+		})
+
+		Convey("SUROMはCHRバンクのbit4で256KBの上位バンクを選ぶ", func() {
+			m := newMMC1(mapper.Config{PRG: newBankedROM(0x80000, 0x4000)})
+			So(m.ReadPRG(0xC000), ShouldEqual, 15)
+
+			writeMMC1(m, 0xA000, 0x10)
+			So(m.ReadPRG(0x8000), ShouldEqual, 16)
+			So(m.ReadPRG(0xC000), ShouldEqual, 31)
+		})
+
+		Convey("SXROMはCHRバンクのbit2, 3で8KBのPRG-RAMのバンクを選ぶ", func() {
+			m := newMMC1(mapper.Config{PRG: newBankedROM(0x80000, 0x4000), PRGRAMSize: 0x8000})
+			m.WritePRG(0x6000, 0x11)
+			writeMMC1(m, 0xA000, 0x08)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x00)
+			m.WritePRG(0x6000, 0x22)
+
+			writeMMC1(m, 0xA000, 0x00)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x11)
+		})
+
+		Convey("SOROMの揮発性の8KBと不揮発性の8KBは、後ろ側のバンクだけを保存する", func() {
+			m := newMMC1(mapper.Config{PRG: newBankedROM(0x40000, 0x4000), PRGRAMSize: 0x4000, PRGNVRAMSize: 0x2000, Battery: true})
+			m.WritePRG(0x6000, 0x11)
+			writeMMC1(m, 0xA000, 0x08)
+			m.WritePRG(0x6000, 0x22)
+
+			save := m.(mapper.BatteryMapper).SaveRAM()
+			So(len(save), ShouldEqual, 0x2000)
+			So(save[0], ShouldEqual, 0x22)
+			So(len(m.(mapper.PRGRAMMapper).PRGRAM()), ShouldEqual, 0x4000)
+		})
+	})
+}
@@ -0,0 +1,209 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/ppu"
+
+func init() {
+	register(4, "MMC3", newMMC3)
+}
+
+// MMC3 mapper 4 (TxROM)
+// doc: https://www.nesdev.org/wiki/MMC3
+//
+// 0x8000(偶数): バンクセレクト、0x8001(奇数): バンクデータ
+// 0xA000(偶数): ミラーリング、0xA001(奇数): PRG-RAMの保護
+// 0xC000(偶数): IRQのラッチ、0xC001(奇数): IRQのリロード
+// 0xE000(偶数): IRQの無効化と解除、0xE001(奇数): IRQの有効化
+//
+// IRQのカウンターはPPUのA12の立ち上がりで数える(通常は1scanlineに1回)
+// A12がCPUの数サイクル以上Lowだった後の立ち上がりだけを数えて、背景のフェッチ中の細かい変化は無視する
+type MMC3 struct {
+	base
+
+	bankSelect byte
+	banks      [8]int // R0-R7
+
+	prgRAMEnabled   bool
+	prgRAMProtected bool
+	fourScreen      bool
+
+	irqLatch   byte
+	irqCounter byte
+	irqReload  bool
+	irqEnabled bool
+	irq        bool
+	// alternateIRQ MMC3A(NES 2.0のsubmapper 4)はカウンターが0から0にリロードされた時にIRQを出さない
+	alternateIRQ bool
+
+	a12      bool
+	cycle    uint64
+	a12LowAt uint64
+}
+
+const (
+	mmc3PRGBankSize = 0x2000 // 8KB
+	mmc3CHRBankSize = 0x0400 // 1KB
+
+	// mmc3A12Filter A12の立ち上がりを数えるのに必要なLowの期間(CPUサイクル)
+	mmc3A12Filter = 3
+
+	// mmc3SubmapperMMC3A 0から0へのリロードでIRQを出さないMMC3A
+	mmc3SubmapperMMC3A = 4
+)
+
+func newMMC3(config Config) (Mapper, error) {
+	m := &MMC3{
+		base:         newBase(config, defaultPRGRAM),
+		fourScreen:   config.Mirroring == ppu.MirroringFourScreen,
+		alternateIRQ: config.Submapper == mmc3SubmapperMMC3A,
+	}
+	m.Reset(ResetTypeHard)
+	return m, nil
+}
+
+// Reset IRQのカウンターを止めて、出しているIRQを取り下げる
+// 電源の入れ直しではバンクのレジスタも0に戻して、PRG-RAMを有効にする
+func (m *MMC3) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		*m = MMC3{
+			base:          m.base,
+			prgRAMEnabled: true,
+			fourScreen:    m.fourScreen,
+			alternateIRQ:  m.alternateIRQ,
+		}
+	}
+	m.irqCounter = 0
+	m.irqReload = false
+	m.irqEnabled = false
+	m.irq = false
+}
+
+func (m *MMC3) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(mmc3PRGBankSize, m.prgBankNo(addr), addr)
+	case addr >= addrPRGRAMStart:
+		if !m.prgRAMEnabled {
+			return openBus
+		}
+		return m.readPRGRAM(addr)
+	}
+	return openBus
+}
+
+func (m *MMC3) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= addrPRGROMStart:
+		m.writeRegister(addr, value)
+	case addr >= addrPRGRAMStart:
+		if m.prgRAMEnabled && !m.prgRAMProtected {
+			m.writePRGRAM(addr, value)
+		}
+	}
+}
+
+func (m *MMC3) writeRegister(addr uint16, value byte) {
+	even := addr%2 == 0
+	switch {
+	case addr < 0xA000 && even:
+		m.bankSelect = value
+	case addr < 0xA000:
+		m.banks[m.bankSelect&0x07] = int(value)
+	case addr < 0xC000 && even:
+		if m.fourScreen {
+			return
+		}
+		if value&0x01 == 0 {
+			m.mirroring = ppu.MirroringVertical
+		} else {
+			m.mirroring = ppu.MirroringHorizontal
+		}
+	case addr < 0xC000:
+		m.prgRAMEnabled = value&0x80 != 0
+		m.prgRAMProtected = value&0x40 != 0
+	case addr < 0xE000 && even:
+		m.irqLatch = value
+	case addr < 0xE000:
+		m.irqCounter = 0
+		m.irqReload = true
+	case even:
+		m.irqEnabled = false
+		m.irq = false
+	default:
+		m.irqEnabled = true
+	}
+}
+
+// prgBankNo addrの8KBのバンク番号、-1, -2は最後と最後から2番目のバンク
+func (m *MMC3) prgBankNo(addr uint16) int {
+	swap := m.bankSelect&0x40 != 0
+	switch slot := (addr - addrPRGROMStart) / mmc3PRGBankSize; {
+	case slot == 0 && !swap, slot == 2 && swap:
+		return m.banks[6] & 0x3F
+	case slot == 1:
+		return m.banks[7] & 0x3F
+	case slot == 3:
+		return -1
+	}
+	return -2
+}
+
+func (m *MMC3) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(mmc3CHRBankSize, m.chrBankNo(addr), addr)
+}
+
+func (m *MMC3) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(mmc3CHRBankSize, m.chrBankNo(addr), addr, value)
+}
+
+// chrBankNo addrの1KBのバンク番号
+// R0, R1は2KB(下位bitは無視)で0x0000-0x0FFF、R2-R5は1KBで0x1000-0x1FFF、bit7が立っている場合は前半と後半を入れ替える
+func (m *MMC3) chrBankNo(addr uint16) int {
+	if m.bankSelect&0x80 != 0 {
+		addr ^= 0x1000
+	}
+	slot := int(addr / mmc3CHRBankSize)
+	if slot < 4 {
+		return m.banks[slot/2]&^0x01 | slot%2
+	}
+	return m.banks[slot-2]
+}
+
+func (m *MMC3) IRQ() bool {
+	return m.irq
+}
+
+func (m *MMC3) Step() {
+	m.cycle++
+}
+
+func (m *MMC3) PPUAddress(addr uint16) {
+	a12 := addr&0x1000 != 0
+	if a12 && !m.a12 && m.cycle-m.a12LowAt >= mmc3A12Filter {
+		m.clockIRQ()
+	}
+	if !a12 && m.a12 {
+		m.a12LowAt = m.cycle
+	}
+	m.a12 = a12
+}
+
+// clockIRQ カウンターが0かリロードが要求されていればラッチの値に、それ以外は1減らして、0になったらIRQを出す
+func (m *MMC3) clockIRQ() {
+	before := m.irqCounter
+	reload := m.irqReload
+	if m.irqCounter == 0 || m.irqReload {
+		m.irqCounter = m.irqLatch
+		m.irqReload = false
+	} else {
+		m.irqCounter--
+	}
+
+	if m.irqCounter != 0 || !m.irqEnabled {
+		return
+	}
+	if m.alternateIRQ && before == 0 && !reload {
+		return
+	}
+	m.irq = true
+}
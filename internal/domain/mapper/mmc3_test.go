@@ -0,0 +1,135 @@
+package mapper_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// clockA12 PPUのA12を十分な期間Lowにしてから立ち上げる(1scanline分)
+func clockA12(m mapper.Mapper) {
+	m.PPUAddress(0x0000)
+	for i := 0; i < 10; i++ {
+		m.Step()
+	}
+	m.PPUAddress(0x1000)
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestMMC3$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestMMC3(t *testing.T) {
+	Convey("TestMMC3", t, func() {
+		newMMC3 := func(config mapper.Config) mapper.Mapper {
+			config.MapperNo = 4
+			m, err := mapper.New(config)
+			So(err, ShouldBeNil)
+			return m
+		}
+		prg := newBankedROM(0x40000, 0x2000) // 8KB x 32
+		chr := newBankedROM(0x40000, 0x0400) // 1KB x 256
+
+		Convey("R6, R7で0x8000, 0xA000を切り替えて、0xC000, 0xE000は最後の2バンクに固定される", func() {
+			m := newMMC3(mapper.Config{PRG: prg, CHR: chr})
+			m.WritePRG(0x8000, 6)
+			m.WritePRG(0x8001, 3)
+			m.WritePRG(0x8000, 7)
+			m.WritePRG(0x8001, 5)
+			So(m.ReadPRG(0x8000), ShouldEqual, 3)
+			So(m.ReadPRG(0xA000), ShouldEqual, 5)
+			So(m.ReadPRG(0xC000), ShouldEqual, 30)
+			So(m.ReadPRG(0xE000), ShouldEqual, 31)
+
+			// PRGモード1は0x8000と0xC000を入れ替える
+			m.WritePRG(0x8000, 0x40)
+			So(m.ReadPRG(0x8000), ShouldEqual, 30)
+			So(m.ReadPRG(0xC000), ShouldEqual, 3)
+		})
+
+		Convey("R0, R1は2KB、R2-R5は1KBで、bit7で前半と後半を入れ替える", func() {
+			m := newMMC3(mapper.Config{PRG: prg, CHR: chr})
+			for i, bank := range []byte{9, 20, 100, 101, 102, 103} {
+				m.WritePRG(0x8000, byte(i))
+				m.WritePRG(0x8001, bank)
+			}
+			So(m.ReadCHR(0x0000), ShouldEqual, 8)
+			So(m.ReadCHR(0x0400), ShouldEqual, 9)
+			So(m.ReadCHR(0x0800), ShouldEqual, 20)
+			So(m.ReadCHR(0x1000), ShouldEqual, 100)
+			So(m.ReadCHR(0x1C00), ShouldEqual, 103)
+
+			m.WritePRG(0x8000, 0x80)
+			So(m.ReadCHR(0x0000), ShouldEqual, 100)
+			So(m.ReadCHR(0x1000), ShouldEqual, 8)
+			So(m.ReadCHR(0x1800), ShouldEqual, 20)
+		})
+
+		Convey("ミラーリングとPRG-RAMの保護を切り替えられる", func() {
+			m := newMMC3(mapper.Config{PRG: prg, CHR: chr})
+			m.WritePRG(0xA000, 1)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringHorizontal)
+			m.WritePRG(0xA000, 0)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringVertical)
+
+			m.WritePRG(0xA001, 0x80)
+			m.WritePRG(0x6000, 0x12)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x12)
+			m.WritePRG(0xA001, 0xC0)
+			m.WritePRG(0x6000, 0x34)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x12)
+			m.WritePRG(0xA001, 0x00)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0xFF)
+		})
+
+		Convey("A12の立ち上がりでカウンターを減らして、0になったらIRQを出す", func() {
+			m := newMMC3(mapper.Config{PRG: prg, CHR: chr})
+			m.WritePRG(0xC000, 3)
+			m.WritePRG(0xC001, 0)
+			m.WritePRG(0xE001, 0)
+
+			clockA12(m) // リロードして3
+			clockA12(m)
+			clockA12(m)
+			So(m.IRQ(), ShouldBeFalse)
+			clockA12(m)
+			So(m.IRQ(), ShouldBeTrue)
+
+			m.WritePRG(0xE000, 0)
+			So(m.IRQ(), ShouldBeFalse)
+		})
+
+		Convey("A12がLowの期間が短い立ち上がりは数えない", func() {
+			m := newMMC3(mapper.Config{PRG: prg, CHR: chr})
+			m.WritePRG(0xC000, 1)
+			m.WritePRG(0xC001, 0)
+			m.WritePRG(0xE001, 0)
+			clockA12(m) // リロードして1
+
+			// 背景のフェッチのようにネームテーブルとパターンを交互に読む
+			for i := 0; i < 16; i++ {
+				m.PPUAddress(0x2000)
+				m.Step()
+				m.PPUAddress(0x1000)
+			}
+			So(m.IRQ(), ShouldBeFalse)
+			clockA12(m)
+			So(m.IRQ(), ShouldBeTrue)
+		})
+
+		Convey("ラッチが0の場合、MMC3Bは毎回IRQを出して、MMC3Aは出さない", func() {
+			for _, tt := range []struct {
+				submapper int
+				want      bool
+			}{
+				{submapper: 0, want: true},
+				{submapper: 4, want: false},
+			} {
+				m := newMMC3(mapper.Config{Submapper: tt.submapper, PRG: prg, CHR: chr})
+				m.WritePRG(0xC000, 0)
+				m.WritePRG(0xE001, 0)
+				clockA12(m) // 0から0へのリロード
+				So(m.IRQ(), ShouldEqual, tt.want)
+			}
+		})
+	})
+}
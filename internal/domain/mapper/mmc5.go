@@ -0,0 +1,510 @@
+package mapper
+
+import (
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+func init() {
+	register(5, "MMC5", newMMC5)
+}
+
+// MMC5 mapper 5 (ExROM)
+// doc: https://www.nesdev.org/wiki/MMC5
+//
+// PPUのアドレスバスを監視して、同じネームテーブルのアドレスを3回連続で読んだらscanlineの始まりとみなす
+// scanlineの始まりからの読み込みの数で、背景(0-127, 160-167)とスプライト(128-159)のフェッチを区別する
+// CPUが3サイクル以上PPUのメモリを読まなければ描画中(in frame)ではなくなる
+type MMC5 struct {
+	base
+
+	prgMode    byte // 0x5100
+	chrMode    byte // 0x5101
+	ramProtect [2]byte
+	exRAMMode  byte    // 0x5104
+	nametables [4]byte // 0x5105
+	fillTile   byte
+	fillColor  byte
+	prgBanks   [5]byte // 0x5113-0x5117
+	chrA       [8]int  // 0x5120-0x5127 スプライト(8x16の場合)とそれ以外
+	chrB       [4]int  // 0x5128-0x512B 8x16の場合の背景
+	lastCHRB   bool    // 描画中でない時は最後に書き込んだ方を使う
+	chrUpper   int     // 0x5130
+
+	splitControl byte // 0x5200
+	splitScroll  byte // 0x5201
+	splitBank    int  // 0x5202
+
+	irqTarget  byte // 0x5203
+	irqEnabled bool
+	irqPending bool
+
+	multiplicand byte
+	multiplier   byte
+
+	exRAM [0x400]byte
+
+	spriteSize16     bool // PPUCTRLのbit5
+	renderingEnabled bool // PPUMASKのbit3, 4
+
+	// scanlineの検出
+	inFrame  bool
+	scanline int
+	lastAddr uint16
+	matches  int
+	fetch    int // scanlineの始まりから何回目の読み込みか
+	idle     int // PPUのメモリを読まなかったCPUサイクル数
+
+	// 背景のタイルごとに、ネームテーブルの読み込みで決まる値
+	exAttribute byte
+	splitTile   bool
+
+	audio *apu.MMC5Audio
+}
+
+const (
+	mmc5PRGBankSize = 0x2000 // 8KB
+	mmc5PRGRAMSize  = 0x10000
+
+	// 1scanlineの読み込みの数
+	mmc5BackgroundFetches = 128 // 32タイル x (ネームテーブル, attribute, パターン下位, パターン上位)
+	mmc5SpriteFetches     = 32  // 8スロット x (ネームテーブル x2, パターン下位, パターン上位)
+	mmc5PrefetchFetches   = 8   // 次のscanlineの2タイル
+
+	mmc5IdleCycles = 3
+
+	mmc5ExRAMStart = 0x5C00 // 0x5C00-0x5FFF
+)
+
+// MMC5のExRAMのモード(0x5104)
+const (
+	mmc5ExRAMNametable = iota
+	mmc5ExRAMExtendedAttribute
+	mmc5ExRAMReadWrite
+	mmc5ExRAMReadOnly
+)
+
+// MMC5のネームテーブルの割り当て(0x5105)
+const (
+	mmc5NametablePage0 = iota
+	mmc5NametablePage1
+	mmc5NametableExRAM
+	mmc5NametableFill
+)
+
+var (
+	_ AudioMapper          = (*MMC5)(nil)
+	_ ppu.NametableMapper  = (*MMC5)(nil)
+	_ ppu.RegisterObserver = (*MMC5)(nil)
+)
+
+func newMMC5(config Config) (Mapper, error) {
+	m := &MMC5{
+		base:  newBase(config, mmc5PRGRAMSize),
+		audio: apu.NewMMC5Audio(),
+	}
+	m.Reset(ResetTypeHard)
+	return m, nil
+}
+
+// Reset 電源を入れた時はPRG, CHRともモード3で、0xE000-0xFFFFに最後のバンクを置く
+// ExRAMとscanlineの検出の状態も消す
+func (m *MMC5) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType != ResetTypeHard {
+		return
+	}
+	*m = MMC5{
+		base:    m.base,
+		prgMode: 3,
+		chrMode: 3,
+		audio:   m.audio,
+	}
+	m.prgBanks[4] = 0xFF
+}
+
+func (m *MMC5) ExpansionAudio() apu.ExpansionAudio {
+	return m.audio
+}
+
+func (m *MMC5) ReadPRG(addr uint16) byte {
+	if value, ok := m.audio.Read(addr); ok {
+		return value
+	}
+
+	switch {
+	case addr >= addrPRGROMStart:
+		bank, rom := m.prgBank(addr)
+		if rom {
+			return m.readPRGBank(mmc5PRGBankSize, bank, addr)
+		}
+		return m.readRAMBank(bank, addr)
+	case addr >= addrPRGRAMStart:
+		return m.readRAMBank(int(m.prgBanks[0]), addr)
+	case addr >= mmc5ExRAMStart:
+		if m.exRAMMode < mmc5ExRAMReadWrite {
+			return openBus
+		}
+		return m.exRAM[addr-mmc5ExRAMStart]
+	case addr == 0x5204:
+		var value byte
+		if m.irqPending {
+			value |= 0x80
+		}
+		if m.inFrame {
+			value |= 0x40
+		}
+		m.irqPending = false
+		return value
+	case addr == 0x5205:
+		return byte(uint16(m.multiplicand) * uint16(m.multiplier))
+	case addr == 0x5206:
+		return byte(uint16(m.multiplicand) * uint16(m.multiplier) >> 8)
+	}
+	return openBus
+}
+
+func (m *MMC5) WritePRG(addr uint16, value byte) {
+	m.audio.Write(addr, value)
+
+	switch {
+	case addr >= addrPRGROMStart:
+		if bank, rom := m.prgBank(addr); !rom {
+			m.writeRAMBank(bank, addr, value)
+		}
+	case addr >= addrPRGRAMStart:
+		m.writeRAMBank(int(m.prgBanks[0]), addr, value)
+	case addr >= mmc5ExRAMStart:
+		m.writeExRAM(addr-mmc5ExRAMStart, value)
+	default:
+		m.writeRegister(addr, value)
+	}
+}
+
+func (m *MMC5) writeRegister(addr uint16, value byte) {
+	switch {
+	case addr == 0x5100:
+		m.prgMode = value & 0x03
+	case addr == 0x5101:
+		m.chrMode = value & 0x03
+	case addr == 0x5102 || addr == 0x5103:
+		m.ramProtect[addr-0x5102] = value & 0x03
+	case addr == 0x5104:
+		m.exRAMMode = value & 0x03
+	case addr == 0x5105:
+		for i := range m.nametables {
+			m.nametables[i] = (value >> (i * 2)) & 0x03
+		}
+	case addr == 0x5106:
+		m.fillTile = value
+	case addr == 0x5107:
+		m.fillColor = value & 0x03
+	case addr >= 0x5113 && addr <= 0x5117:
+		m.prgBanks[addr-0x5113] = value
+	case addr >= 0x5120 && addr <= 0x5127:
+		m.chrA[addr-0x5120] = int(value) | m.chrUpper<<8
+		m.lastCHRB = false
+	case addr >= 0x5128 && addr <= 0x512B:
+		m.chrB[addr-0x5128] = int(value) | m.chrUpper<<8
+		m.lastCHRB = true
+	case addr == 0x5130:
+		m.chrUpper = int(value & 0x03)
+	case addr == 0x5200:
+		m.splitControl = value
+	case addr == 0x5201:
+		m.splitScroll = value
+	case addr == 0x5202:
+		m.splitBank = int(value)
+	case addr == 0x5203:
+		m.irqTarget = value
+	case addr == 0x5204:
+		m.irqEnabled = value&0x80 != 0
+	case addr == 0x5205:
+		m.multiplicand = value
+	case addr == 0x5206:
+		m.multiplier = value
+	}
+}
+
+// writeExRAM ネームテーブルとして使うモードでは、描画中でない時に書き込むと0が書き込まれる
+func (m *MMC5) writeExRAM(offset uint16, value byte) {
+	switch m.exRAMMode {
+	case mmc5ExRAMReadOnly:
+		return
+	case mmc5ExRAMNametable, mmc5ExRAMExtendedAttribute:
+		if !m.inFrame {
+			value = 0
+		}
+	}
+	m.exRAM[offset] = value
+}
+
+// prgBank 0x8000-0xFFFFの8KBのバンク番号と、ROMかRAMか
+func (m *MMC5) prgBank(addr uint16) (bank int, rom bool) {
+	slot := int(addr-addrPRGROMStart) / mmc5PRGBankSize
+	var value byte
+	switch m.prgMode {
+	case 0:
+		// 32KB
+		value = m.prgBanks[4]&^0x03 | byte(slot)
+	case 1:
+		// 16KB x2
+		value = m.prgBanks[2+slot/2*2]&^0x01 | byte(slot&0x01)
+	case 2:
+		// 16KB + 8KB x2
+		if slot < 2 {
+			value = m.prgBanks[2]&^0x01 | byte(slot)
+		} else {
+			value = m.prgBanks[1+slot]
+		}
+	default:
+		// 8KB x4
+		value = m.prgBanks[1+slot]
+	}
+	// 0x5117は常にROM
+	rom = slot == 3 || m.prgMode == 0 || (m.prgMode == 1 && slot >= 2) || value&0x80 != 0
+	return int(value & 0x7F), rom
+}
+
+func (m *MMC5) readRAMBank(bank int, addr uint16) byte {
+	if len(m.prgRAM) == 0 {
+		return openBus
+	}
+	return m.prgRAM[m.ramIndex(bank, addr)]
+}
+
+// writeRAMBank 0x5102に2、0x5103に1を書き込んだ時だけ書き込める
+func (m *MMC5) writeRAMBank(bank int, addr uint16, value byte) {
+	if len(m.prgRAM) == 0 || m.ramProtect != [2]byte{0x02, 0x01} {
+		return
+	}
+	m.prgRAM[m.ramIndex(bank, addr)] = value
+}
+
+func (m *MMC5) ramIndex(bank int, addr uint16) int {
+	return bankIndex(len(m.prgRAM), mmc5PRGBankSize, bank&0x07) + int(addr)%mmc5PRGBankSize%len(m.prgRAM)
+}
+
+func (m *MMC5) ReadCHR(addr uint16) byte {
+	if m.backgroundFetch() {
+		switch {
+		case m.splitTile:
+			offset := addr&0x0FF8 | uint16(m.splitY()&0x07)
+			return m.readCHRBank(0x1000, m.splitBank, offset)
+		case m.exRAMMode == mmc5ExRAMExtendedAttribute:
+			bank := int(m.exAttribute&0x3F) | m.chrUpper<<6
+			return m.readCHRBank(0x1000, bank, addr)
+		}
+	}
+	size, bank := m.chrBank(addr)
+	return m.readCHRBank(size, bank, addr)
+}
+
+func (m *MMC5) WriteCHR(addr uint16, value byte) {
+	size, bank := m.chrBank(addr)
+	m.writeCHRBank(size, bank, addr, value)
+}
+
+// chrBank addrのバンクのサイズと番号
+// 8x16のスプライトの描画中は、スプライトはA(0x5120-0x5127)、背景はB(0x5128-0x512B)を使う
+func (m *MMC5) chrBank(addr uint16) (size int, bank int) {
+	useB := m.lastCHRB
+	if m.inFrame && m.renderingEnabled {
+		useB = m.spriteSize16 && !m.spriteFetch()
+	}
+
+	// バンクのサイズごとに範囲の最後のレジスタを使う(A 8KB: 0x5127, 4KB: 0x5123, 0x5127, 2KB: 0x5121, 0x5123, ...)
+	size = 0x2000 >> m.chrMode
+	slot := int(addr) / size
+	if useB {
+		// Bは4KB分のレジスタで、0x0000-0x0FFFと0x1000-0x1FFFが同じ
+		slots := max(0x1000/size, 1)
+		step := len(m.chrB) / slots
+		return size, m.chrB[(slot%slots+1)*step-1]
+	}
+	step := len(m.chrA) >> m.chrMode
+	return size, m.chrA[(slot+1)*step-1]
+}
+
+func (m *MMC5) Mirroring() ppu.Mirroring {
+	switch m.nametables {
+	case [4]byte{0, 1, 0, 1}:
+		return ppu.MirroringVertical
+	case [4]byte{0, 0, 1, 1}:
+		return ppu.MirroringHorizontal
+	case [4]byte{1, 1, 1, 1}:
+		return ppu.MirroringSingleScreenUpper
+	}
+	return ppu.MirroringSingleScreenLower
+}
+
+func (m *MMC5) NametablePage(table int) int {
+	return int(m.nametables[table&0x03] & 0x01)
+}
+
+func (m *MMC5) ReadNametable(addr uint16) (byte, bool) {
+	offset := (addr - 0x2000) % 0x400
+	if m.backgroundFetch() {
+		if m.splitTile {
+			return m.splitNametable(offset), true
+		}
+		if m.exRAMMode == mmc5ExRAMExtendedAttribute && offset >= 0x3C0 {
+			// 4つの2x2タイル全てに同じパレットを返す
+			palette := m.exAttribute >> 6
+			return palette * 0x55, true
+		}
+	}
+
+	switch m.nametables[(addr-0x2000)/0x400%4] {
+	case mmc5NametableExRAM:
+		if m.exRAMMode >= mmc5ExRAMReadWrite {
+			return 0, true
+		}
+		return m.exRAM[offset], true
+	case mmc5NametableFill:
+		if offset >= 0x3C0 {
+			return m.fillColor * 0x55, true
+		}
+		return m.fillTile, true
+	}
+	return 0, false
+}
+
+func (m *MMC5) WriteNametable(addr uint16, value byte) bool {
+	offset := (addr - 0x2000) % 0x400
+	switch m.nametables[(addr-0x2000)/0x400%4] {
+	case mmc5NametableExRAM:
+		if m.exRAMMode < mmc5ExRAMReadOnly {
+			m.exRAM[offset] = value
+		}
+		return true
+	case mmc5NametableFill:
+		return true
+	}
+	return false
+}
+
+// splitNametable 分割した部分はExRAMをネームテーブルとして、0x5201のスクロールで縦に動かす
+func (m *MMC5) splitNametable(offset uint16) byte {
+	y := m.splitY()
+	tile, _ := m.backgroundTile()
+	if offset < 0x3C0 {
+		return m.exRAM[y/8*32+tile]
+	}
+	attribute := m.exRAM[0x3C0+y/32*8+tile/4]
+	shift := (y/16&0x01)*4 + (tile/2&0x01)*2
+	return (attribute >> shift & 0x03) * 0x55
+}
+
+func (m *MMC5) IRQ() bool {
+	return m.irqEnabled && m.irqPending
+}
+
+func (m *MMC5) Step() {
+	m.idle++
+	if m.idle >= mmc5IdleCycles {
+		m.inFrame = false
+		m.lastAddr = 0
+		m.matches = 0
+	}
+}
+
+// PPURegister 8x16のスプライトと描画が有効かを見る
+func (m *MMC5) PPURegister(addr uint16, value byte) {
+	switch addr {
+	case 0x2000:
+		m.spriteSize16 = value&0x20 != 0
+	case 0x2001:
+		m.renderingEnabled = value&0x18 != 0
+		if !m.renderingEnabled {
+			m.inFrame = false
+		}
+	}
+}
+
+func (m *MMC5) PPUAddress(addr uint16) {
+	m.idle = 0
+	if addr == m.lastAddr {
+		m.matches++
+	} else {
+		m.matches = 0
+	}
+	m.lastAddr = addr
+
+	if m.matches == 2 && addr >= 0x2000 && addr < 0x3000 {
+		m.detectScanline()
+		m.fetch = 0
+	} else {
+		m.fetch++
+	}
+
+	if tile, phase := m.backgroundTile(); m.backgroundFetch() && phase == 0 {
+		m.splitTile = m.inSplit(tile)
+		m.exAttribute = m.exRAM[(addr-0x2000)%0x400]
+	}
+}
+
+// detectScanline 描画中でなければ描画の始まり(scanline 0)、描画中ならscanlineを進めて0x5203と一致したらIRQを出す
+func (m *MMC5) detectScanline() {
+	if !m.inFrame {
+		m.inFrame = true
+		m.scanline = 0
+		m.irqPending = false
+		return
+	}
+	m.scanline++
+	if m.irqTarget != 0 && m.scanline == int(m.irqTarget) {
+		m.irqPending = true
+	}
+}
+
+// spriteFetch スプライトのパターンを読み込んでいるか
+func (m *MMC5) spriteFetch() bool {
+	return m.fetch >= mmc5BackgroundFetches && m.fetch < mmc5BackgroundFetches+mmc5SpriteFetches
+}
+
+// backgroundFetch 描画中に背景のタイルを読み込んでいるか
+func (m *MMC5) backgroundFetch() bool {
+	if !m.inFrame {
+		return false
+	}
+	_, phase := m.backgroundTile()
+	return phase >= 0
+}
+
+// backgroundTile 読み込み中の背景のタイルの画面上の位置(0-33)と、読み込みの種類(0: ネームテーブル, 1: attribute, 2, 3: パターン)
+// scanlineの始まりで読むのは3番目のタイル(最初の2タイルは前のscanlineの最後に読み込む)
+func (m *MMC5) backgroundTile() (tile int, phase int) {
+	switch {
+	case m.fetch < mmc5BackgroundFetches:
+		return 2 + m.fetch/4, m.fetch % 4
+	case m.fetch < mmc5BackgroundFetches+mmc5SpriteFetches:
+		return 0, -1
+	case m.fetch < mmc5BackgroundFetches+mmc5SpriteFetches+mmc5PrefetchFetches:
+		fetch := m.fetch - mmc5BackgroundFetches - mmc5SpriteFetches
+		return fetch / 4, fetch % 4
+	}
+	return 0, -1
+}
+
+// inSplit タイルが縦の分割の範囲に入るか
+// 0x5200 bit7: 有効, bit6: 0の場合は左側、1の場合は右側, bit0-4: 分割する位置のタイル
+func (m *MMC5) inSplit(tile int) bool {
+	if m.splitControl&0x80 == 0 || m.exRAMMode > mmc5ExRAMExtendedAttribute {
+		return false
+	}
+	count := int(m.splitControl & 0x1F)
+	if m.splitControl&0x40 == 0 {
+		return tile < count
+	}
+	return tile >= count
+}
+
+// splitY 分割した部分のスクロールを加えたY座標、次のscanlineの最初の2タイルは次のscanlineとして数える
+func (m *MMC5) splitY() int {
+	line := m.scanline
+	if m.fetch >= mmc5BackgroundFetches {
+		line++
+	}
+	return (int(m.splitScroll) + line) % 240
+}
@@ -0,0 +1,246 @@
+package mapper_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// mmc5Fetch PPUが1scanlineで読み込むアドレスの順番を再現する
+// visitにはscanlineの始まりから何回目の読み込みかと、読み込んだ値を渡す
+type mmc5Fetch struct {
+	m     mapper.Mapper
+	visit func(i int, addr uint16, value byte)
+}
+
+func (f *mmc5Fetch) read(i int, addr uint16) {
+	f.m.PPUAddress(addr)
+	var value byte
+	if addr >= 0x2000 {
+		// 本体のVRAMは0として扱う
+		value, _ = f.m.(ppu.NametableMapper).ReadNametable(addr)
+	} else {
+		value = f.m.ReadCHR(addr)
+	}
+	if f.visit != nil {
+		f.visit(i, addr, value)
+	}
+	if i%4 == 3 {
+		f.m.Step()
+	}
+}
+
+// frame 描画するscanlineの数だけ読み込む、最初のscanlineの前にpre-renderの最後の2回の読み込みをする
+func (f *mmc5Fetch) frame(lines int) {
+	nametable := func(line, tile int) uint16 { return 0x2000 + uint16(line/8*32+tile%32) }
+	f.read(-2, nametable(0, 2))
+	f.read(-1, nametable(0, 2))
+	for line := 0; line < lines; line++ {
+		i := 0
+		for tile := 2; tile < 34; tile++ {
+			f.read(i, nametable(line, tile))
+			f.read(i+1, 0x23C0+uint16(line/32*8+tile%32/4))
+			f.read(i+2, uint16(tile)*16+uint16(line%8))
+			f.read(i+3, uint16(tile)*16+uint16(line%8)+8)
+			i += 4
+		}
+		for slot := 0; slot < 8; slot++ {
+			f.read(i, 0x2000)
+			f.read(i+1, 0x2000)
+			f.read(i+2, 0x1FF0)
+			f.read(i+3, 0x1FF8)
+			i += 4
+		}
+		for tile := 0; tile < 2; tile++ {
+			f.read(i, nametable(line+1, tile))
+			f.read(i+1, 0x23C0)
+			f.read(i+2, uint16(tile)*16)
+			f.read(i+3, uint16(tile)*16+8)
+			i += 4
+		}
+		f.read(i, nametable(line+1, 2))
+		f.read(i+1, nametable(line+1, 2))
+	}
+}
+
+// idle 描画が終わってPPUのメモリを読まない期間
+func (f *mmc5Fetch) idle() {
+	for i := 0; i < 10; i++ {
+		f.m.Step()
+	}
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestMMC5$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestMMC5(t *testing.T) {
+	Convey("TestMMC5", t, func() {
+		newMMC5 := func() mapper.Mapper {
+			m, err := mapper.New(mapper.Config{
+				MapperNo: 5,
+				PRG:      newBankedROM(0x40000, 0x2000),
+				CHR:      newBankedROM(0x40000, 0x0400),
+			})
+			So(err, ShouldBeNil)
+			return m
+		}
+		ppuRegister := func(m mapper.Mapper, addr uint16, value byte) {
+			m.(ppu.RegisterObserver).PPURegister(addr, value)
+		}
+
+		Convey("起動時はPRGモード3で0xE000に最後のバンクが入る", func() {
+			m := newMMC5()
+			So(m.ReadPRG(0xE000), ShouldEqual, 31)
+
+			m.WritePRG(0x5100, 0x01)
+			m.WritePRG(0x5115, 0x85)
+			m.WritePRG(0x5117, 0x07)
+			So(m.ReadPRG(0x8000), ShouldEqual, 4)
+			So(m.ReadPRG(0xA000), ShouldEqual, 5)
+			So(m.ReadPRG(0xC000), ShouldEqual, 6)
+			So(m.ReadPRG(0xE000), ShouldEqual, 7)
+
+			m.WritePRG(0x5100, 0x00)
+			So(m.ReadPRG(0x8000), ShouldEqual, 4)
+			So(m.ReadPRG(0xE000), ShouldEqual, 7)
+		})
+
+		Convey("PRG-RAMは0x5102, 0x5103で書き込みを許可して、0x8000-0xDFFFにも割り当てられる", func() {
+			m := newMMC5()
+			m.WritePRG(0x5113, 0x01)
+			m.WritePRG(0x6000, 0x11)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x00)
+
+			m.WritePRG(0x5102, 0x02)
+			m.WritePRG(0x5103, 0x01)
+			m.WritePRG(0x6000, 0x11)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x11)
+
+			m.WritePRG(0x5114, 0x01) // bit7が0なのでRAM
+			So(m.ReadPRG(0x8000), ShouldEqual, 0x11)
+			m.WritePRG(0x8001, 0x22)
+			m.WritePRG(0x5113, 0x00)
+			So(m.ReadPRG(0x6001), ShouldEqual, 0x00)
+			m.WritePRG(0x5113, 0x01)
+			So(m.ReadPRG(0x6001), ShouldEqual, 0x22)
+		})
+
+		Convey("0x5205, 0x5206は8bitの乗算器", func() {
+			m := newMMC5()
+			m.WritePRG(0x5205, 0xC8)
+			m.WritePRG(0x5206, 0x7B)
+			So(m.ReadPRG(0x5205), ShouldEqual, byte(200*123&0xFF))
+			So(m.ReadPRG(0x5206), ShouldEqual, byte(200*123>>8))
+		})
+
+		Convey("fill modeのネームテーブルは0x5106のタイルと0x5107のパレットを返す", func() {
+			m := newMMC5()
+			nametable := m.(ppu.NametableMapper)
+			m.WritePRG(0x5105, 0xFF)
+			m.WritePRG(0x5106, 0x42)
+			m.WritePRG(0x5107, 0x02)
+			value, ok := nametable.ReadNametable(0x2C10)
+			So(ok, ShouldBeTrue)
+			So(value, ShouldEqual, 0x42)
+			value, _ = nametable.ReadNametable(0x23C0)
+			So(value, ShouldEqual, 0xAA)
+
+			// ExRAMをネームテーブルにする
+			m.WritePRG(0x5105, 0x02)
+			So(nametable.WriteNametable(0x2005, 0x33), ShouldBeTrue)
+			value, _ = nametable.ReadNametable(0x2005)
+			So(value, ShouldEqual, 0x33)
+			So(nametable.NametablePage(1), ShouldEqual, 0)
+		})
+
+		Convey("同じネームテーブルのアドレスを3回読むとscanlineを数えて、0x5203のscanlineでIRQを出す", func() {
+			m := newMMC5()
+			f := &mmc5Fetch{m: m}
+			ppuRegister(m, 0x2001, 0x18)
+			m.WritePRG(0x5203, 3)
+			m.WritePRG(0x5204, 0x80)
+
+			f.frame(3)
+			So(m.IRQ(), ShouldBeFalse)
+			f.frame(1)
+			So(m.IRQ(), ShouldBeTrue)
+
+			status := m.ReadPRG(0x5204)
+			So(status, ShouldEqual, 0xC0)
+			So(m.IRQ(), ShouldBeFalse)
+
+			// 描画が終わるとin frameではなくなる
+			f.idle()
+			So(m.ReadPRG(0x5204), ShouldEqual, 0x00)
+		})
+
+		Convey("8x16のスプライトの描画中は、背景はB、スプライトはAのCHRバンクを使う", func() {
+			m := newMMC5()
+			f := &mmc5Fetch{m: m}
+			ppuRegister(m, 0x2000, 0x20)
+			ppuRegister(m, 0x2001, 0x18)
+			for i := 0; i < 8; i++ {
+				m.WritePRG(0x5120+uint16(i), byte(0x10+i))
+			}
+			for i := 0; i < 4; i++ {
+				m.WritePRG(0x5128+uint16(i), byte(0x20+i))
+			}
+			// 描画中でない時は最後に書き込んだB
+			So(m.ReadCHR(0x0000), ShouldEqual, 0x20)
+			So(m.ReadCHR(0x1C00), ShouldEqual, 0x23)
+
+			var background, sprite byte
+			f.visit = func(i int, addr uint16, value byte) {
+				switch i {
+				case 2:
+					background = value
+				case 130:
+					sprite = value
+				}
+			}
+			f.frame(1)
+			So(background, ShouldEqual, 0x20)
+			So(sprite, ShouldEqual, 0x17)
+		})
+
+		Convey("拡張attributeモードでは、タイルごとにExRAMでパレットと4KBのCHRバンクを選ぶ", func() {
+			m := newMMC5()
+			f := &mmc5Fetch{m: m}
+			ppuRegister(m, 0x2001, 0x18)
+			m.WritePRG(0x5104, 0x02)
+			m.WritePRG(0x5C02, 0xC5) // タイル2: パレット3、CHRバンク5
+			m.WritePRG(0x5104, 0x01)
+
+			values := map[int]byte{}
+			f.visit = func(i int, addr uint16, value byte) {
+				values[i] = value
+			}
+			f.frame(1)
+			So(values[1], ShouldEqual, 0xFF)
+			// 4KBのバンク5は1KBのバンク20から始まる
+			So(values[2], ShouldEqual, 20)
+		})
+
+		Convey("縦の分割の範囲は、ExRAMをネームテーブルとして0x5202のCHRバンクで描画する", func() {
+			m := newMMC5()
+			f := &mmc5Fetch{m: m}
+			ppuRegister(m, 0x2001, 0x18)
+			m.WritePRG(0x5104, 0x02)
+			m.WritePRG(0x5C00+2, 0x77) // 1行目のタイル2
+			m.WritePRG(0x5104, 0x00)
+			m.WritePRG(0x5200, 0x80|0x04) // 左側の4タイル
+			m.WritePRG(0x5202, 0x03)
+			m.WritePRG(0x5120, 0x09)
+
+			values := map[int]byte{}
+			f.visit = func(i int, addr uint16, value byte) {
+				values[i] = value
+			}
+			f.frame(1)
+			So(values[0], ShouldEqual, 0x77)
+			So(values[2], ShouldEqual, 12) // 4KBのバンク3
+			// 分割の範囲外のタイル6は通常のCHRバンク
+			So(values[4*4+2], ShouldEqual, 9)
+		})
+	})
+}
@@ -0,0 +1,175 @@
+package mapper
+
+import (
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+func init() {
+	register(19, "Namco 163", newN163)
+}
+
+// N163 mapper 19 (Namco 129, 163)
+// doc: https://www.nesdev.org/wiki/Namco_163
+//
+// 0x4800: 拡張音源のデータ、0x5000, 0x5800: IRQのカウンターの下位・上位(bit7: IRQの有効化)
+// 0x8000-0xB800: 1KBのCHRバンク、0xC000-0xD800: ネームテーブル(0xE0以上は本体VRAMのページ、それ以外はCHR-ROMのバンク)
+// 0xE000: 0x8000のPRGバンク(bit6: 音源の停止)、0xE800: 0xA000のPRGバンク、0xF000: 0xC000のPRGバンク(0xE000は最後のバンクに固定)
+// 0xF800: PRG-RAMの書き込み保護と拡張音源のアドレス
+//
+// CHRバンクの0xE0以上の値で本体VRAMをパターンテーブルに使う設定は未対応で、CHR-ROMのバンクとして扱う
+type N163 struct {
+	base
+
+	chrBanks       [8]int
+	nametableBanks [4]byte
+	prgBanks       [3]int
+	writeProtect   byte
+
+	irqCounter uint16
+	irqEnabled bool
+	irq        bool
+
+	audio *apu.N163
+}
+
+const (
+	n163PRGBankSize = 0x2000 // 8KB
+	n163CHRBankSize = 0x0400 // 1KB
+
+	// n163CIRAMBank これ以上のネームテーブルのバンクは本体VRAMのページ(bit0)
+	n163CIRAMBank = 0xE0
+	n163IRQMax    = 0x7FFF
+
+	n163AudioDisable = 0x40
+)
+
+var (
+	_ AudioMapper         = (*N163)(nil)
+	_ ppu.NametableMapper = (*N163)(nil)
+)
+
+func newN163(config Config) (Mapper, error) {
+	return &N163{
+		base:  newBase(config, defaultPRGRAM),
+		audio: apu.NewN163(),
+	}, nil
+}
+
+func (m *N163) ExpansionAudio() apu.ExpansionAudio {
+	return m.audio
+}
+
+// Reset 電源の入れ直しでバンクとIRQのレジスタを0に戻す
+func (m *N163) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		*m = N163{base: m.base, audio: m.audio}
+	}
+}
+
+func (m *N163) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= 0xE000:
+		return m.readPRGBank(n163PRGBankSize, -1, addr)
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(n163PRGBankSize, m.prgBanks[(addr-addrPRGROMStart)/n163PRGBankSize], addr)
+	case addr >= addrPRGRAMStart:
+		return m.readPRGRAM(addr)
+	case addr >= 0x5800:
+		value := byte(m.irqCounter >> 8)
+		if m.irqEnabled {
+			value |= 0x80
+		}
+		return value
+	case addr >= 0x5000:
+		return byte(m.irqCounter)
+	case addr >= 0x4800:
+		value, _ := m.audio.Read(addr)
+		return value
+	}
+	return openBus
+}
+
+func (m *N163) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= 0xF800:
+		m.writeProtect = value
+		m.audio.Write(addr, value)
+	case addr >= 0xE000:
+		m.prgBanks[(addr-0xE000)/0x0800] = int(value & 0x3F)
+		if addr < 0xE800 {
+			m.audio.SetDisabled(value&n163AudioDisable != 0)
+		}
+	case addr >= 0xC000:
+		m.nametableBanks[(addr-0xC000)/0x0800] = value
+	case addr >= addrPRGROMStart:
+		m.chrBanks[(addr-addrPRGROMStart)/0x0800] = int(value)
+	case addr >= addrPRGRAMStart:
+		if m.prgRAMWritable(addr) {
+			m.writePRGRAM(addr, value)
+		}
+	case addr >= 0x5800:
+		m.irqCounter = m.irqCounter&0x00FF | uint16(value&0x7F)<<8
+		m.irqEnabled = value&0x80 != 0
+		m.irq = false
+	case addr >= 0x5000:
+		m.irqCounter = m.irqCounter&0xFF00 | uint16(value)
+		m.irq = false
+	case addr >= 0x4800:
+		m.audio.Write(addr, value)
+	}
+}
+
+// prgRAMWritable 0xF800の上位4bitが0100の時に書き込める、下位4bitは2KBごとの書き込み保護
+func (m *N163) prgRAMWritable(addr uint16) bool {
+	if m.writeProtect&0xF0 != 0x40 {
+		return false
+	}
+	return m.writeProtect>>((addr-addrPRGRAMStart)/0x0800)&0x01 == 0
+}
+
+func (m *N163) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(n163CHRBankSize, m.chrBanks[addr/n163CHRBankSize], addr)
+}
+
+func (m *N163) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(n163CHRBankSize, m.chrBanks[addr/n163CHRBankSize], addr, value)
+}
+
+func (m *N163) NametablePage(table int) int {
+	return int(m.nametableBanks[table&0x03] & 0x01)
+}
+
+// ReadNametable バンクが0xE0より小さい場合はCHR-ROMをネームテーブルにする
+func (m *N163) ReadNametable(addr uint16) (byte, bool) {
+	bank := m.nametableBanks[(addr-0x2000)/n163CHRBankSize%4]
+	if bank >= n163CIRAMBank {
+		return 0, false
+	}
+	return m.readCHRBank(n163CHRBankSize, int(bank), addr), true
+}
+
+func (m *N163) WriteNametable(addr uint16, value byte) bool {
+	bank := m.nametableBanks[(addr-0x2000)/n163CHRBankSize%4]
+	if bank >= n163CIRAMBank {
+		return false
+	}
+	m.writeCHRBank(n163CHRBankSize, int(bank), addr, value)
+	return true
+}
+
+func (m *N163) IRQ() bool {
+	return m.irq
+}
+
+// Step カウンターはCPUの1サイクルごとに増え、0x7FFFになったらIRQを出して止まる
+func (m *N163) Step() {
+	if !m.irqEnabled || m.irqCounter == n163IRQMax {
+		return
+	}
+	m.irqCounter++
+	if m.irqCounter == n163IRQMax {
+		m.irq = true
+	}
+}
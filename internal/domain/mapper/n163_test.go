@@ -0,0 +1,81 @@
+package mapper_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestN163$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestN163(t *testing.T) {
+	Convey("TestN163", t, func() {
+		m, err := mapper.New(mapper.Config{
+			MapperNo: 19,
+			PRG:      newBankedROM(0x40000, 0x2000),
+			CHR:      newBankedROM(0x40000, 0x0400),
+		})
+		So(err, ShouldBeNil)
+
+		Convey("0xE000, 0xE800, 0xF000でPRGバンク、0x8000-0xB800でCHRバンクを切り替える", func() {
+			m.WritePRG(0xE000, 0x41)
+			m.WritePRG(0xE800, 0x02)
+			m.WritePRG(0xF000, 0x03)
+			m.WritePRG(0xB800, 0x55)
+			So(m.ReadPRG(0x8000), ShouldEqual, 1)
+			So(m.ReadPRG(0xA000), ShouldEqual, 2)
+			So(m.ReadPRG(0xC000), ShouldEqual, 3)
+			So(m.ReadPRG(0xE000), ShouldEqual, 31)
+			So(m.ReadCHR(0x1C00), ShouldEqual, 0x55)
+		})
+
+		Convey("ネームテーブルのバンクが0xE0以上は本体VRAMのページ、それ以外はCHR-ROM", func() {
+			nametable := m.(ppu.NametableMapper)
+			m.WritePRG(0xC000, 0xE0)
+			m.WritePRG(0xC800, 0xE1)
+			m.WritePRG(0xD000, 0x07)
+			So(nametable.NametablePage(0), ShouldEqual, 0)
+			So(nametable.NametablePage(1), ShouldEqual, 1)
+			_, ok := nametable.ReadNametable(0x2400)
+			So(ok, ShouldBeFalse)
+			value, ok := nametable.ReadNametable(0x2810)
+			So(ok, ShouldBeTrue)
+			So(value, ShouldEqual, 7)
+		})
+
+		Convey("PRG-RAMは0xF800の上位4bitが0100で、保護されていない2KBだけ書き込める", func() {
+			m.WritePRG(0x6000, 0x11)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x00)
+			m.WritePRG(0xF800, 0x42)
+			m.WritePRG(0x6000, 0x11)
+			m.WritePRG(0x6800, 0x22)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x11)
+			So(m.ReadPRG(0x6800), ShouldEqual, 0x00)
+		})
+
+		Convey("IRQのカウンターは0x7FFFまで増えてIRQを出し、読み込める", func() {
+			m.WritePRG(0x5000, 0xFD)
+			m.WritePRG(0x5800, 0xFF)
+			So(m.ReadPRG(0x5000), ShouldEqual, 0xFD)
+			So(m.ReadPRG(0x5800), ShouldEqual, 0xFF)
+			m.Step()
+			So(m.IRQ(), ShouldBeFalse)
+			m.Step()
+			So(m.IRQ(), ShouldBeTrue)
+			m.Step()
+			So(m.ReadPRG(0x5000), ShouldEqual, 0xFF)
+			m.WritePRG(0x5000, 0x00)
+			So(m.IRQ(), ShouldBeFalse)
+		})
+
+		Convey("拡張音源の内部RAMは0xF800でアドレスを指定して0x4800で読み書きする", func() {
+			m.WritePRG(0xF800, 0x80|0x10)
+			m.WritePRG(0x4800, 0xAB)
+			m.WritePRG(0x4800, 0xCD)
+			m.WritePRG(0xF800, 0x80|0x10)
+			So(m.ReadPRG(0x4800), ShouldEqual, 0xAB)
+			So(m.ReadPRG(0x4800), ShouldEqual, 0xCD)
+		})
+	})
+}
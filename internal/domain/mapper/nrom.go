@@ -0,0 +1,51 @@
+package mapper
+
+import "fmt"
+
+func init() {
+	register(0, "NROM", newNROM)
+}
+
+// NROM mapper 0、バンク切り替えなし
+// doc: https://www.nesdev.org/wiki/NROM
+//
+// 0x6000-0x7FFF: PRG-RAM(Family BASICは2KB/4KBで、この範囲にミラーリングされる)
+// 0x8000-0xFFFF: PRG-ROM、NROM-128(16KB)の場合は0xC000-0xFFFFにミラーリング、NROM-256(32KB)はそのまま
+type NROM struct {
+	base
+}
+
+const nromPRGSize = 0x8000
+
+func newNROM(config Config) (Mapper, error) {
+	if len(config.PRG) > nromPRGSize {
+		return nil, fmt.Errorf("PRG-ROM is too large for NROM. size: %d", len(config.PRG))
+	}
+	// PRG-RAMのない基板では使われないので、ヘッダーで指定がない場合も8KB用意しておく
+	return &NROM{base: newBase(config, defaultPRGRAM)}, nil
+}
+
+func (m *NROM) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= addrPRGROMStart:
+		return m.prg[int(addr-addrPRGROMStart)%len(m.prg)]
+	case addr >= addrPRGRAMStart:
+		return m.readPRGRAM(addr)
+	}
+	return openBus
+}
+
+// WritePRG NROMにはレジスタがないので、PRG-RAMへの書き込みだけ
+func (m *NROM) WritePRG(addr uint16, value byte) {
+	if addr >= addrPRGRAMStart && addr <= addrPRGRAMEnd {
+		m.writePRGRAM(addr, value)
+	}
+}
+
+func (m *NROM) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(chrSize, 0, addr)
+}
+
+func (m *NROM) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(chrSize, 0, addr, value)
+}
@@ -0,0 +1,78 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// ErrUnsupportedMapper 対応していないmapper番号
+var ErrUnsupportedMapper = errors.New("Mapper: unsupported mapper")
+
+// Config mapperを作るためのカートリッジの情報
+type Config struct {
+	MapperNo  int
+	Submapper int
+	PRG       []byte
+	// CHR 空の場合はCHRRAMSizeのCHR-RAMを使う
+	CHR        []byte
+	CHRRAMSize int
+	// PRGRAMSize 0の場合はmapperごとの標準のサイズ、PRGNVRAMSizeも含めたサイズ
+	PRGRAMSize int
+	// PRGNVRAMSize PRG-RAMのうちバッテリーバックアップされる後ろ側のサイズ、0の場合はPRG-RAMの全体
+	// SOROMのように揮発性のRAMと両方ある基板は、NES 2.0のヘッダーで揮発性の8KB + 不揮発性の8KBのように指定される
+	PRGNVRAMSize int
+	// Mirroring ヘッダーで指定されたミラーリング(mapperで切り替えない場合に使う)
+	Mirroring ppu.Mirroring
+	Battery   bool
+}
+
+type constructor func(config Config) (Mapper, error)
+
+type entry struct {
+	name string
+	new  constructor
+}
+
+var registry = map[int]entry{}
+
+// register mapper番号でmapperを登録する、各mapperのinit()で呼ぶ
+func register(mapperNo int, name string, new constructor) {
+	if _, ok := registry[mapperNo]; ok {
+		panic(fmt.Sprintf("Mapper: mapper is already registered. mapperNo: %d", mapperNo))
+	}
+	registry[mapperNo] = entry{name: name, new: new}
+}
+
+// New config.MapperNoのmapperを作る
+func New(config Config) (Mapper, error) {
+	e, ok := registry[config.MapperNo]
+	if !ok {
+		return nil, fmt.Errorf("%w. mapperNo: %d", ErrUnsupportedMapper, config.MapperNo)
+	}
+	if len(config.PRG) == 0 {
+		return nil, fmt.Errorf("Mapper: PRG-ROM is empty. mapperNo: %d", config.MapperNo)
+	}
+	m, err := e.new(config)
+	if err != nil {
+		return nil, fmt.Errorf("Mapper: failed new %s. err: %w", e.name, err)
+	}
+	return m, nil
+}
+
+// Name mapper番号の名前、対応していない場合は空
+func Name(mapperNo int) string {
+	return registry[mapperNo].name
+}
+
+// Supported 対応しているmapper番号
+func Supported() []int {
+	numbers := make([]int, 0, len(registry))
+	for no := range registry {
+		numbers = append(numbers, no)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
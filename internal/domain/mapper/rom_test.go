@@ -0,0 +1,11 @@
+package mapper_test
+
+// newBankedROM 各バンクの全てのバイトをバンク番号で埋めたROM
+// どのoffsetを読んでもバンク番号になるので、バンク切り替えとバス競合(書き込んだ値とROMの値のAND)のテストに使う
+func newBankedROM(size, bankSize int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i / bankSize)
+	}
+	return data
+}
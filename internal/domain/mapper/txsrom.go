@@ -0,0 +1,40 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/ppu"
+
+func init() {
+	register(118, "TxSROM", newTxSROM)
+}
+
+// TxSROM mapper 118 (TKSROM, TLSROM)
+// doc: https://www.nesdev.org/wiki/INES_Mapper_118
+//
+// MMC3のCHRバンクのbit7を本体VRAMのページ(CIRAMのA10)につないだボード
+// ネームテーブルはパターンテーブルの0x0000-0x0FFF(CHRの反転時は0x1000-0x1FFF)と同じバンクのレジスタで選ぶ
+// 0xA000のミラーリングのレジスタは使わない
+type TxSROM struct {
+	*MMC3
+}
+
+var _ ppu.NametableMapper = (*TxSROM)(nil)
+
+func newTxSROM(config Config) (Mapper, error) {
+	m, err := newMMC3(config)
+	if err != nil {
+		return nil, err
+	}
+	return &TxSROM{MMC3: m.(*MMC3)}, nil
+}
+
+// NametablePage ネームテーブルの1KBごとに、同じ位置のCHRバンクのbit7
+func (m *TxSROM) NametablePage(table int) int {
+	return m.chrBankNo(uint16(table&0x03)*mmc3CHRBankSize) >> 7 & 0x01
+}
+
+func (m *TxSROM) ReadNametable(addr uint16) (byte, bool) {
+	return 0, false
+}
+
+func (m *TxSROM) WriteNametable(addr uint16, value byte) bool {
+	return false
+}
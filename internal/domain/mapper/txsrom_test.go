@@ -0,0 +1,41 @@
+package mapper_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestTxSROM$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestTxSROM(t *testing.T) {
+	Convey("TestTxSROM", t, func() {
+		m, err := mapper.New(mapper.Config{
+			MapperNo: 118,
+			PRG:      newBankedROM(0x20000, 0x2000),
+			CHR:      newBankedROM(0x20000, 0x0400),
+		})
+		So(err, ShouldBeNil)
+		nametable := m.(ppu.NametableMapper)
+
+		Convey("ネームテーブルのページはCHRバンクのbit7で選ぶ", func() {
+			m.WritePRG(0x8000, 0x00)
+			m.WritePRG(0x8001, 0x80) // R0
+			m.WritePRG(0x8000, 0x01)
+			m.WritePRG(0x8001, 0x00) // R1
+			// ミラーリングのレジスタは使わない
+			m.WritePRG(0xA000, 0x01)
+			So(nametable.NametablePage(0), ShouldEqual, 1)
+			So(nametable.NametablePage(1), ShouldEqual, 1)
+			So(nametable.NametablePage(2), ShouldEqual, 0)
+			So(nametable.NametablePage(3), ShouldEqual, 0)
+
+			// CHRの反転時はR2-R5
+			m.WritePRG(0x8000, 0x80|0x04)
+			m.WritePRG(0x8001, 0x80) // R4
+			So(nametable.NametablePage(0), ShouldEqual, 0)
+			So(nametable.NametablePage(2), ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,64 @@
+package mapper
+
+func init() {
+	register(2, "UxROM", newUxROM)
+}
+
+// UxROM mapper 2、0x8000-0xBFFFの16KBを切り替えて、0xC000-0xFFFFは最後のバンクに固定
+// doc: https://www.nesdev.org/wiki/UxROM
+//
+// UNROM/UOROMはバス競合があるので、書き込んだ値とROMの値のANDがバンク番号になる
+type UxROM struct {
+	base
+	busConflicts bool
+	prgBank      int
+}
+
+const uxromPRGBankSize = 0x4000 // 16KB
+
+func newUxROM(config Config) (Mapper, error) {
+	return &UxROM{
+		base:         newBase(config, 0),
+		busConflicts: hasBusConflicts(config, true),
+	}, nil
+}
+
+// Reset 電源の入れ直しで0x8000のバンクを0に戻す
+func (m *UxROM) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		m.prgBank = 0
+	}
+}
+
+func (m *UxROM) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= 0xC000:
+		return m.readPRGBank(uxromPRGBankSize, -1, addr) // -1は最後のバンク
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(uxromPRGBankSize, m.prgBank, addr)
+	case addr >= addrPRGRAMStart:
+		return m.readPRGRAM(addr)
+	}
+	return openBus
+}
+
+func (m *UxROM) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= addrPRGROMStart:
+		if m.busConflicts {
+			value &= m.ReadPRG(addr)
+		}
+		m.prgBank = int(value)
+	case addr >= addrPRGRAMStart:
+		m.writePRGRAM(addr, value)
+	}
+}
+
+func (m *UxROM) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(chrSize, 0, addr)
+}
+
+func (m *UxROM) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(chrSize, 0, addr, value)
+}
@@ -0,0 +1,101 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/ppu"
+
+// KonamiのVRCで共通の部分
+// doc: https://www.nesdev.org/wiki/VRC_IRQ
+
+// vrcPins 基板のどのアドレス線がVRCのA0, A1につながっているか
+// 基板の種類がわからない場合は、候補のアドレス線をまとめて(ORで)つないだものとして扱う
+type vrcPins struct {
+	a0 uint16
+	a1 uint16
+}
+
+// register addrのレジスタ番号(0-3)
+func (p vrcPins) register(addr uint16) int {
+	var reg int
+	if addr&p.a0 != 0 {
+		reg |= 0x01
+	}
+	if addr&p.a1 != 0 {
+		reg |= 0x02
+	}
+	return reg
+}
+
+// vrcMirroring VRC4, VRC6, VRC7のミラーリングのレジスタ(下位2bit)
+func vrcMirroring(value byte) ppu.Mirroring {
+	switch value & 0x03 {
+	case 0:
+		return ppu.MirroringVertical
+	case 1:
+		return ppu.MirroringHorizontal
+	case 2:
+		return ppu.MirroringSingleScreenLower
+	}
+	return ppu.MirroringSingleScreenUpper
+}
+
+// vrcIRQ VRC4, VRC6, VRC7のIRQカウンター
+//
+// カウンターはCPUサイクルで動き、0xFFから増える時にラッチの値に戻してIRQを出す
+// scanlineモードではprescalerでCPUの341/3サイクル(1scanline)ごとに、cycleモードではCPUの1サイクルごとに増える
+type vrcIRQ struct {
+	latch     byte
+	counter   byte
+	prescaler int
+	enabled   bool
+	// enableAfterAck 確認(acknowledge)した後のenabledの値
+	enableAfterAck bool
+	cycleMode      bool
+	pending        bool
+}
+
+const (
+	// vrcPrescalerReload prescalerはCPUの1サイクルで3減って、0以下になるとカウンターを進める
+	vrcPrescalerReload = 341
+	vrcPrescalerStep   = 3
+)
+
+// writeControl bit0: 確認した後にIRQを有効にする, bit1: IRQを有効にする(カウンターにラッチの値を入れる), bit2: cycleモード
+func (irq *vrcIRQ) writeControl(value byte) {
+	irq.enableAfterAck = value&0x01 != 0
+	irq.enabled = value&0x02 != 0
+	irq.cycleMode = value&0x04 != 0
+	irq.pending = false
+	if irq.enabled {
+		irq.counter = irq.latch
+		irq.prescaler = vrcPrescalerReload
+	}
+}
+
+func (irq *vrcIRQ) acknowledge() {
+	irq.pending = false
+	irq.enabled = irq.enableAfterAck
+}
+
+// step CPUの1サイクル分進める
+func (irq *vrcIRQ) step() {
+	if !irq.enabled {
+		return
+	}
+	if irq.cycleMode {
+		irq.clock()
+		return
+	}
+	irq.prescaler -= vrcPrescalerStep
+	if irq.prescaler <= 0 {
+		irq.prescaler += vrcPrescalerReload
+		irq.clock()
+	}
+}
+
+func (irq *vrcIRQ) clock() {
+	if irq.counter == 0xFF {
+		irq.counter = irq.latch
+		irq.pending = true
+		return
+	}
+	irq.counter++
+}
@@ -0,0 +1,196 @@
+package mapper
+
+func init() {
+	register(21, "VRC4a/VRC4c", newVRC4)
+	register(22, "VRC2a", newVRC4)
+	register(23, "VRC2b/VRC4e/VRC4f", newVRC4)
+	register(25, "VRC2c/VRC4b/VRC4d", newVRC4)
+}
+
+// VRC4 mapper 21, 22, 23, 25 (VRC2, VRC4)
+// doc: https://www.nesdev.org/wiki/VRC2_and_VRC4
+//
+// 0x8000: 0x8000(swapモードでは0xC000)の8KBのPRGバンク、0x9000: ミラーリング、0x9002: PRGのswapモード(VRC4のみ)
+// 0xA000: 0xA000の8KBのPRGバンク、0xB000-0xE003: 1KBのCHRバンク(2つのレジスタで下位4bitと上位bit)
+// 0xF000-0xF003: IRQ(VRC4のみ)
+//
+// 基板ごとにVRCのA0, A1につながっているアドレス線が違うので、mapper番号とNES 2.0のsubmapperで基板を選ぶ
+// submapperがない場合は候補のアドレス線を全てつないだものとして扱う(VRC4はVRC2の上位互換なのでVRC4として動かす)
+// VRC2の0x6000-0x7FFFの1bitのラッチは、読み書きできるPRG-RAMで代わりになるのでPRG-RAMとして扱う
+type VRC4 struct {
+	base
+	board vrc4Board
+
+	prgBanks [2]int
+	prgSwap  bool
+	chrBanks [8]int
+
+	irq vrcIRQ
+}
+
+// vrc4Board 基板の種類
+type vrc4Board struct {
+	pins vrcPins
+	vrc2 bool
+	// chrShift VRC2a(mapper 22)はCHRバンクの最下位bitがつながっていない
+	chrShift int
+}
+
+const (
+	vrc4PRGBankSize = 0x2000 // 8KB
+	vrc4CHRBankSize = 0x0400 // 1KB
+)
+
+// vrc4Boards mapper番号とsubmapperごとの基板、submapper 0は候補を全てつないだもの
+// doc: https://www.nesdev.org/wiki/NES_2.0_submappers#021,_022,_023,_025:_Konami_VRC2/VRC4
+var vrc4Boards = map[int]map[int]vrc4Board{
+	21: {
+		0: {pins: vrcPins{a0: 0x02 | 0x40, a1: 0x04 | 0x80}},
+		1: {pins: vrcPins{a0: 0x02, a1: 0x04}}, // VRC4a
+		2: {pins: vrcPins{a0: 0x40, a1: 0x80}}, // VRC4c
+	},
+	22: {
+		0: {pins: vrcPins{a0: 0x02, a1: 0x01}, vrc2: true, chrShift: 1}, // VRC2a
+	},
+	23: {
+		0: {pins: vrcPins{a0: 0x01 | 0x04, a1: 0x02 | 0x08}},
+		1: {pins: vrcPins{a0: 0x01, a1: 0x02}},             // VRC4f
+		2: {pins: vrcPins{a0: 0x04, a1: 0x08}},             // VRC4e
+		3: {pins: vrcPins{a0: 0x01, a1: 0x02}, vrc2: true}, // VRC2b
+	},
+	25: {
+		0: {pins: vrcPins{a0: 0x02 | 0x08, a1: 0x01 | 0x04}},
+		1: {pins: vrcPins{a0: 0x02, a1: 0x01}},             // VRC4b
+		2: {pins: vrcPins{a0: 0x08, a1: 0x04}},             // VRC4d
+		3: {pins: vrcPins{a0: 0x02, a1: 0x01}, vrc2: true}, // VRC2c
+	},
+}
+
+func newVRC4(config Config) (Mapper, error) {
+	boards := vrc4Boards[config.MapperNo]
+	board, ok := boards[config.Submapper]
+	if !ok {
+		board = boards[0]
+	}
+	return &VRC4{
+		base:  newBase(config, defaultPRGRAM),
+		board: board,
+	}, nil
+}
+
+// Reset 電源の入れ直しでバンクとIRQのレジスタを0に戻す
+func (m *VRC4) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		*m = VRC4{base: m.base, board: m.board}
+	}
+}
+
+func (m *VRC4) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(vrc4PRGBankSize, m.prgBankNo(addr), addr)
+	case addr >= addrPRGRAMStart:
+		return m.readPRGRAM(addr)
+	}
+	return openBus
+}
+
+// prgBankNo addrの8KBのバンク番号、-1, -2は最後と最後から2番目のバンク
+func (m *VRC4) prgBankNo(addr uint16) int {
+	switch slot := (addr - addrPRGROMStart) / vrc4PRGBankSize; {
+	case slot == 0 && !m.prgSwap, slot == 2 && m.prgSwap:
+		return m.prgBanks[0]
+	case slot == 1:
+		return m.prgBanks[1]
+	case slot == 3:
+		return -1
+	}
+	return -2
+}
+
+func (m *VRC4) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= addrPRGROMStart:
+		m.writeRegister(addr, value)
+	case addr >= addrPRGRAMStart:
+		m.writePRGRAM(addr, value)
+	}
+}
+
+func (m *VRC4) writeRegister(addr uint16, value byte) {
+	reg := m.board.pins.register(addr)
+	switch addr & 0xF000 {
+	case 0x8000:
+		m.prgBanks[0] = int(value & 0x1F)
+	case 0x9000:
+		m.writeControl(reg, value)
+	case 0xA000:
+		m.prgBanks[1] = int(value & 0x1F)
+	case 0xB000, 0xC000, 0xD000, 0xE000:
+		m.writeCHRRegister(addr, reg, value)
+	case 0xF000:
+		if m.board.vrc2 {
+			return
+		}
+		switch reg {
+		case 0:
+			m.irq.latch = m.irq.latch&0xF0 | value&0x0F
+		case 1:
+			m.irq.latch = m.irq.latch&0x0F | value<<4
+		case 2:
+			m.irq.writeControl(value)
+		case 3:
+			m.irq.acknowledge()
+		}
+	}
+}
+
+// writeControl 0x9000-0x9003
+// VRC2はミラーリングの1bitのみ、VRC4は0x9000, 0x9001がミラーリング、0x9002, 0x9003のbit1がPRGのswapモード
+// VRC4のbit0(PRG-RAMの有効化)は無視してPRG-RAMは常に使えるものとする
+func (m *VRC4) writeControl(reg int, value byte) {
+	if m.board.vrc2 {
+		m.mirroring = vrcMirroring(value & 0x01)
+		return
+	}
+	if reg < 2 {
+		m.mirroring = vrcMirroring(value)
+		return
+	}
+	m.prgSwap = value&0x02 != 0
+}
+
+// writeCHRRegister 0xB000-0xE003、0xB000, 0xB001が0番目のバンクの下位4bitと上位bit、0xB002, 0xB003が1番目のバンク
+func (m *VRC4) writeCHRRegister(addr uint16, reg int, value byte) {
+	index := int(addr-0xB000)/0x1000*2 + reg/2
+	if reg%2 == 0 {
+		m.chrBanks[index] = m.chrBanks[index]&^0x0F | int(value&0x0F)
+		return
+	}
+	high := value & 0x1F
+	if m.board.vrc2 {
+		high &= 0x0F
+	}
+	m.chrBanks[index] = m.chrBanks[index]&0x0F | int(high)<<4
+}
+
+func (m *VRC4) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(vrc4CHRBankSize, m.chrBankNo(addr), addr)
+}
+
+func (m *VRC4) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(vrc4CHRBankSize, m.chrBankNo(addr), addr, value)
+}
+
+func (m *VRC4) chrBankNo(addr uint16) int {
+	return m.chrBanks[addr/vrc4CHRBankSize] >> m.board.chrShift
+}
+
+func (m *VRC4) IRQ() bool {
+	return m.irq.pending
+}
+
+func (m *VRC4) Step() {
+	m.irq.step()
+}
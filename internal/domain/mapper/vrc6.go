@@ -0,0 +1,153 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/apu"
+
+func init() {
+	register(24, "VRC6a", newVRC6)
+	register(26, "VRC6b", newVRC6)
+}
+
+// VRC6 mapper 24 (VRC6a), 26 (VRC6b)
+// doc: https://www.nesdev.org/wiki/VRC6
+//
+// 0x8000: 0x8000の16KBのPRGバンク、0x9000-0xB002: 拡張音源、0xB003: CHRのモードとミラーリング、PRG-RAMの有効化
+// 0xC000: 0xC000の8KBのPRGバンク(0xE000は最後のバンクに固定)、0xD000-0xE003: 1KBのCHRバンク
+// 0xF000: IRQのラッチ、0xF001: IRQのコントロール、0xF002: IRQの確認
+//
+// VRC6b(mapper 26)はA0とA1が入れ替わっているので、レジスタ番号に変換してから処理する
+// 0xB003のbit4(CHR-ROMをネームテーブルに使う)は未対応
+type VRC6 struct {
+	base
+	pins vrcPins
+
+	prgBank16 int
+	prgBank8  int
+	chrBanks  [8]int
+	banking   byte // 0xB003
+
+	irq   vrcIRQ
+	audio *apu.VRC6
+}
+
+const (
+	vrc6PRG16BankSize = 0x4000 // 16KB
+	vrc6PRG8BankSize  = 0x2000 // 8KB
+	vrc6CHRBankSize   = 0x0400 // 1KB
+
+	vrc6PRGRAMEnable = 0x80
+	// vrc6CHRA10 2KBのCHRバンクのA10をPPUのA10にする(0の場合はレジスタのbit0)
+	vrc6CHRA10 = 0x20
+)
+
+var _ AudioMapper = (*VRC6)(nil)
+
+func newVRC6(config Config) (Mapper, error) {
+	pins := vrcPins{a0: 0x01, a1: 0x02}
+	if config.MapperNo == 26 {
+		pins = vrcPins{a0: 0x02, a1: 0x01}
+	}
+	return &VRC6{
+		base:  newBase(config, defaultPRGRAM),
+		pins:  pins,
+		audio: apu.NewVRC6(),
+	}, nil
+}
+
+func (m *VRC6) ExpansionAudio() apu.ExpansionAudio {
+	return m.audio
+}
+
+// Reset 電源の入れ直しでバンクとIRQのレジスタを0に戻す
+func (m *VRC6) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		*m = VRC6{base: m.base, pins: m.pins, audio: m.audio}
+	}
+}
+
+func (m *VRC6) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= 0xE000:
+		return m.readPRGBank(vrc6PRG8BankSize, -1, addr)
+	case addr >= 0xC000:
+		return m.readPRGBank(vrc6PRG8BankSize, m.prgBank8, addr)
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(vrc6PRG16BankSize, m.prgBank16, addr)
+	case addr >= addrPRGRAMStart:
+		if m.banking&vrc6PRGRAMEnable == 0 {
+			return openBus
+		}
+		return m.readPRGRAM(addr)
+	}
+	return openBus
+}
+
+func (m *VRC6) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= addrPRGROMStart:
+		m.writeRegister(addr&0xF000|uint16(m.pins.register(addr)), value)
+	case addr >= addrPRGRAMStart:
+		if m.banking&vrc6PRGRAMEnable != 0 {
+			m.writePRGRAM(addr, value)
+		}
+	}
+}
+
+// writeRegister addrはVRC6aのアドレス(0xX000-0xX003)に変換したもの
+func (m *VRC6) writeRegister(addr uint16, value byte) {
+	switch {
+	case addr < 0x9000:
+		m.prgBank16 = int(value & 0x0F)
+	case addr == 0xB003:
+		m.banking = value
+		m.mirroring = vrcMirroring(value >> 2)
+	case addr < 0xC000:
+		m.audio.Write(addr, value)
+	case addr < 0xD000:
+		m.prgBank8 = int(value & 0x1F)
+	case addr < 0xF000:
+		m.chrBanks[int(addr-0xD000)/0x1000*4+int(addr&0x03)] = int(value)
+	case addr == 0xF000:
+		m.irq.latch = value
+	case addr == 0xF001:
+		m.irq.writeControl(value)
+	case addr == 0xF002:
+		m.irq.acknowledge()
+	}
+}
+
+func (m *VRC6) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(vrc6CHRBankSize, m.chrBankNo(addr), addr)
+}
+
+func (m *VRC6) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(vrc6CHRBankSize, m.chrBankNo(addr), addr, value)
+}
+
+// chrBankNo addrの1KBのバンク番号
+// モード0: R0-R7の1KB、モード1: R0-R3の2KB、モード2, 3: 0x0000-0x0FFFはR0-R3の1KB、0x1000-0x1FFFはR4, R5の2KB
+func (m *VRC6) chrBankNo(addr uint16) int {
+	slot := int(addr / vrc6CHRBankSize)
+	switch mode := m.banking & 0x03; {
+	case mode == 0, slot < 4 && mode != 1:
+		return m.chrBanks[slot]
+	case mode == 1:
+		return m.chrBank2K(m.chrBanks[slot/2], addr)
+	}
+	return m.chrBank2K(m.chrBanks[4+(slot-4)/2], addr)
+}
+
+func (m *VRC6) chrBank2K(bank int, addr uint16) int {
+	if m.banking&vrc6CHRA10 == 0 {
+		return bank
+	}
+	return bank&^0x01 | int(addr/vrc6CHRBankSize)&0x01
+}
+
+func (m *VRC6) IRQ() bool {
+	return m.irq.pending
+}
+
+func (m *VRC6) Step() {
+	m.irq.step()
+}
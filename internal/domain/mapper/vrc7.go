@@ -0,0 +1,135 @@
+package mapper
+
+import "github.com/sunjin110/nes_emu/internal/domain/apu"
+
+func init() {
+	register(85, "VRC7", newVRC7)
+}
+
+// VRC7 mapper 85
+// doc: https://www.nesdev.org/wiki/VRC7
+//
+// 0x8000, 0x8010, 0x9000: 0x8000, 0xA000, 0xC000の8KBのPRGバンク(0xE000は最後のバンクに固定)
+// 0x9010, 0x9030: 拡張音源、0xA000-0xD010: 1KBのCHRバンク
+// 0xE000: ミラーリング、音源の停止、PRG-RAMの有効化、0xE010: IRQのラッチ、0xF000: IRQのコントロール、0xF010: IRQの確認
+//
+// VRC7a(Lagrange Point)はA4、VRC7b(Tiny Toon Adventures 2)はA3で0xX000と0xX010のレジスタを選ぶ
+// NES 2.0のsubmapperがない場合は両方をつないだものとして扱う
+type VRC7 struct {
+	base
+	pins vrcPins
+
+	prgBanks [3]int
+	chrBanks [8]int
+	control  byte // 0xE000
+
+	irq   vrcIRQ
+	audio *apu.VRC7
+}
+
+const (
+	vrc7PRGBankSize = 0x2000 // 8KB
+	vrc7CHRBankSize = 0x0400 // 1KB
+
+	vrc7AudioDisable = 0x40
+	vrc7PRGRAMEnable = 0x80
+)
+
+var _ AudioMapper = (*VRC7)(nil)
+
+func newVRC7(config Config) (Mapper, error) {
+	pins := vrcPins{a0: 0x08 | 0x10}
+	switch config.Submapper {
+	case 1:
+		pins.a0 = 0x08 // VRC7b
+	case 2:
+		pins.a0 = 0x10 // VRC7a
+	}
+	return &VRC7{
+		base:  newBase(config, defaultPRGRAM),
+		pins:  pins,
+		audio: apu.NewVRC7(),
+	}, nil
+}
+
+func (m *VRC7) ExpansionAudio() apu.ExpansionAudio {
+	return m.audio
+}
+
+// Reset 電源の入れ直しでバンクとIRQのレジスタを0に戻す
+func (m *VRC7) Reset(resetType ResetType) {
+	m.base.Reset(resetType)
+	if resetType == ResetTypeHard {
+		*m = VRC7{base: m.base, pins: m.pins, audio: m.audio}
+	}
+}
+
+func (m *VRC7) ReadPRG(addr uint16) byte {
+	switch {
+	case addr >= 0xE000:
+		return m.readPRGBank(vrc7PRGBankSize, -1, addr)
+	case addr >= addrPRGROMStart:
+		return m.readPRGBank(vrc7PRGBankSize, m.prgBanks[(addr-addrPRGROMStart)/vrc7PRGBankSize], addr)
+	case addr >= addrPRGRAMStart:
+		if m.control&vrc7PRGRAMEnable == 0 {
+			return openBus
+		}
+		return m.readPRGRAM(addr)
+	}
+	return openBus
+}
+
+func (m *VRC7) WritePRG(addr uint16, value byte) {
+	switch {
+	case addr >= addrPRGROMStart:
+		m.writeRegister(addr, value)
+	case addr >= addrPRGRAMStart:
+		if m.control&vrc7PRGRAMEnable != 0 {
+			m.writePRGRAM(addr, value)
+		}
+	}
+}
+
+func (m *VRC7) writeRegister(addr uint16, value byte) {
+	// 0xX000が0、0xX010(0xX008)が1
+	index := int(addr-addrPRGROMStart)/0x1000*2 + m.pins.register(addr)
+	switch {
+	case index < 3:
+		m.prgBanks[index] = int(value & 0x3F)
+	case index == 3:
+		// 音源はA5でアドレスとデータを選ぶ
+		if addr&0x20 == 0 {
+			m.audio.Write(0x9010, value)
+		} else {
+			m.audio.Write(0x9030, value)
+		}
+	case index < 12:
+		m.chrBanks[index-4] = int(value)
+	case index == 12:
+		m.control = value
+		m.mirroring = vrcMirroring(value)
+		m.audio.SetDisabled(value&vrc7AudioDisable != 0)
+	case index == 13:
+		m.irq.latch = value
+	case index == 14:
+		m.irq.writeControl(value)
+	default:
+		m.irq.acknowledge()
+	}
+}
+
+func (m *VRC7) ReadCHR(addr uint16) byte {
+	return m.readCHRBank(vrc7CHRBankSize, m.chrBanks[addr/vrc7CHRBankSize], addr)
+}
+
+func (m *VRC7) WriteCHR(addr uint16, value byte) {
+	m.writeCHRBank(vrc7CHRBankSize, m.chrBanks[addr/vrc7CHRBankSize], addr, value)
+}
+
+func (m *VRC7) IRQ() bool {
+	return m.irq.pending
+}
+
+func (m *VRC7) Step() {
+	m.irq.step()
+}
@@ -0,0 +1,141 @@
+package mapper_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/mapper"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestVRC$ github.com/sunjin110/nes_emu/internal/domain/mapper
+func TestVRC(t *testing.T) {
+	Convey("TestVRC", t, func() {
+		newVRC := func(mapperNo, submapper int) mapper.Mapper {
+			m, err := mapper.New(mapper.Config{
+				MapperNo:  mapperNo,
+				Submapper: submapper,
+				PRG:       newBankedROM(0x40000, 0x2000),
+				CHR:       newBankedROM(0x40000, 0x0400),
+			})
+			So(err, ShouldBeNil)
+			return m
+		}
+		steps := func(m mapper.Mapper, count int) {
+			for i := 0; i < count; i++ {
+				m.Step()
+			}
+		}
+
+		Convey("VRC4はsubmapperがない場合、候補のどのアドレス線でもレジスタを選べる", func() {
+			for _, addr := range []uint16{0x9004, 0x9080} { // VRC4a, VRC4c
+				m := newVRC(21, 0)
+				m.WritePRG(0x8000, 5)
+				m.WritePRG(addr, 0x02)
+				So(m.ReadPRG(0x8000), ShouldEqual, 30)
+				So(m.ReadPRG(0xC000), ShouldEqual, 5)
+				So(m.ReadPRG(0xE000), ShouldEqual, 31)
+			}
+		})
+
+		Convey("VRC4cのsubmapperではVRC4aのアドレス線でレジスタを選べない", func() {
+			m := newVRC(21, 2)
+			// VRC4cではA1, A2はつながっていないので0x9006は0x9000
+			m.WritePRG(0x9006, 0x03)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringSingleScreenUpper)
+			So(m.ReadPRG(0xC000), ShouldEqual, 30)
+		})
+
+		Convey("VRC4のCHRバンクは下位4bitと上位5bitを別のレジスタに書き込む", func() {
+			m := newVRC(25, 1) // VRC4b: A1がA0、A0がA1
+			m.WritePRG(0xC001, 0x0A)
+			m.WritePRG(0xC003, 0x0F)
+			So(m.ReadCHR(0x0C00), ShouldEqual, 0xFA)
+		})
+
+		Convey("VRC2aはCHRバンクの最下位bitを使わない", func() {
+			m := newVRC(22, 0)
+			m.WritePRG(0xB000, 0x07)
+			So(m.ReadCHR(0x0000), ShouldEqual, 3)
+			// VRC2はIRQを持たない
+			m.WritePRG(0xF002, 0x02)
+			m.WritePRG(0xF003, 0x02)
+			steps(m, 341*256)
+			So(m.IRQ(), ShouldBeFalse)
+		})
+
+		Convey("VRCのIRQはscanlineモードではCPUの341/3サイクルごとに、cycleモードでは1サイクルごとに数える", func() {
+			m := newVRC(23, 0)
+			// ラッチ0xFE: 2回数えるとIRQ
+			m.WritePRG(0xF000, 0x0E)
+			m.WritePRG(0xF001, 0x0F)
+			m.WritePRG(0xF002, 0x03)
+			steps(m, 227)
+			So(m.IRQ(), ShouldBeFalse)
+			steps(m, 1)
+			So(m.IRQ(), ShouldBeTrue)
+
+			// 確認するとbit0の値で有効かどうかが決まる
+			m.WritePRG(0xF003, 0)
+			So(m.IRQ(), ShouldBeFalse)
+			steps(m, 228)
+			So(m.IRQ(), ShouldBeTrue)
+
+			m.WritePRG(0xF002, 0x06)
+			So(m.IRQ(), ShouldBeFalse)
+			steps(m, 1)
+			So(m.IRQ(), ShouldBeFalse)
+			steps(m, 1)
+			So(m.IRQ(), ShouldBeTrue)
+		})
+
+		Convey("VRC6bはA0とA1が入れ替わっていて、0xB003でCHRのモードとミラーリングを選ぶ", func() {
+			m := newVRC(26, 0)
+			So(m.ReadPRG(0xE000), ShouldEqual, 31)
+			m.WritePRG(0x8000, 0x02)
+			m.WritePRG(0xC000, 0x09)
+			So(m.ReadPRG(0x8000), ShouldEqual, 4)
+			So(m.ReadPRG(0xA000), ShouldEqual, 5)
+			So(m.ReadPRG(0xC000), ShouldEqual, 9)
+
+			m.WritePRG(0xB003, 0xA9)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringSingleScreenLower)
+			m.WritePRG(0x6000, 0x12)
+			So(m.ReadPRG(0x6000), ShouldEqual, 0x12)
+
+			// モード1: R0-R3の2KB、bit5が立っているのでA10はPPUのA10
+			m.WritePRG(0xD002, 0x11) // VRC6aの0xD001(R1)
+			So(m.ReadCHR(0x0800), ShouldEqual, 0x10)
+			So(m.ReadCHR(0x0C00), ShouldEqual, 0x11)
+
+			_, ok := m.(mapper.AudioMapper)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("VRC7はA3とA4のどちらでも0xX000と0xX010のレジスタを選べる", func() {
+			m := newVRC(85, 0)
+			m.WritePRG(0x8000, 1)
+			m.WritePRG(0x8010, 2)
+			m.WritePRG(0x9000, 3)
+			So(m.ReadPRG(0x8000), ShouldEqual, 1)
+			So(m.ReadPRG(0xA000), ShouldEqual, 2)
+			So(m.ReadPRG(0xC000), ShouldEqual, 3)
+			So(m.ReadPRG(0xE000), ShouldEqual, 31)
+
+			m.WritePRG(0xD008, 0x44)
+			So(m.ReadCHR(0x1C00), ShouldEqual, 0x44)
+
+			m.WritePRG(0xE000, 0x81)
+			So(m.Mirroring(), ShouldEqual, ppu.MirroringHorizontal)
+			m.WritePRG(0x7FFF, 0x56)
+			So(m.ReadPRG(0x7FFF), ShouldEqual, 0x56)
+
+			m.WritePRG(0xE010, 0xFF)
+			m.WritePRG(0xF000, 0x06)
+			steps(m, 1)
+			So(m.IRQ(), ShouldBeTrue)
+			m.WritePRG(0xF010, 0)
+			So(m.IRQ(), ShouldBeFalse)
+		})
+	})
+}
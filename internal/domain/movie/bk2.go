@@ -0,0 +1,140 @@
+package movie
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+)
+
+// BK2 BizHawkのムービー(zip)
+// doc: https://tasvideos.org/Bizhawk/BK2Format
+//
+// "Input Log.txt"のLogKeyにボタンの並びがあり、その後に1フレーム1行で入力が続く
+// LogKey:#Reset|Power|#P1 Up|P1 Down|P1 Left|P1 Right|P1 Start|P1 Select|P1 B|P1 A|#P2 Up|...
+// |..|UDLRSsBA|........|
+// 押していないボタンは'.'、押しているボタンはそれ以外の文字になる
+
+const bk2InputLog = "Input Log.txt"
+
+var bk2Buttons = map[string]controller.ButtonState{
+	"Up":     controller.ButtonUp,
+	"Down":   controller.ButtonDown,
+	"Left":   controller.ButtonLeft,
+	"Right":  controller.ButtonRight,
+	"Start":  controller.ButtonStart,
+	"Select": controller.ButtonSelect,
+	"B":      controller.ButtonB,
+	"A":      controller.ButtonA,
+}
+
+// bk2Key LogKeyの1つのボタン
+type bk2Key struct {
+	command Command
+	player  int
+	button  controller.ButtonState
+}
+
+// ParseBK2 BK2ファイル(zip)の入力を読み込む、ROMのチェックサムは読み込まない
+func ParseBK2(data []byte) (*Movie, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("Movie: failed open bk2. err: %w", err)
+	}
+	for _, file := range archive.File {
+		if file.Name != bk2InputLog {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("Movie: failed open bk2 input log. err: %w", err)
+		}
+		defer r.Close()
+		return ParseBK2InputLog(r)
+	}
+	return nil, fmt.Errorf("Movie: %s is not found in bk2", bk2InputLog)
+}
+
+// ParseBK2InputLog BK2の中の"Input Log.txt"を読み込む
+func ParseBK2InputLog(r io.Reader) (*Movie, error) {
+	movie := &Movie{}
+	var keys []bk2Key
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.HasPrefix(line, "LogKey:"):
+			var err error
+			keys, err = parseBK2LogKey(strings.TrimPrefix(line, "LogKey:"))
+			if err != nil {
+				return nil, fmt.Errorf("Movie: invalid bk2 LogKey. line: %d, err: %w", lineNo, err)
+			}
+			for _, key := range keys {
+				if key.player >= 2 {
+					movie.FourScore = true
+				}
+			}
+		case strings.HasPrefix(line, "|"):
+			if keys == nil {
+				return nil, fmt.Errorf("Movie: bk2 LogKey is not found before input. line: %d", lineNo)
+			}
+			frame, err := parseBK2Frame(line, keys)
+			if err != nil {
+				return nil, fmt.Errorf("Movie: invalid bk2 input. line: %d, err: %w", lineNo, err)
+			}
+			movie.Frames = append(movie.Frames, frame)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Movie: failed read bk2 input log. err: %w", err)
+	}
+	return movie, nil
+}
+
+func parseBK2LogKey(logKey string) ([]bk2Key, error) {
+	var keys []bk2Key
+	for _, name := range strings.FieldsFunc(logKey, func(r rune) bool { return r == '#' || r == '|' }) {
+		var key bk2Key
+		switch name {
+		case "Reset":
+			key.command = CommandSoftReset
+		case "Power":
+			key.command = CommandPowerCycle
+		default:
+			var player int
+			var button string
+			if _, err := fmt.Sscanf(name, "P%d %s", &player, &button); err != nil || player < 1 || player > 4 {
+				return nil, fmt.Errorf("unsupported key. key: %s", name)
+			}
+			b, ok := bk2Buttons[button]
+			if !ok {
+				return nil, fmt.Errorf("unsupported button. key: %s", name)
+			}
+			key.player = player - 1
+			key.button = b
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func parseBK2Frame(line string, keys []bk2Key) (Frame, error) {
+	var frame Frame
+	input := strings.ReplaceAll(line, "|", "")
+	if len(input) != len(keys) {
+		return frame, fmt.Errorf("input length does not match LogKey. input: %d, keys: %d", len(input), len(keys))
+	}
+	for i, key := range keys {
+		if input[i] == '.' {
+			continue
+		}
+		frame.Command |= key.command
+		frame.Buttons[key.player] |= key.button
+	}
+	return frame, nil
+}
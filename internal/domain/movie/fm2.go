@@ -0,0 +1,253 @@
+package movie
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+)
+
+// FM2 FCEUXのムービーのテキスト形式
+// doc: https://fceux.com/web/help/fm2.html
+//
+// "key value"のヘッダーの後に、1フレーム1行で |commands|port0|port1|port2| の入力が続く
+// ゲームパッドは RLDUTSBA の順に1文字ずつで、押していない場合は'.'か' '
+// Four Scoreの場合は |commands|1P|2P|3P|4P|port2|
+
+// fm2Buttons ゲームパッドの文字の順番
+var fm2Buttons = [8]struct {
+	char   byte
+	button controller.ButtonState
+}{
+	{'R', controller.ButtonRight},
+	{'L', controller.ButtonLeft},
+	{'D', controller.ButtonDown},
+	{'U', controller.ButtonUp},
+	{'T', controller.ButtonStart},
+	{'S', controller.ButtonSelect},
+	{'B', controller.ButtonB},
+	{'A', controller.ButtonA},
+}
+
+// fm2のcommandsのbit
+const (
+	fm2CommandSoftReset = 1 << 0
+	fm2CommandHardReset = 1 << 1
+)
+
+// fm2のport0, port1の値
+const (
+	fm2PortNone    = "0"
+	fm2PortGamepad = "1"
+)
+
+const fm2ChecksumPrefix = "base64:"
+
+// ParseFM2 テキスト形式のFM2を読み込む、ゲームパッド以外のデバイスの入力には対応していない
+func ParseFM2(r io.Reader) (*Movie, error) {
+	movie := &Movie{}
+	ports := [2]string{fm2PortGamepad, fm2PortGamepad}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		if line[0] == '|' {
+			frame, err := parseFM2Frame(line, movie.FourScore, ports)
+			if err != nil {
+				return nil, fmt.Errorf("Movie: invalid fm2 input. line: %d, err: %w", lineNo, err)
+			}
+			movie.Frames = append(movie.Frames, frame)
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "binary":
+			if value == "1" {
+				return nil, fmt.Errorf("Movie: binary fm2 is not supported")
+			}
+		case "romFilename":
+			movie.ROMFilename = value
+		case "romChecksum":
+			checksum, err := parseFM2Checksum(value)
+			if err != nil {
+				return nil, fmt.Errorf("Movie: invalid romChecksum. line: %d, err: %w", lineNo, err)
+			}
+			movie.ROMChecksum = checksum
+		case "fourscore":
+			movie.FourScore = value == "1"
+		case "port0":
+			ports[0] = value
+		case "port1":
+			ports[1] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Movie: failed read fm2. err: %w", err)
+	}
+	return movie, nil
+}
+
+func parseFM2Checksum(value string) (checksum [16]byte, err error) {
+	if !strings.HasPrefix(value, fm2ChecksumPrefix) {
+		return checksum, fmt.Errorf("base64 is required. value: %s", value)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, fm2ChecksumPrefix))
+	if err != nil {
+		return checksum, err
+	}
+	if len(data) != len(checksum) {
+		return checksum, fmt.Errorf("invalid checksum size. size: %d", len(data))
+	}
+	copy(checksum[:], data)
+	return checksum, nil
+}
+
+func parseFM2Frame(line string, fourScore bool, ports [2]string) (Frame, error) {
+	var frame Frame
+	fields := strings.Split(line, "|")
+	// 先頭と末尾の"|"の外側は空になる
+	if len(fields) < 3 {
+		return frame, fmt.Errorf("too few fields")
+	}
+	fields = fields[1 : len(fields)-1]
+
+	commands, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+	if err != nil {
+		return frame, fmt.Errorf("invalid commands. commands: %s", fields[0])
+	}
+	if commands&fm2CommandSoftReset != 0 {
+		frame.Command |= CommandSoftReset
+	}
+	if commands&fm2CommandHardReset != 0 {
+		frame.Command |= CommandPowerCycle
+	}
+
+	pads := fields[1:]
+	players := 2
+	if fourScore {
+		players = 4
+	}
+	for i := 0; i < players; i++ {
+		if !fourScore {
+			switch ports[i] {
+			case fm2PortNone:
+				continue
+			case fm2PortGamepad:
+			default:
+				return frame, fmt.Errorf("unsupported port device. port%d: %s", i, ports[i])
+			}
+		}
+		if i >= len(pads) {
+			return frame, fmt.Errorf("gamepad input is missing. player: %d", i+1)
+		}
+		buttons, err := parseFM2Gamepad(pads[i])
+		if err != nil {
+			return frame, fmt.Errorf("invalid gamepad input. player: %d, err: %w", i+1, err)
+		}
+		frame.Buttons[i] = buttons
+	}
+	return frame, nil
+}
+
+func parseFM2Gamepad(field string) (controller.ButtonState, error) {
+	if len(field) != len(fm2Buttons) {
+		return 0, fmt.Errorf("gamepad input must be %d characters. input: %q", len(fm2Buttons), field)
+	}
+	var buttons controller.ButtonState
+	for i, b := range fm2Buttons {
+		if field[i] != '.' && field[i] != ' ' {
+			buttons |= b.button
+		}
+	}
+	return buttons, nil
+}
+
+func formatFM2Gamepad(buttons controller.ButtonState) string {
+	var sb strings.Builder
+	for _, b := range fm2Buttons {
+		if buttons&b.button != 0 {
+			sb.WriteByte(b.char)
+		} else {
+			sb.WriteByte('.')
+		}
+	}
+	return sb.String()
+}
+
+// WriteFM2 テキスト形式のFM2を書き出す
+func (m *Movie) WriteFM2(w io.Writer) error {
+	guid, err := newGUID()
+	if err != nil {
+		return fmt.Errorf("Movie: failed new guid. err: %w", err)
+	}
+	fourScore := "0"
+	if m.FourScore {
+		fourScore = "1"
+	}
+
+	bw := bufio.NewWriter(w)
+	header := [][2]string{
+		{"version", "3"},
+		{"emuVersion", "22020"},
+		{"rerecordCount", "0"},
+		{"palFlag", "0"},
+		{"romFilename", m.ROMFilename},
+		{"romChecksum", fm2ChecksumPrefix + base64.StdEncoding.EncodeToString(m.ROMChecksum[:])},
+		{"guid", guid},
+		{"fourscore", fourScore},
+		{"microphone", "0"},
+		{"port0", fm2PortGamepad},
+		{"port1", fm2PortGamepad},
+		{"port2", "0"},
+		{"FDS", "0"},
+		{"NewPPU", "0"},
+	}
+	if m.FourScore {
+		// Four Scoreの場合はport0, port1は使わない
+		header[9][1] = fm2PortNone
+		header[10][1] = fm2PortNone
+	}
+	for _, kv := range header {
+		fmt.Fprintf(bw, "%s %s\n", kv[0], kv[1])
+	}
+
+	for _, frame := range m.Frames {
+		var commands int
+		if frame.Command&CommandSoftReset != 0 {
+			commands |= fm2CommandSoftReset
+		}
+		if frame.Command&CommandPowerCycle != 0 {
+			commands |= fm2CommandHardReset
+		}
+		fmt.Fprintf(bw, "|%d|", commands)
+		for i := 0; i < m.players(); i++ {
+			bw.WriteString(formatFM2Gamepad(frame.Buttons[i]))
+			bw.WriteByte('|')
+		}
+		// port2(拡張端子)は使わない
+		bw.WriteString("|\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("Movie: failed write fm2. err: %w", err)
+	}
+	return nil
+}
+
+// newGUID FM2のヘッダーに必要なランダムなGUID
+func newGUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
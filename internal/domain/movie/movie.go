@@ -0,0 +1,78 @@
+package movie
+
+import (
+	"crypto/md5"
+
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+)
+
+// Command フレームの開始時に行う操作
+type Command byte
+
+const (
+	CommandSoftReset  Command = 1 << 0
+	CommandPowerCycle Command = 1 << 1
+)
+
+// Frame 1フレーム分の入力
+type Frame struct {
+	Command Command
+	Buttons [4]controller.ButtonState // 1P-4P
+}
+
+// Movie フレームごとのコントローラーの入力とリセット・電源の操作の記録
+type Movie struct {
+	ROMFilename string
+	// ROMChecksum PRG-ROMとCHR-ROMを繋げたもののMD5(FM2のromChecksum)、わからない場合は0
+	ROMChecksum [md5.Size]byte
+	// FourScore 1P-4Pの入力を記録している
+	FourScore bool
+	Frames    []Frame
+}
+
+func NewMovie(romFilename string, checksum [md5.Size]byte, fourScore bool) *Movie {
+	return &Movie{
+		ROMFilename: romFilename,
+		ROMChecksum: checksum,
+		FourScore:   fourScore,
+	}
+}
+
+// ROMChecksum FM2のromChecksumと同じくPRG-ROM + CHR-ROMのMD5を計算する
+func ROMChecksum(prg, chr []byte) [md5.Size]byte {
+	hash := md5.New()
+	hash.Write(prg)
+	hash.Write(chr)
+	var sum [md5.Size]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum
+}
+
+func (m *Movie) players() int {
+	if m.FourScore {
+		return 4
+	}
+	return 2
+}
+
+// Record 現在のコントローラーのボタンの状態とcommandを1フレーム分記録する
+func (m *Movie) Record(c *controller.Controller, command Command) {
+	frame := Frame{Command: command}
+	for i := 0; i < m.players(); i++ {
+		frame.Buttons[i] = c.Buttons(controller.Player(i))
+	}
+	m.Frames = append(m.Frames, frame)
+}
+
+// Apply frame番目の入力をコントローラーに設定し、そのフレームの開始時に行うcommandを返す
+// 記録が終わっている場合はokがfalse
+func (m *Movie) Apply(frame int, c *controller.Controller) (command Command, ok bool) {
+	if frame < 0 || frame >= len(m.Frames) {
+		return 0, false
+	}
+	f := m.Frames[frame]
+	for i := 0; i < m.players(); i++ {
+		c.SetButtons(controller.Player(i), f.Buttons[i])
+	}
+	return f.Command, true
+}
@@ -0,0 +1,84 @@
+package movie_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+	"github.com/sunjin110/nes_emu/internal/domain/movie"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestFM2$ github.com/sunjin110/nes_emu/internal/domain/movie
+func TestFM2(t *testing.T) {
+	Convey("TestFM2", t, func() {
+		Convey("記録した入力をFM2に書き出して読み込むと同じ入力が再生される", func() {
+			c := controller.NewController()
+			m := movie.NewMovie("hello.nes", movie.ROMChecksum([]byte{1, 2}, []byte{3}), false)
+			c.SetButtons(controller.Player1, controller.ButtonA|controller.ButtonRight)
+			m.Record(c, 0)
+			c.SetButtons(controller.Player2, controller.ButtonStart)
+			m.Record(c, movie.CommandSoftReset)
+
+			var buf bytes.Buffer
+			So(m.WriteFM2(&buf), ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, "|0|R......A|........||\n|1|R......A|....T...||\n")
+
+			parsed, err := movie.ParseFM2(&buf)
+			So(err, ShouldBeNil)
+			So(parsed.ROMFilename, ShouldEqual, "hello.nes")
+			So(parsed.ROMChecksum, ShouldEqual, m.ROMChecksum)
+			So(parsed.Frames, ShouldResemble, m.Frames)
+
+			replay := controller.NewController()
+			command, ok := parsed.Apply(1, replay)
+			So(ok, ShouldBeTrue)
+			So(command, ShouldEqual, movie.CommandSoftReset)
+			So(replay.Buttons(controller.Player2), ShouldEqual, controller.ButtonStart)
+			_, ok = parsed.Apply(2, replay)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Four Scoreの場合は4人分の入力を読み込む", func() {
+			m, err := movie.ParseFM2(strings.NewReader("version 3\nfourscore 1\n|2|.......A|......B.|.....S..|U.......||\n"))
+			So(err, ShouldBeNil)
+			So(m.FourScore, ShouldBeTrue)
+			So(m.Frames[0].Command, ShouldEqual, movie.CommandPowerCycle)
+			So(m.Frames[0].Buttons, ShouldResemble, [4]controller.ButtonState{
+				controller.ButtonA, controller.ButtonB, controller.ButtonSelect, controller.ButtonRight,
+			})
+		})
+
+		Convey("ゲームパッド以外のデバイスはエラーになる", func() {
+			_, err := movie.ParseFM2(strings.NewReader("port1 2\n|0|........|0 0 0||\n"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestBK2$ github.com/sunjin110/nes_emu/internal/domain/movie
+func TestBK2(t *testing.T) {
+	Convey("TestBK2", t, func() {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		w, err := archive.Create("Input Log.txt")
+		So(err, ShouldBeNil)
+		_, err = w.Write([]byte("[Input]\n" +
+			"LogKey:#Reset|Power|#P1 Up|P1 Down|P1 Left|P1 Right|P1 Start|P1 Select|P1 B|P1 A|#P2 Up|P2 Down|P2 Left|P2 Right|P2 Start|P2 Select|P2 B|P2 A|\n" +
+			"|..|U......A|........|\n" +
+			"|r.|....S...|.D......|\n" +
+			"[/Input]\n"))
+		So(err, ShouldBeNil)
+		So(archive.Close(), ShouldBeNil)
+
+		m, err := movie.ParseBK2(buf.Bytes())
+		So(err, ShouldBeNil)
+		So(m.FourScore, ShouldBeFalse)
+		So(m.Frames, ShouldResemble, []movie.Frame{
+			{Buttons: [4]controller.ButtonState{controller.ButtonUp | controller.ButtonA}},
+			{Command: movie.CommandSoftReset, Buttons: [4]controller.ButtonState{controller.ButtonStart, controller.ButtonDown}},
+		})
+	})
+}
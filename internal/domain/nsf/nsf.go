@@ -0,0 +1,214 @@
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// NSF NES Sound Format
+// doc: https://www.nesdev.org/wiki/NSF
+// doc: https://www.nesdev.org/wiki/NSFe
+
+const (
+	nsfHeaderSize = 0x80
+
+	// DefaultPlaySpeedNTSC PLAYの呼び出し間隔(µs)が指定されていない場合の値(約60.1Hz)
+	DefaultPlaySpeedNTSC = 16639
+	// DefaultPlaySpeedPAL PLAYの呼び出し間隔(µs)が指定されていない場合の値(約50Hz)
+	DefaultPlaySpeedPAL = 19997
+)
+
+var (
+	nsfMagic  = []byte{'N', 'E', 'S', 'M', 0x1A}
+	nsfeMagic = []byte{'N', 'S', 'F', 'E'}
+)
+
+// Region NSFが想定しているCPUのタイミング
+type Region int
+
+const (
+	RegionNTSC Region = iota
+	RegionPAL
+	RegionDual // NTSCとPALの両方に対応している
+)
+
+// ExpansionChip NSFが使う拡張音源、ヘッダーの0x7Bのビットに対応する
+type ExpansionChip byte
+
+const (
+	ChipVRC6 ExpansionChip = 1 << iota
+	ChipVRC7
+	ChipFDS
+	ChipMMC5
+	ChipN163
+	ChipSunsoft5B
+)
+
+type NSF struct {
+	Version      int
+	SongCount    int
+	StartingSong int // 1始まり
+
+	LoadAddr uint16
+	InitAddr uint16
+	PlayAddr uint16
+
+	Title     string
+	Artist    string
+	Copyright string
+
+	PlaySpeedNTSC uint16 // PLAYの呼び出し間隔(µs)
+	PlaySpeedPAL  uint16 // PLAYの呼び出し間隔(µs)
+
+	// 0x8000〜0xFFFFの4KBごとのバンクの初期値
+	// いずれかが0以外の場合はバンク切り替えを使う
+	Bankswitch [8]byte
+
+	Region         Region
+	ExpansionChips ExpansionChip
+
+	Data []byte
+
+	// 以下はNSFeのみ、NSFの場合は空
+	TrackLabels []string
+	TrackTimes  []int // ミリ秒、不明の場合は-1
+	TrackFades  []int // ミリ秒、不明の場合は-1
+	Playlist    []int // 0始まりの曲番号
+}
+
+// IsNSF NSFまたはNSFeのデータかどうか
+func IsNSF(data []byte) bool {
+	return bytes.HasPrefix(data, nsfMagic) || bytes.HasPrefix(data, nsfeMagic)
+}
+
+// Parse NSFとNSFeのデータを読み込む
+func Parse(data []byte) (*NSF, error) {
+	switch {
+	case bytes.HasPrefix(data, nsfMagic):
+		return parseNSF(data)
+	case bytes.HasPrefix(data, nsfeMagic):
+		return parseNSFE(data)
+	default:
+		return nil, errors.New("NSF: invalid header")
+	}
+}
+
+func parseNSF(data []byte) (*NSF, error) {
+	if len(data) < nsfHeaderSize {
+		return nil, fmt.Errorf("NSF: data is too short to contain header. size: %d", len(data))
+	}
+
+	n := &NSF{
+		Version:        int(data[0x05]),
+		SongCount:      int(data[0x06]),
+		StartingSong:   int(data[0x07]),
+		LoadAddr:       binary.LittleEndian.Uint16(data[0x08:]),
+		InitAddr:       binary.LittleEndian.Uint16(data[0x0A:]),
+		PlayAddr:       binary.LittleEndian.Uint16(data[0x0C:]),
+		Title:          cString(data[0x0E:0x2E]),
+		Artist:         cString(data[0x2E:0x4E]),
+		Copyright:      cString(data[0x4E:0x6E]),
+		PlaySpeedNTSC:  binary.LittleEndian.Uint16(data[0x6E:]),
+		PlaySpeedPAL:   binary.LittleEndian.Uint16(data[0x78:]),
+		Region:         regionFromFlags(data[0x7A]),
+		ExpansionChips: ExpansionChip(data[0x7B]),
+	}
+	copy(n.Bankswitch[:], data[0x70:0x78])
+
+	body := data[nsfHeaderSize:]
+	// NSF2ではプログラムの長さが指定されている場合があり、その後ろはメタデータになる
+	if n.Version >= 2 {
+		length := int(data[0x7D]) | int(data[0x7E])<<8 | int(data[0x7F])<<16
+		if length > 0 && length <= len(body) {
+			body = body[:length]
+		}
+	}
+	n.Data = body
+
+	if err := n.validate(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *NSF) validate() error {
+	if n.SongCount == 0 {
+		return errors.New("NSF: song count is 0")
+	}
+	if n.StartingSong < 1 || n.StartingSong > n.SongCount {
+		return fmt.Errorf("NSF: invalid starting song. startingSong: %d, songCount: %d", n.StartingSong, n.SongCount)
+	}
+	if len(n.Data) == 0 {
+		return errors.New("NSF: program data is empty")
+	}
+
+	minLoadAddr := uint16(0x8000)
+	if n.ExpansionChips&ChipFDS != 0 {
+		minLoadAddr = 0x6000
+	}
+	if n.LoadAddr < minLoadAddr {
+		return fmt.Errorf("NSF: invalid load address. loadAddr: %x", n.LoadAddr)
+	}
+	return nil
+}
+
+// IsBankswitched バンク切り替えを使うかどうか
+func (n *NSF) IsBankswitched() bool {
+	for _, b := range n.Bankswitch {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PlaySpeed regionに応じたPLAYの呼び出し間隔(µs)
+func (n *NSF) PlaySpeed(region Region) uint16 {
+	if region == RegionPAL {
+		if n.PlaySpeedPAL == 0 {
+			return DefaultPlaySpeedPAL
+		}
+		return n.PlaySpeedPAL
+	}
+	if n.PlaySpeedNTSC == 0 {
+		return DefaultPlaySpeedNTSC
+	}
+	return n.PlaySpeedNTSC
+}
+
+// TrackTime 曲の長さ(ミリ秒)、NSFeで指定されていない場合は-1
+func (n *NSF) TrackTime(track int) int {
+	if track < 1 || track > len(n.TrackTimes) {
+		return -1
+	}
+	return n.TrackTimes[track-1]
+}
+
+// TrackFade 曲のフェードアウトの長さ(ミリ秒)、NSFeで指定されていない場合は-1
+func (n *NSF) TrackFade(track int) int {
+	if track < 1 || track > len(n.TrackFades) {
+		return -1
+	}
+	return n.TrackFades[track-1]
+}
+
+func regionFromFlags(flags byte) Region {
+	switch {
+	case flags&0x02 != 0:
+		return RegionDual
+	case flags&0x01 != 0:
+		return RegionPAL
+	default:
+		return RegionNTSC
+	}
+}
+
+// cString NULL終端の文字列
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
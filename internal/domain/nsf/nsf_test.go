@@ -0,0 +1,114 @@
+package nsf_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/nsf"
+)
+
+func nsfHeader(songs, start byte, load, init, play uint16) []byte {
+	header := make([]byte, 0x80)
+	copy(header, "NESM\x1A")
+	header[0x05] = 1
+	header[0x06] = songs
+	header[0x07] = start
+	binary.LittleEndian.PutUint16(header[0x08:], load)
+	binary.LittleEndian.PutUint16(header[0x0A:], init)
+	binary.LittleEndian.PutUint16(header[0x0C:], play)
+	copy(header[0x0E:], "Title")
+	copy(header[0x2E:], "Artist")
+	copy(header[0x4E:], "2025")
+	binary.LittleEndian.PutUint16(header[0x6E:], 16639)
+	binary.LittleEndian.PutUint16(header[0x78:], 19997)
+	return header
+}
+
+func nsfeChunk(id string, data []byte) []byte {
+	chunk := make([]byte, 8, 8+len(data))
+	binary.LittleEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], id)
+	return append(chunk, data...)
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestParse$ github.com/sunjin110/nes_emu/internal/domain/nsf
+func TestParse(t *testing.T) {
+	Convey("TestParse", t, func() {
+		Convey("NSFのヘッダーが読み込めること", func() {
+			data := nsfHeader(3, 2, 0x8000, 0x8000, 0x8003)
+			data[0x70+1] = 1 // バンク切り替えあり
+			data[0x7A] = 0x01
+			data[0x7B] = byte(nsf.ChipVRC6 | nsf.ChipN163)
+			data = append(data, 0x60, 0x60, 0x60, 0x60)
+
+			n, err := nsf.Parse(data)
+			So(err, ShouldBeNil)
+			So(n.SongCount, ShouldEqual, 3)
+			So(n.StartingSong, ShouldEqual, 2)
+			So(n.LoadAddr, ShouldEqual, 0x8000)
+			So(n.PlayAddr, ShouldEqual, 0x8003)
+			So(n.Title, ShouldEqual, "Title")
+			So(n.Artist, ShouldEqual, "Artist")
+			So(n.Copyright, ShouldEqual, "2025")
+			So(n.Region, ShouldEqual, nsf.RegionPAL)
+			So(n.PlaySpeed(nsf.RegionPAL), ShouldEqual, 19997)
+			So(n.ExpansionChips, ShouldEqual, nsf.ChipVRC6|nsf.ChipN163)
+			So(n.IsBankswitched(), ShouldBeTrue)
+			So(n.Data, ShouldResemble, []byte{0x60, 0x60, 0x60, 0x60})
+		})
+
+		Convey("開始曲が曲数を超える場合はエラー", func() {
+			data := append(nsfHeader(1, 2, 0x8000, 0x8000, 0x8003), 0x60)
+			_, err := nsf.Parse(data)
+			So(err, ShouldBeError)
+		})
+
+		Convey("ヘッダーが短い場合はエラー", func() {
+			_, err := nsf.Parse([]byte("NESM\x1A\x01"))
+			So(err, ShouldBeError)
+		})
+
+		Convey("NSFeのチャンクが読み込めること", func() {
+			info := []byte{0x00, 0x80, 0x00, 0x80, 0x03, 0x80, 0x02, 0x00, 0x02, 0x01}
+			times := make([]byte, 8)
+			binary.LittleEndian.PutUint32(times, 90000)
+			binary.LittleEndian.PutUint32(times[4:], 0xFFFFFFFF)
+
+			data := []byte("NSFE")
+			data = append(data, nsfeChunk("INFO", info)...)
+			data = append(data, nsfeChunk("DATA", []byte{0x60, 0x60, 0x60, 0x60})...)
+			data = append(data, nsfeChunk("auth", []byte("Title\x00Artist\x00(C)\x00Ripper\x00"))...)
+			data = append(data, nsfeChunk("tlbl", []byte("Intro\x00Stage\x00"))...)
+			data = append(data, nsfeChunk("time", times)...)
+			data = append(data, nsfeChunk("xtra", []byte{1, 2, 3})...)
+			data = append(data, nsfeChunk("NEND", nil)...)
+
+			n, err := nsf.Parse(data)
+			So(err, ShouldBeNil)
+			So(n.SongCount, ShouldEqual, 2)
+			So(n.StartingSong, ShouldEqual, 2)
+			So(n.InitAddr, ShouldEqual, 0x8000)
+			So(n.PlayAddr, ShouldEqual, 0x8003)
+			So(n.Region, ShouldEqual, nsf.RegionDual)
+			So(n.Title, ShouldEqual, "Title")
+			So(n.Copyright, ShouldEqual, "(C)")
+			So(n.TrackLabels, ShouldResemble, []string{"Intro", "Stage"})
+			So(n.TrackTime(1), ShouldEqual, 90000)
+			So(n.TrackTime(2), ShouldEqual, -1)
+			So(n.TrackFade(1), ShouldEqual, -1)
+		})
+
+		Convey("NSFeの未知の必須チャンクはエラー", func() {
+			info := []byte{0x00, 0x80, 0x00, 0x80, 0x03, 0x80, 0x00, 0x00}
+			data := []byte("NSFE")
+			data = append(data, nsfeChunk("INFO", info)...)
+			data = append(data, nsfeChunk("DATA", []byte{0x60})...)
+			data = append(data, nsfeChunk("XTRA", nil)...)
+			data = append(data, nsfeChunk("NEND", nil)...)
+
+			_, err := nsf.Parse(data)
+			So(err, ShouldBeError)
+		})
+	})
+}
@@ -0,0 +1,140 @@
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// parseNSFE NSFeはチャンクの並びで構成される
+// 各チャンクは 長さ(4byte) + FourCC(4byte) + データ
+// FourCCの先頭が大文字のチャンクは必須で、理解できない場合はエラーにする
+// doc: https://www.nesdev.org/wiki/NSFe
+func parseNSFE(data []byte) (*NSF, error) {
+	n := &NSF{
+		Version:      1,
+		SongCount:    1,
+		StartingSong: 1,
+	}
+
+	var hasInfo, hasData, hasEnd bool
+	offset := len(nsfeMagic)
+	for offset < len(data) && !hasEnd {
+		if offset+8 > len(data) {
+			return nil, fmt.Errorf("NSFe: chunk header is truncated. offset: %d", offset)
+		}
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		id := string(data[offset+4 : offset+8])
+		offset += 8
+		if size < 0 || offset+size > len(data) {
+			return nil, fmt.Errorf("NSFe: chunk is truncated. id: %s, size: %d", id, size)
+		}
+		chunk := data[offset : offset+size]
+		offset += size
+
+		switch id {
+		case "INFO":
+			if err := n.parseInfoChunk(chunk); err != nil {
+				return nil, err
+			}
+			hasInfo = true
+		case "DATA":
+			if !hasInfo {
+				return nil, errors.New("NSFe: DATA chunk appeared before INFO chunk")
+			}
+			n.Data = chunk
+			hasData = true
+		case "NEND":
+			hasEnd = true
+		case "BANK":
+			copy(n.Bankswitch[:], chunk)
+		case "RATE":
+			if len(chunk) >= 2 {
+				n.PlaySpeedNTSC = binary.LittleEndian.Uint16(chunk)
+			}
+			if len(chunk) >= 4 {
+				n.PlaySpeedPAL = binary.LittleEndian.Uint16(chunk[2:])
+			}
+		case "auth":
+			fields := splitCStrings(chunk)
+			for i, field := range fields {
+				switch i {
+				case 0:
+					n.Title = field
+				case 1:
+					n.Artist = field
+				case 2:
+					n.Copyright = field
+				}
+			}
+		case "tlbl":
+			n.TrackLabels = splitCStrings(chunk)
+		case "time":
+			n.TrackTimes = int32s(chunk)
+		case "fade":
+			n.TrackFades = int32s(chunk)
+		case "plst":
+			n.Playlist = make([]int, len(chunk))
+			for i, track := range chunk {
+				n.Playlist[i] = int(track)
+			}
+		default:
+			if id[0] >= 'A' && id[0] <= 'Z' {
+				return nil, fmt.Errorf("NSFe: unsupported required chunk. id: %s", id)
+			}
+			// 小文字のチャンクは読み飛ばしてよい
+		}
+	}
+
+	if !hasInfo || !hasData {
+		return nil, errors.New("NSFe: INFO and DATA chunks are required")
+	}
+	if !hasEnd {
+		return nil, errors.New("NSFe: NEND chunk is missing")
+	}
+	if err := n.validate(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// parseInfoChunk load(2) init(2) play(2) region(1) chips(1) [songs(1)] [starting song(1), 0始まり]
+func (n *NSF) parseInfoChunk(chunk []byte) error {
+	if len(chunk) < 8 {
+		return fmt.Errorf("NSFe: INFO chunk is too short. size: %d", len(chunk))
+	}
+	n.LoadAddr = binary.LittleEndian.Uint16(chunk[0:])
+	n.InitAddr = binary.LittleEndian.Uint16(chunk[2:])
+	n.PlayAddr = binary.LittleEndian.Uint16(chunk[4:])
+	n.Region = regionFromFlags(chunk[6])
+	n.ExpansionChips = ExpansionChip(chunk[7])
+	if len(chunk) >= 9 {
+		n.SongCount = int(chunk[8])
+	}
+	if len(chunk) >= 10 {
+		n.StartingSong = int(chunk[9]) + 1
+	}
+	return nil
+}
+
+func splitCStrings(chunk []byte) []string {
+	chunk = bytes.TrimSuffix(chunk, []byte{0})
+	if len(chunk) == 0 {
+		return nil
+	}
+	parts := bytes.Split(chunk, []byte{0})
+	strs := make([]string, len(parts))
+	for i, part := range parts {
+		strs[i] = string(part)
+	}
+	return strs
+}
+
+func int32s(chunk []byte) []int {
+	values := make([]int, len(chunk)/4)
+	for i := range values {
+		values[i] = int(int32(binary.LittleEndian.Uint32(chunk[i*4:])))
+	}
+	return values
+}
@@ -0,0 +1,200 @@
+package nsf
+
+import (
+	"fmt"
+
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/controller"
+	"github.com/sunjin110/nes_emu/internal/domain/cpu"
+)
+
+const (
+	// returnAddr INIT/PLAYからRTSで戻ってくるアドレス、実際にこのアドレスの命令は実行しない
+	returnAddr = 0x4100
+
+	// initCycleLimit INITが戻ってこない場合に諦めるまでのCPUサイクル数(約5秒)
+	initCycleLimit = apu.CPUClockNTSC * 5
+)
+
+// Player NSFの曲をPPUなしでCPUとAPUだけで再生する
+// doc: https://www.nesdev.org/wiki/NSF#Initializing_a_tune
+//
+// 現在のAPUはNTSCのみ対応のため、PALの曲もNTSCのクロックで再生される(PLAYの呼び出し間隔はPALのものを使う)
+type Player struct {
+	nsf      *NSF
+	sink     apu.SampleSink
+	controls apu.ChannelControls
+
+	rom *rom
+	cpu *cpu.CPU
+	apu *apu.APU
+
+	track      int
+	playPeriod int  // PLAYを呼び出す間隔(CPUサイクル)
+	untilPlay  int  // 次にPLAYを呼び出すまでのCPUサイクル
+	inRoutine  bool // INITかPLAYを実行中
+}
+
+func NewPlayer(n *NSF) *Player {
+	return &Player{
+		nsf:      n,
+		controls: apu.NewChannelControls(),
+	}
+}
+
+// SetSampleSink APUの出力先を設定する、Initより前に設定すること
+func (p *Player) SetSampleSink(sink apu.SampleSink) {
+	p.sink = sink
+	if p.apu != nil {
+		p.apu.SetSampleSink(sink)
+	}
+}
+
+// SetChannelControls チャンネルごとのミュート・ソロ・音量を設定する、Initで曲を切り替えても引き継がれる
+func (p *Player) SetChannelControls(controls apu.ChannelControls) {
+	p.controls = controls
+	if p.apu != nil {
+		p.apu.SetChannelControls(controls)
+	}
+}
+
+// Track 現在の曲番号(1始まり)
+func (p *Player) Track() int {
+	return p.track
+}
+
+// Init 曲を選択してINITを呼び出す
+// track: 1始まりの曲番号
+func (p *Player) Init(track int) error {
+	if track < 1 || track > p.nsf.SongCount {
+		return fmt.Errorf("NSF: Player: invalid track. track: %d, songCount: %d", track, p.nsf.SongCount)
+	}
+
+	p.rom = newROM(p.nsf)
+	p.apu = apu.NewAPU()
+	p.apu.SetSampleSink(p.sink)
+	p.apu.SetChannelControls(p.controls)
+	for _, expansion := range newExpansionAudios(p.nsf.ExpansionChips) {
+		p.rom.addExpansionAudio(expansion)
+		p.apu.AddExpansionAudio(expansion)
+	}
+	c, err := cpu.NewCPU(p.rom, nil, p.apu, controller.NewController())
+	if err != nil {
+		return fmt.Errorf("NSF: Player: failed new cpu. err: %w", err)
+	}
+	p.cpu = c
+	p.track = track
+
+	// APUの初期化
+	for addr := uint16(0x4000); addr <= 0x4013; addr++ {
+		p.apu.Write(addr, 0)
+	}
+	p.apu.Write(0x4015, 0x0F)
+	p.apu.Write(0x4017, 0x40)
+
+	region := p.nsf.Region
+	if region == RegionDual {
+		region = RegionNTSC
+	}
+	p.playPeriod = int(uint64(p.nsf.PlaySpeed(region)) * apu.CPUClockNTSC / 1000000)
+
+	var x byte
+	if region == RegionPAL {
+		x = 1
+	}
+	if err := p.cpu.CallSubroutine(p.nsf.InitAddr, returnAddr, byte(track-1), x); err != nil {
+		return fmt.Errorf("NSF: Player: failed call INIT. err: %w", err)
+	}
+	p.inRoutine = true
+
+	for cycles := 0; p.inRoutine; {
+		if cycles > initCycleLimit {
+			return fmt.Errorf("NSF: Player: INIT did not return. initAddr: %x", p.nsf.InitAddr)
+		}
+		n, err := p.step()
+		if err != nil {
+			return fmt.Errorf("NSF: Player: failed run INIT. err: %w", err)
+		}
+		cycles += n
+	}
+	p.untilPlay = 0
+	return nil
+}
+
+// newExpansionAudios ヘッダーで指定された拡張音源を生成する
+func newExpansionAudios(chips ExpansionChip) []apu.ExpansionAudio {
+	var expansions []apu.ExpansionAudio
+	if chips&ChipVRC6 != 0 {
+		expansions = append(expansions, apu.NewVRC6())
+	}
+	if chips&ChipVRC7 != 0 {
+		expansions = append(expansions, apu.NewVRC7())
+	}
+	if chips&ChipFDS != 0 {
+		expansions = append(expansions, apu.NewFDSAudio())
+	}
+	if chips&ChipMMC5 != 0 {
+		expansions = append(expansions, apu.NewMMC5Audio())
+	}
+	if chips&ChipN163 != 0 {
+		expansions = append(expansions, apu.NewN163())
+	}
+	if chips&ChipSunsoft5B != 0 {
+		expansions = append(expansions, apu.NewSunsoft5B())
+	}
+	return expansions
+}
+
+// Run 指定したCPUサイクル数だけ再生を進める
+// PLAYはplayPeriodごとに呼び出し、前回のPLAYがまだ終わっていない場合はスキップする
+func (p *Player) Run(cycles int) error {
+	if p.cpu == nil {
+		return fmt.Errorf("NSF: Player: Init is not called")
+	}
+
+	for elapsed := 0; elapsed < cycles; {
+		if p.untilPlay <= 0 {
+			if !p.inRoutine {
+				if err := p.cpu.CallSubroutine(p.nsf.PlayAddr, returnAddr, 0, 0); err != nil {
+					return fmt.Errorf("NSF: Player: failed call PLAY. err: %w", err)
+				}
+				p.inRoutine = true
+			}
+			p.untilPlay += p.playPeriod
+		}
+
+		n, err := p.step()
+		if err != nil {
+			return fmt.Errorf("NSF: Player: failed run PLAY. err: %w", err)
+		}
+		elapsed += n
+		p.untilPlay -= n
+	}
+	return nil
+}
+
+// RunSeconds 指定した秒数だけ再生を進める
+func (p *Player) RunSeconds(seconds float64) error {
+	return p.Run(int(seconds * apu.CPUClockNTSC))
+}
+
+// step INIT/PLAYの実行中は1命令、それ以外は1サイクル分進める
+func (p *Player) step() (cycles int, err error) {
+	cycles = 1
+	if p.inRoutine {
+		if p.cpu.PC() == returnAddr {
+			p.inRoutine = false
+		} else {
+			c, err := p.cpu.Run()
+			if err != nil {
+				return 0, err
+			}
+			cycles = int(c)
+		}
+	}
+
+	for i := 0; i < cycles; i++ {
+		p.apu.Step()
+	}
+	return cycles, nil
+}
@@ -0,0 +1,85 @@
+package nsf
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testProgram INITで曲番号を0x6001に保存してpulse1を鳴らし、PLAYで0x6000をインクリメントする
+var testProgram = []byte{
+	0x8D, 0x01, 0x60, // $8000 STA $6001
+	0xA9, 0x00, // LDA #$00
+	0x8D, 0x00, 0x60, // STA $6000
+	0xA9, 0x01, 0x8D, 0x15, 0x40, // LDA #$01, STA $4015
+	0xA9, 0xBF, 0x8D, 0x00, 0x40, // LDA #$BF, STA $4000
+	0xA9, 0x00, 0x8D, 0x02, 0x40, // LDA #$00, STA $4002
+	0xA9, 0x09, 0x8D, 0x03, 0x40, // LDA #$09, STA $4003
+	0x60,             // RTS
+	0xEE, 0x00, 0x60, // $801D INC $6000
+	0x60, // RTS
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestPlayer$ github.com/sunjin110/nes_emu/internal/domain/nsf
+func TestPlayer(t *testing.T) {
+	Convey("TestPlayer", t, func() {
+		n := &NSF{
+			SongCount:    2,
+			StartingSong: 1,
+			LoadAddr:     0x8000,
+			InitAddr:     0x8000,
+			PlayAddr:     0x801D,
+			Data:         testProgram,
+		}
+		player := NewPlayer(n)
+
+		Convey("INITにAレジスタで0始まりの曲番号が渡されること", func() {
+			So(player.Init(2), ShouldBeNil)
+			So(player.rom.ram[1], ShouldEqual, 1)
+			So(player.apu.Read(0x4015)&0x01, ShouldEqual, 0x01)
+		})
+
+		Convey("PLAYが約60Hzで呼び出されること", func() {
+			So(player.Init(1), ShouldBeNil)
+			So(player.RunSeconds(1), ShouldBeNil)
+			So(player.rom.ram[0], ShouldBeBetweenOrEqual, 60, 61)
+		})
+
+		Convey("存在しない曲番号はエラー", func() {
+			So(player.Init(3), ShouldBeError)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestROM_Bankswitch$ github.com/sunjin110/nes_emu/internal/domain/nsf
+func TestROM_Bankswitch(t *testing.T) {
+	Convey("TestROM_Bankswitch", t, func() {
+		data := make([]byte, 3*bankSize-0x100)
+		data[0] = 0xAA              // bank0の0x100
+		data[bankSize-0x100] = 0xBB // bank1の先頭
+		data[2*bankSize-0x100] = 0xCC
+
+		n := &NSF{
+			SongCount:    1,
+			StartingSong: 1,
+			LoadAddr:     0x8100,
+			InitAddr:     0x8100,
+			PlayAddr:     0x8100,
+			Bankswitch:   [8]byte{0, 1, 2, 0, 0, 0, 0, 0},
+			Data:         data,
+		}
+		r := newROM(n)
+		So(r.Read(0x8100), ShouldEqual, 0xAA)
+		So(r.Read(0x9000), ShouldEqual, 0xBB)
+		So(r.Read(0xA000), ShouldEqual, 0xCC)
+
+		r.Write(0x5FF8, 2)
+		So(r.Read(0x8000), ShouldEqual, 0xCC)
+
+		// ROMへの書き込みは無視されてWRAMには書き込める
+		r.Write(0x8000, 0x11)
+		So(r.Read(0x8000), ShouldEqual, 0xCC)
+		r.Write(0x6000, 0x22)
+		So(r.Read(0x6000), ShouldEqual, 0x22)
+	})
+}
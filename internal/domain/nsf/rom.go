@@ -0,0 +1,173 @@
+package nsf
+
+import (
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/domain/prgrom"
+)
+
+const (
+	bankSize = 4 * 1024 // 4KB
+
+	addrFDSBankStart = 0x5FF6 // FDSの場合は0x5FF6, 0x5FF7で0x6000〜0x7FFFのバンクを切り替える
+	addrBankStart    = 0x5FF8
+	addrBankEnd      = 0x5FFF
+	addrRAMStart     = 0x6000
+	addrRAMEnd       = 0x7FFF
+	addrPRGStart     = 0x8000
+
+	addrMMC5ExRAMStart = 0x5C00 // MMC5のExRAMはNSFでは通常のRAMとして使える
+	addrMMC5ExRAMEnd   = 0x5FF5
+	addrMMC5MulA       = 0x5205 // MMC5の8bit乗算器
+	addrMMC5MulB       = 0x5206
+)
+
+// rom NSFのデータをCPUのアドレス空間(0x4020〜0xFFFF)にマッピングする
+// prgrom.PRGROMの代わりにCPUのメモリに渡す
+//
+// 通常: 0x6000〜0x7FFFはWRAM、0x8000〜0xFFFFは4KBごとにバンク切り替えできるROM
+// FDS: 0x6000〜0xFFFFの全てがRAMになり、バンク切り替えはRAMにバンクをコピーする
+type rom struct {
+	// 4KB単位のバンクに揃えたデータ
+	// バンク切り替えありの場合は先頭に(LoadAddr & 0x0FFF)のパディングが入る
+	// バンク切り替えなしの場合は0x8000からの位置に合わせたパディングが入る
+	banks []byte
+
+	bankswitched bool
+	fds          bool
+	bankNo       [8]int // 0x8000〜0xFFFFの4KBごとのバンク番号
+
+	ram    [0x2000]byte // 0x6000〜0x7FFF
+	fdsRAM [0xA000]byte // 0x6000〜0xFFFF (FDSの場合)
+
+	initPC uint16
+
+	// 拡張音源、レジスタへの読み書きを転送する
+	expansions []apu.ExpansionAudio
+	mmc5       bool
+	mmc5ExRAM  [0x400]byte
+	mmc5Mul    [2]byte
+}
+
+var _ prgrom.ExpansionPRGROM = (*rom)(nil)
+
+func newROM(n *NSF) *rom {
+	r := &rom{
+		bankswitched: n.IsBankswitched(),
+		fds:          n.ExpansionChips&ChipFDS != 0,
+		mmc5:         n.ExpansionChips&ChipMMC5 != 0,
+		initPC:       n.InitAddr,
+	}
+
+	switch {
+	case r.bankswitched:
+		padding := int(n.LoadAddr & 0x0FFF)
+		r.banks = make([]byte, alignBank(padding+len(n.Data)))
+		copy(r.banks[padding:], n.Data)
+		if r.fds {
+			// FDSの場合は0x6000, 0x7000にもバンクの初期値(0x76, 0x77と同じ値)をロードする
+			r.writeBank(addrFDSBankStart, n.Bankswitch[6])
+			r.writeBank(addrFDSBankStart+1, n.Bankswitch[7])
+		}
+		for i, bank := range n.Bankswitch {
+			r.writeBank(addrBankStart+uint16(i), bank)
+		}
+	case r.fds:
+		copy(r.fdsRAM[n.LoadAddr-addrRAMStart:], n.Data)
+	default:
+		padding := int(n.LoadAddr - addrPRGStart)
+		r.banks = make([]byte, alignBank(padding+len(n.Data)))
+		copy(r.banks[padding:], n.Data)
+		for i := range r.bankNo {
+			r.bankNo[i] = i
+		}
+	}
+	return r
+}
+
+func alignBank(size int) int {
+	return (size + bankSize - 1) / bankSize * bankSize
+}
+
+// addExpansionAudio 拡張音源のレジスタへの読み書きを転送する
+func (r *rom) addExpansionAudio(expansion apu.ExpansionAudio) {
+	r.expansions = append(r.expansions, expansion)
+}
+
+func (r *rom) Read(addr uint16) byte {
+	for _, expansion := range r.expansions {
+		if value, ok := expansion.Read(addr); ok {
+			return value
+		}
+	}
+
+	switch {
+	case r.mmc5 && addr >= addrMMC5ExRAMStart && addr <= addrMMC5ExRAMEnd:
+		return r.mmc5ExRAM[addr-addrMMC5ExRAMStart]
+	case r.mmc5 && addr == addrMMC5MulA:
+		return byte(uint16(r.mmc5Mul[0]) * uint16(r.mmc5Mul[1]))
+	case r.mmc5 && addr == addrMMC5MulB:
+		return byte(uint16(r.mmc5Mul[0]) * uint16(r.mmc5Mul[1]) >> 8)
+	case addr >= addrRAMStart && r.fds:
+		return r.fdsRAM[addr-addrRAMStart]
+	case addr >= addrRAMStart && addr <= addrRAMEnd:
+		return r.ram[addr-addrRAMStart]
+	case addr >= addrPRGStart:
+		slot := (addr - addrPRGStart) / bankSize
+		index := r.bankNo[slot]*bankSize + int(addr%bankSize)
+		if index >= len(r.banks) {
+			return 0
+		}
+		return r.banks[index]
+	}
+	// 拡張音源のレジスタ以外は何もつながっていない
+	return 0
+}
+
+func (r *rom) Write(addr uint16, value byte) {
+	for _, expansion := range r.expansions {
+		expansion.Write(addr, value)
+	}
+
+	switch {
+	case r.mmc5 && addr >= addrMMC5ExRAMStart && addr <= addrMMC5ExRAMEnd:
+		r.mmc5ExRAM[addr-addrMMC5ExRAMStart] = value
+	case r.mmc5 && (addr == addrMMC5MulA || addr == addrMMC5MulB):
+		r.mmc5Mul[addr-addrMMC5MulA] = value
+	case addr >= addrFDSBankStart && addr <= addrBankEnd:
+		r.writeBank(addr, value)
+	case addr >= addrRAMStart && r.fds:
+		r.fdsRAM[addr-addrRAMStart] = value
+	case addr >= addrRAMStart && addr <= addrRAMEnd:
+		r.ram[addr-addrRAMStart] = value
+	}
+	// 0x8000〜0xFFFFはROMなので書き込みは無視する
+}
+
+func (r *rom) writeBank(addr uint16, bank byte) {
+	if !r.bankswitched {
+		return
+	}
+
+	bankCount := len(r.banks) / bankSize
+	bankNo := int(bank)
+	if bankNo >= bankCount {
+		bankNo %= bankCount
+	}
+	data := r.banks[bankNo*bankSize : (bankNo+1)*bankSize]
+
+	if r.fds {
+		// FDSはRAMにバンクをコピーする
+		// 0x5FF6 -> 0x6000, 0x5FF7 -> 0x7000, 0x5FF8 -> 0x8000 ...
+		offset := int(addr-addrFDSBankStart) * bankSize
+		copy(r.fdsRAM[offset:], data)
+		return
+	}
+	if addr < addrBankStart {
+		return
+	}
+	r.bankNo[addr-addrBankStart] = bankNo
+}
+
+func (r *rom) InitPC() uint16 {
+	return r.initPC
+}
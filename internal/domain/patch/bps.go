@@ -0,0 +1,174 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// doc: https://www.romhacking.net/documents/746/
+//
+// "BPS1"、元のサイズ、当てた後のサイズ、メタデータのサイズ(可変長)とメタデータの後に、
+// 当てた後のデータを先頭から作るコマンドが続く、最後の12byteはUPSと同じCRC32
+// コマンド(可変長)の下位2bitが種類、残りがbyte数-1
+//
+//	SourceRead: 元のデータの同じ位置からコピー
+//	TargetRead: パッチのデータをコピー
+//	SourceCopy: 元のデータの任意の位置からコピー、位置は前回からの相対(可変長、bit0が符号)
+//	TargetCopy: 当てた後のデータの任意の位置からコピー、重なってもよい(RLE)
+
+var bpsMagic = []byte("BPS1")
+
+const (
+	bpsSourceRead = iota
+	bpsTargetRead
+	bpsSourceCopy
+	bpsTargetCopy
+)
+
+// bpsMinSourceRead CreateBPSでTargetReadを区切ってSourceReadにする、一致するbyte数の下限
+const bpsMinSourceRead = 4
+
+// ApplyBPS originalにBPSのデータを当てたコピー
+func ApplyBPS(original, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, bpsMagic) {
+		return nil, fmt.Errorf("%w. BPS magic is missing", ErrInvalidPatch)
+	}
+	body, err := verifyFooter(FormatBPS, original, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := len(bpsMagic)
+	var sizes [3]uint64
+	for i := range sizes {
+		if sizes[i], offset, err = readVarint(body, offset); err != nil {
+			return nil, err
+		}
+	}
+	sourceSize, targetSize, metadataSize := sizes[0], sizes[1], sizes[2]
+	if sourceSize != uint64(len(original)) || targetSize > maxTargetSize {
+		return nil, fmt.Errorf("%w. BPS size mismatch. source: %d, target: %d, original: %d", ErrInvalidPatch, sourceSize, targetSize, len(original))
+	}
+	if metadataSize > uint64(len(body)-offset) {
+		return nil, fmt.Errorf("%w. BPS metadata is truncated. size: %d", ErrInvalidPatch, metadataSize)
+	}
+	offset += int(metadataSize)
+
+	target := make([]byte, 0, targetSize)
+	var sourceRelative, targetRelative int
+	for offset < len(body) {
+		var data uint64
+		if data, offset, err = readVarint(body, offset); err != nil {
+			return nil, err
+		}
+		command := data & 0x03
+		length := int(data>>2) + 1
+		if uint64(len(target)+length) > targetSize {
+			return nil, fmt.Errorf("%w. BPS command exceeds target. offset: %d", ErrInvalidPatch, offset)
+		}
+
+		switch command {
+		case bpsSourceRead:
+			if len(target)+length > len(original) {
+				return nil, fmt.Errorf("%w. BPS SourceRead exceeds source. offset: %d", ErrInvalidPatch, offset)
+			}
+			target = append(target, original[len(target):len(target)+length]...)
+		case bpsTargetRead:
+			if offset+length > len(body) {
+				return nil, fmt.Errorf("%w. BPS TargetRead is truncated. offset: %d", ErrInvalidPatch, offset)
+			}
+			target = append(target, body[offset:offset+length]...)
+			offset += length
+		case bpsSourceCopy:
+			var relative int
+			if relative, offset, err = readRelative(body, offset); err != nil {
+				return nil, err
+			}
+			sourceRelative += relative
+			if sourceRelative < 0 || sourceRelative+length > len(original) {
+				return nil, fmt.Errorf("%w. BPS SourceCopy exceeds source. offset: %d", ErrInvalidPatch, offset)
+			}
+			target = append(target, original[sourceRelative:sourceRelative+length]...)
+			sourceRelative += length
+		case bpsTargetCopy:
+			var relative int
+			if relative, offset, err = readRelative(body, offset); err != nil {
+				return nil, err
+			}
+			targetRelative += relative
+			if targetRelative < 0 || targetRelative >= len(target) {
+				return nil, fmt.Errorf("%w. BPS TargetCopy exceeds target. offset: %d", ErrInvalidPatch, offset)
+			}
+			// 1byteずつコピーして、コピーしたbyteも続けて読めるようにする
+			for i := 0; i < length; i++ {
+				target = append(target, target[targetRelative])
+				targetRelative++
+			}
+		}
+	}
+	if uint64(len(target)) != targetSize {
+		return nil, fmt.Errorf("%w. BPS target is truncated. size: %d, expected: %d", ErrInvalidPatch, len(target), targetSize)
+	}
+
+	if crc := crc32.ChecksumIEEE(target); crc != binary.LittleEndian.Uint32(patch[len(patch)-8:]) {
+		return nil, fmt.Errorf("%w. BPS target crc32: %08X", ErrChecksumMismatch, crc)
+	}
+	return target, nil
+}
+
+// readRelative SourceCopy, TargetCopyの相対位置、bit0が1の場合は負
+func readRelative(data []byte, offset int) (int, int, error) {
+	value, offset, err := readVarint(data, offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	relative := int(value >> 1)
+	if value&0x01 != 0 {
+		relative = -relative
+	}
+	return relative, offset, nil
+}
+
+// CreateBPS originalをmodifiedにするBPSのデータ
+// 同じ位置のbyteが一致する部分はSourceRead、それ以外はTargetReadにする
+func CreateBPS(original, modified []byte) ([]byte, error) {
+	if len(modified) > maxTargetSize {
+		return nil, fmt.Errorf("Patch: data is too large for BPS. size: %d", len(modified))
+	}
+
+	patch := append([]byte(nil), bpsMagic...)
+	patch = appendVarint(patch, uint64(len(original)))
+	patch = appendVarint(patch, uint64(len(modified)))
+	patch = appendVarint(patch, 0)
+
+	for offset := 0; offset < len(modified); {
+		if n := sameLength(original, modified, offset); n > 0 {
+			patch = appendVarint(patch, uint64(n-1)<<2|bpsSourceRead)
+			offset += n
+			continue
+		}
+		// 短い一致はSourceReadに切り替えずにTargetReadに含める
+		end := offset + 1
+		for end < len(modified) && sameLength(original, modified, end) < min(bpsMinSourceRead, len(modified)-end) {
+			end++
+		}
+		patch = appendVarint(patch, uint64(end-offset-1)<<2|bpsTargetRead)
+		patch = append(patch, modified[offset:end]...)
+		offset = end
+	}
+
+	patch = binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(original))
+	patch = binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(modified))
+	return binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(patch)), nil
+}
+
+// sameLength offsetからoriginalとmodifiedが一致するbyte数
+func sameLength(original, modified []byte, offset int) int {
+	n := 0
+	for offset+n < len(original) && offset+n < len(modified) && original[offset+n] == modified[offset+n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,12 @@
+package patch
+
+import "errors"
+
+var (
+	// ErrUnknownFormat IPS, UPS, BPSのどれでもない
+	ErrUnknownFormat = errors.New("Patch: unknown format")
+	// ErrInvalidPatch パッチのデータが不正
+	ErrInvalidPatch = errors.New("Patch: invalid patch")
+	// ErrChecksumMismatch UPS, BPSのCRC32が元のデータ、当てた後のデータ、パッチと合わない
+	ErrChecksumMismatch = errors.New("Patch: checksum mismatch")
+)
@@ -0,0 +1,108 @@
+package patch
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// doc: https://zerosoft.zophar.net/ips.php
+//
+// "PATCH"の後に、offset(3byte, ビッグエンディアン), size(2byte), データのレコードが続き、"EOF"で終わる
+// sizeが0のレコードはRLEで、size(2byte)と値(1byte)が続く
+// "EOF"の後に3byteある場合は、当てた後のデータをそのサイズに切り詰める(Lunar IPSの拡張)
+
+var (
+	ipsMagic = []byte("PATCH")
+	ipsEOF   = []byte("EOF")
+)
+
+const (
+	ipsMaxOffset     = 0xFFFFFF
+	ipsMaxRecordSize = 0xFFFF
+	// ipsEOFOffset "EOF"と同じoffsetのレコードは書けない
+	ipsEOFOffset = 0x454F46
+)
+
+// CreateIPS originalをmodifiedにするIPSのデータ、同じ長さのデータのみ
+func CreateIPS(original, modified []byte) ([]byte, error) {
+	if len(original) != len(modified) {
+		return nil, fmt.Errorf("Patch: size mismatch. original: %d, modified: %d", len(original), len(modified))
+	}
+	if len(modified) > ipsMaxOffset {
+		return nil, fmt.Errorf("Patch: data is too large for IPS. size: %d", len(modified))
+	}
+
+	patch := append([]byte(nil), ipsMagic...)
+	for offset := 0; offset < len(modified); {
+		if original[offset] == modified[offset] {
+			offset++
+			continue
+		}
+		// "EOF"と読めるoffsetは1byte前から書く
+		start := offset
+		if start == ipsEOFOffset {
+			start--
+		}
+		end := offset
+		for end < len(modified) && end-start < ipsMaxRecordSize && original[end] != modified[end] {
+			end++
+		}
+		patch = append(patch, byte(start>>16), byte(start>>8), byte(start))
+		patch = append(patch, byte((end-start)>>8), byte(end-start))
+		patch = append(patch, modified[start:end]...)
+		offset = end
+	}
+	return append(patch, ipsEOF...), nil
+}
+
+// ApplyIPS originalにIPSのデータを当てたコピー、originalより後ろへのレコードはデータを伸ばす
+func ApplyIPS(original, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, ipsMagic) {
+		return nil, fmt.Errorf("%w. IPS magic is missing", ErrInvalidPatch)
+	}
+	data := append([]byte(nil), original...)
+	for offset := len(ipsMagic); ; {
+		if bytes.Equal(patch[offset:min(offset+len(ipsEOF), len(patch))], ipsEOF) {
+			return truncateIPS(data, patch[offset+len(ipsEOF):]), nil
+		}
+		if offset+5 > len(patch) {
+			return nil, fmt.Errorf("%w. IPS record is truncated. offset: %d", ErrInvalidPatch, offset)
+		}
+		target := int(patch[offset])<<16 | int(patch[offset+1])<<8 | int(patch[offset+2])
+		size := int(patch[offset+3])<<8 | int(patch[offset+4])
+		offset += 5
+
+		var record []byte
+		if size == 0 {
+			// RLE
+			if offset+3 > len(patch) {
+				return nil, fmt.Errorf("%w. IPS RLE record is truncated. offset: %d", ErrInvalidPatch, offset)
+			}
+			size = int(patch[offset])<<8 | int(patch[offset+1])
+			record = bytes.Repeat(patch[offset+2:offset+3], size)
+			offset += 3
+		} else {
+			if offset+size > len(patch) {
+				return nil, fmt.Errorf("%w. IPS record is truncated. offset: %d", ErrInvalidPatch, offset)
+			}
+			record = patch[offset : offset+size]
+			offset += size
+		}
+
+		if target+len(record) > len(data) {
+			data = append(data, make([]byte, target+len(record)-len(data))...)
+		}
+		copy(data[target:], record)
+	}
+}
+
+func truncateIPS(data, tail []byte) []byte {
+	if len(tail) != 3 {
+		return data
+	}
+	size := int(tail[0])<<16 | int(tail[1])<<8 | int(tail[2])
+	if size < len(data) {
+		return data[:size]
+	}
+	return data
+}
@@ -0,0 +1,89 @@
+package patch
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Format 先頭のマジックナンバーで判定したパッチの形式
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatIPS
+	FormatUPS
+	FormatBPS
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatIPS:
+		return "IPS"
+	case FormatUPS:
+		return "UPS"
+	case FormatBPS:
+		return "BPS"
+	}
+	return "unknown"
+}
+
+// DetectFormat 拡張子ではなく中身で判定する
+func DetectFormat(patch []byte) Format {
+	switch {
+	case bytes.HasPrefix(patch, ipsMagic):
+		return FormatIPS
+	case bytes.HasPrefix(patch, upsMagic):
+		return FormatUPS
+	case bytes.HasPrefix(patch, bpsMagic):
+		return FormatBPS
+	}
+	return FormatUnknown
+}
+
+// Apply 形式を判定してdataにパッチを当てたコピー、UPS, BPSはCRC32も検証する
+func Apply(data, patch []byte) ([]byte, error) {
+	switch DetectFormat(patch) {
+	case FormatIPS:
+		return ApplyIPS(data, patch)
+	case FormatUPS:
+		return ApplyUPS(data, patch)
+	case FormatBPS:
+		return ApplyBPS(data, patch)
+	}
+	return nil, fmt.Errorf("%w. magic: %q", ErrUnknownFormat, patch[:min(len(patch), 5)])
+}
+
+// readVarint UPS, BPSの可変長の数値、7bitずつ下位から並び、最後のbyteはbit7が1
+// 同じ値の表現が1つになるように、続きがある場合は1を足してある
+func readVarint(data []byte, offset int) (uint64, int, error) {
+	var value uint64
+	shift := uint64(1)
+	for {
+		if offset >= len(data) {
+			return 0, 0, fmt.Errorf("%w. number is truncated", ErrInvalidPatch)
+		}
+		if shift > 1<<56 {
+			return 0, 0, fmt.Errorf("%w. number is too large. offset: %d", ErrInvalidPatch, offset)
+		}
+		b := data[offset]
+		offset++
+		value += uint64(b&0x7F) * shift
+		if b&0x80 != 0 {
+			return value, offset, nil
+		}
+		shift <<= 7
+		value += shift
+	}
+}
+
+func appendVarint(data []byte, value uint64) []byte {
+	for {
+		b := byte(value & 0x7F)
+		value >>= 7
+		if value == 0 {
+			return append(data, b|0x80)
+		}
+		data = append(data, b)
+		value--
+	}
+}
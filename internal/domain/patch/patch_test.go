@@ -0,0 +1,154 @@
+package patch_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/patch"
+)
+
+// withFooter UPS, BPSの最後に元のデータ、当てた後のデータ、パッチのCRC32を付ける
+func withFooter(body, original, modified []byte) []byte {
+	data := append([]byte(nil), body...)
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(original))
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(modified))
+	return binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestIPS$ github.com/sunjin110/nes_emu/internal/domain/patch
+func TestIPS(t *testing.T) {
+	Convey("TestIPS", t, func() {
+		Convey("変更した部分だけのレコードを作り、元のデータに当てると変更後のデータになる", func() {
+			original := make([]byte, 0x100)
+			modified := append([]byte(nil), original...)
+			modified[0x10] = 1
+			modified[0x11] = 2
+			modified[0xF0] = 3
+
+			data, err := patch.CreateIPS(original, modified)
+			So(err, ShouldBeNil)
+			So(data, ShouldResemble, []byte("PATCH\x00\x00\x10\x00\x02\x01\x02\x00\x00\xF0\x00\x01\x03EOF"))
+
+			got, err := patch.ApplyIPS(original, data)
+			So(err, ShouldBeNil)
+			So(got, ShouldResemble, modified)
+			So(original[0x10], ShouldEqual, 0)
+		})
+
+		Convey("RLEのレコードと、元のデータより後ろへのレコード", func() {
+			data := []byte("PATCH\x00\x00\x01\x00\x00\x00\x03\x7F\x00\x00\x05\x00\x01\x09EOF")
+			got, err := patch.ApplyIPS([]byte{0, 0}, data)
+			So(err, ShouldBeNil)
+			So(got, ShouldResemble, []byte{0, 0x7F, 0x7F, 0x7F, 0, 0x09})
+		})
+
+		Convey("EOFの後の3byteでデータを切り詰める", func() {
+			got, err := patch.ApplyIPS([]byte{1, 2, 3, 4}, []byte("PATCH\x00\x00\x00\x00\x01\x09EOF\x00\x00\x02"))
+			So(err, ShouldBeNil)
+			So(got, ShouldResemble, []byte{9, 2})
+		})
+
+		Convey("不正なデータはErrInvalidPatch", func() {
+			_, err := patch.ApplyIPS(nil, []byte("PATCH\x00\x00"))
+			So(errors.Is(err, patch.ErrInvalidPatch), ShouldBeTrue)
+			_, err = patch.ApplyIPS(nil, []byte("IPS"))
+			So(errors.Is(err, patch.ErrInvalidPatch), ShouldBeTrue)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestUPS$ github.com/sunjin110/nes_emu/internal/domain/patch
+func TestUPS(t *testing.T) {
+	Convey("TestUPS", t, func() {
+		original := []byte("ABCDEFGH")
+		modified := []byte("ABXDEFGHIJ")
+		// 2byte進めて'C'を'X'にし、終端の0で1byte進む、4byte進めて"IJ"を足す
+		body := append([]byte("UPS1\x88\x8A\x82"), 'C'^'X', 0x00, 0x84, 'I', 'J', 0x00)
+
+		Convey("XORのレコードを当てて、サイズも変える", func() {
+			got, err := patch.Apply(original, withFooter(body, original, modified))
+			So(err, ShouldBeNil)
+			So(got, ShouldResemble, modified)
+		})
+
+		Convey("元のデータのCRC32が違う場合はErrChecksumMismatch", func() {
+			_, err := patch.Apply([]byte("abcdefgh"), withFooter(body, original, modified))
+			So(errors.Is(err, patch.ErrChecksumMismatch), ShouldBeTrue)
+		})
+
+		Convey("パッチが壊れている場合はErrChecksumMismatch", func() {
+			data := withFooter(body, original, modified)
+			data[7] ^= 0xFF
+			_, err := patch.Apply(original, data)
+			So(errors.Is(err, patch.ErrChecksumMismatch), ShouldBeTrue)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestBPS$ github.com/sunjin110/nes_emu/internal/domain/patch
+func TestBPS(t *testing.T) {
+	Convey("TestBPS", t, func() {
+		Convey("4種類のコマンドで当てた後のデータを作る", func() {
+			original := []byte("ABCDEFGH")
+			modified := []byte("ABCDxxxxxEF")
+			body := []byte("BPS1\x88\x8B\x80")
+			// SourceRead 4byte, TargetRead 'x', TargetCopy 4byte(+4), SourceCopy 2byte(+4)
+			body = append(body, 0x8C, 0x81, 'x', 0x8F, 0x88, 0x86, 0x88)
+
+			got, err := patch.Apply(original, withFooter(body, original, modified))
+			So(err, ShouldBeNil)
+			So(got, ShouldResemble, modified)
+
+			_, err = patch.Apply([]byte("ABCDEFGX"), withFooter(body, original, modified))
+			So(errors.Is(err, patch.ErrChecksumMismatch), ShouldBeTrue)
+		})
+
+		Convey("CreateBPSで作ったパッチを当てると変更後のデータになる", func() {
+			original := make([]byte, 0x8010)
+			for i := range original {
+				original[i] = byte(i * 7)
+			}
+			tests := map[string][]byte{
+				"同じ":      append([]byte(nil), original...),
+				"書き換え":    append(append(append([]byte(nil), original[:0x100]...), "TRANSLATED"...), original[0x10A:]...),
+				"伸ばす":     append(append([]byte(nil), original...), bytes.Repeat([]byte{0xFF}, 0x4000)...),
+				"縮める":     original[:0x4010],
+				"空にする":    {},
+				"1byte違い": append(append(append([]byte(nil), original[:5]...), 0x00), original[6:]...),
+			}
+			for name, modified := range tests {
+				Convey(name, func() {
+					data, err := patch.CreateBPS(original, modified)
+					So(err, ShouldBeNil)
+					So(patch.DetectFormat(data), ShouldEqual, patch.FormatBPS)
+					got, err := patch.ApplyBPS(original, data)
+					So(err, ShouldBeNil)
+					So(bytes.Equal(got, modified), ShouldBeTrue)
+				})
+			}
+		})
+
+		Convey("書き換えた部分だけがパッチに入る", func() {
+			original := make([]byte, 0x8000)
+			modified := append([]byte(nil), original...)
+			copy(modified[0x1000:], "HELLO")
+			data, err := patch.CreateBPS(original, modified)
+			So(err, ShouldBeNil)
+			So(len(data), ShouldBeLessThan, 40)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestApply$ github.com/sunjin110/nes_emu/internal/domain/patch
+func TestApply(t *testing.T) {
+	Convey("TestApply", t, func() {
+		So(patch.DetectFormat([]byte("PATCHEOF")), ShouldEqual, patch.FormatIPS)
+		So(patch.DetectFormat([]byte("UPS1")), ShouldEqual, patch.FormatUPS)
+		_, err := patch.Apply([]byte{0}, []byte("NES\x1A"))
+		So(errors.Is(err, patch.ErrUnknownFormat), ShouldBeTrue)
+	})
+}
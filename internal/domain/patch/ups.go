@@ -0,0 +1,93 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// doc: https://www.romhacking.net/documents/392/
+//
+// "UPS1"、元のサイズ、当てた後のサイズ(可変長)の後に、
+// 前のレコードからの距離(可変長)と、0で終わるXORのデータのレコードが続く
+// 最後の12byteは元のデータ、当てた後のデータ、パッチ(最後の4byte以外)のCRC32(リトルエンディアン)
+
+var upsMagic = []byte("UPS1")
+
+const (
+	// footerSize UPS, BPSの最後にある3つのCRC32
+	footerSize = 12
+	// maxTargetSize 壊れたパッチで巨大なメモリを確保しないための上限
+	maxTargetSize = 64 << 20
+)
+
+// ApplyUPS originalにUPSのデータを当てたコピー
+func ApplyUPS(original, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, upsMagic) {
+		return nil, fmt.Errorf("%w. UPS magic is missing", ErrInvalidPatch)
+	}
+	body, err := verifyFooter(FormatUPS, original, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := len(upsMagic)
+	sourceSize, offset, err := readVarint(body, offset)
+	if err != nil {
+		return nil, err
+	}
+	targetSize, offset, err := readVarint(body, offset)
+	if err != nil {
+		return nil, err
+	}
+	if sourceSize != uint64(len(original)) || targetSize > maxTargetSize {
+		return nil, fmt.Errorf("%w. UPS size mismatch. source: %d, target: %d, original: %d", ErrInvalidPatch, sourceSize, targetSize, len(original))
+	}
+
+	target := make([]byte, targetSize)
+	copy(target, original)
+	position := uint64(0)
+	for offset < len(body) {
+		var skip uint64
+		if skip, offset, err = readVarint(body, offset); err != nil {
+			return nil, err
+		}
+		position += skip
+		for {
+			if offset >= len(body) {
+				return nil, fmt.Errorf("%w. UPS record is truncated. offset: %d", ErrInvalidPatch, offset)
+			}
+			value := body[offset]
+			offset++
+			if position < targetSize {
+				target[position] ^= value
+			}
+			position++
+			if value == 0 {
+				break
+			}
+		}
+	}
+
+	if crc := crc32.ChecksumIEEE(target); crc != binary.LittleEndian.Uint32(patch[len(patch)-8:]) {
+		return nil, fmt.Errorf("%w. UPS target crc32: %08X", ErrChecksumMismatch, crc)
+	}
+	return target, nil
+}
+
+// verifyFooter パッチと元のデータのCRC32を確かめて、最後の12byteを除いたデータを返す
+func verifyFooter(format Format, original, patch []byte) ([]byte, error) {
+	// 4byteのマジックナンバーと3つのサイズ
+	if len(patch) < 4+3+footerSize {
+		return nil, fmt.Errorf("%w. %s patch is too short. size: %d", ErrInvalidPatch, format, len(patch))
+	}
+	footer := patch[len(patch)-footerSize:]
+	if crc := crc32.ChecksumIEEE(patch[:len(patch)-4]); crc != binary.LittleEndian.Uint32(footer[8:]) {
+		return nil, fmt.Errorf("%w. %s patch is corrupted. crc32: %08X", ErrChecksumMismatch, format, crc)
+	}
+	if crc := crc32.ChecksumIEEE(original); crc != binary.LittleEndian.Uint32(footer[0:]) {
+		return nil, fmt.Errorf("%w. %s patch is for another ROM. crc32: %08X, expected: %08X", ErrChecksumMismatch, format, crc, binary.LittleEndian.Uint32(footer[0:]))
+	}
+	return patch[:len(patch)-footerSize], nil
+}
@@ -0,0 +1,123 @@
+package ppu
+
+// Cartridge PPUから見たカートリッジ(CHR-ROM/CHR-RAMとネームテーブルのミラーリング)
+type Cartridge interface {
+	// ReadCHR 0x0000-0x1fffの読み込み
+	ReadCHR(addr uint16) byte
+	// WriteCHR 0x0000-0x1fffの書き込み、CHR-ROMの場合は無視する
+	WriteCHR(addr uint16, value byte)
+	Mirroring() Mirroring
+}
+
+// Mirroring ネームテーブルのミラーリング
+// doc: https://www.nesdev.org/wiki/Mirroring#Nametable_Mirroring
+type Mirroring int
+
+const (
+	MirroringHorizontal        Mirroring = iota // 0x2000と0x2400, 0x2800と0x2c00が同じ(縦スクロール用)
+	MirroringVertical                           // 0x2000と0x2800, 0x2400と0x2c00が同じ(横スクロール用)
+	MirroringSingleScreenLower                  // 全て本体VRAMのページ0
+	MirroringSingleScreenUpper                  // 全て本体VRAMのページ1
+	MirroringFourScreen                         // カートリッジのVRAMを使って4画面
+)
+
+func (m Mirroring) String() string {
+	switch m {
+	case MirroringHorizontal:
+		return "horizontal"
+	case MirroringVertical:
+		return "vertical"
+	case MirroringSingleScreenLower:
+		return "single-screen-lower"
+	case MirroringSingleScreenUpper:
+		return "single-screen-upper"
+	case MirroringFourScreen:
+		return "four-screen"
+	}
+	return "unknown"
+}
+
+// BusObserver PPUのアドレスバスを監視するカートリッジ(MMC3のA12によるIRQなど)
+type BusObserver interface {
+	// PPUAddress PPUが読み書きしたアドレス(0x0000-0x3EFF)
+	PPUAddress(addr uint16)
+}
+
+// ScanlineObserver 描画中のscanlineを数えるカートリッジ
+type ScanlineObserver interface {
+	// Scanline 描画が有効な時に、描画するscanlineとpre-renderのdot 260で呼ばれる
+	Scanline()
+}
+
+// NametableMapper ネームテーブルの割り当てをMirroringより細かく切り替えたり、カートリッジのメモリを使うカートリッジ(MMC5など)
+type NametableMapper interface {
+	// NametablePage ネームテーブル(0-3)に割り当てる本体VRAMのページ(0, 1)
+	NametablePage(table int) int
+	// ReadNametable 本体のVRAMの代わりにカートリッジから読み込む場合はokがtrue
+	ReadNametable(addr uint16) (value byte, ok bool)
+	// WriteNametable 本体のVRAMの代わりにカートリッジに書き込んだ場合はtrue
+	WriteNametable(addr uint16, value byte) bool
+}
+
+// RegisterObserver CPUからPPUのレジスタへの書き込みを監視するカートリッジ(MMC5はPPUCTRLのスプライトのサイズを見る)
+type RegisterObserver interface {
+	// PPURegister 0x2000-0x2007への書き込み
+	PPURegister(addr uint16, value byte)
+}
+
+// nametablePages ミラーリングごとのネームテーブル(0-3)からVRAMのページへの割り当て
+var nametablePages = [...][4]int{
+	MirroringHorizontal:        {0, 0, 1, 1},
+	MirroringVertical:          {0, 1, 0, 1},
+	MirroringSingleScreenLower: {0, 0, 0, 0},
+	MirroringSingleScreenUpper: {1, 1, 1, 1},
+	MirroringFourScreen:        {0, 1, 2, 3},
+}
+
+// cartridgeBus ppu/internal/memoryからカートリッジを参照するためのアダプター
+type cartridgeBus struct {
+	Cartridge
+	observer  BusObserver
+	nametable NametableMapper
+}
+
+func newCartridgeBus(cartridge Cartridge) *cartridgeBus {
+	observer, _ := cartridge.(BusObserver)
+	nametable, _ := cartridge.(NametableMapper)
+	return &cartridgeBus{
+		Cartridge: cartridge,
+		observer:  observer,
+		nametable: nametable,
+	}
+}
+
+func (c *cartridgeBus) NametablePage(table int) int {
+	if c.nametable != nil {
+		return c.nametable.NametablePage(table)
+	}
+	mirroring := c.Mirroring()
+	if mirroring < 0 || int(mirroring) >= len(nametablePages) {
+		mirroring = MirroringHorizontal
+	}
+	return nametablePages[mirroring][table&0x03]
+}
+
+func (c *cartridgeBus) PPUAddress(addr uint16) {
+	if c.observer != nil {
+		c.observer.PPUAddress(addr)
+	}
+}
+
+func (c *cartridgeBus) ReadNametable(addr uint16) (byte, bool) {
+	if c.nametable == nil {
+		return 0, false
+	}
+	return c.nametable.ReadNametable(addr)
+}
+
+func (c *cartridgeBus) WriteNametable(addr uint16, value byte) bool {
+	if c.nametable == nil {
+		return false
+	}
+	return c.nametable.WriteNametable(addr, value)
+}
@@ -9,206 +9,130 @@ type Memory interface {
 	Write(addr uint16, value byte) error
 }
 
-func NewMemory(chrROM []byte) (Memory, error) {
-	if len(chrROM) != 0x2000 { // 8KBではない場合はエラー
-		return nil, fmt.Errorf("CHR-ROM must be 8KB. chrROM size: %x", len(chrROM))
-	}
-
-	var data0 [4096]byte
-	var data1 [4096]byte
-
-	copy(data0[:], chrROM[:0x1000])
-	copy(data1[:], chrROM[0x1000:0x2000])
+// Cartridge PPUのアドレス空間のうちカートリッジにつながっている部分
+type Cartridge interface {
+	// ReadCHR 0x0000-0x1fff
+	ReadCHR(addr uint16) byte
+	WriteCHR(addr uint16, value byte)
+	// NametablePage ネームテーブル(0-3)を本体のVRAMのどのページ(0-3)に割り当てるか
+	// 本体のVRAMは2KB(ページ0, 1)で、ページ2, 3は4画面ミラーリングのカートリッジのVRAM
+	NametablePage(table int) int
+	// ReadNametable 本体のVRAMの代わりにカートリッジから読み込む場合はokがtrue(MMC5のExRAMやfill modeなど)
+	ReadNametable(addr uint16) (value byte, ok bool)
+	// WriteNametable 本体のVRAMの代わりにカートリッジに書き込んだ場合はtrue
+	WriteNametable(addr uint16, value byte) bool
+	// PPUAddress 読み書きしたアドレスをカートリッジに通知する(0x0000-0x3EFF)
+	PPUAddress(addr uint16)
+}
 
+func NewMemory(cartridge Cartridge) Memory {
 	return &memory{
-		patternTable0: patternTable{
-			data: data0,
-		},
-		patternTable1: patternTable{
-			data: data1,
-		},
-	}, nil
+		cartridge: cartridge,
+	}
 }
 
-// TODO PPUの0x0000-0x1fffはCHRになる、ここでCHR-RAMとCHR-ROMがあるのでこれは、カートリッジの参照を直接持っちゃったほうがいいかもしれない
-// TODO PPU CHR-ROMのバンク切り替え
-
 // PPUのメモリ構成を考える
 type memory struct {
-	patternTable0   patternTable   // 0x0000-0x0fff
-	patternTable1   patternTable   // 01000-0x1fff
-	nametable0      nametable      // 02000-0x23bf
-	attributeTable0 attributeTable // 0x23c0-0x23ff
-	nametable1      nametable      // 0x2400-0x27bf
-	attributeTable1 attributeTable // 0x27c0-0x27ff
-	nametable2      nametable      // 0x2800-0x2bbf
-	attributeTable2 attributeTable // 0x2bc0-0x2bff
-	nametable3      nametable      // 0x2c00-0x2fbf
-	attributeTable3 attributeTable // 0x2fc0-0x2fff
+	cartridge Cartridge // 0x0000-0x1fff: patternTable0, patternTable1 (CHR-ROM / CHR-RAM)
+
+	// 0x2000-0x2fff: nametable0-3 (それぞれ最後の64byteはattribute table)
+	// カートリッジのミラーリングの設定でvramのページに割り当てる
 	// 0x3000 - 0x3eff mirror of 0x2000-0x2eff
-	backgroundPallet backgroundPallet // 0x3f00-0x3f0f
-	splitePallet     splitePallet     // 0x3f10-0x3f1f
+	vram [4][nametableSize]byte
+
+	// 0x3f00-0x3f0f: backgroundPallet, 0x3f10-0x3f1f: splitePallet
 	// 0x3f20-0x3fff mirror of 0x3f00-0x3f1f
+	pallet [0x20]byte
 }
 
+const (
+	nametableSize = 0x400
+	addrPallet    = 0x3f00
+)
+
 func (m *memory) Read(addr uint16) (byte, error) {
-	switch {
-	case addr <= 0x0fff:
-		// 0x0000-0x0fff : patternTable0
-		return m.patternTable0.data[addr], nil
+	if addr < addrPallet {
+		m.cartridge.PPUAddress(addr)
+	}
+	return m.read(addr)
+}
 
+func (m *memory) read(addr uint16) (byte, error) {
+	switch {
 	case addr <= 0x1fff:
-		// 0x1000-0x1fff : patternTable1
-		return m.patternTable1.data[addr-0x1000], nil
-
-	case addr <= 0x23bf:
-		// 0x2000-0x23bf : nametable0
-		return m.nametable0.data[addr-0x2000], nil
-
-	case addr <= 0x23ff:
-		// 0x23c0-0x23ff : attributeTable0
-		return m.attributeTable0.data[addr-0x23c0], nil
-
-	case addr <= 0x27bf:
-		// 0x2400-0x27bf : nametable1
-		return m.nametable1.data[addr-0x2400], nil
-
-	case addr <= 0x27ff:
-		// 0x27c0-0x27ff : attributeTable1
-		return m.attributeTable1.data[addr-0x27c0], nil
-
-	case addr <= 0x2bbf:
-		// 0x2800-0x2bbf : nametable2
-		return m.nametable2.data[addr-0x2800], nil
-
-	case addr <= 0x2bff:
-		// 0x2bc0-0x2bff : attributeTable2
-		return m.attributeTable2.data[addr-0x2bc0], nil
-
-	case addr <= 0x2fbf:
-		// 0x2c00-0x2fbf : nametable3
-		return m.nametable3.data[addr-0x2c00], nil
+		// 0x0000-0x1fff : patternTable0, patternTable1
+		return m.cartridge.ReadCHR(addr), nil
 
 	case addr <= 0x2fff:
-		// 0x2fc0-0x2fff : attributeTable3
-		return m.attributeTable3.data[addr-0x2fc0], nil
+		// 0x2000-0x2fff : nametable0-3
+		if value, ok := m.cartridge.ReadNametable(addr); ok {
+			return value, nil
+		}
+		page, offset := m.nametable(addr)
+		return m.vram[page][offset], nil
 
 	case addr <= 0x3eff:
 		// 0x3000-0x3eff : mirror of 0x2000-0x2eff
 		// 0x3000 ～ 0x3eff は 0x2000 ～ 0x2eff のミラー領域なので
 		// 0x1000 引いたアドレスでもう一度 Read() を呼ぶ
-		return m.Read(addr - 0x1000)
-
-	case addr <= 0x3f0f:
-		// 0x3f00-0x3f0f : backgroundPallet
-		return m.backgroundPallet.data[addr-0x3f00], nil
-
-	case addr <= 0x3f1f:
-		// 0x3f10-0x3f1f : splitePallet
-		return m.splitePallet.data[addr-0x3f10], nil
+		return m.read(addr - 0x1000)
 
 	case addr <= 0x3fff:
-		// 0x3f20-0x3fff : mirror of 0x3f00-0x3f1f
-		// 下位 5bit (0x1f) をマスクして 0x3f00 に加算すれば実アドレスが得られる
-		return m.Read(0x3f00 + (addr & 0x1f))
+		// 0x3f00-0x3fff : pallet, 0x3f20-0x3fffは0x3f00-0x3f1fのミラー
+		return m.pallet[palletIndex(addr)], nil
 	default:
 		return 0, fmt.Errorf("invalid addr: %x", addr)
 	}
 }
 
 func (m *memory) Write(addr uint16, value byte) error {
-	switch {
-	case addr <= 0x0fff:
-		// 0x0000-0x0fff: patternTable0
-		m.patternTable0.data[addr] = value
-		return nil
+	if addr < addrPallet {
+		m.cartridge.PPUAddress(addr)
+	}
+	return m.write(addr, value)
+}
 
+func (m *memory) write(addr uint16, value byte) error {
+	switch {
 	case addr <= 0x1fff:
-		// 0x1000-0x1fff: patternTable1
-		m.patternTable1.data[addr-0x1000] = value
-		return nil
-
-	case addr <= 0x23bf:
-		// 0x2000-0x23bf: nametable0
-		m.nametable0.data[addr-0x2000] = value
-		return nil
-
-	case addr <= 0x23ff:
-		// 0x23c0-0x23ff: attributeTable0
-		m.attributeTable0.data[addr-0x23c0] = value
-		return nil
-
-	case addr <= 0x27bf:
-		// 0x2400-0x27bf: nametable1
-		m.nametable1.data[addr-0x2400] = value
-		return nil
-
-	case addr <= 0x27ff:
-		// 0x27c0-0x27ff: attributeTable1
-		m.attributeTable1.data[addr-0x27c0] = value
-		return nil
-
-	case addr <= 0x2bbf:
-		// 0x2800-0x2bbf: nametable2
-		m.nametable2.data[addr-0x2800] = value
-		return nil
-
-	case addr <= 0x2bff:
-		// 0x2bc0-0x2bff: attributeTable2
-		m.attributeTable2.data[addr-0x2bc0] = value
-		return nil
-
-	case addr <= 0x2fbf:
-		// 0x2c00-0x2fbf: nametable3
-		m.nametable3.data[addr-0x2c00] = value
+		// 0x0000-0x1fff: patternTable0, patternTable1 (CHR-ROMの場合はカートリッジ側で無視される)
+		m.cartridge.WriteCHR(addr, value)
 		return nil
 
 	case addr <= 0x2fff:
-		// 0x2fc0-0x2fff: attributeTable3
-		m.attributeTable3.data[addr-0x2fc0] = value
+		// 0x2000-0x2fff: nametable0-3
+		if m.cartridge.WriteNametable(addr, value) {
+			return nil
+		}
+		page, offset := m.nametable(addr)
+		m.vram[page][offset] = value
 		return nil
 
 	case addr <= 0x3eff:
 		// 0x3000-0x3eff: mirror of 0x2000-0x2eff
 		// ミラー先に書き込む
-		return m.Write(addr-0x1000, value)
-
-	case addr <= 0x3f0f:
-		// 0x3f00-0x3f0f: backgroundPallet
-		m.backgroundPallet.data[addr-0x3f00] = value
-		return nil
-
-	case addr <= 0x3f1f:
-		// 0x3f10-0x3f1f: splitePallet
-		m.splitePallet.data[addr-0x3f10] = value
-		return nil
+		return m.write(addr-0x1000, value)
 
 	case addr <= 0x3fff:
-		// 0x3f20-0x3fff: mirror of 0x3f00-0x3f1f
-		// 下位5ビットをマスクして 0x3f00 に加算 → そこへ書き込む
-		return m.Write(0x3f00+(addr&0x1f), value)
+		// 0x3f00-0x3fff: pallet
+		m.pallet[palletIndex(addr)] = value & 0x3f
+		return nil
 
 	default:
 		return fmt.Errorf("invalid addr: %x", addr)
 	}
 }
 
-type patternTable struct {
-	data [0x1000]byte
+func (m *memory) nametable(addr uint16) (page int, offset uint16) {
+	table := int(addr-0x2000) / nametableSize
+	return m.cartridge.NametablePage(table) & 0x03, (addr - 0x2000) % nametableSize
 }
 
-type nametable struct {
-	data [0x03c]byte
-}
-
-type attributeTable struct {
-	data [64]byte
-}
-
-type backgroundPallet struct {
-	data [0x10]byte
-}
-
-type splitePallet struct {
-	data [0x10]byte
+// palletIndex 0x3f10, 0x3f14, 0x3f18, 0x3f1cは0x3f00, 0x3f04, 0x3f08, 0x3f0cのミラー
+func palletIndex(addr uint16) uint16 {
+	index := addr & 0x1f
+	if index >= 0x10 && index%4 == 0 {
+		index -= 0x10
+	}
+	return index
 }
@@ -19,6 +19,10 @@ type Register interface {
 	GetFindX() byte
 	GetW() wData
 
+	// PPUDATA の読み書き用
+	GetVRAMAddr() uint16
+	IncrementVRAMAddr(step uint16)
+
 	// 描画中のインクリメント
 	IncrementCoarseX()
 	IncrementY()
@@ -51,6 +55,10 @@ func NewRegister() Register {
 }
 
 // register ppuのための内部register
+// doc: https://www.nesdev.org/wiki/PPU_scrolling
+//
+// v, t は 0yyy NNYY YYYX XXXX の15bit
+// yyy: fine Y scroll, NN: nametable select, YYYYY: coarse Y scroll, XXXXX: coarse X scroll
 type register struct {
 	v uint16 // 現在参照するVRAMのアドレス 15bit
 	t uint16 // temporary VRAM アドレス(v に書き込む値を構築するために使用) 15bit
@@ -58,102 +66,149 @@ type register struct {
 	w bool   // PPUSCROLL, PPUADDR の書き込みが1回目なのか2回目なのかを判定するためのフラグ
 }
 
-// GetAttributeAddress implements Register.
+const (
+	coarseXMask         uint16 = 0x001F
+	coarseYMask         uint16 = 0x03E0
+	nametableSelectMask uint16 = 0x0C00
+	fineYMask           uint16 = 0x7000
+
+	coarseYShift         = 5
+	nametableSelectShift = 10
+	fineYShift           = 12
+)
+
+func (r *register) target(target registerTarget) *uint16 {
+	if target == TargetT {
+		return &r.t
+	}
+	return &r.v
+}
+
+func (r *register) set(target registerTarget, mask uint16, shift int, data byte) {
+	value := r.target(target)
+	*value = (*value &^ mask) | ((uint16(data) << shift) & mask)
+}
+
+func (r *register) get(target registerTarget, mask uint16, shift int) byte {
+	return byte((*r.target(target) & mask) >> shift)
+}
+
+// GetAttributeAddress 現在のタイルのattribute tableのアドレス
 func (r *register) GetAttributeAddress() uint16 {
-	panic("unimplemented")
+	return 0x23C0 | (r.v & nametableSelectMask) | ((r.v >> 4) & 0x38) | ((r.v >> 2) & 0x07)
 }
 
-// GetCoarseX implements Register.
 func (r *register) GetCoarseX(target registerTarget) byte {
-	panic("unimplemented")
+	return r.get(target, coarseXMask, 0)
 }
 
-// GetCoarseY implements Register.
 func (r *register) GetCoarseY(target registerTarget) byte {
-	panic("unimplemented")
+	return r.get(target, coarseYMask, coarseYShift)
 }
 
-// GetFindX implements Register.
 func (r *register) GetFindX() byte {
-	panic("unimplemented")
+	return r.x
 }
 
-// GetFineY implements Register.
 func (r *register) GetFineY(target registerTarget) byte {
-	panic("unimplemented")
+	return r.get(target, fineYMask, fineYShift)
 }
 
-// GetNametableSelect implements Register.
 func (r *register) GetNametableSelect(target registerTarget) byte {
-	panic("unimplemented")
+	return r.get(target, nametableSelectMask, nametableSelectShift)
 }
 
-// GetTileAddress implements Register.
+// GetTileAddress 現在のタイルのnametableのアドレス
 func (r *register) GetTileAddress() uint16 {
-	panic("unimplemented")
+	return 0x2000 | (r.v & 0x0FFF)
 }
 
-// GetW implements Register.
 func (r *register) GetW() wData {
-	panic("unimplemented")
+	return wData(r.w)
+}
+
+// GetVRAMAddr PPUDATAで読み書きするアドレス
+func (r *register) GetVRAMAddr() uint16 {
+	return r.v & 0x3FFF
+}
+
+// IncrementVRAMAddr PPUDATAの読み書きの後にPPUCTRLのbit2に応じて1か32進める
+func (r *register) IncrementVRAMAddr(step uint16) {
+	r.v = (r.v + step) & 0x7FFF
 }
 
-// IncrementCoarseX implements Register.
+// IncrementCoarseX 次のタイルに進める、右端の場合は隣のnametableに切り替える
 func (r *register) IncrementCoarseX() {
-	panic("unimplemented")
+	if r.v&coarseXMask == 31 {
+		r.v &^= coarseXMask
+		r.v ^= 0x0400
+		return
+	}
+	r.v++
 }
 
-// IncrementY implements Register.
+// IncrementY 次の行に進める、fine Yが7の場合はcoarse Yを進め、30行目で隣のnametableに切り替える
 func (r *register) IncrementY() {
-	panic("unimplemented")
+	if r.v&fineYMask != fineYMask {
+		r.v += 0x1000
+		return
+	}
+	r.v &^= fineYMask
+	y := (r.v & coarseYMask) >> coarseYShift
+	switch y {
+	case 29:
+		y = 0
+		r.v ^= 0x0800
+	case 31:
+		// attribute tableの範囲を指している場合はnametableを切り替えずに0に戻る
+		y = 0
+	default:
+		y++
+	}
+	r.v = (r.v &^ coarseYMask) | (y << coarseYShift)
 }
 
-// SetCoarseX implements Register.
 func (r *register) SetCoarseX(target registerTarget, data byte) {
-	panic("unimplemented")
+	r.set(target, coarseXMask, 0, data)
 }
 
-// SetCoarseY implements Register.
 func (r *register) SetCoarseY(target registerTarget, data byte) {
-	panic("unimplemented")
+	r.set(target, coarseYMask, coarseYShift, data)
 }
 
-// SetFindX implements Register.
 func (r *register) SetFindX(data byte) {
-	panic("unimplemented")
+	r.x = data & 0x07
 }
 
-// SetFineY implements Register.
 func (r *register) SetFineY(target registerTarget, data byte) {
-	panic("unimplemented")
+	r.set(target, fineYMask, fineYShift, data)
 }
 
-// SetLowerPPUAddr implements Register.
+// SetLowerPPUAddr PPUADDRの2回目の書き込み、tの下位8bitを設定してvに反映する
 func (r *register) SetLowerPPUAddr(data byte) {
-	panic("unimplemented")
+	r.t = (r.t & 0xFF00) | uint16(data)
+	r.v = r.t
 }
 
-// SetNametableSelect implements Register.
 func (r *register) SetNametableSelect(target registerTarget, data byte) {
-	panic("unimplemented")
+	r.set(target, nametableSelectMask, nametableSelectShift, data)
 }
 
-// SetUpperPPUAddr implements Register.
+// SetUpperPPUAddr PPUADDRの1回目の書き込み、tの上位6bitを設定する(bit14は0になる)
 func (r *register) SetUpperPPUAddr(data byte) {
-	panic("unimplemented")
+	r.t = (r.t & 0x00FF) | (uint16(data&0x3F) << 8)
 }
 
-// SetW implements Register.
 func (r *register) SetW(data wData) {
-	panic("unimplemented")
+	r.w = bool(data)
 }
 
-// UpdateHorizontalV implements Register.
+// UpdateHorizontalV tのcoarse Xとnametable select(水平)をvに反映する
 func (r *register) UpdateHorizontalV() {
-	panic("unimplemented")
+	r.v = (r.v &^ 0x041F) | (r.t & 0x041F)
 }
 
-// UpdateVerticalV implements Register.
+// UpdateVerticalV tのfine Y, coarse Yとnametable select(垂直)をvに反映する
 func (r *register) UpdateVerticalV() {
-	panic("unimplemented")
+	r.v = (r.v &^ 0x7BE0) | (r.t & 0x7BE0)
 }
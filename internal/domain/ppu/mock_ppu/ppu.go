@@ -39,6 +39,120 @@ func (m *MockPPU) EXPECT() *MockPPUMockRecorder {
 	return m.recorder
 }
 
+// Brightness mocks base method.
+func (m *MockPPU) Brightness(x int, y int) float32 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Brightness", x, y)
+	ret0, _ := ret[0].(float32)
+	return ret0
+}
+
+// Brightness indicates an expected call of Brightness.
+func (mr *MockPPUMockRecorder) Brightness(x, y any) *MockPPUBrightnessCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Brightness", reflect.TypeOf((*MockPPU)(nil).Brightness), x, y)
+	return &MockPPUBrightnessCall{Call: call}
+}
+
+// MockPPUBrightnessCall wrap *gomock.Call
+type MockPPUBrightnessCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockPPUBrightnessCall) Return(arg0 float32) *MockPPUBrightnessCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockPPUBrightnessCall) Do(f func(int, int) float32) *MockPPUBrightnessCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockPPUBrightnessCall) DoAndReturn(f func(int, int) float32) *MockPPUBrightnessCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Frame mocks base method.
+func (m *MockPPU) Frame() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Frame")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// Frame indicates an expected call of Frame.
+func (mr *MockPPUMockRecorder) Frame() *MockPPUFrameCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Frame", reflect.TypeOf((*MockPPU)(nil).Frame))
+	return &MockPPUFrameCall{Call: call}
+}
+
+// MockPPUFrameCall wrap *gomock.Call
+type MockPPUFrameCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockPPUFrameCall) Return(arg0 uint64) *MockPPUFrameCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockPPUFrameCall) Do(f func() uint64) *MockPPUFrameCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockPPUFrameCall) DoAndReturn(f func() uint64) *MockPPUFrameCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// FrameBuffer mocks base method.
+func (m *MockPPU) FrameBuffer() []byte {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FrameBuffer")
+	ret0, _ := ret[0].([]byte)
+	return ret0
+}
+
+// FrameBuffer indicates an expected call of FrameBuffer.
+func (mr *MockPPUMockRecorder) FrameBuffer() *MockPPUFrameBufferCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FrameBuffer", reflect.TypeOf((*MockPPU)(nil).FrameBuffer))
+	return &MockPPUFrameBufferCall{Call: call}
+}
+
+// MockPPUFrameBufferCall wrap *gomock.Call
+type MockPPUFrameBufferCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockPPUFrameBufferCall) Return(arg0 []byte) *MockPPUFrameBufferCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockPPUFrameBufferCall) Do(f func() []byte) *MockPPUFrameBufferCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockPPUFrameBufferCall) DoAndReturn(f func() []byte) *MockPPUFrameBufferCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // IsPPU mocks base method.
 func (m *MockPPU) IsPPU() {
 	m.ctrl.T.Helper()
@@ -75,6 +189,83 @@ func (c *MockPPUIsPPUCall) DoAndReturn(f func()) *MockPPUIsPPUCall {
 	return c
 }
 
+// PollNMI mocks base method.
+func (m *MockPPU) PollNMI() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PollNMI")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// PollNMI indicates an expected call of PollNMI.
+func (mr *MockPPUMockRecorder) PollNMI() *MockPPUPollNMICall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PollNMI", reflect.TypeOf((*MockPPU)(nil).PollNMI))
+	return &MockPPUPollNMICall{Call: call}
+}
+
+// MockPPUPollNMICall wrap *gomock.Call
+type MockPPUPollNMICall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockPPUPollNMICall) Return(arg0 bool) *MockPPUPollNMICall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockPPUPollNMICall) Do(f func() bool) *MockPPUPollNMICall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockPPUPollNMICall) DoAndReturn(f func() bool) *MockPPUPollNMICall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Position mocks base method.
+func (m *MockPPU) Position() (int, int) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Position")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(int)
+	return ret0, ret1
+}
+
+// Position indicates an expected call of Position.
+func (mr *MockPPUMockRecorder) Position() *MockPPUPositionCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Position", reflect.TypeOf((*MockPPU)(nil).Position))
+	return &MockPPUPositionCall{Call: call}
+}
+
+// MockPPUPositionCall wrap *gomock.Call
+type MockPPUPositionCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockPPUPositionCall) Return(arg0 int, arg1 int) *MockPPUPositionCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockPPUPositionCall) Do(f func() (int, int)) *MockPPUPositionCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockPPUPositionCall) DoAndReturn(f func() (int, int)) *MockPPUPositionCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // Read mocks base method.
 func (m *MockPPU) Read(addr uint16) (byte, error) {
 	m.ctrl.T.Helper()
@@ -114,6 +305,80 @@ func (c *MockPPUReadCall) DoAndReturn(f func(uint16) (byte, error)) *MockPPURead
 	return c
 }
 
+// Reset mocks base method.
+func (m *MockPPU) Reset() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Reset")
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockPPUMockRecorder) Reset() *MockPPUResetCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockPPU)(nil).Reset))
+	return &MockPPUResetCall{Call: call}
+}
+
+// MockPPUResetCall wrap *gomock.Call
+type MockPPUResetCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockPPUResetCall) Return() *MockPPUResetCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockPPUResetCall) Do(f func()) *MockPPUResetCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockPPUResetCall) DoAndReturn(f func()) *MockPPUResetCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Step mocks base method.
+func (m *MockPPU) Step() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Step")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Step indicates an expected call of Step.
+func (mr *MockPPUMockRecorder) Step() *MockPPUStepCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Step", reflect.TypeOf((*MockPPU)(nil).Step))
+	return &MockPPUStepCall{Call: call}
+}
+
+// MockPPUStepCall wrap *gomock.Call
+type MockPPUStepCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockPPUStepCall) Return(arg0 error) *MockPPUStepCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockPPUStepCall) Do(f func() error) *MockPPUStepCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockPPUStepCall) DoAndReturn(f func() error) *MockPPUStepCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // Write mocks base method.
 func (m *MockPPU) Write(addr uint16, value byte) error {
 	m.ctrl.T.Helper()
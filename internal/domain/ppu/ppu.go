@@ -12,15 +12,75 @@ type PPU interface {
 	Read(addr uint16) (byte, error)
 	Write(addr uint16, value byte) error
 	IsPPU()
+
+	// Step PPUを1ドット進める(CPUの1サイクルで3ドット)
+	Step() error
+	// Reset リセットボタンや電源を入れ直した時の状態にする(VRAM, OAMとフレーム番号はそのまま)
+	Reset()
+	// PollNMI NMIが発生していればtrueを返してクリアする
+	PollNMI() bool
+	// Position 現在描画しているscanline(0-261)とdot(0-340)
+	Position() (scanline, dot int)
+	// Frame VBlankに入るたびに増えるフレーム番号
+	Frame() uint64
+	// FrameBuffer 256x240のパレットのインデックス(0x00-0x3f)
+	FrameBuffer() []byte
+	// Brightness 画面の(x, y)のピクセルの明るさ(0-1)
+	Brightness(x, y int) float32
 }
 
 type ppu struct {
 	internalRegister register.Register
 	memory           memory.Memory
+	bus              *cartridgeBus
+	scanlineObserver ScanlineObserver
+	registerObserver RegisterObserver
+
+	ctrl    byte // PPUCTRL
+	mask    byte // PPUMASK
+	status  byte // PPUSTATUS (bit7: vblank, bit6: sprite 0 hit, bit5: sprite overflow)
+	oamAddr byte
+	oam     [oamSize]byte
+
+	readBuffer byte // PPUDATAの読み込みは1回遅れて返る
+	openBus    byte // 書き込み専用レジスタを読み込んだ時は最後に書き込んだ値が返る
+
+	nmi bool
+
+	scanline int
+	dot      int
+	frame    uint64
+	oddFrame bool
+
+	// 背景のタイルのフェッチ
+	nametableByte byte
+	attributeByte byte
+	lowTileByte   byte
+	highTileByte  byte
+	tileData      uint64 // 2タイル分のピクセル(4bit: attribute 2bit + pattern 2bit)
+
+	// 次のscanlineに描画するスプライト(最大8個)
+	spriteCount      int
+	spritePatterns   [maxSprites]uint32
+	spritePositions  [maxSprites]byte
+	spritePriorities [maxSprites]byte
+	spriteIndexes    [maxSprites]byte
+	spriteLowByte    byte
+
+	frameBuffer [ScreenWidth * ScreenHeight]byte
 }
 
-func NewPPU() PPU {
-	return &ppu{}
+func NewPPU(cartridge Cartridge) PPU {
+	scanlineObserver, _ := cartridge.(ScanlineObserver)
+	registerObserver, _ := cartridge.(RegisterObserver)
+	bus := newCartridgeBus(cartridge)
+	return &ppu{
+		internalRegister: register.NewRegister(),
+		memory:           memory.NewMemory(bus),
+		bus:              bus,
+		scanlineObserver: scanlineObserver,
+		registerObserver: registerObserver,
+	}
 }
 
 const (
@@ -59,103 +119,194 @@ const (
 
 // Read CPUがreadする
 func (p *ppu) Read(addr uint16) (byte, error) {
+	// 0x2008-0x3fffは0x2000-0x2007のミラー
+	addr = addrPPUStart + (addr-addrPPUStart)%8
+
 	switch addr {
 	case ppuStatus:
-		value, err := p.readPPUStatus()
-		if err != nil {
-			return 0, fmt.Errorf("failed readPPUStatus. err: %w", err)
-		}
-		return value, nil
+		return p.readPPUStatus(), nil
+	case oamData:
+		return p.oam[p.oamAddr], nil
 	case ppuData:
 		value, err := p.readPPUData()
 		if err != nil {
-			return 0, fmt.Errorf("failed readPPUData. err: %w", err)
+			return 0, fmt.Errorf("PPU: failed readPPUData. err: %w", err)
 		}
 		return value, nil
 	}
 
-	return 0, fmt.Errorf("undefined ppu register read. addr: %x", addr)
+	// 書き込み専用のレジスタ
+	return p.openBus, nil
 }
 
 // Write CPUがwriteする
 func (p *ppu) Write(addr uint16, value byte) error {
+	addr = addrPPUStart + (addr-addrPPUStart)%8
+	p.openBus = value
+	if p.registerObserver != nil {
+		p.registerObserver.PPURegister(addr, value)
+	}
+
 	switch addr {
 	case ppuCTRL:
-		if err := p.writePPUCtrl(value); err != nil {
-			return fmt.Errorf("failed writePPUCtrl. err: %w", err)
-		}
+		p.writePPUCtrl(value)
 	case ppuMask:
-		if err := p.writePPUMask(value); err != nil {
-			return fmt.Errorf("failed writePPUMask. err: %w", err)
-		}
+		p.mask = value
 	case ppuStatus:
-		// 読み込み専用
-		return fmt.Errorf("PPU: Write: 0x2002は読み込み専用. addr: %x, value: %x", addr, value)
+		// 読み込み専用なので何もしない
 	case oamAddr:
-		if err := p.writeOAMADDR(value); err != nil {
-			return fmt.Errorf("failed writeOAMADDR. err: %w", err)
-		}
+		p.oamAddr = value
 	case oamData:
-		if err := p.writeOAMData(value); err != nil {
-			return fmt.Errorf("failed writeOAMData. err: %w", err)
-		}
+		p.oam[p.oamAddr] = value
+		p.oamAddr++
 	case ppuScroll:
-		if err := p.writePPUScroll(value); err != nil {
-			return fmt.Errorf("failed writePPUScroll. err: %w", err)
-		}
+		p.writePPUScroll(value)
 	case ppuAddr:
-		if err := p.writePPUADDR(value); err != nil {
-			return fmt.Errorf("failed writePPUADDR. err: %w", err)
-		}
+		p.writePPUADDR(value)
 	case ppuData:
 		if err := p.writePPUData(value); err != nil {
-			return fmt.Errorf("failed writePPUData. err: %w", err)
+			return fmt.Errorf("PPU: failed writePPUData. err: %w", err)
 		}
 	}
-	return fmt.Errorf("undefined ppu register addr: %x. value: %x", addr, value)
+	return nil
 }
 
 func (*ppu) IsPPU() {
 	// PPUのinterfaceのため
 }
 
-func (p *ppu) writePPUCtrl(value byte) error {
-	panic("todo")
+func (p *ppu) Reset() {
+	p.ctrl = 0
+	p.mask = 0
+	p.status = 0
+	p.readBuffer = 0
+	p.openBus = 0
+	p.nmi = false
+	p.scanline = 0
+	p.dot = 0
+	p.oddFrame = false
+	p.spriteCount = 0
+	p.internalRegister.SetW(register.WData0)
 }
 
-func (p *ppu) writePPUMask(value byte) error {
-	panic("todo")
+func (p *ppu) PollNMI() bool {
+	nmi := p.nmi
+	p.nmi = false
+	return nmi
 }
 
-func (p *ppu) writeOAMADDR(value byte) error {
-	panic("todo")
+func (p *ppu) Position() (scanline, dot int) {
+	return p.scanline, p.dot
 }
 
-func (p *ppu) writeOAMData(value byte) error {
-	panic("todo")
+func (p *ppu) Frame() uint64 {
+	return p.frame
 }
 
-func (p *ppu) writePPUScroll(value byte) error {
-	panic("todo")
+func (p *ppu) FrameBuffer() []byte {
+	return p.frameBuffer[:]
 }
 
-func (p *ppu) writePPUADDR(value byte) error {
-	panic("todo")
+func (p *ppu) Brightness(x, y int) float32 {
+	if x < 0 || x >= ScreenWidth || y < 0 || y >= ScreenHeight {
+		return 0
+	}
+	return brightness(p.frameBuffer[y*ScreenWidth+x])
+}
+
+// writePPUCtrl
+// bit0-1: nametable select, bit2: VRAMアドレスの増加量(0: 1, 1: 32), bit3: スプライトのパターンテーブル
+// bit4: 背景のパターンテーブル, bit5: スプライトのサイズ(0: 8x8, 1: 8x16), bit7: VBlankでNMIを発生させる
+func (p *ppu) writePPUCtrl(value byte) {
+	before := p.ctrl
+	p.ctrl = value
+	p.internalRegister.SetNametableSelect(register.TargetT, value&0x03)
+
+	// VBlank中にNMIを有効にした場合はその時点でNMIが発生する
+	if before&ctrlNMIEnable == 0 && value&ctrlNMIEnable != 0 && p.status&statusVBlank != 0 {
+		p.nmi = true
+	}
+}
+
+// writePPUScroll 1回目はX、2回目はYのスクロール値
+func (p *ppu) writePPUScroll(value byte) {
+	if p.internalRegister.GetW() == register.WData0 {
+		p.internalRegister.SetCoarseX(register.TargetT, value>>3)
+		p.internalRegister.SetFindX(value & 0x07)
+		p.internalRegister.SetW(register.WData1)
+		return
+	}
+	p.internalRegister.SetFineY(register.TargetT, value&0x07)
+	p.internalRegister.SetCoarseY(register.TargetT, value>>3)
+	p.internalRegister.SetW(register.WData0)
+}
+
+// writePPUADDR 1回目は上位、2回目は下位のアドレス
+// 2回目の書き込みでvがアドレスバスに出るので、MMC3などはこれでもA12の変化を見る
+func (p *ppu) writePPUADDR(value byte) {
+	if p.internalRegister.GetW() == register.WData0 {
+		p.internalRegister.SetUpperPPUAddr(value)
+		p.internalRegister.SetW(register.WData1)
+		return
+	}
+	p.internalRegister.SetLowerPPUAddr(value)
+	p.internalRegister.SetW(register.WData0)
+	if addr := p.internalRegister.GetVRAMAddr(); addr < addrPallet {
+		p.bus.PPUAddress(addr)
+	}
 }
 
 func (p *ppu) writePPUData(value byte) error {
-	panic("todo")
+	if err := p.memory.Write(p.internalRegister.GetVRAMAddr(), value); err != nil {
+		return fmt.Errorf("PPU: failed write memory. err: %w", err)
+	}
+	p.internalRegister.IncrementVRAMAddr(p.vramIncrement())
+	return nil
 }
 
-func (p *ppu) readPPUStatus() (byte, error) {
-	panic("todo")
+// readPPUStatus 読み込むとVBlankのフラグとwがクリアされる
+func (p *ppu) readPPUStatus() byte {
+	value := p.status&0xE0 | p.openBus&0x1F
+	p.status &^= statusVBlank
+	p.internalRegister.SetW(register.WData0)
+	return value
 }
 
+// readPPUData パレット以外は内部バッファの値を返して、バッファを更新する
+// パレットはすぐに返り、バッファにはパレットの下にあるネームテーブルの値が入る
 func (p *ppu) readPPUData() (byte, error) {
-	panic("todo")
+	addr := p.internalRegister.GetVRAMAddr()
+	value, err := p.memory.Read(addr)
+	if err != nil {
+		return 0, fmt.Errorf("PPU: failed read memory. addr: %x, err: %w", addr, err)
+	}
+
+	if addr < addrPallet {
+		value, p.readBuffer = p.readBuffer, value
+	} else {
+		buffer, err := p.memory.Read(addr - 0x1000)
+		if err != nil {
+			return 0, fmt.Errorf("PPU: failed read memory. addr: %x, err: %w", addr-0x1000, err)
+		}
+		p.readBuffer = buffer
+		value = p.openBus&0xC0 | value
+	}
+	p.internalRegister.IncrementVRAMAddr(p.vramIncrement())
+	return value, nil
+}
+
+func (p *ppu) vramIncrement() uint16 {
+	if p.ctrl&ctrlIncrement32 != 0 {
+		return 32
+	}
+	return 1
 }
 
 func IsPPUAddrRange(addr uint16) bool {
-	// TODO OAM_DMAの場合どうなるかを確認すること
 	return addr >= addrPPUStart && addr <= addrPPUEnd
 }
+
+// IsOAMDMAAddr OAMDMAはCPUのメモリから転送する必要があるのでCPUのメモリで処理する
+func IsOAMDMAAddr(addr uint16) bool {
+	return addr == oamDMA
+}
@@ -0,0 +1,139 @@
+package ppu_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+type cartridge struct {
+	chr [0x2000]byte
+}
+
+func (c *cartridge) ReadCHR(addr uint16) byte         { return c.chr[addr] }
+func (c *cartridge) WriteCHR(addr uint16, value byte) { c.chr[addr] = value }
+func (c *cartridge) Mirroring() ppu.Mirroring         { return ppu.MirroringVertical }
+
+// a12Cartridge PPUのアドレスバスのA12の立ち上がりを数える
+// MMC3と同じように、Lowの期間が短い(CPUの3サイクル未満の)立ち上がりは数えない
+type a12Cartridge struct {
+	cartridge
+	ppu    ppu.PPU
+	a12    bool
+	lowAt  int
+	rising int
+}
+
+func (c *a12Cartridge) PPUAddress(addr uint16) {
+	scanline, dot := c.ppu.Position()
+	now := scanline*341 + dot
+	if now < c.lowAt {
+		// pre-renderからscanline 0に戻った
+		now += 341 * 262
+	}
+	a12 := addr&0x1000 != 0
+	if a12 && !c.a12 && now-c.lowAt >= 9 {
+		c.rising++
+	}
+	if !a12 && c.a12 {
+		c.lowAt = scanline*341 + dot
+	}
+	c.a12 = a12
+}
+
+func setAddr(p ppu.PPU, addr uint16) {
+	_ = p.Write(0x2006, byte(addr>>8))
+	_ = p.Write(0x2006, byte(addr))
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestPPU$ github.com/sunjin110/nes_emu/internal/domain/ppu
+func TestPPU(t *testing.T) {
+	Convey("TestPPU", t, func() {
+		p := ppu.NewPPU(&cartridge{})
+
+		Convey("PPUDATAの読み込みは1回遅れて返り、パレットはすぐに返る", func() {
+			setAddr(p, 0x2400)
+			So(p.Write(0x2007, 0x11), ShouldBeNil)
+			So(p.Write(0x2007, 0x22), ShouldBeNil)
+
+			// 垂直ミラーリングなので0x2c00は0x2400と同じ
+			setAddr(p, 0x2c00)
+			_, _ = p.Read(0x2007)
+			value, err := p.Read(0x2007)
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 0x11)
+			value, _ = p.Read(0x200f) // 0x2007のミラー
+			So(value, ShouldEqual, 0x22)
+
+			setAddr(p, 0x3f10)
+			So(p.Write(0x2007, 0x0f), ShouldBeNil)
+			setAddr(p, 0x3f00)
+			value, _ = p.Read(0x2007)
+			So(value, ShouldEqual, 0x0f)
+		})
+
+		Convey("VBlankに入るとフレームが進み、NMIが有効な場合はNMIが発生する", func() {
+			So(p.Write(0x2000, 0x80), ShouldBeNil)
+			var err error
+			for p.Frame() == 0 && err == nil {
+				err = p.Step()
+			}
+			So(err, ShouldBeNil)
+			scanline, dot := p.Position()
+			So(scanline, ShouldEqual, 241)
+			So(dot, ShouldEqual, 1)
+			So(p.PollNMI(), ShouldBeTrue)
+			So(p.PollNMI(), ShouldBeFalse)
+
+			status, _ := p.Read(0x2002)
+			So(status&0x80, ShouldEqual, 0x80)
+			status, _ = p.Read(0x2002)
+			So(status&0x80, ShouldEqual, 0)
+		})
+
+		Convey("背景を描画するとFrameBufferにパレットの色が入る", func() {
+			setAddr(p, 0x3f00)
+			_ = p.Write(0x2007, 0x0f)
+			_ = p.Write(0x2007, 0x30)
+			setAddr(p, 0x0000)
+			_ = p.Write(0x2007, 0xff) // タイル0の1行目を色1にする
+			_ = p.Write(0x2001, 0x0a)
+			setAddr(p, 0x0000)
+
+			var err error
+			for frame := p.Frame(); p.Frame() < frame+2 && err == nil; {
+				err = p.Step()
+			}
+			So(err, ShouldBeNil)
+			So(p.FrameBuffer()[0], ShouldEqual, 0x30)
+			So(p.FrameBuffer()[256], ShouldEqual, 0x0f)
+			So(p.Brightness(0, 0), ShouldBeGreaterThan, 0.85)
+			So(p.Brightness(0, 1), ShouldBeLessThan, 0.1)
+		})
+
+		Convey("背景が0x0000、スプライトが0x1000の場合、A12は描画するscanlineごとに1回立ち上がる", func() {
+			c := &a12Cartridge{}
+			p := ppu.NewPPU(c)
+			c.ppu = p
+			_ = p.Write(0x2000, 0x08)
+			_ = p.Write(0x2001, 0x18)
+
+			var err error
+			for p.Frame() == 0 && err == nil {
+				err = p.Step()
+			}
+			c.rising = 0
+			for frame := p.Frame(); p.Frame() == frame && err == nil; {
+				err = p.Step()
+			}
+			So(err, ShouldBeNil)
+			// 描画する240本とpre-renderの1本
+			So(c.rising, ShouldEqual, 241)
+
+			// PPUADDRの書き込みでもアドレスバスに出る
+			setAddr(p, 0x1000)
+			So(c.rising, ShouldEqual, 242)
+		})
+	})
+}
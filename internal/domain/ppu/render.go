@@ -0,0 +1,338 @@
+package ppu
+
+import (
+	"fmt"
+
+	"github.com/sunjin110/nes_emu/internal/domain/ppu/internal/register"
+)
+
+// 描画のタイミング
+// doc: https://www.nesdev.org/wiki/PPU_rendering
+//
+// 1フレームは262本のscanline(0-239: 描画, 240: post-render, 241-260: VBlank, 261: pre-render)
+// 1本のscanlineは341dot(0-340)
+const (
+	ScreenWidth  = 256
+	ScreenHeight = 240
+
+	dotsPerScanline   = 341
+	scanlinesPerFrame = 262
+	vblankScanline    = 241
+	preRenderScanline = 261
+
+	addrPallet = 0x3F00
+	oamSize    = 256
+	maxSprites = 8
+)
+
+// PPUCTRL, PPUMASK, PPUSTATUSのbit
+const (
+	ctrlIncrement32      byte = 1 << 2
+	ctrlSpriteTable      byte = 1 << 3
+	ctrlBackgroundTable  byte = 1 << 4
+	ctrlSpriteSize16     byte = 1 << 5
+	ctrlNMIEnable        byte = 1 << 7
+	maskGreyscale        byte = 1 << 0
+	maskLeftBackground   byte = 1 << 1
+	maskLeftSprites      byte = 1 << 2
+	maskShowBackground   byte = 1 << 3
+	maskShowSprites      byte = 1 << 4
+	statusSpriteOverflow byte = 1 << 5
+	statusSprite0Hit     byte = 1 << 6
+	statusVBlank         byte = 1 << 7
+)
+
+func (p *ppu) Step() error {
+	p.tick()
+
+	rendering := p.renderingEnabled()
+	preLine := p.scanline == preRenderScanline
+	visibleLine := p.scanline < ScreenHeight
+	renderLine := preLine || visibleLine
+	visibleDot := p.dot >= 1 && p.dot <= 256
+	// 321-336は次のscanlineの最初の2タイルをフェッチする
+	fetchDot := visibleDot || (p.dot >= 321 && p.dot <= 336)
+
+	if rendering {
+		if visibleLine && visibleDot {
+			p.renderPixel()
+		}
+		if renderLine && fetchDot {
+			if err := p.fetchBackground(); err != nil {
+				return fmt.Errorf("PPU: failed fetch background. scanline: %d, dot: %d, err: %w", p.scanline, p.dot, err)
+			}
+		}
+		if renderLine && (p.dot == 337 || p.dot == 339) {
+			// 使われないネームテーブルの読み込み(MMC5は同じアドレスの3回連続の読み込みでscanlineの始まりを検出する)
+			if _, err := p.memory.Read(p.internalRegister.GetTileAddress()); err != nil {
+				return fmt.Errorf("PPU: failed fetch unused nametable. scanline: %d, dot: %d, err: %w", p.scanline, p.dot, err)
+			}
+		}
+		if renderLine {
+			if fetchDot && p.dot%8 == 0 {
+				p.internalRegister.IncrementCoarseX()
+			}
+			if p.dot == 256 {
+				p.internalRegister.IncrementY()
+			}
+			if p.dot == 257 {
+				p.internalRegister.UpdateHorizontalV()
+				p.evaluateSprites(visibleLine)
+			}
+			if p.dot >= 257 && p.dot <= 320 {
+				if err := p.fetchSprite(); err != nil {
+					return fmt.Errorf("PPU: failed fetch sprite. scanline: %d, dot: %d, err: %w", p.scanline, p.dot, err)
+				}
+			}
+		}
+		if renderLine && p.dot == 260 && p.scanlineObserver != nil {
+			p.scanlineObserver.Scanline()
+		}
+		if preLine && p.dot >= 280 && p.dot <= 304 {
+			p.internalRegister.UpdateVerticalV()
+		}
+	}
+
+	if p.scanline == vblankScanline && p.dot == 1 {
+		p.status |= statusVBlank
+		p.frame++
+		if p.ctrl&ctrlNMIEnable != 0 {
+			p.nmi = true
+		}
+	}
+	if preLine && p.dot == 1 {
+		p.status &^= statusVBlank | statusSprite0Hit | statusSpriteOverflow
+	}
+	return nil
+}
+
+// tick 1dot進める、描画中の奇数フレームはpre-renderの最後の1dotを飛ばす
+func (p *ppu) tick() {
+	if p.renderingEnabled() && p.oddFrame && p.scanline == preRenderScanline && p.dot == dotsPerScanline-2 {
+		p.dot = 0
+		p.scanline = 0
+		p.oddFrame = !p.oddFrame
+		return
+	}
+
+	p.dot++
+	if p.dot < dotsPerScanline {
+		return
+	}
+	p.dot = 0
+	p.scanline++
+	if p.scanline >= scanlinesPerFrame {
+		p.scanline = 0
+		p.oddFrame = !p.oddFrame
+	}
+}
+
+func (p *ppu) renderingEnabled() bool {
+	return p.mask&(maskShowBackground|maskShowSprites) != 0
+}
+
+// fetchBackground 8dotごとにネームテーブル、attribute、パターンの下位・上位を読み込む
+func (p *ppu) fetchBackground() error {
+	p.tileData <<= 4
+
+	var err error
+	switch p.dot % 8 {
+	case 1:
+		p.nametableByte, err = p.memory.Read(p.internalRegister.GetTileAddress())
+	case 3:
+		var attribute byte
+		attribute, err = p.memory.Read(p.internalRegister.GetAttributeAddress())
+		// 4x4タイルのうちどの2x2タイルか
+		shift := (p.internalRegister.GetCoarseY(register.TargetV)&0x02)<<1 | p.internalRegister.GetCoarseX(register.TargetV)&0x02
+		p.attributeByte = (attribute >> shift) & 0x03 << 2
+	case 5:
+		p.lowTileByte, err = p.memory.Read(p.backgroundTileAddress())
+	case 7:
+		p.highTileByte, err = p.memory.Read(p.backgroundTileAddress() + 8)
+	case 0:
+		var data uint32
+		for i := 0; i < 8; i++ {
+			pixel := p.attributeByte | (p.lowTileByte&0x80)>>7 | (p.highTileByte&0x80)>>6
+			p.lowTileByte <<= 1
+			p.highTileByte <<= 1
+			data = data<<4 | uint32(pixel)
+		}
+		p.tileData |= uint64(data)
+	}
+	return err
+}
+
+func (p *ppu) backgroundTileAddress() uint16 {
+	var table uint16
+	if p.ctrl&ctrlBackgroundTable != 0 {
+		table = 0x1000
+	}
+	return table + uint16(p.nametableByte)*16 + uint16(p.internalRegister.GetFineY(register.TargetV))
+}
+
+func (p *ppu) renderPixel() {
+	x := p.dot - 1
+	y := p.scanline
+
+	var background byte
+	if p.mask&maskShowBackground != 0 && (x >= 8 || p.mask&maskLeftBackground != 0) {
+		background = byte(p.tileData>>32>>((7-p.internalRegister.GetFindX())*4)) & 0x0F
+	}
+	index, sprite := p.spritePixel(x)
+	if p.mask&maskShowSprites == 0 || (x < 8 && p.mask&maskLeftSprites == 0) {
+		sprite = 0
+	}
+
+	opaqueBackground := background%4 != 0
+	opaqueSprite := sprite%4 != 0
+
+	var color byte
+	switch {
+	case !opaqueBackground && !opaqueSprite:
+		color = 0
+	case !opaqueBackground:
+		color = sprite | 0x10
+	case !opaqueSprite:
+		color = background
+	default:
+		if p.spriteIndexes[index] == 0 && x < 255 {
+			p.status |= statusSprite0Hit
+		}
+		if p.spritePriorities[index] == 0 {
+			color = sprite | 0x10
+		} else {
+			color = background
+		}
+	}
+
+	// パレットの読み込みでエラーになることはない
+	value, _ := p.memory.Read(addrPallet + uint16(color))
+	if p.mask&maskGreyscale != 0 {
+		value &= 0x30
+	}
+	p.frameBuffer[y*ScreenWidth+x] = value & 0x3F
+}
+
+// spritePixel xの位置にある最初の不透明なスプライトのピクセル
+func (p *ppu) spritePixel(x int) (index int, pixel byte) {
+	for i := 0; i < p.spriteCount; i++ {
+		offset := x - int(p.spritePositions[i])
+		if offset < 0 || offset > 7 {
+			continue
+		}
+		pixel := byte(p.spritePatterns[i]>>((7-offset)*4)) & 0x0F
+		if pixel%4 == 0 {
+			continue
+		}
+		return i, pixel
+	}
+	return 0, 0
+}
+
+// evaluateSprites 次のscanlineに描画するスプライトを最大8個選ぶ、パターンはdot 257-320でfetchSpriteが読み込む
+func (p *ppu) evaluateSprites(visibleLine bool) {
+	height := p.spriteHeight()
+	count := 0
+	for i := 0; i < oamSize/4 && visibleLine; i++ {
+		row := p.scanline - int(p.oam[i*4])
+		if row < 0 || row >= height {
+			continue
+		}
+		if count >= maxSprites {
+			p.status |= statusSpriteOverflow
+			break
+		}
+		p.spritePositions[count] = p.oam[i*4+3]
+		p.spritePriorities[count] = (p.oam[i*4+2] >> 5) & 0x01
+		p.spriteIndexes[count] = byte(i)
+		count++
+	}
+	p.spriteCount = count
+}
+
+func (p *ppu) spriteHeight() int {
+	if p.ctrl&ctrlSpriteSize16 != 0 {
+		return 16
+	}
+	return 8
+}
+
+// fetchSprite dot 257-320の8dotごとに1スロット分、使われないネームテーブルを2回読んでからパターンの下位・上位を読み込む
+// 空きのスロットはタイル0xFFのパターンを読み込む(MMC3のIRQやMMC5のようにPPUのアドレスを見ているmapperのため)
+func (p *ppu) fetchSprite() error {
+	slot := (p.dot - 257) / 8
+	switch (p.dot - 257) % 8 {
+	case 0, 2:
+		_, err := p.memory.Read(p.internalRegister.GetTileAddress())
+		return err
+	case 4:
+		var err error
+		p.spriteLowByte, err = p.memory.Read(p.spritePatternAddress(slot))
+		return err
+	case 6:
+		high, err := p.memory.Read(p.spritePatternAddress(slot) + 8)
+		if err != nil {
+			return err
+		}
+		if slot < p.spriteCount {
+			p.spritePatterns[slot] = spritePattern(p.oam[int(p.spriteIndexes[slot])*4+2], p.spriteLowByte, high)
+		}
+	}
+	return nil
+}
+
+// spritePatternAddress スロットのスプライトのパターンの下位のアドレス
+func (p *ppu) spritePatternAddress(slot int) uint16 {
+	height := p.spriteHeight()
+	if slot >= p.spriteCount {
+		return p.spriteTileAddress(0xFF, 0, height)
+	}
+
+	index := int(p.spriteIndexes[slot])
+	row := p.scanline - int(p.oam[index*4])
+	if p.oam[index*4+2]&0x80 != 0 {
+		// 上下反転
+		row = height - 1 - row
+	}
+	return p.spriteTileAddress(p.oam[index*4+1], row, height)
+}
+
+// spritePattern 8ピクセル分(4bit: palette 2bit + pattern 2bit)
+func spritePattern(attributes byte, low byte, high byte) uint32 {
+	palette := (attributes & 0x03) << 2
+	var data uint32
+	for i := 0; i < 8; i++ {
+		var pixel byte
+		if attributes&0x40 != 0 {
+			// 左右反転
+			pixel = palette | low&0x01 | (high&0x01)<<1
+			low >>= 1
+			high >>= 1
+		} else {
+			pixel = palette | (low&0x80)>>7 | (high&0x80)>>6
+			low <<= 1
+			high <<= 1
+		}
+		data = data<<4 | uint32(pixel)
+	}
+	return data
+}
+
+// spriteTileAddress 8x16の場合はタイル番号のbit0がパターンテーブルになる
+func (p *ppu) spriteTileAddress(tile byte, row int, height int) uint16 {
+	if height == 8 {
+		var table uint16
+		if p.ctrl&ctrlSpriteTable != 0 {
+			table = 0x1000
+		}
+		return table + uint16(tile)*16 + uint16(row)
+	}
+
+	table := uint16(tile&0x01) * 0x1000
+	tile &= 0xFE
+	if row > 7 {
+		tile++
+		row -= 8
+	}
+	return table + uint16(tile)*16 + uint16(row)
+}
@@ -0,0 +1,107 @@
+package ppu_test
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/ppu"
+)
+
+// stepFrame 次のフレームのVBlankまで進めて、かかったdot数を返す
+func stepFrame(p ppu.PPU) (int, error) {
+	dots := 0
+	for frame := p.Frame(); p.Frame() == frame; dots++ {
+		if err := p.Step(); err != nil {
+			return 0, err
+		}
+	}
+	return dots, nil
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestPPU_Timing$ github.com/sunjin110/nes_emu/internal/domain/ppu
+func TestPPU_Timing(t *testing.T) {
+	Convey("TestPPU_Timing", t, func() {
+		p := ppu.NewPPU(&cartridge{})
+		_, err := stepFrame(p)
+		So(err, ShouldBeNil)
+
+		Convey("描画していない場合は1フレーム341dot x 262scanline", func() {
+			for i := 0; i < 2; i++ {
+				dots, err := stepFrame(p)
+				So(err, ShouldBeNil)
+				So(dots, ShouldEqual, 341*262)
+			}
+		})
+
+		Convey("描画中は奇数フレームだけpre-renderの最後の1dotを飛ばす", func() {
+			So(p.Write(0x2001, 0x08), ShouldBeNil)
+			first, err := stepFrame(p)
+			So(err, ShouldBeNil)
+			second, err := stepFrame(p)
+			So(err, ShouldBeNil)
+			So(first+second, ShouldEqual, 341*262*2-1)
+			So(first, ShouldNotEqual, second)
+		})
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestPPU_Sprite0Hit$ github.com/sunjin110/nes_emu/internal/domain/ppu
+func TestPPU_Sprite0Hit(t *testing.T) {
+	Convey("TestPPU_Sprite0Hit", t, func() {
+		p := ppu.NewPPU(&cartridge{})
+		// タイル0の1行目だけ不透明、ネームテーブルは全てタイル0なので8scanlineごとに不透明な行がある
+		setAddr(p, 0x0000)
+		_ = p.Write(0x2007, 0xff)
+
+		setSprite0 := func(y, x byte) {
+			_ = p.Write(0x2003, 0x00)
+			for _, value := range []byte{y, 0x00, 0x00, x} {
+				_ = p.Write(0x2004, value)
+			}
+		}
+		// hitScanline sprite 0 hitが立ったscanline、立たなかった場合は-1
+		hitScanline := func() int {
+			_, _ = stepFrame(p)
+			_ = p.Write(0x2001, 0x1e)
+			for frame := p.Frame(); p.Frame() == frame; {
+				if err := p.Step(); err != nil {
+					return -1
+				}
+				if status, _ := p.Read(0x2002); status&0x40 != 0 {
+					scanline, _ := p.Position()
+					return scanline
+				}
+			}
+			return -1
+		}
+
+		Convey("sprite 0と背景の不透明なピクセルが重なったscanlineでフラグが立つ", func() {
+			// OAMのYは1scanline遅れて描画される
+			setSprite0(7, 16)
+			So(hitScanline(), ShouldEqual, 8)
+
+			Convey("フラグはpre-render scanlineで消える", func() {
+				_, err := stepFrame(p)
+				So(err, ShouldBeNil)
+				for {
+					So(p.Step(), ShouldBeNil)
+					if scanline, dot := p.Position(); scanline == 261 && dot == 2 {
+						break
+					}
+				}
+				status, _ := p.Read(0x2002)
+				So(status&0x40, ShouldEqual, 0)
+			})
+		})
+
+		Convey("背景が透明な位置ではフラグが立たない", func() {
+			setSprite0(8, 16)
+			So(hitScanline(), ShouldEqual, -1)
+		})
+
+		Convey("x=255ではフラグが立たない", func() {
+			setSprite0(7, 255)
+			So(hitScanline(), ShouldEqual, -1)
+		})
+	})
+}
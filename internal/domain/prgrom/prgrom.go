@@ -4,3 +4,19 @@ type PRGROM interface {
 	Read(addr uint16) byte
 	InitPC() uint16
 }
+
+// ExpansionPRGROM 0x4020〜0x7FFFの読み書きと、PRGROMの領域への書き込みを受け付けるPRGROM
+// NSFのバンク切り替えレジスタ(0x5FF8〜0x5FFF)やWRAM(0x6000〜0x7FFF)のために使う
+type ExpansionPRGROM interface {
+	PRGROM
+	Write(addr uint16, value byte)
+}
+
+const (
+	addrExpansionStart = 0x4020
+	addrExpansionEnd   = 0x7FFF
+)
+
+func IsExpansionRange(addr uint16) bool {
+	return addr >= addrExpansionStart && addr <= addrExpansionEnd
+}
@@ -0,0 +1,239 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+)
+
+const (
+	DefaultSampleRate = 44100
+
+	// 実機の出力段にある90Hzのハイパスフィルタ、DC成分を取り除く
+	// doc: https://www.nesdev.org/wiki/APU_Mixer
+	highPassCutoff = 90.0
+)
+
+type RecorderOption struct {
+	SampleRate int // 0の場合はDefaultSampleRate
+	// ClockRate WriteSampleが呼ばれるCPUのクロック周波数(Hz)、0の場合はapu.CPUClockNTSC
+	// PAL, Dendyのゲームはcartridge.Timing.CPUClockを渡す
+	ClockRate int
+
+	// trueの場合はミックスした音声とは別に、チャンネルごとのファイルも書き出す
+	// out.wav の場合は out.pulse1.wav, out.pulse2.wav, out.triangle.wav, out.noise.wav, out.dmc.wav, out.expansion.wav
+	// expansionは拡張音源の出力の合計
+	SplitChannels bool
+}
+
+// AudioRecorder APUの出力をWAVファイルに書き出す
+// apu.SampleSinkを実装しているので、APU.SetSampleSinkで設定して使う
+type AudioRecorder struct {
+	tracks     []*track
+	sampleRate int
+	clockRate  int
+	phase      int
+	count      int
+	err        error
+}
+
+// trackOutput 書き出し先のファイルと、サンプルから書き出す値を取り出す関数
+type trackOutput struct {
+	path  string
+	value func(sample apu.Sample) float32
+}
+
+// track 1つのWAVファイルに書き出す出力
+type track struct {
+	name   string
+	file   *os.File // NewStreamRecorderの場合はnil
+	wav    *WAVWriter
+	value  func(sample apu.Sample) float32
+	sum    float64
+	filter highPassFilter
+}
+
+func NewAudioRecorder(path string, option RecorderOption) (*AudioRecorder, error) {
+	sampleRate := option.SampleRate
+	if sampleRate == 0 {
+		sampleRate = DefaultSampleRate
+	}
+	clockRate := option.ClockRate
+	if clockRate == 0 {
+		clockRate = apu.CPUClockNTSC
+	}
+	if clockRate < 0 {
+		return nil, fmt.Errorf("AudioRecorder: invalid clock rate. clockRate: %d", clockRate)
+	}
+	if sampleRate < 0 || sampleRate > clockRate {
+		return nil, fmt.Errorf("AudioRecorder: invalid sample rate. sampleRate: %d", sampleRate)
+	}
+
+	recorder := &AudioRecorder{
+		sampleRate: sampleRate,
+		clockRate:  clockRate,
+	}
+
+	outputs := []trackOutput{
+		{path: path, value: func(s apu.Sample) float32 { return s.Mixed }},
+	}
+	if option.SplitChannels {
+		outputs = append(outputs,
+			trackOutput{path: channelPath(path, "pulse1"), value: func(s apu.Sample) float32 { return s.Pulse1 }},
+			trackOutput{path: channelPath(path, "pulse2"), value: func(s apu.Sample) float32 { return s.Pulse2 }},
+			trackOutput{path: channelPath(path, "triangle"), value: func(s apu.Sample) float32 { return s.Triangle }},
+			trackOutput{path: channelPath(path, "noise"), value: func(s apu.Sample) float32 { return s.Noise }},
+			trackOutput{path: channelPath(path, "dmc"), value: func(s apu.Sample) float32 { return s.DMC }},
+			trackOutput{path: channelPath(path, "expansion"), value: func(s apu.Sample) float32 { return s.Expansion }},
+		)
+	}
+
+	for _, output := range outputs {
+		t, err := newTrack(output.path, sampleRate, output.value)
+		if err != nil {
+			_ = recorder.Close()
+			return nil, fmt.Errorf("AudioRecorder: failed create track. err: %w", err)
+		}
+		recorder.tracks = append(recorder.tracks, t)
+	}
+	return recorder, nil
+}
+
+func newTrack(path string, sampleRate int, value func(sample apu.Sample) float32) (*track, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed create file. path: %s, err: %w", path, err)
+	}
+	wav, err := NewWAVWriter(file, sampleRate, 1)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed new wav writer. path: %s, err: %w", path, err)
+	}
+	return &track{
+		name:   path,
+		file:   file,
+		wav:    wav,
+		value:  value,
+		filter: newHighPassFilter(highPassCutoff, sampleRate),
+	}, nil
+}
+
+// NewStreamRecorder 標準出力などのio.Writerにミックスした音声をWAVとして書き出す
+// wはCloseで閉じないので呼び出し元で閉じること
+func NewStreamRecorder(w io.Writer, sampleRate int) (*AudioRecorder, error) {
+	if sampleRate == 0 {
+		sampleRate = DefaultSampleRate
+	}
+	if sampleRate < 0 || sampleRate > apu.CPUClockNTSC {
+		return nil, fmt.Errorf("AudioRecorder: invalid sample rate. sampleRate: %d", sampleRate)
+	}
+
+	wav, err := NewWAVWriter(w, sampleRate, 1)
+	if err != nil {
+		return nil, fmt.Errorf("AudioRecorder: failed new wav writer. err: %w", err)
+	}
+	return &AudioRecorder{
+		sampleRate: sampleRate,
+		clockRate:  apu.CPUClockNTSC,
+		tracks: []*track{
+			{
+				name:   "stream",
+				wav:    wav,
+				value:  func(s apu.Sample) float32 { return s.Mixed },
+				filter: newHighPassFilter(highPassCutoff, sampleRate),
+			},
+		},
+	}, nil
+}
+
+// channelPath out.wav -> out.pulse1.wav
+func channelPath(path string, channel string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + channel + ext
+}
+
+// WriteSample CPUサイクルごとのサンプルを受け取り、サンプリングレートに合わせて平均をとって書き出す
+// 書き込みのエラーはCloseで返す
+func (r *AudioRecorder) WriteSample(sample apu.Sample) {
+	if r.err != nil {
+		return
+	}
+
+	for _, t := range r.tracks {
+		t.sum += float64(t.value(sample))
+	}
+	r.count++
+
+	r.phase += r.sampleRate
+	if r.phase < r.clockRate {
+		return
+	}
+	r.phase -= r.clockRate
+
+	for _, t := range r.tracks {
+		value := t.filter.apply(t.sum / float64(r.count))
+		t.sum = 0
+		if err := t.wav.WriteSamples(toPCM16(value)); err != nil {
+			r.err = fmt.Errorf("AudioRecorder: failed write sample. err: %w", err)
+			return
+		}
+	}
+	r.count = 0
+}
+
+// Close WAVのヘッダーを確定させてファイルを閉じる
+func (r *AudioRecorder) Close() error {
+	errs := []error{r.err}
+	for _, t := range r.tracks {
+		if err := t.wav.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("AudioRecorder: failed close wav. name: %s, err: %w", t.name, err))
+		}
+		if t.file == nil {
+			continue
+		}
+		if err := t.file.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("AudioRecorder: failed close file. name: %s, err: %w", t.name, err))
+		}
+	}
+	r.tracks = nil
+	return errors.Join(errs...)
+}
+
+func toPCM16(value float64) int16 {
+	v := math.Round(value * math.MaxInt16)
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
+
+// highPassFilter 1次のハイパスフィルタ
+type highPassFilter struct {
+	alpha     float64
+	prevInput float64
+	prevOut   float64
+}
+
+func newHighPassFilter(cutoff float64, sampleRate int) highPassFilter {
+	rc := 1 / (2 * math.Pi * cutoff)
+	dt := 1 / float64(sampleRate)
+	return highPassFilter{
+		alpha: rc / (rc + dt),
+	}
+}
+
+func (f *highPassFilter) apply(input float64) float64 {
+	out := f.alpha * (f.prevOut + input - f.prevInput)
+	f.prevInput = input
+	f.prevOut = out
+	return out
+}
@@ -0,0 +1,131 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// RIFF/WAVEの16bit PCMのヘッダーサイズ
+// doc: http://soundfile.sapp.org/doc/WaveFormat/
+const (
+	wavHeaderSize    = 44
+	wavBitsPerSample = 16
+)
+
+// streamDataSize 書き込み先がSeekできない場合のデータサイズ、長さ不明のストリームとして扱われる
+const streamDataSize = 0xFFFFFFFF - (wavHeaderSize - 8)
+
+// WAVWriter 16bit PCMのWAVファイルを書き込む
+// データサイズは書き込み終わるまでわからないので、io.WriteSeekerの場合はCloseでヘッダーを書き直す
+// 標準出力のようにSeekできない場合は長さ不明のストリームとしてヘッダーを書き込む
+type WAVWriter struct {
+	w          io.Writer
+	buf        *bufio.Writer
+	seeker     io.WriteSeeker // Seekできない場合はnil
+	sampleRate int
+	channels   int
+	dataSize   uint32
+}
+
+func NewWAVWriter(w io.Writer, sampleRate int, channels int) (*WAVWriter, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("WAVWriter: invalid sample rate. sampleRate: %d", sampleRate)
+	}
+	if channels <= 0 {
+		return nil, fmt.Errorf("WAVWriter: invalid channels. channels: %d", channels)
+	}
+
+	writer := &WAVWriter{
+		w:          w,
+		buf:        bufio.NewWriter(w),
+		sampleRate: sampleRate,
+		channels:   channels,
+	}
+
+	// パイプの場合は*os.Fileでも実際にはSeekできないので確認する
+	if seeker, ok := w.(io.WriteSeeker); ok {
+		if _, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			writer.seeker = seeker
+		}
+	}
+
+	// ヘッダーを仮に書き込んでおく
+	if writer.seeker == nil {
+		writer.dataSize = streamDataSize
+	}
+	if err := writer.writeHeader(writer.buf); err != nil {
+		return nil, fmt.Errorf("WAVWriter: failed write header. err: %w", err)
+	}
+	return writer, nil
+}
+
+// WriteSamples チャンネル数分のサンプルをインターリーブして書き込む
+func (w *WAVWriter) WriteSamples(samples ...int16) error {
+	if len(samples)%w.channels != 0 {
+		return fmt.Errorf("WAVWriter: samples must be a multiple of channels. samples: %d, channels: %d", len(samples), w.channels)
+	}
+	for _, sample := range samples {
+		if err := binary.Write(w.buf, binary.LittleEndian, sample); err != nil {
+			return fmt.Errorf("WAVWriter: failed write sample. err: %w", err)
+		}
+	}
+	if w.dataSize != streamDataSize {
+		w.dataSize += uint32(len(samples) * wavBitsPerSample / 8)
+	}
+	return nil
+}
+
+// Close 書き込んだデータサイズでヘッダーを書き直す
+// 元のio.Writerは閉じないので呼び出し元で閉じること
+func (w *WAVWriter) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("WAVWriter: failed flush. err: %w", err)
+	}
+	seeker := w.seeker
+	if seeker == nil {
+		return nil
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("WAVWriter: failed seek to header. err: %w", err)
+	}
+	if err := w.writeHeader(seeker); err != nil {
+		return fmt.Errorf("WAVWriter: failed rewrite header. err: %w", err)
+	}
+	if _, err := seeker.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("WAVWriter: failed seek to end. err: %w", err)
+	}
+	return nil
+}
+
+func (w *WAVWriter) writeHeader(out io.Writer) error {
+	blockAlign := w.channels * wavBitsPerSample / 8
+	byteRate := w.sampleRate * blockAlign
+
+	header := []any{
+		[4]byte{'R', 'I', 'F', 'F'},
+		uint32(wavHeaderSize - 8 + w.dataSize),
+		[4]byte{'W', 'A', 'V', 'E'},
+
+		// fmt chunk
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(16), // chunk size
+		uint16(1),  // PCM
+		uint16(w.channels),
+		uint32(w.sampleRate),
+		uint32(byteRate),
+		uint16(blockAlign),
+		uint16(wavBitsPerSample),
+
+		// data chunk
+		[4]byte{'d', 'a', 't', 'a'},
+		w.dataSize,
+	}
+	for _, v := range header {
+		if err := binary.Write(out, binary.LittleEndian, v); err != nil {
+			return fmt.Errorf("failed write wav header. err: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package audio_test
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/sunjin110/nes_emu/internal/domain/apu"
+	"github.com/sunjin110/nes_emu/internal/infrastructure/audio"
+)
+
+// go test -v -count=1 -timeout 30s -run ^TestWAVWriter$ github.com/sunjin110/nes_emu/internal/infrastructure/audio
+func TestWAVWriter(t *testing.T) {
+	Convey("TestWAVWriter", t, func() {
+		path := filepath.Join(t.TempDir(), "out.wav")
+		f, err := os.Create(path)
+		So(err, ShouldBeNil)
+
+		w, err := audio.NewWAVWriter(f, 44100, 1)
+		So(err, ShouldBeNil)
+		So(w.WriteSamples(0, 1, -1, 32767), ShouldBeNil)
+		So(w.Close(), ShouldBeNil)
+		So(f.Close(), ShouldBeNil)
+
+		data, err := os.ReadFile(path)
+		So(err, ShouldBeNil)
+		So(len(data), ShouldEqual, 44+8)
+		So(string(data[0:4]), ShouldEqual, "RIFF")
+		So(binary.LittleEndian.Uint32(data[4:8]), ShouldEqual, 36+8)
+		So(string(data[8:12]), ShouldEqual, "WAVE")
+		So(binary.LittleEndian.Uint32(data[24:28]), ShouldEqual, 44100)
+		So(binary.LittleEndian.Uint16(data[34:36]), ShouldEqual, 16)
+		So(string(data[36:40]), ShouldEqual, "data")
+		So(binary.LittleEndian.Uint32(data[40:44]), ShouldEqual, 8)
+		So(int16(binary.LittleEndian.Uint16(data[48:50])), ShouldEqual, -1)
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestAudioRecorder$ github.com/sunjin110/nes_emu/internal/infrastructure/audio
+func TestAudioRecorder(t *testing.T) {
+	Convey("TestAudioRecorder", t, func() {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "out.wav")
+		recorder, err := audio.NewAudioRecorder(path, audio.RecorderOption{
+			SampleRate:    48000,
+			SplitChannels: true,
+		})
+		So(err, ShouldBeNil)
+
+		// 1秒分のCPUサイクル
+		for i := 0; i < 1789773; i++ {
+			recorder.WriteSample(apuSample(i))
+		}
+		So(recorder.Close(), ShouldBeNil)
+
+		for _, name := range []string{"out.wav", "out.pulse1.wav", "out.pulse2.wav", "out.triangle.wav", "out.noise.wav", "out.dmc.wav", "out.expansion.wav"} {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			So(err, ShouldBeNil)
+			So(binary.LittleEndian.Uint32(data[40:44]), ShouldEqual, 48000*2)
+		}
+	})
+}
+
+// go test -v -count=1 -timeout 30s -run ^TestAudioRecorder_ClockRate$ github.com/sunjin110/nes_emu/internal/infrastructure/audio
+func TestAudioRecorder_ClockRate(t *testing.T) {
+	Convey("TestAudioRecorder_ClockRate", t, func() {
+		path := filepath.Join(t.TempDir(), "out.wav")
+		recorder, err := audio.NewAudioRecorder(path, audio.RecorderOption{
+			SampleRate: 48000,
+			ClockRate:  apu.CPUClockPAL,
+		})
+		So(err, ShouldBeNil)
+
+		// PALの1秒分のCPUサイクルで1秒分のサンプルになる
+		for i := 0; i < apu.CPUClockPAL; i++ {
+			recorder.WriteSample(apuSample(i))
+		}
+		So(recorder.Close(), ShouldBeNil)
+
+		data, err := os.ReadFile(path)
+		So(err, ShouldBeNil)
+		So(binary.LittleEndian.Uint32(data[40:44]), ShouldEqual, 48000*2)
+
+		_, err = audio.NewAudioRecorder(path, audio.RecorderOption{ClockRate: -1})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func apuSample(cycle int) apu.Sample {
+	v := float32(0)
+	if (cycle/1000)%2 == 0 {
+		v = 0.1
+	}
+	return apu.Sample{Mixed: v, Pulse1: v}
+}
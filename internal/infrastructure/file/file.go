@@ -3,12 +3,74 @@ package file
 import (
 	"fmt"
 	"os"
+
+	"github.com/sunjin110/nes_emu/internal/domain/cartridge"
+	"github.com/sunjin110/nes_emu/internal/domain/fds"
+	"github.com/sunjin110/nes_emu/internal/domain/nsf"
 )
 
-func LoadNESFile(path string) ([]byte, error) {
+// Format ファイルの先頭のマジックナンバーで判定した形式
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatINES           // iNES, NES 2.0
+	FormatUNIF           // UNIF
+	FormatNSF            // NSF, NSFe
+	FormatFDS            // ディスクシステムの.fds, .qd
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatINES:
+		return "iNES"
+	case FormatUNIF:
+		return "UNIF"
+	case FormatNSF:
+		return "NSF"
+	case FormatFDS:
+		return "FDS"
+	}
+	return "unknown"
+}
+
+// IsCartridge cartridge.NewCartridgeで読み込める形式か
+func (f Format) IsCartridge() bool {
+	return f == FormatINES || f == FormatUNIF
+}
+
+// DetectFormat 拡張子ではなく中身で判定する
+func DetectFormat(data []byte) Format {
+	switch {
+	case cartridge.IsINES(data):
+		return FormatINES
+	case cartridge.IsUNIF(data):
+		return FormatUNIF
+	case nsf.IsNSF(data):
+		return FormatNSF
+	case fds.IsDisk(data):
+		return FormatFDS
+	}
+	return FormatUnknown
+}
+
+// LoadNESFile ROMかNSFのファイルを読み込んで形式を判定する、対応していない形式の場合はエラー
+func LoadNESFile(path string) ([]byte, Format, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed read file. path: %s, err: %w", path, err)
+		return nil, FormatUnknown, fmt.Errorf("failed read file. path: %s, err: %w", path, err)
+	}
+	format, err := detectNESFile(path, data)
+	if err != nil {
+		return nil, FormatUnknown, err
+	}
+	return data, format, nil
+}
+
+func detectNESFile(path string, data []byte) (Format, error) {
+	format := DetectFormat(data)
+	if format == FormatUnknown {
+		return FormatUnknown, fmt.Errorf("unknown file format. path: %s", path)
 	}
-	return data, nil
+	return format, nil
 }
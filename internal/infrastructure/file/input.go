@@ -0,0 +1,27 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// InputConfigPath ROMと同じディレクトリにある、拡張子を.inputにしたパス
+func InputConfigPath(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".input"
+}
+
+// LoadInputConfigFile 入力デバイスの設定ファイルを読み込む、ファイルがない場合はnil
+func LoadInputConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed read input config. path: %s, err: %w", path, err)
+	}
+	return data, nil
+}
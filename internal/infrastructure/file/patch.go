@@ -0,0 +1,54 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sunjin110/nes_emu/internal/domain/patch"
+)
+
+// patchExtensions ROMと同じ場所から探すパッチの拡張子、複数ある場合は先にあるものを使う
+var patchExtensions = []string{".bps", ".ups", ".ips"}
+
+// FindPatch ROMと同じディレクトリにある、拡張子だけ違うパッチのパス、ない場合は""
+func FindPatch(romPath string) (string, error) {
+	base := strings.TrimSuffix(romPath, filepath.Ext(romPath))
+	for _, ext := range patchExtensions {
+		path := base + ext
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed stat patch. path: %s, err: %w", path, err)
+		}
+	}
+	return "", nil
+}
+
+// LoadPatchedNESFile ROMにパッチ(IPS, UPS, BPS)を順に当ててから形式を判定する
+// 元のファイルは書き換えない
+func LoadPatchedNESFile(path string, patchPaths []string) ([]byte, Format, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, FormatUnknown, fmt.Errorf("failed read file. path: %s, err: %w", path, err)
+	}
+	for _, patchPath := range patchPaths {
+		p, err := os.ReadFile(patchPath)
+		if err != nil {
+			return nil, FormatUnknown, fmt.Errorf("failed read patch. path: %s, err: %w", patchPath, err)
+		}
+		if data, err = patch.Apply(data, p); err != nil {
+			return nil, FormatUnknown, fmt.Errorf("failed apply patch. path: %s, err: %w", patchPath, err)
+		}
+	}
+	format, err := detectNESFile(path, data)
+	if err != nil {
+		return nil, FormatUnknown, err
+	}
+	return data, format, nil
+}
@@ -0,0 +1,53 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SaveFilePath ROMと同じディレクトリにある、拡張子を.savにしたパス
+func SaveFilePath(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sav"
+}
+
+// LoadSaveFile .savファイルを読み込む、ファイルがない場合はnil
+func LoadSaveFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed read save file. path: %s, err: %w", path, err)
+	}
+	return data, nil
+}
+
+// WriteSaveFile 書き込みの途中で終了してもセーブデータが壊れないように、一時ファイルに書いてからrenameする
+func WriteSaveFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed create temp save file. path: %s, err: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTempは0600で作るので、通常のファイルと同じにする
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed chmod save file. path: %s, err: %w", path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed write save file. path: %s, err: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed close save file. path: %s, err: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed rename save file. path: %s, err: %w", path, err)
+	}
+	return nil
+}
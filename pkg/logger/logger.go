@@ -14,7 +14,8 @@ type loggerItf interface {
 	Error(msg string, args ...any)
 }
 
+// 標準出力はnes nsf playのWAVなどのデータに使うので、ログは標準エラー出力に書く
 func init() {
-	slogLogger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	slogLogger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	Logger = slogLogger
 }